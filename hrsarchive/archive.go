@@ -0,0 +1,283 @@
+package hrsarchive
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+)
+
+const (
+	// NDJSON Constant
+	NDJSON = "ndjson"
+	// ARCHIVE Constant
+	ARCHIVE = "tar.gz"
+	// NDJSONTYPE Constant
+	NDJSONTYPE = "application/x-ndjson"
+	// ARCHIVETYPE Constant
+	ARCHIVETYPE = "application/gzip"
+	// RECIPE Constant
+	RECIPE = "recipe"
+	// INGREDIENT Constant
+	INGREDIENT = "ingredient"
+	// MAXRECORDSIZE Constant
+	MAXRECORDSIZE = 4 << 20
+)
+
+// ErrFormat - the format is neither NDJSON nor an archive
+var ErrFormat = errors.New("unknown export format")
+
+// folders - the archive folder of every kind of record
+var folders = map[string]string{RECIPE: "recipes", INGREDIENT: "ingredients"}
+
+// Record - one element of the collection: {"kind": "recipe", "recipe": {...}}
+type Record struct {
+	Kind       string               `json:"kind"`
+	Recipe     *hrsmodel.Recipe     `json:"recipe,omitempty"`
+	Ingredient *hrsmodel.Ingredient `json:"ingredient,omitempty"`
+}
+
+// Code - the code of the element the record holds
+func (r *Record) Code() string {
+	switch {
+	case r.Recipe != nil:
+		return r.Recipe.Code
+	case r.Ingredient != nil:
+		return r.Ingredient.Code
+	}
+	return ""
+}
+
+// RecordError - a record which can't be read; Position is the line or the
+// archive entry it came from. Reading goes on with the next record.
+type RecordError struct {
+	Position string
+	Err      error
+}
+
+func (e *RecordError) Error() string {
+	return e.Position + ": " + e.Err.Error()
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// Writer - writes the records of a collection one at a time
+type Writer interface {
+	Write(record *Record) error
+	Close() error
+}
+
+// ImageWriter - a writer which keeps image files too, like the archive
+type ImageWriter interface {
+	WriteImage(recipe string, name string, size int64, data io.Reader) error
+}
+
+// Reader - reads the records of a collection one at a time. Next gives
+// io.EOF at the end and a *RecordError for a record it can't read; other
+// errors end the reading. Position tells where the last record came from.
+type Reader interface {
+	Next() (*Record, error)
+	Position() string
+}
+
+// MediaType - the media type of a format
+func MediaType(format string) string {
+	if format == ARCHIVE {
+		return ARCHIVETYPE
+	}
+	return NDJSONTYPE
+}
+
+// NewWriter - a writer of the format: NDJSON, one record per line, or a
+// tar.gz with a JSON file per record and the images of the recipes
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case NDJSON:
+		return &ndjsonWriter{enc: json.NewEncoder(w)}, nil
+	case ARCHIVE:
+		gz := gzip.NewWriter(w)
+		return &archiveWriter{gz: gz, tw: tar.NewWriter(gz), now: time.Now()}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrFormat, format)
+	}
+}
+
+// NewReader - a reader of what NewWriter writes, the format told by the
+// first bytes
+func NewReader(r io.Reader) (Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return &archiveReader{tr: tar.NewReader(gz)}, nil
+	}
+	return newNDJSONReader(br, "line"), nil
+}
+
+/** PRIVATE METHODS **/
+
+// ndjsonWriter - one record per line
+type ndjsonWriter struct {
+	enc *json.Encoder
+}
+
+func (n *ndjsonWriter) Write(record *Record) error {
+	return n.enc.Encode(record)
+}
+
+func (n *ndjsonWriter) Close() error {
+	return nil
+}
+
+// archiveWriter - a tar.gz with ingredients/<code>.json,
+// recipes/<code>.json and images/<recipe>/<name> entries
+type archiveWriter struct {
+	gz  *gzip.Writer
+	tw  *tar.Writer
+	now time.Time
+}
+
+func (a *archiveWriter) Write(record *Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	name := path.Join(folders[record.Kind], url.PathEscape(record.Code())+".json")
+	return a.entry(name, int64(len(data)), bytes.NewReader(data))
+}
+
+func (a *archiveWriter) WriteImage(recipe string, name string, size int64, data io.Reader) error {
+	return a.entry(path.Join("images", url.PathEscape(recipe), url.PathEscape(name)), size, data)
+}
+
+func (a *archiveWriter) Close() error {
+	if err := a.tw.Close(); err != nil {
+		return err
+	}
+	return a.gz.Close()
+}
+
+// entry - adds a file of the given size
+func (a *archiveWriter) entry(name string, size int64, data io.Reader) error {
+	header := &tar.Header{Name: name, Mode: 0644, Size: size, ModTime: a.now, Typeflag: tar.TypeReg}
+	if err := a.tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := io.Copy(a.tw, data)
+	return err
+}
+
+// ndjsonReader - reads line after line, none longer than MAXRECORDSIZE;
+// name tells where the lines come from in errors
+type ndjsonReader struct {
+	sc   *bufio.Scanner
+	name string
+	line int
+}
+
+func newNDJSONReader(r io.Reader, name string) *ndjsonReader {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64<<10), MAXRECORDSIZE)
+	return &ndjsonReader{sc: sc, name: name}
+}
+
+func (n *ndjsonReader) Position() string {
+	return fmt.Sprintf("%s %d", n.name, n.line)
+}
+
+func (n *ndjsonReader) Next() (*Record, error) {
+	for n.sc.Scan() {
+		n.line++
+		if data := bytes.TrimSpace(n.sc.Bytes()); len(data) > 0 {
+			return decode(bytes.NewReader(data), n.Position())
+		}
+	}
+	if err := n.sc.Err(); err != nil {
+		return nil, fmt.Errorf("%s %d: %w", n.name, n.line+1, err)
+	}
+	return nil, io.EOF
+}
+
+// archiveReader - reads the record entries of the archive, and the lines
+// of its NDJSON entries; images are skipped
+type archiveReader struct {
+	tr    *tar.Reader
+	lines *ndjsonReader
+	entry string
+}
+
+func (a *archiveReader) Position() string {
+	if a.lines != nil {
+		return a.lines.Position()
+	}
+	return a.entry
+}
+
+func (a *archiveReader) Next() (*Record, error) {
+	for {
+		if a.lines != nil {
+			record, err := a.lines.Next()
+			if err != io.EOF {
+				return record, err
+			}
+			a.lines = nil
+		}
+
+		header, err := a.tr.Next()
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		a.entry = header.Name
+
+		switch path.Ext(header.Name) {
+		case ".json":
+			if strings.HasPrefix(header.Name, "images/") {
+				continue
+			}
+			if header.Size > MAXRECORDSIZE {
+				return nil, &RecordError{Position: header.Name, Err: bufio.ErrTooLong}
+			}
+			return decode(a.tr, header.Name)
+		case ".ndjson":
+			a.lines = newNDJSONReader(a.tr, header.Name+" line")
+		}
+	}
+}
+
+// decode - reads a record, checking it holds the element of its kind
+func decode(r io.Reader, position string) (*Record, error) {
+	record := &Record{}
+	if err := json.NewDecoder(r).Decode(record); err != nil {
+		return nil, &RecordError{Position: position, Err: err}
+	}
+
+	switch {
+	case record.Kind == RECIPE && record.Recipe != nil && record.Ingredient == nil:
+	case record.Kind == INGREDIENT && record.Ingredient != nil && record.Recipe == nil:
+	default:
+		return nil, &RecordError{Position: position, Err: fmt.Errorf("not a %s or %s record", RECIPE, INGREDIENT)}
+	}
+	return record, nil
+}
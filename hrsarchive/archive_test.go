@@ -0,0 +1,114 @@
+package hrsarchive
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/hrstypes"
+)
+
+func records() []*Record {
+	return []*Record{
+		{Kind: INGREDIENT, Ingredient: &hrsmodel.Ingredient{Ingredient: hrstypes.Ingredient{Code: "i1", Name: "harina"}}},
+		{Kind: RECIPE, Recipe: &hrsmodel.Recipe{Recipe: hrstypes.Recipe{Code: "r1", Name: "Pan"}}},
+	}
+}
+
+// readAll - the codes of the records read and the positions of the
+// records which couldn't be
+func readAll(t *testing.T, data []byte) ([]string, []string) {
+	in, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+
+	codes, failed := []string{}, []string{}
+	for {
+		record, err := in.Next()
+		var recordErr *RecordError
+		switch {
+		case err == io.EOF:
+			return codes, failed
+		case errors.As(err, &recordErr):
+			failed = append(failed, recordErr.Position)
+		case err != nil:
+			t.Fatalf("Next() error = %v", err)
+		default:
+			codes = append(codes, record.Kind+":"+record.Code())
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, format := range []string{NDJSON, ARCHIVE} {
+		var buf bytes.Buffer
+		out, err := NewWriter(&buf, format)
+		if err != nil {
+			t.Fatalf("NewWriter(%s) error = %v", format, err)
+		}
+		for _, record := range records() {
+			if err = out.Write(record); err != nil {
+				t.Fatalf("Write(%s) error = %v", format, err)
+			}
+		}
+		if images, ok := out.(ImageWriter); ok {
+			if err = images.WriteImage("r1", "1.jpg", 3, strings.NewReader("jpg")); err != nil {
+				t.Fatalf("WriteImage() error = %v", err)
+			}
+		}
+		if err = out.Close(); err != nil {
+			t.Fatalf("Close(%s) error = %v", format, err)
+		}
+
+		codes, failed := readAll(t, buf.Bytes())
+		if strings.Join(codes, ",") != "ingredient:i1,recipe:r1" || len(failed) != 0 {
+			t.Errorf("%s read back %v, failed %v", format, codes, failed)
+		}
+	}
+}
+
+func TestNDJSONReader_BadLines(t *testing.T) {
+	data := `{"kind":"ingredient","ingredient":{"code":"i1","name":"sal"}}
+
+{"kind":"recipe",
+{"kind":"menu","recipe":{"code":"r0"}}
+{"kind":"recipe","recipe":{"code":"r1","name":"Pan"}}`
+
+	codes, failed := readAll(t, []byte(data))
+	if strings.Join(codes, ",") != "ingredient:i1,recipe:r1" {
+		t.Errorf("Next() read %v", codes)
+	}
+	if strings.Join(failed, ",") != "line 3,line 4" {
+		t.Errorf("Next() failed at %v", failed)
+	}
+}
+
+func TestNDJSONReader_LongLine(t *testing.T) {
+	data := `{"kind":"ingredient","ingredient":{"code":"i1","name":"sal"}}
+{"kind":"recipe","recipe":{"code":"r1","name":"` + strings.Repeat("a", MAXRECORDSIZE) + `"}}
+`
+	in, err := NewReader(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	if _, err = in.Next(); err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+
+	// The line isn't read whole, the import stops there
+	var recordErr *RecordError
+	if _, err = in.Next(); !errors.Is(err, bufio.ErrTooLong) || errors.As(err, &recordErr) {
+		t.Errorf("Next() of a line too long error = %v", err)
+	}
+}
+
+func TestNewWriter_Format(t *testing.T) {
+	if _, err := NewWriter(io.Discard, "zip"); !errors.Is(err, ErrFormat) {
+		t.Errorf("NewWriter() error = %v", err)
+	}
+}
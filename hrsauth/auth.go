@@ -0,0 +1,195 @@
+package hrsauth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// MINPASSWORD Constant
+	MINPASSWORD = 8
+	// TOKENTTL Constant
+	TOKENTTL = 24 * time.Hour
+	// APIKEYPREFIX Constant
+	APIKEYPREFIX = "hrs_"
+)
+
+var (
+	// ErrPassword - the password is too weak to be set
+	ErrPassword = fmt.Errorf("the password must have at least %d characters", MINPASSWORD)
+	// ErrToken - the token is malformed, forged or expired
+	ErrToken = errors.New("invalid or expired token")
+)
+
+// header - the only JWT header tokens are issued with
+var header = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// nobody - a hash no password matches, checked instead of the missing one so
+// that unknown users take as long as wrong passwords
+const nobody = "$2a$10$06SxK18JB8iedN3I3g5SDu7vVYDBPml92edpEfNDsGN9v5fqhPkrO"
+
+// Claims - what a token says: whose it is, when it was issued and until
+// when it is good, as JWT registered claims. IssuedAtMs is the issue time
+// to the millisecond, telling apart the tokens issued in the second a
+// password changed.
+type Claims struct {
+	Subject    string `json:"sub"`
+	IssuedAt   int64  `json:"iat"`
+	IssuedAtMs int64  `json:"iat_ms,omitempty"`
+	ExpiresAt  int64  `json:"exp"`
+}
+
+// Issued - when the token was issued, to the second for the tokens
+// without IssuedAtMs
+func (c *Claims) Issued() time.Time {
+	if c.IssuedAtMs != 0 {
+		return time.UnixMilli(c.IssuedAtMs)
+	}
+	return time.Unix(c.IssuedAt, 0)
+}
+
+// Signer - issues and verifies HS256 JSON Web Tokens
+type Signer struct {
+	key []byte
+	ttl time.Duration
+	now func() time.Time
+}
+
+// HashPassword - the bcrypt hash of a password
+func HashPassword(password string) (string, error) {
+	if len([]rune(password)) < MINPASSWORD {
+		return "", ErrPassword
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// CheckPassword - whether the password is the one hashed; an empty hash
+// never matches but takes as long to check
+func CheckPassword(hash string, password string) bool {
+	if hash == "" {
+		bcrypt.CompareHashAndPassword([]byte(nobody), []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// NewSigner - a signer with the secret key, a random one when empty (tokens
+// won't outlive the process then); tokens last ttl, TOKENTTL when zero
+func NewSigner(secret string, ttl time.Duration) (*Signer, error) {
+	key := []byte(secret)
+	if secret == "" {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	}
+	if ttl <= 0 {
+		ttl = TOKENTTL
+	}
+	return &Signer{key: key, ttl: ttl, now: time.Now}, nil
+}
+
+// Issue - a token for the subject and when it expires
+func (s *Signer) Issue(subject string) (string, time.Time, error) {
+	now := s.now()
+	expires := now.Add(s.ttl)
+
+	payload, err := json.Marshal(Claims{Subject: subject, IssuedAt: now.Unix(), IssuedAtMs: now.UnixMilli(), ExpiresAt: expires.Unix()})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + s.sign(unsigned), expires, nil
+}
+
+// Verify - the claims of a token signed with the key and not expired
+func (s *Signer) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != header {
+		return nil, ErrToken
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(s.sign(parts[0]+"."+parts[1]))) {
+		return nil, ErrToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrToken
+	}
+	claims := &Claims{}
+	if err = json.Unmarshal(payload, claims); err != nil || claims.Subject == "" {
+		return nil, ErrToken
+	}
+	if s.now().Unix() >= claims.ExpiresAt {
+		return nil, ErrToken
+	}
+	return claims, nil
+}
+
+// NewAPIKey - a random API key and its id. Keys read hrs_<id>_<secret>:
+// the id tells which key it is, in logs and listings, the secret stays
+// with whoever holds the key.
+func NewAPIKey() (key string, id string, err error) {
+	random := make([]byte, 5+32)
+	if _, err = rand.Read(random); err != nil {
+		return "", "", err
+	}
+	id = strings.ToLower(base32.StdEncoding.EncodeToString(random[:5]))
+	return APIKEYPREFIX + id + "_" + base64.RawURLEncoding.EncodeToString(random[5:]), id, nil
+}
+
+// APIKeyID - the id of an API key, false when it isn't one
+func APIKeyID(key string) (string, bool) {
+	if !strings.HasPrefix(key, APIKEYPREFIX) {
+		return "", false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(key, APIKEYPREFIX), "_", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", false
+	}
+	return parts[0], true
+}
+
+// HashAPIKey - the hash API keys are kept as. Keys are random enough for
+// a plain SHA-256 to do.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// CheckAPIKey - whether the key is the one hashed
+func CheckAPIKey(hash string, key string) bool {
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(HashAPIKey(key))) == 1
+}
+
+// NewShareCode - a random code for a share link, URL safe and as hard to
+// guess as the secret of an API key
+func NewShareCode() (string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(random), nil
+}
+
+/** PRIVATE METHODS **/
+
+// sign - the base64url HMAC-SHA256 of the unsigned token
+func (s *Signer) sign(unsigned string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
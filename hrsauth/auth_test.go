@@ -0,0 +1,89 @@
+package hrsauth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPassword(t *testing.T) {
+	if _, err := HashPassword("short"); err != ErrPassword {
+		t.Errorf("HashPassword() error = %v", err)
+	}
+
+	hash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+	if !CheckPassword(hash, "correct horse") || CheckPassword(hash, "correct hors") {
+		t.Errorf("CheckPassword() doesn't tell the passwords apart")
+	}
+	if CheckPassword("", "homerecipes") {
+		t.Errorf("CheckPassword() matches an empty hash")
+	}
+}
+
+func TestSigner(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 250e6, time.UTC)
+	s, _ := NewSigner("secret", time.Hour)
+	s.now = func() time.Time { return now }
+
+	token, expires, err := s.Issue("u1")
+	if err != nil || !expires.Equal(now.Add(time.Hour)) {
+		t.Fatalf("Issue() = %v, %v", expires, err)
+	}
+
+	claims, err := s.Verify(token)
+	if err != nil || claims.Subject != "u1" || claims.IssuedAt != now.Unix() || !claims.Issued().Equal(now) {
+		t.Errorf("Verify() = %+v, %v", claims, err)
+	}
+
+	other, _ := NewSigner("other", time.Hour)
+	parts := strings.Split(token, ".")
+	tests := map[string]string{
+		"other key": func() string { t, _, _ := other.Issue("u1"); return t }(),
+		"tampered":  parts[0] + "." + strings.TrimRight(parts[1], "=") + "x." + parts[2],
+		"alg none":  "eyJhbGciOiJub25lIn0." + parts[1] + ".",
+		"garbage":   "abc",
+	}
+	for name, token := range tests {
+		if _, err := s.Verify(token); err != ErrToken {
+			t.Errorf("Verify(%s) error = %v", name, err)
+		}
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := s.Verify(token); err != ErrToken {
+		t.Errorf("Verify() of an expired token error = %v", err)
+	}
+}
+
+func TestAPIKey(t *testing.T) {
+	key, id, err := NewAPIKey()
+	if err != nil || !strings.HasPrefix(key, APIKEYPREFIX+id+"_") || len(id) != 8 {
+		t.Fatalf("NewAPIKey() = %q, %q, %v", key, id, err)
+	}
+	if got, ok := APIKeyID(key); !ok || got != id {
+		t.Errorf("APIKeyID() = %q, %v", got, ok)
+	}
+	for _, other := range []string{"hrs_", "hrs_abc", "hrs__x", "eyJhbGciOiJIUzI1NiJ9.e30.x"} {
+		if _, ok := APIKeyID(other); ok {
+			t.Errorf("APIKeyID(%q) is a key", other)
+		}
+	}
+
+	hash := HashAPIKey(key)
+	if !CheckAPIKey(hash, key) || CheckAPIKey(hash, key+"x") {
+		t.Errorf("CheckAPIKey() doesn't tell the keys apart")
+	}
+}
+
+func TestNewShareCode(t *testing.T) {
+	code, err := NewShareCode()
+	if err != nil || len(code) != 43 || strings.ContainsAny(code, "+/=") {
+		t.Fatalf("NewShareCode() = %q, %v", code, err)
+	}
+	if other, _ := NewShareCode(); other == code {
+		t.Errorf("NewShareCode() twice = %q", code)
+	}
+}
@@ -0,0 +1,157 @@
+package hrscli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/homerecipes/server"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+// apiKeyCommand - manages the API keys scripts authenticate with
+func apiKeyCommand() cli.Command {
+	command := cli.Command{}
+	command.Name = "apikey"
+	command.Usage = "Manages the API keys of the users"
+	command.Subcommands = []cli.Command{
+		apiKeyCreateCommand(),
+		apiKeyListCommand(),
+		apiKeyRevokeCommand(),
+	}
+	return command
+}
+
+/** PRIVATE METHODS **/
+
+// apiKeyCreateCommand - creates an API key and prints it, the only time it
+// can be read
+func apiKeyCreateCommand() cli.Command {
+	command := cli.Command{}
+	command.Name = "create"
+	command.Usage = "Creates an API key of a user, printing it"
+	command.ArgsUsage = "<username>"
+	command.Description = "The scopes are " + strings.Join([]string{hrsmodel.RECIPESREAD, hrsmodel.RECIPESWRITE,
+		hrsmodel.INGREDIENTSWRITE}, ", ") + ". The key is printed once, it is only kept hashed."
+	command.Flags = append(StoreFlags(),
+		cli.StringFlag{
+			Name:  "name",
+			Usage: "What the key is for, mandatory",
+		},
+		cli.StringSliceFlag{
+			Name:  "scope",
+			Usage: "A scope of the key, repeated or comma separated",
+		},
+		cli.DurationFlag{
+			Name:  "ttl",
+			Usage: "How long the key lasts; forever when not given",
+		},
+	)
+
+	command.Action = func(c *cli.Context) error {
+		return withUser(c, "apikey create", func(worker *server.Worker, user *hrsmodel.User) error {
+			key := &hrsmodel.APIKey{Name: c.String("name")}
+			for _, value := range c.StringSlice("scope") {
+				for _, scope := range strings.Split(value, ",") {
+					if scope = strings.TrimSpace(scope); scope != "" {
+						key.Scopes = append(key.Scopes, scope)
+					}
+				}
+			}
+			if ttl := c.Duration("ttl"); ttl > 0 {
+				key.Expires = time.Now().Add(ttl).Truncate(time.Second)
+			}
+
+			rsp := worker.CreateAPIKey(user, key)
+			if rsp.Error != nil {
+				return cli.NewExitError(rsp.Error.ShowError(), 1)
+			}
+			fmt.Printf("%s: %s\n%s\n", rsp.Status.Description, rsp.RespObj.GetObjectInfo(), rsp.RespObj.(*server.NewAPIKey).Key)
+			return nil
+		})
+	}
+	return command
+}
+
+// apiKeyListCommand - lists the API keys of a user
+func apiKeyListCommand() cli.Command {
+	command := cli.Command{}
+	command.Name = "list"
+	command.Usage = "Lists the API keys of a user"
+	command.ArgsUsage = "<username>"
+	command.Flags = StoreFlags()
+
+	command.Action = func(c *cli.Context) error {
+		return withUser(c, "apikey list", func(worker *server.Worker, user *hrsmodel.User) error {
+			rsp := worker.ListAPIKeys(user)
+			if rsp.Error != nil {
+				return cli.NewExitError(rsp.Error.ShowError(), 1)
+			}
+
+			report := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(report, "ID\tNAME\tSCOPES\tCREATED\tEXPIRES")
+			for _, key := range rsp.RespObj.(*server.APIKeys).Items {
+				expires := "never"
+				if !key.Expires.IsZero() {
+					expires = key.Expires.Format(time.RFC3339)
+				}
+				fmt.Fprintf(report, "%s\t%s\t%s\t%s\t%s\n", key.Code, key.Name, strings.Join(key.Scopes, ","),
+					key.Created.Format(time.RFC3339), expires)
+			}
+			return report.Flush()
+		})
+	}
+	return command
+}
+
+// apiKeyRevokeCommand - removes an API key of a user
+func apiKeyRevokeCommand() cli.Command {
+	command := cli.Command{}
+	command.Name = "revoke"
+	command.Usage = "Revokes an API key of a user"
+	command.ArgsUsage = "<username> <id>"
+	command.Flags = StoreFlags()
+
+	command.Action = func(c *cli.Context) error {
+		if c.NArg() != 2 {
+			return cli.NewExitError("the username and the key id are mandatory", 1)
+		}
+		return withUser(c, "apikey revoke", func(worker *server.Worker, user *hrsmodel.User) error {
+			rsp := worker.RevokeAPIKey(user, c.Args().Get(1))
+			if rsp.Error != nil {
+				return cli.NewExitError(rsp.Error.ShowError(), 1)
+			}
+			fmt.Printf("%s: %s\n", rsp.Status.Description, rsp.RespObj.GetObjectInfo())
+			return nil
+		})
+	}
+	return command
+}
+
+// withUser - runs fn with a worker over the store and the user named by the
+// first argument
+func withUser(c *cli.Context, name string, fn func(worker *server.Worker, user *hrsmodel.User) error) error {
+	if c.NArg() == 0 {
+		return cli.NewExitError("the username is mandatory", 1)
+	}
+
+	store, err := accountStore(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	defer store.Close()
+
+	user, err := store.GetUserByName(c.Args().First())
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("user %s: %s", c.Args().First(), err.Error()), 1)
+	}
+
+	worker := &server.Worker{}
+	worker.Init(context.Background(), log.WithField("command", name), store)
+	return fn(worker, user)
+}
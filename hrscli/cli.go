@@ -1,6 +1,9 @@
 package hrscli
 
 import (
+	"strconv"
+	"time"
+
 	"github.com/urfave/cli"
 )
 
@@ -8,6 +11,131 @@ import (
 func GetCommands() []cli.Command {
 	var commands []cli.Command = nil
 
-	//	document :=
+	commands = append(commands, migrateCommand())
+	commands = append(commands, importNutritionCommand())
+	commands = append(commands, importHTMLCommand())
+	commands = append(commands, importCooklangCommand())
+	commands = append(commands, exportCooklangCommand())
+	commands = append(commands, userCommand())
+	commands = append(commands, householdCommand())
+	commands = append(commands, apiKeyCommand())
 	return commands
 }
+
+// StoreFlags - flags selecting the storage backend, for every command using it
+func StoreFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  "store, s",
+			Value: "mongo",
+			Usage: "Storage backend: mongo, memory or bolt; mongo keeps no users nor households",
+		},
+		cli.StringFlag{
+			Name:  "snapshot",
+			Usage: "JSON file the memory store is loaded from and saved to",
+		},
+		cli.StringFlag{
+			Name:  "db",
+			Value: "hrs.db",
+			Usage: "Database file of the bolt store",
+		},
+	}
+}
+
+// HouseholdFlags - the StoreFlags plus the household, for the commands
+// working on household data
+func HouseholdFlags() []cli.Flag {
+	return append(StoreFlags(), cli.StringFlag{
+		Name:  "household",
+		Usage: "Code of the household whose data is used; the one stored before there were households when empty",
+	})
+}
+
+// StoreConfig - the store configuration given through the StoreFlags or
+// the HouseholdFlags
+func StoreConfig(c *cli.Context) map[string]string {
+	return map[string]string{
+		"store":     c.String("store"),
+		"snapshot":  c.String("snapshot"),
+		"db":        c.String("db"),
+		"household": c.String("household"),
+	}
+}
+
+// AuthFlags - flags configuring how the server authenticates its users
+func AuthFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:   "token-secret",
+			EnvVar: "HRS_TOKEN_SECRET",
+			Usage:  "Key the tokens are signed with; a random one, lost on restart, when empty",
+		},
+		cli.DurationFlag{
+			Name:  "token-ttl",
+			Value: 24 * time.Hour,
+			Usage: "How long the issued tokens last",
+		},
+		cli.BoolFlag{
+			Name:  "anonymous-read",
+			Usage: "Lets the GET requests through without a token",
+		},
+		cli.StringFlag{
+			Name:  "anonymous-household",
+			Usage: "Code of the household the anonymous reads see",
+		},
+		cli.StringFlag{
+			Name:  "oidc-issuer",
+			Usage: "Issuer URL of the OpenID Connect provider users may log in with; none when empty",
+		},
+		cli.StringFlag{
+			Name:  "oidc-client-id",
+			Usage: "Client id of the server at the OpenID Connect provider",
+		},
+		cli.StringFlag{
+			Name:   "oidc-client-secret",
+			EnvVar: "HRS_OIDC_CLIENT_SECRET",
+			Usage:  "Client secret of the server at the OpenID Connect provider; none for public clients",
+		},
+		cli.StringFlag{
+			Name:  "oidc-redirect-url",
+			Usage: "URL of /hrs/oidc/callback as the browsers reach it, registered at the provider",
+		},
+		cli.StringFlag{
+			Name:  "oidc-scopes",
+			Value: "openid,profile,email",
+			Usage: "Scopes asked to the OpenID Connect provider",
+		},
+		cli.StringFlag{
+			Name:  "oidc-username-claims",
+			Value: "preferred_username,email",
+			Usage: "ID token claims the username is taken from, the first one present",
+		},
+		cli.StringFlag{
+			Name:  "oidc-household",
+			Usage: "Code of the household the users logging in the first time join; a household of their own when empty",
+		},
+		cli.StringFlag{
+			Name:  "oidc-role",
+			Value: "viewer",
+			Usage: "Role the users logging in the first time have in --oidc-household, viewer or editor",
+		},
+	}
+}
+
+// AuthConfig - the authentication configuration given through the AuthFlags
+func AuthConfig(c *cli.Context) map[string]string {
+	return map[string]string{
+		"token-secret":         c.String("token-secret"),
+		"token-ttl":            c.Duration("token-ttl").String(),
+		"anonymous-read":       strconv.FormatBool(c.Bool("anonymous-read")),
+		"anonymous-household":  c.String("anonymous-household"),
+		"oidc-issuer":          c.String("oidc-issuer"),
+		"oidc-client-id":       c.String("oidc-client-id"),
+		"oidc-client-secret":   c.String("oidc-client-secret"),
+		"oidc-redirect-url":    c.String("oidc-redirect-url"),
+		"oidc-scopes":          c.String("oidc-scopes"),
+		"oidc-username-claims": c.String("oidc-username-claims"),
+		"oidc-household":       c.String("oidc-household"),
+		"oidc-role":            c.String("oidc-role"),
+	}
+}
@@ -0,0 +1,238 @@
+package hrscli
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+
+	"github.com/ninh0gauch0/homerecipes/hrscooklang"
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/homerecipes/hrssearch"
+	"github.com/ninh0gauch0/homerecipes/hrsstore"
+	"github.com/ninh0gauch0/homerecipes/server"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+// UPDATED Constant
+const UPDATED = "updated"
+
+// importCooklangCommand - creates the recipes of Cooklang files
+func importCooklangCommand() cli.Command {
+	command := cli.Command{}
+	command.Name = "import-cooklang"
+	command.Usage = "Creates the recipes of Cooklang files"
+	command.ArgsUsage = "<file-or-dir>"
+	command.Description = "A directory is walked for " + hrscooklang.EXTENSION + " files; a recipe without title " +
+		"takes the name of its file. Recipes are created as POST /hrs/recipes does, creating the ingredients not found; " +
+		"the ones named like a stored recipe are skipped, or patched with --update."
+	command.Flags = append(HouseholdFlags(),
+		cli.BoolFlag{
+			Name:  "dry-run, n",
+			Usage: "Reports the recipes found without creating anything",
+		},
+		cli.BoolFlag{
+			Name:  "update, u",
+			Usage: "Patches the stored recipes named like the imported ones",
+		},
+	)
+
+	command.Action = func(c *cli.Context) error {
+		if c.NArg() != 1 {
+			return cli.NewExitError("the file or directory is mandatory", 1)
+		}
+
+		files, err := cooklangFiles(c.Args().First())
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		store, err := hrsstore.New(context.Background(), StoreConfig(c))
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		defer store.Close()
+
+		codes := map[string]string{}
+		err = hrsstore.EachRecipe(store, func(recipe *hrsmodel.Recipe) error {
+			codes[hrssearch.Fold(recipe.Name)] = recipe.Code
+			return nil
+		})
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		worker := &server.Worker{}
+		worker.Init(context.Background(), log.WithField("command", command.Name), store)
+
+		report := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(report, "STATUS\tRECIPE\tINGREDIENTS\tSTEPS\tFILE")
+		counts := map[string]int{}
+
+		for _, file := range files {
+			recipe, err := parseCooklangFile(file)
+			if err != nil {
+				counts[FAILED]++
+				fmt.Fprintf(report, "%s\t\t\t\t%s: %s\n", FAILED, file, err.Error())
+				continue
+			}
+
+			status, detail := NEW, ""
+			name := hrssearch.Fold(recipe.Name)
+			code, found := codes[name]
+
+			switch {
+			case found && !c.Bool("update"):
+				status = DUPLICATE
+			case c.Bool("dry-run"):
+			case found:
+				status = UPDATED
+				if rsp := worker.ReimportRecipe(code, recipe); rsp.Error != nil {
+					status, detail = FAILED, ": "+rsp.Error.ShowError()
+				}
+			default:
+				status = CREATED
+				rsp := worker.ImportRecipes([]*hrsmodel.Recipe{recipe})
+				if rsp.Error != nil {
+					status, detail = FAILED, ": "+rsp.Error.ShowError()
+				} else {
+					codes[name] = rsp.RespObj.(*server.RecipeImport).Items[0].Code
+				}
+			}
+
+			counts[status]++
+			fmt.Fprintf(report, "%s\t%s\t%d\t%d\t%s%s\n", status, recipe.Name, len(recipe.Lines), len(recipe.Steps), file, detail)
+		}
+		report.Flush()
+
+		fmt.Printf("\n%d files read: %d recipes created, %d updated, %d new, %d duplicates, %d failed\n",
+			len(files), counts[CREATED], counts[UPDATED], counts[NEW], counts[DUPLICATE], counts[FAILED])
+		if c.Bool("dry-run") {
+			fmt.Println("Dry run, no recipe was created")
+		}
+		return nil
+	}
+
+	return command
+}
+
+// exportCooklangCommand - writes every recipe as a Cooklang file
+func exportCooklangCommand() cli.Command {
+	command := cli.Command{}
+	command.Name = "export-cooklang"
+	command.Usage = "Writes every recipe as a Cooklang file"
+	command.ArgsUsage = "<dir>"
+	command.Description = "Every recipe is written to <dir>/<name>" + hrscooklang.EXTENSION +
+		", as GET /hrs/recipes/{id} with Accept: " + hrscooklang.MEDIATYPE + " renders it. " +
+		"Files already there are replaced."
+	command.Flags = HouseholdFlags()
+
+	command.Action = func(c *cli.Context) error {
+		if c.NArg() != 1 {
+			return cli.NewExitError("the directory is mandatory", 1)
+		}
+		dir := c.Args().First()
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		store, err := hrsstore.New(context.Background(), StoreConfig(c))
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		defer store.Close()
+
+		worker := &server.Worker{}
+		worker.Init(context.Background(), log.WithField("command", command.Name), store)
+
+		written := map[string]bool{}
+		err = hrsstore.EachRecipe(store, func(recipe *hrsmodel.Recipe) error {
+			base := fileName(recipe.Name)
+			name := base
+			for i := 2; written[name]; i++ {
+				name = fmt.Sprintf("%s-%d", base, i)
+			}
+			written[name] = true
+
+			file := filepath.Join(dir, name+hrscooklang.EXTENSION)
+			if err := ioutil.WriteFile(file, []byte(worker.RecipeCooklang(recipe)), 0644); err != nil {
+				return err
+			}
+			fmt.Println(file)
+			return nil
+		})
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		fmt.Printf("\n%d recipes written to %s\n", len(written), dir)
+		return nil
+	}
+
+	return command
+}
+
+/** PRIVATE METHODS **/
+
+// cooklangFiles - the file, or the Cooklang files under the directory
+func cooklangFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	files := []string{}
+	err = filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && strings.EqualFold(filepath.Ext(file), hrscooklang.EXTENSION) {
+			files = append(files, file)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// parseCooklangFile - the recipe of a Cooklang file. A recipe without title
+// takes the name of the file.
+func parseCooklangFile(path string) (*hrsmodel.Recipe, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	recipe, err := hrscooklang.Parse(string(data))
+	if err != nil {
+		return nil, err
+	}
+	if recipe.Name == "" {
+		recipe.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return recipe, nil
+}
+
+// fileName - a recipe name fit for a file name: "Tortilla de patatas"
+// stays, "Pan / Bollos" becomes "Pan - Bollos"
+func fileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune(" -_'(),", r) {
+			return r
+		}
+		return '-'
+	}, name)
+
+	name = strings.Join(strings.Fields(name), " ")
+	if name = strings.Trim(name, " -."); name == "" {
+		name = "recipe"
+	}
+	return name
+}
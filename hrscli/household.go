@@ -0,0 +1,115 @@
+package hrscli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/homerecipes/server"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+// householdCommand - manages the households the data is kept in
+func householdCommand() cli.Command {
+	command := cli.Command{}
+	command.Name = "household"
+	command.Usage = "Manages the households"
+	command.Subcommands = []cli.Command{
+		householdCreateCommand(),
+		householdListCommand(),
+	}
+	return command
+}
+
+/** PRIVATE METHODS **/
+
+// householdCreateCommand - creates a household owned by a user
+func householdCreateCommand() cli.Command {
+	command := cli.Command{}
+	command.Name = "create"
+	command.Usage = "Creates a household owned by a user"
+	command.ArgsUsage = "<name>"
+	command.Description = "With --adopt the recipes, ingredients, shopping lists, meal plans and pantry stored " +
+		"before there were households are moved into the new one."
+	command.Flags = append(StoreFlags(),
+		cli.StringFlag{
+			Name:  "owner",
+			Usage: "Username of the owner, mandatory",
+		},
+		cli.BoolFlag{
+			Name:  "adopt",
+			Usage: "Moves the data stored without household into it",
+		},
+	)
+
+	command.Action = func(c *cli.Context) error {
+		if c.NArg() != 1 || c.String("owner") == "" {
+			return cli.NewExitError("the name and the owner are mandatory", 1)
+		}
+
+		store, err := accountStore(c)
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		defer store.Close()
+
+		owner, err := store.GetUserByName(c.String("owner"))
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("user %s: %s", c.String("owner"), err.Error()), 1)
+		}
+
+		worker := &server.Worker{}
+		worker.Init(context.Background(), log.WithField("command", "household create"), store)
+
+		rsp := worker.CreateHousehold(owner, c.Args().First())
+		if rsp.Error != nil {
+			return cli.NewExitError(rsp.Error.ShowError(), 1)
+		}
+		household := rsp.RespObj.(*hrsmodel.Household)
+		fmt.Printf("%s: %s\n", rsp.Status.Description, household.GetObjectInfo())
+
+		if c.Bool("adopt") {
+			moved, err := store.AdoptUnscoped(household.Code)
+			if err != nil {
+				return cli.NewExitError(err.Error(), 1)
+			}
+			fmt.Printf("%d documents moved into the household\n", moved)
+		}
+		return nil
+	}
+	return command
+}
+
+// householdListCommand - lists every household and its members
+func householdListCommand() cli.Command {
+	command := cli.Command{}
+	command.Name = "list"
+	command.Usage = "Lists the households and their members"
+	command.Flags = StoreFlags()
+
+	command.Action = func(c *cli.Context) error {
+		store, err := accountStore(c)
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		defer store.Close()
+
+		households, err := store.ListHouseholds("")
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		report := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(report, "CODE\tNAME\tMEMBER\tROLE")
+		for _, household := range households {
+			for _, member := range household.Members {
+				fmt.Fprintf(report, "%s\t%s\t%s\t%s\n", household.Code, household.Name, member.Username, member.Role)
+			}
+		}
+		return report.Flush()
+	}
+	return command
+}
@@ -0,0 +1,164 @@
+package hrscli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ninh0gauch0/homerecipes/hrshtml"
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/homerecipes/hrssearch"
+	"github.com/ninh0gauch0/homerecipes/hrsstore"
+	"github.com/ninh0gauch0/homerecipes/server"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+const (
+	// CREATED Constant
+	CREATED = "created"
+	// NEW Constant
+	NEW = "new"
+	// DUPLICATE Constant
+	DUPLICATE = "duplicate"
+	// FAILED Constant
+	FAILED = "failed"
+)
+
+// importHTMLCommand - creates the recipes of saved web pages
+func importHTMLCommand() cli.Command {
+	command := cli.Command{}
+	command.Name = "import-html"
+	command.Usage = "Creates the recipes of saved web pages"
+	command.ArgsUsage = "<file-or-dir>"
+	command.Description = "Recipes are read from the schema.org JSON-LD or microdata of the pages, " +
+		"else from the lists under their ingredient and step headings. A directory is walked for .html and .htm files. " +
+		"Recipes are created as POST /hrs/recipes does, creating the ingredients not found; " +
+		"the ones named like a stored recipe are skipped."
+	command.Flags = append(HouseholdFlags(),
+		cli.BoolFlag{
+			Name:  "dry-run, n",
+			Usage: "Reports the recipes found without creating anything",
+		},
+	)
+
+	command.Action = func(c *cli.Context) error {
+		if c.NArg() != 1 {
+			return cli.NewExitError("the file or directory is mandatory", 1)
+		}
+
+		files, err := htmlFiles(c.Args().First())
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		store, err := hrsstore.New(context.Background(), StoreConfig(c))
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		defer store.Close()
+
+		names := map[string]bool{}
+		err = hrsstore.EachRecipe(store, func(recipe *hrsmodel.Recipe) error {
+			names[hrssearch.Fold(recipe.Name)] = true
+			return nil
+		})
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		worker := &server.Worker{}
+		worker.Init(context.Background(), log.WithField("command", command.Name), store)
+
+		report := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(report, "STATUS\tRECIPE\tINGREDIENTS\tSTEPS\tFOUND IN\tFILE")
+		counts := map[string]int{}
+
+		for _, file := range files {
+			recipes, method, err := extractFile(file)
+			if err != nil {
+				counts[FAILED]++
+				fmt.Fprintf(report, "%s\t\t\t\t\t%s: %s\n", FAILED, file, err.Error())
+				continue
+			}
+
+			for _, recipe := range recipes {
+				status, detail := NEW, ""
+				name := hrssearch.Fold(recipe.Name)
+
+				switch {
+				case names[name]:
+					status = DUPLICATE
+				case !c.Bool("dry-run"):
+					status = CREATED
+					if rsp := worker.ImportRecipes([]*hrsmodel.Recipe{recipe}); rsp.Error != nil {
+						status, detail = FAILED, ": "+rsp.Error.ShowError()
+					}
+				}
+				if status != FAILED {
+					names[name] = true
+				}
+
+				counts[status]++
+				fmt.Fprintf(report, "%s\t%s\t%d\t%d\t%s\t%s%s\n", status, recipe.Name, len(recipe.Lines), len(recipe.Steps), method, file, detail)
+			}
+		}
+		report.Flush()
+
+		fmt.Printf("\n%d files read: %d recipes created, %d new, %d duplicates, %d failed\n",
+			len(files), counts[CREATED], counts[NEW], counts[DUPLICATE], counts[FAILED])
+		if c.Bool("dry-run") {
+			fmt.Println("Dry run, no recipe was created")
+		}
+		return nil
+	}
+
+	return command
+}
+
+/** PRIVATE METHODS **/
+
+// htmlFiles - the file, or the .html and .htm files under the directory
+func htmlFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	files := []string{}
+	err = filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(file))
+		if !info.IsDir() && (ext == ".html" || ext == ".htm") {
+			files = append(files, file)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// extractFile - the recipes of a saved web page and how they were found.
+// Recipes without name take the one of the file.
+func extractFile(path string) ([]*hrsmodel.Recipe, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer file.Close()
+
+	recipes, method, err := hrshtml.Extract(file)
+	for _, recipe := range recipes {
+		if recipe.Name == "" {
+			recipe.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+	}
+	return recipes, method, err
+}
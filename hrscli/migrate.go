@@ -0,0 +1,128 @@
+package hrscli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/homerecipes/hrsstore"
+	"github.com/urfave/cli"
+)
+
+// migrateCommand - rewrites stored recipes whose ingredients are bare
+// references into structured ingredient lines referencing them, in place
+// or copying them to another store
+func migrateCommand() cli.Command {
+	command := cli.Command{}
+	command.Name = "migrate"
+	command.Usage = "Converts the string ingredients of stored recipes into ingredient lines"
+	command.ArgsUsage = "[recipe id...]"
+	command.Description = "Without ids every recipe is migrated. With --to the recipes are copied to that store, " +
+		"along with every ingredient, and the store read is left as it was; the ones already there are kept. " +
+		"That is the way from mongo, which keeps no users nor households, to bolt: " +
+		"hrs migrate --to bolt --to-db hrs.db, then hrs household create <name> --owner <username> --adopt --store bolt --db hrs.db."
+	command.Flags = append(HouseholdFlags(),
+		cli.StringFlag{
+			Name:  "to",
+			Usage: "Storage backend the recipes and ingredients are copied to: bolt or memory",
+		},
+		cli.StringFlag{
+			Name:  "to-db",
+			Value: "hrs.db",
+			Usage: "Database file of the bolt store copied to",
+		},
+		cli.StringFlag{
+			Name:  "to-snapshot",
+			Usage: "JSON file the memory store copied to is saved to",
+		},
+	)
+
+	command.Action = func(c *cli.Context) error {
+		store, err := hrsstore.New(context.Background(), StoreConfig(c))
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		defer store.Close()
+
+		save := func(recipe *hrsmodel.Recipe) error {
+			_, err := store.UpdateRecipe(recipe.Code, recipe)
+			return err
+		}
+		kept := 0
+		if c.String("to") != "" {
+			target, err := hrsstore.New(context.Background(), map[string]string{
+				"store":    c.String("to"),
+				"db":       c.String("to-db"),
+				"snapshot": c.String("to-snapshot"),
+			})
+			if err != nil {
+				return cli.NewExitError(err.Error(), 1)
+			}
+			defer target.Close()
+
+			copied := 0
+			err = hrsstore.EachIngredient(store, func(ingredient *hrsmodel.Ingredient) error {
+				switch err := target.InsertIngredient(ingredient); {
+				case errors.Is(err, hrsstore.ErrConflict):
+					kept++
+				case err != nil:
+					return fmt.Errorf("ingredient %s: %s", ingredient.Code, err.Error())
+				default:
+					copied++
+				}
+				return nil
+			})
+			fmt.Printf("%d ingredients copied\n", copied)
+			if err != nil {
+				return cli.NewExitError(err.Error(), 1)
+			}
+
+			save = target.InsertRecipe
+		}
+
+		migrated := 0
+		migrate := func(recipe *hrsmodel.Recipe) error {
+			for i := range recipe.Lines {
+				if line := &recipe.Lines[i]; line.IsRaw() {
+					line.Ingredient, line.Text = line.Text, ""
+				}
+			}
+			recipe.Sync()
+			switch err := save(recipe); {
+			case errors.Is(err, hrsstore.ErrConflict):
+				kept++
+			case err != nil:
+				return fmt.Errorf("recipe %s: %s", recipe.Code, err.Error())
+			default:
+				migrated++
+			}
+			return nil
+		}
+
+		if c.NArg() == 0 {
+			err = hrsstore.EachRecipe(store, migrate)
+		}
+		for _, id := range c.Args() {
+			recipe, getErr := store.GetRecipe(id)
+			if getErr == nil {
+				getErr = migrate(recipe)
+			}
+			if getErr != nil {
+				err = getErr
+				break
+			}
+		}
+
+		fmt.Printf("%d recipes migrated\n", migrated)
+		if kept > 0 {
+			fmt.Printf("%d already in the store copied to, kept\n", kept)
+		}
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		return nil
+	}
+
+	return command
+}
@@ -0,0 +1,127 @@
+package hrscli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ninh0gauch0/homerecipes/hrsfood"
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/homerecipes/hrsstore"
+	"github.com/urfave/cli"
+)
+
+const (
+	// LINKED Constant
+	LINKED = "linked"
+	// REVIEW Constant
+	REVIEW = "review"
+	// UNMATCHED Constant
+	UNMATCHED = "unmatched"
+	// KEPT Constant
+	KEPT = "kept"
+)
+
+// importNutritionCommand - fills the nutrition facts of the stored
+// ingredients from a food composition dataset, matching them by name
+func importNutritionCommand() cli.Command {
+	command := cli.Command{}
+	command.Name = "import-nutrition"
+	command.Usage = "Links ingredients to the foods of a USDA or Open Food Facts dump and copies their nutrition facts"
+	command.ArgsUsage = "<file>"
+	command.Description = "Ingredients are matched by name. Matches scoring at least --min-score are linked; " +
+		"those above --review-score are only reported, to be checked and fixed by hand. " +
+		"Ingredients with nutrition facts are kept unless --overwrite is given."
+	command.Flags = append(HouseholdFlags(),
+		cli.BoolFlag{
+			Name:  "dry-run, n",
+			Usage: "Reports the matches without changing any ingredient",
+		},
+		cli.BoolFlag{
+			Name:  "overwrite",
+			Usage: "Replaces the nutrition facts ingredients already have",
+		},
+		cli.Float64Flag{
+			Name:  "min-score",
+			Value: 0.85,
+			Usage: "Score from 0 to 1 a match needs to be linked",
+		},
+		cli.Float64Flag{
+			Name:  "review-score",
+			Value: 0.5,
+			Usage: "Score from 0 to 1 a match needs to be reported for review",
+		},
+	)
+
+	command.Action = func(c *cli.Context) error {
+		if c.NArg() != 1 {
+			return cli.NewExitError("the dataset file is mandatory", 1)
+		}
+
+		file, err := os.Open(c.Args().First())
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		defer file.Close()
+
+		source := strings.TrimSuffix(filepath.Base(file.Name()), filepath.Ext(file.Name()))
+		foods, err := hrsfood.Read(file, source)
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		matcher := hrsfood.NewMatcher(foods)
+
+		store, err := hrsstore.New(context.Background(), StoreConfig(c))
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		defer store.Close()
+
+		report := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(report, "STATUS\tINGREDIENT\tFOOD\tSOURCE\tSCORE")
+		counts := map[string]int{}
+
+		err = hrsstore.EachIngredient(store, func(ingredient *hrsmodel.Ingredient) error {
+			status, candidate := UNMATCHED, hrsfood.Candidate{}
+
+			if ingredient.Nutrition != nil && !c.Bool("overwrite") {
+				status = KEPT
+			} else if best, ok := matcher.Best(ingredient.Name); ok && best.Score >= c.Float64("min-score") {
+				status, candidate = LINKED, best
+			} else if ok && best.Score >= c.Float64("review-score") {
+				status, candidate = REVIEW, best
+			}
+
+			if status == LINKED && !c.Bool("dry-run") {
+				patch := &hrsmodel.Ingredient{Nutrition: &candidate.Food.Nutrition, NutritionSource: candidate.Food.Source}
+				if _, err := store.UpdateIngredient(ingredient.Code, patch); err != nil {
+					return fmt.Errorf("ingredient %s: %s", ingredient.Code, err.Error())
+				}
+			}
+
+			counts[status]++
+			if candidate.Food != nil {
+				fmt.Fprintf(report, "%s\t%s\t%s\t%s\t%.2f\n", status, ingredient.Name, candidate.Food.Name, candidate.Food.Source, candidate.Score)
+			} else {
+				fmt.Fprintf(report, "%s\t%s\t\t\t\n", status, ingredient.Name)
+			}
+			return nil
+		})
+		report.Flush()
+
+		fmt.Printf("\n%d foods read: %d ingredients linked, %d to review, %d unmatched, %d kept\n",
+			len(foods), counts[LINKED], counts[REVIEW], counts[UNMATCHED], counts[KEPT])
+		if c.Bool("dry-run") {
+			fmt.Println("Dry run, no ingredient was changed")
+		}
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		return nil
+	}
+
+	return command
+}
@@ -0,0 +1,110 @@
+package hrscli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ninh0gauch0/homerecipes/hrsstore"
+	"github.com/ninh0gauch0/homerecipes/server"
+	"github.com/ninh0gauch0/hrstypes"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+// userCommand - manages the users who can log in to the server
+func userCommand() cli.Command {
+	command := cli.Command{}
+	command.Name = "user"
+	command.Usage = "Manages the users who can log in"
+	command.Subcommands = []cli.Command{
+		userSubcommand("add", "Creates a user", true,
+			func(w *server.Worker, username string, password string) hrstypes.HRAResponse {
+				return w.CreateUser(username, password)
+			}),
+		userSubcommand("remove", "Removes a user, their tokens stop working", false,
+			func(w *server.Worker, username string, password string) hrstypes.HRAResponse {
+				return w.DeleteUserByName(username)
+			}),
+		userSubcommand("reset-password", "Sets a new password, the tokens issued before stop working", true,
+			func(w *server.Worker, username string, password string) hrstypes.HRAResponse {
+				return w.ResetPassword(username, password)
+			}),
+	}
+	return command
+}
+
+/** PRIVATE METHODS **/
+
+// accountStore - the store the account commands change. The server holds
+// the bolt file while it runs, so they can't open it then, and it would
+// overwrite what they change in a memory store snapshot when it stops.
+func accountStore(c *cli.Context) (hrsstore.Store, error) {
+	if c.String("store") == hrsstore.MEMORY {
+		return nil, errors.New("the memory store lives in the server, its accounts can't be changed from the command line; use the bolt store, with the server stopped")
+	}
+	return hrsstore.New(context.Background(), StoreConfig(c))
+}
+
+// userSubcommand - a user subcommand over the store; the ones setting a
+// password take it from --password or else from the first line of stdin
+func userSubcommand(name string, usage string, password bool,
+	action func(w *server.Worker, username string, password string) hrstypes.HRAResponse) cli.Command {
+
+	command := cli.Command{}
+	command.Name = name
+	command.Usage = usage
+	command.ArgsUsage = "<username>"
+	command.Flags = StoreFlags()
+	if password {
+		command.Flags = append(command.Flags, cli.StringFlag{
+			Name:  "password",
+			Usage: "The password, read from stdin when not given",
+		})
+	}
+
+	command.Action = func(c *cli.Context) error {
+		if c.NArg() != 1 {
+			return cli.NewExitError("the username is mandatory", 1)
+		}
+
+		secret := c.String("password")
+		if password && secret == "" {
+			var err error
+			if secret, err = readPassword(os.Stdin); err != nil {
+				return cli.NewExitError(err.Error(), 1)
+			}
+		}
+
+		store, err := accountStore(c)
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		defer store.Close()
+
+		worker := &server.Worker{}
+		worker.Init(context.Background(), log.WithField("command", "user "+name), store)
+
+		rsp := action(worker, c.Args().First(), secret)
+		if rsp.Error != nil {
+			return cli.NewExitError(rsp.Error.ShowError(), 1)
+		}
+		fmt.Printf("%s: %s\n", rsp.Status.Description, rsp.RespObj.GetObjectInfo())
+		return nil
+	}
+	return command
+}
+
+// readPassword - the first line of the input, without its line break
+func readPassword(in io.Reader) (string, error) {
+	fmt.Fprint(os.Stderr, "Password: ")
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,146 @@
+package hrscooklang
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/hrstypes"
+)
+
+const tortilla = `>> title: Tortilla de patatas
+>> servings: 4
+>> prep time: 15 min
+>> cook time: 1 hour 5 minutes
+
+> La de siempre.
+
+-- the onion is a must
+Pelar y cortar @patatas{1%kg} y @cebolla{1}(en juliana).
+
+== Fritura ==
+Freír en #sartén grande{} con @aceite de oliva{250%ml} durante ~{20%minutos}.
+Escurrir. [- keep the oil -]
+
+Batir @huevos{6} con @sal{una pizca}(opcional) y mezclar.
+`
+
+func TestParse(t *testing.T) {
+	r, err := Parse(tortilla)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if r.Name != "Tortilla de patatas" || r.Servings != 4 || r.PrepTime != 15 || r.CookTime != 65 || r.Description != "La de siempre." {
+		t.Errorf("Parse() = %+v", r)
+	}
+	steps := []string{
+		"Pelar y cortar patatas y cebolla.",
+		"Freír en sartén grande con aceite de oliva durante 20 minutos. Escurrir.",
+		"Batir huevos con sal y mezclar.",
+	}
+	if !reflect.DeepEqual(r.Steps, steps) {
+		t.Errorf("Parse() steps = %q", r.Steps)
+	}
+	if !reflect.DeepEqual(r.StepGroups, []string{"", "Fritura", "Fritura"}) {
+		t.Errorf("Parse() groups = %q", r.StepGroups)
+	}
+
+	if len(r.Lines) != 5 {
+		t.Fatalf("Parse() lines = %+v", r.Lines)
+	}
+	if l := r.Lines[0]; l.Name != "patatas" || l.Quantity.Value != 1 || l.Unit != "kg" {
+		t.Errorf("Parse() line 0 = %+v", l)
+	}
+	if l := r.Lines[1]; l.Name != "cebolla" || l.Quantity.Value != 1 || l.Unit != "" || l.Note != "en juliana" {
+		t.Errorf("Parse() line 1 = %+v", l)
+	}
+	if l := r.Lines[4]; l.Name != "sal" || l.Quantity != nil || !l.Optional || l.Note != "" {
+		t.Errorf("Parse() line 4 = %+v", l)
+	}
+
+	mentions := []hrsmodel.Mention{
+		{Step: 0, Start: 15, Text: "patatas", Kind: hrsmodel.INGREDIENTMENTION, Line: 0},
+		{Step: 0, Start: 25, Text: "cebolla", Kind: hrsmodel.INGREDIENTMENTION, Line: 1},
+		{Step: 1, Start: 10, Text: "sartén grande", Kind: hrsmodel.COOKWAREMENTION},
+		{Step: 1, Start: 29, Text: "aceite de oliva", Kind: hrsmodel.INGREDIENTMENTION, Line: 2},
+		{Step: 1, Start: 53, Text: "20 minutos", Kind: hrsmodel.TIMERMENTION, Amount: "20%minutos"},
+		{Step: 2, Start: 6, Text: "huevos", Kind: hrsmodel.INGREDIENTMENTION, Line: 3},
+		{Step: 2, Start: 17, Text: "sal", Kind: hrsmodel.INGREDIENTMENTION, Line: 4, Amount: "una pizca"},
+	}
+	if !reflect.DeepEqual(r.Mentions, mentions) {
+		t.Errorf("Parse() mentions = %+v", r.Mentions)
+	}
+	for _, m := range r.Mentions {
+		if step := r.Steps[m.Step]; step[m.Start:m.Start+len(m.Text)] != m.Text {
+			t.Errorf("Parse() mention %q at %d of %q", m.Text, m.Start, step)
+		}
+	}
+}
+
+func TestParse_Empty(t *testing.T) {
+	if _, err := Parse("-- nothing\n\n"); err != ErrEmpty {
+		t.Errorf("Parse() error = %v", err)
+	}
+}
+
+func TestFormat_RoundTrip(t *testing.T) {
+	r, err := Parse(tortilla)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	text := Format(r, nil)
+	again, err := Parse(text)
+	if err != nil {
+		t.Fatalf("Parse(Format()) error = %v\n%s", err, text)
+	}
+	if !reflect.DeepEqual(again, r) {
+		t.Errorf("Parse(Format()) = %+v, want %+v\n%s", again, r, text)
+	}
+	if !strings.Contains(text, "@aceite de oliva{250%ml}") || !strings.Contains(text, "@sal{una pizca}(optional)") {
+		t.Errorf("Format() =\n%s", text)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	r := &hrsmodel.Recipe{
+		Recipe: hrstypes.Recipe{
+			Name:  "Pan",
+			Steps: []string{"Mezclar la Harina y el agua.", "Hornear 40 minutos."},
+		},
+		Lines: []hrsmodel.IngredientLine{
+			{Ingredient: "i1", Quantity: &hrsmodel.Quantity{Value: 500}, Unit: "g"},
+			{Name: "agua", Quantity: &hrsmodel.Quantity{Value: 1.5}, Unit: "cup"},
+			{Name: "levadura", Quantity: &hrsmodel.Quantity{Value: 10}, Unit: "g"},
+		},
+		// Step edited after: the mention is found again by its text
+		Mentions: []hrsmodel.Mention{{Step: 0, Start: 3, Text: "agua", Kind: hrsmodel.INGREDIENTMENTION, Line: 1}},
+	}
+	ingredients := map[string]*hrsmodel.Ingredient{
+		"i1": {Ingredient: hrstypes.Ingredient{Code: "i1", Name: "harina"}},
+	}
+
+	want := ">> title: Pan\n\n" +
+		"@levadura{10%g}\n\n" +
+		"Mezclar la @Harina{500%g} y el @agua{1 1/2%cup}.\n\n" +
+		"Hornear 40 minutos.\n"
+	if got := Format(r, ingredients); got != want {
+		t.Errorf("Format() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestParseMinutes(t *testing.T) {
+	tests := map[string]int{
+		"45": 45, "1 hour 30 minutes": 90, "1h30m": 90, "PT2H": 120, "2 horas": 120, "90 sec": 2, "soon": 0,
+	}
+	for value, want := range tests {
+		if got := ParseMinutes(value); got != want {
+			t.Errorf("ParseMinutes(%q) = %d, want %d", value, got, want)
+		}
+	}
+	if got := FormatMinutes(65); got != "1 hour 5 minutes" {
+		t.Errorf("FormatMinutes() = %q", got)
+	}
+}
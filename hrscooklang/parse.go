@@ -0,0 +1,314 @@
+package hrscooklang
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ninh0gauch0/homerecipes/hrsjsonld"
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/homerecipes/hrssearch"
+	"github.com/ninh0gauch0/homerecipes/hrsunits"
+)
+
+const (
+	// MEDIATYPE Constant
+	MEDIATYPE = "text/x-cooklang"
+	// EXTENSION Constant
+	EXTENSION = ".cook"
+)
+
+// ErrEmpty - the text has no recipe
+var ErrEmpty = errors.New("no Cooklang recipe found")
+
+var (
+	// blockComment - [- comments -], even across lines
+	blockComment = regexp.MustCompile(`(?s)\[-.*?-\]`)
+	// section - "= Dough" and "== Dough ==" lines
+	section = regexp.MustCompile(`^=+\s*(.*?)\s*=*$`)
+	// durationPart - "1 hour", "30 min", "1h"
+	durationPart = regexp.MustCompile(`(\d+(?:[.,]\d+)?)\s*([[:alpha:]]*)`)
+)
+
+// optionalWords - notes marking an optional ingredient
+var optionalWords = []string{"optional", "opcional"}
+
+// Parse - reads a Cooklang recipe: >> metadata (or a front matter), notes
+// as the description, = sections and one step per paragraph. Every
+// @ingredient mark becomes an ingredient line, named but without reference,
+// and every mark a mention of the step.
+func Parse(text string) (*hrsmodel.Recipe, error) {
+	p := &parser{recipe: &hrsmodel.Recipe{}}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = blockComment.ReplaceAllString(text, "")
+
+	lines := strings.Split(text, "\n")
+	if len(lines) > 0 && strings.TrimSpace(lines[0]) == "---" {
+		for i := 1; i < len(lines); i++ {
+			if strings.TrimSpace(lines[i]) == "---" {
+				lines = lines[i+1:]
+				break
+			}
+			p.metadata(lines[i])
+		}
+	}
+
+	paragraph, group := []string{}, ""
+	flush := func() {
+		if len(paragraph) > 0 {
+			p.step(strings.Join(paragraph, " "), group)
+			paragraph = paragraph[:0]
+		}
+	}
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, ">>"):
+			p.metadata(trimmed[2:])
+			continue
+		case strings.HasPrefix(trimmed, ">"):
+			p.notes = append(p.notes, strings.TrimSpace(trimmed[1:]))
+			continue
+		}
+
+		if i := strings.Index(trimmed, "--"); i >= 0 {
+			trimmed = strings.TrimSpace(trimmed[:i])
+			if trimmed == "" {
+				continue
+			}
+		}
+		switch m := section.FindStringSubmatch(trimmed); {
+		case trimmed == "":
+			flush()
+		case m != nil:
+			flush()
+			group = m[1]
+		default:
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+	flush()
+
+	recipe := p.recipe
+	if recipe.Description == "" {
+		recipe.Description = strings.Join(p.notes, "\n")
+	}
+	if !p.grouped {
+		recipe.StepGroups = nil
+	}
+	if recipe.Name == "" && len(recipe.Steps) == 0 {
+		return nil, ErrEmpty
+	}
+	return recipe, nil
+}
+
+// ParseMinutes - the minutes of a time like "1 hour 30 minutes", "1h30m",
+// "45" or PT45M; zero when it isn't one
+func ParseMinutes(value string) int {
+	if minutes := hrsjsonld.ParseDuration(value); minutes > 0 {
+		return minutes
+	}
+
+	total := 0.0
+	for _, m := range durationPart.FindAllStringSubmatch(strings.ToLower(value), -1) {
+		n, err := strconv.ParseFloat(strings.Replace(m[1], ",", ".", 1), 64)
+		if err != nil {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(m[2], "d"):
+			total += n * 24 * 60
+		case strings.HasPrefix(m[2], "h"):
+			total += n * 60
+		case strings.HasPrefix(m[2], "s"):
+			total += n / 60
+		default:
+			total += n
+		}
+	}
+	return int(total + 0.5)
+}
+
+/** PRIVATE METHODS **/
+
+// parser - the recipe being read and the notes found
+type parser struct {
+	recipe  *hrsmodel.Recipe
+	notes   []string
+	grouped bool
+}
+
+// metadata - applies a "key: value" line; unknown keys are skipped
+func (p *parser) metadata(line string) {
+	i := strings.Index(line, ":")
+	if i < 0 {
+		return
+	}
+	key := strings.ToLower(strings.TrimSpace(line[:i]))
+	value := strings.TrimSpace(line[i+1:])
+	r := p.recipe
+
+	switch key {
+	case "title", "name":
+		r.Name = value
+	case "description":
+		r.Description = value
+	case "servings", "serves":
+		if n, err := strconv.Atoi(strings.TrimSpace(strings.Split(value, " ")[0])); err == nil {
+			r.Servings = n
+		} else {
+			r.Yield = value
+		}
+	case "yield":
+		r.Yield = value
+	case "prep time", "prep_time", "preptime":
+		r.PrepTime = ParseMinutes(value)
+	case "cook time", "cook_time", "cooktime":
+		r.CookTime = ParseMinutes(value)
+	case "total time", "total_time", "time", "time required", "duration":
+		r.TotalTime = ParseMinutes(value)
+	case "image", "images", "picture":
+		if value != "" {
+			r.Images = append(r.Images, value)
+		}
+	}
+}
+
+// step - reads a paragraph as a step, taking its marks out
+func (p *parser) step(source string, group string) {
+	r := p.recipe
+	index := len(r.Steps)
+	var b strings.Builder
+
+	for i := 0; i < len(source); {
+		if m, n := p.mark(source[i:], index, b.Len()); n > 0 {
+			b.WriteString(m.Text)
+			r.Mentions = append(r.Mentions, m)
+			i += n
+			continue
+		}
+		b.WriteByte(source[i])
+		i++
+	}
+
+	r.Steps = append(r.Steps, strings.TrimSpace(b.String()))
+	r.StepGroups = append(r.StepGroups, group)
+	p.grouped = p.grouped || group != ""
+}
+
+// mark - reads the @ingredient, #cookware or ~timer mark the text starts
+// with; n is zero when there's none
+func (p *parser) mark(text string, step int, start int) (m hrsmodel.Mention, n int) {
+	kinds := map[byte]string{'@': hrsmodel.INGREDIENTMENTION, '#': hrsmodel.COOKWAREMENTION, '~': hrsmodel.TIMERMENTION}
+	kind, ok := kinds[text[0]]
+	if !ok {
+		return m, 0
+	}
+
+	name, amount, braced := "", "", false
+	rest := text[1:]
+	brace := strings.IndexByte(rest, '{')
+	stop := strings.IndexAny(rest, "@#~}")
+	if brace >= 0 && (stop < 0 || brace < stop) {
+		if end := strings.IndexByte(rest[brace:], '}'); end > 0 {
+			name = rest[:brace]
+			amount = strings.TrimSpace(rest[brace+1 : brace+end])
+			n = 1 + brace + end + 1
+			braced = name == strings.TrimSpace(name)
+		}
+	}
+	if !braced {
+		name, amount = word(rest), ""
+		n = 1 + len(name)
+	}
+	if name == "" && (kind != hrsmodel.TIMERMENTION || !braced) {
+		return m, 0
+	}
+
+	m = hrsmodel.Mention{Step: step, Start: start, Kind: kind, Text: name}
+	switch kind {
+	case hrsmodel.INGREDIENTMENTION:
+		note := ""
+		if strings.HasPrefix(text[n:], "(") {
+			if end := strings.IndexByte(text[n:], ')'); end > 0 {
+				note = text[n+1 : n+end]
+				n += end + 1
+			}
+		}
+		m.Line = len(p.recipe.Lines)
+		m.Amount = p.ingredient(name, amount, note)
+	case hrsmodel.COOKWAREMENTION:
+		m.Amount = amount
+	case hrsmodel.TIMERMENTION:
+		m.Name, m.Amount = name, amount
+		if amount != "" {
+			m.Text = strings.TrimSpace(strings.Replace(amount, "%", " ", 1))
+		}
+	}
+	return m, n
+}
+
+// ingredient - adds the line of an ingredient mark; the amount is given
+// back when it isn't a quantity
+func (p *parser) ingredient(name string, amount string, note string) string {
+	line := hrsmodel.IngredientLine{Name: name}
+
+	for _, part := range strings.Split(note, ";") {
+		part = strings.TrimSpace(part)
+		switch {
+		case part == "":
+		case contains(optionalWords, hrssearch.Fold(part)):
+			line.Optional = true
+		case line.Note == "":
+			line.Note = part
+		default:
+			line.Note += "; " + part
+		}
+	}
+
+	value, unit := amount, ""
+	if i := strings.IndexByte(amount, '%'); i >= 0 {
+		value, unit = strings.TrimSpace(amount[:i]), strings.TrimSpace(amount[i+1:])
+	}
+	if value != "" {
+		q, err := hrsmodel.ParseQuantity(value)
+		if err != nil {
+			p.recipe.Lines = append(p.recipe.Lines, line)
+			return amount
+		}
+		line.Quantity = q
+	}
+	if u, ok := hrsunits.Lookup(unit); ok {
+		unit = u.Code
+	}
+	line.Unit = unit
+
+	p.recipe.Lines = append(p.recipe.Lines, line)
+	return ""
+}
+
+// word - the letters, digits, _ and - the text starts with
+func word(text string) string {
+	end := 0
+	for end < len(text) {
+		r, size := utf8.DecodeRuneInString(text[end:])
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' {
+			break
+		}
+		end += size
+	}
+	return strings.TrimRight(text[:end], "-")
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
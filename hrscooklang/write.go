@@ -0,0 +1,248 @@
+package hrscooklang
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+)
+
+// Format - the recipe in Cooklang. Marks go back where the mentions say,
+// with the quantities of the lines, so a scaled recipe is written scaled.
+// A line no step mentions marks the first step naming it, or else goes in
+// a step of its own ahead of the others. Lines referencing an ingredient
+// are written with its name.
+func Format(recipe *hrsmodel.Recipe, ingredients map[string]*hrsmodel.Ingredient) string {
+	var b strings.Builder
+
+	metadata := func(key string, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, ">> %s: %s\n", key, value)
+		}
+	}
+	metadata("title", recipe.Name)
+	if recipe.Servings > 0 {
+		metadata("servings", strconv.Itoa(recipe.Servings))
+	}
+	metadata("yield", recipe.Yield)
+	metadata("prep time", FormatMinutes(recipe.PrepTime))
+	metadata("cook time", FormatMinutes(recipe.CookTime))
+	metadata("total time", FormatMinutes(recipe.TotalTime))
+	for _, image := range recipe.Images {
+		metadata("image", image)
+	}
+
+	if recipe.Description != "" {
+		b.WriteString("\n")
+		for _, note := range strings.Split(recipe.Description, "\n") {
+			b.WriteString(strings.TrimSpace("> "+note) + "\n")
+		}
+	}
+
+	names := make([]string, len(recipe.Lines))
+	for i, line := range recipe.Lines {
+		names[i] = lineName(line, ingredients)
+	}
+
+	marks := locate(recipe, names)
+	if unmarked := unmarkedLines(recipe, marks); len(unmarked) > 0 {
+		items := []string{}
+		for _, i := range unmarked {
+			items = append(items, ingredientMark(names[i], recipe.Lines[i], "", ""))
+		}
+		b.WriteString("\n" + strings.Join(items, ", ") + "\n")
+	}
+
+	group := ""
+	for i, step := range recipe.Steps {
+		if g := recipe.StepGroup(i); g != group {
+			group = g
+			b.WriteString("\n" + strings.TrimSpace("== "+group+" ==") + "\n")
+		}
+
+		var s strings.Builder
+		pos := 0
+		for _, m := range marks[i] {
+			s.WriteString(step[pos:m.Start])
+			pos = m.Start + len(m.Text)
+			s.WriteString(render(recipe, m, step[pos:]))
+		}
+		s.WriteString(step[pos:])
+		b.WriteString("\n" + strings.Join(strings.Fields(s.String()), " ") + "\n")
+	}
+	return b.String()
+}
+
+// FormatMinutes - minutes as Cooklang metadata writes them: "1 hour 30
+// minutes"; none for zero
+func FormatMinutes(minutes int) string {
+	if minutes <= 0 {
+		return ""
+	}
+
+	parts := []string{}
+	if hours := minutes / 60; hours == 1 {
+		parts = append(parts, "1 hour")
+	} else if hours > 1 {
+		parts = append(parts, strconv.Itoa(hours)+" hours")
+	}
+	if minutes%60 > 0 {
+		parts = append(parts, strconv.Itoa(minutes%60)+" minutes")
+	}
+	return strings.Join(parts, " ")
+}
+
+/** PRIVATE METHODS **/
+
+// locate - the mentions of every step in order, found again by their text
+// when the steps were edited after; mentions which can't be found, or
+// whose line is gone, are dropped. Lines not mentioned get the first
+// occurrence of their name.
+func locate(recipe *hrsmodel.Recipe, names []string) map[int][]hrsmodel.Mention {
+	byStep := map[int][]hrsmodel.Mention{}
+	for _, m := range recipe.Mentions {
+		if m.Step < len(recipe.Steps) && (m.Kind != hrsmodel.INGREDIENTMENTION || m.Line < len(recipe.Lines)) {
+			byStep[m.Step] = append(byStep[m.Step], m)
+		}
+	}
+
+	marks := map[int][]hrsmodel.Mention{}
+	for i, step := range recipe.Steps {
+		mentions := byStep[i]
+		sort.SliceStable(mentions, func(a, b int) bool { return mentions[a].Start < mentions[b].Start })
+
+		pos := 0
+		for _, m := range mentions {
+			if m.Start < pos || m.Start+len(m.Text) > len(step) || step[m.Start:m.Start+len(m.Text)] != m.Text {
+				found := strings.Index(step[pos:], m.Text)
+				if m.Text == "" || found < 0 {
+					continue
+				}
+				m.Start = pos + found
+			}
+			marks[i] = append(marks[i], m)
+			pos = m.Start + len(m.Text)
+		}
+	}
+
+	for _, line := range unmarkedLines(recipe, marks) {
+		if names[line] == "" {
+			continue
+		}
+		pattern := regexp.MustCompile(`(?i)` + regexp.QuoteMeta(names[line]))
+	steps:
+		for i, step := range recipe.Steps {
+			for _, at := range pattern.FindAllStringIndex(step, -1) {
+				if !isWordBoundary(step, at[0], at[1]) || overlaps(marks[i], at[0], at[1]) {
+					continue
+				}
+				m := hrsmodel.Mention{Step: i, Start: at[0], Text: step[at[0]:at[1]], Kind: hrsmodel.INGREDIENTMENTION, Line: line}
+				marks[i] = append(marks[i], m)
+				sort.SliceStable(marks[i], func(a, b int) bool { return marks[i][a].Start < marks[i][b].Start })
+				break steps
+			}
+		}
+	}
+	return marks
+}
+
+// unmarkedLines - the indexes of the lines without a mark
+func unmarkedLines(recipe *hrsmodel.Recipe, marks map[int][]hrsmodel.Mention) []int {
+	marked := map[int]bool{}
+	for _, mentions := range marks {
+		for _, m := range mentions {
+			if m.Kind == hrsmodel.INGREDIENTMENTION {
+				marked[m.Line] = true
+			}
+		}
+	}
+
+	lines := []int{}
+	for i := range recipe.Lines {
+		if !marked[i] {
+			lines = append(lines, i)
+		}
+	}
+	return lines
+}
+
+// render - the mark of a mention; after is the step text that follows, a
+// word there needs the mark closed with braces
+func render(recipe *hrsmodel.Recipe, m hrsmodel.Mention, after string) string {
+	switch m.Kind {
+	case hrsmodel.INGREDIENTMENTION:
+		return ingredientMark(m.Text, recipe.Lines[m.Line], m.Amount, after)
+	case hrsmodel.COOKWAREMENTION:
+		return "#" + m.Text + braces(m.Text, m.Amount, after)
+	default:
+		if m.Amount == "" {
+			return "~" + m.Text + braces(m.Text, "", after)
+		}
+		return "~" + m.Name + "{" + m.Amount + "}"
+	}
+}
+
+// ingredientMark - @name{qty%unit}(note) of a line
+func ingredientMark(text string, line hrsmodel.IngredientLine, amount string, after string) string {
+	if line.Quantity != nil {
+		amount = line.Quantity.String()
+		if line.Unit != "" {
+			amount += "%" + line.Unit
+		}
+	}
+
+	note := line.Note
+	if line.Optional {
+		note = strings.TrimPrefix(note+"; optional", "; ")
+	}
+	if note != "" {
+		note = "(" + note + ")"
+		after = note
+	}
+	return "@" + text + braces(text, amount, after) + note
+}
+
+// braces - the {amount} closing a mark, left out when nothing needs it
+func braces(name string, amount string, after string) string {
+	if amount != "" || word(name) != name {
+		return "{" + amount + "}"
+	}
+	if r, _ := utf8.DecodeRuneInString(after); after != "" && (r == '(' || isWordRune(r)) {
+		return "{}"
+	}
+	return ""
+}
+
+// lineName - what an ingredient mark of the line says
+func lineName(line hrsmodel.IngredientLine, ingredients map[string]*hrsmodel.Ingredient) string {
+	if ingredient := ingredients[line.Ingredient]; line.Name == "" && ingredient != nil {
+		return ingredient.Name
+	}
+	return line.Ref()
+}
+
+// isWordBoundary - whether text[start:end] isn't part of a longer word
+func isWordBoundary(text string, start int, end int) bool {
+	before, _ := utf8.DecodeLastRuneInString(text[:start])
+	after, _ := utf8.DecodeRuneInString(text[end:])
+	return (start == 0 || !isWordRune(before)) && (end == len(text) || !isWordRune(after))
+}
+
+// overlaps - whether a mark covers part of text[start:end]
+func overlaps(marks []hrsmodel.Mention, start int, end int) bool {
+	for _, m := range marks {
+		if start < m.Start+len(m.Text) && m.Start < end {
+			return true
+		}
+	}
+	return false
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
@@ -0,0 +1,325 @@
+package hrsfood
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+)
+
+// ErrFormat - the dataset can't be read
+var ErrFormat = errors.New("unknown food dataset format")
+
+// Food - an entry of a food composition dataset, nutrition per 100 g.
+// Source identifies it, like "usda:171413" or "off:8410000810004".
+type Food struct {
+	Source    string
+	Name      string
+	Nutrition hrsmodel.Nutrition
+}
+
+// columns - the names a field goes by in flat CSV or JSON datasets,
+// compared lowercased with spaces as underscores. Open Food Facts names
+// come first.
+var columns = map[string][]string{
+	"id":            {"code", "id", "fdc_id", "ndb_no"},
+	"name":          {"product_name", "name", "description", "food", "food_name"},
+	"energy":        {"energy-kcal_100g", "energy_kcal", "energy", "kcal", "calories"},
+	"protein":       {"proteins_100g", "protein", "proteins"},
+	"fat":           {"fat_100g", "fat", "total_fat", "total_lipid_(fat)"},
+	"saturatedFat":  {"saturated-fat_100g", "saturated_fat", "fatty_acids,_total_saturated"},
+	"carbohydrates": {"carbohydrates_100g", "carbohydrates", "carbohydrate", "carbs", "carbohydrate,_by_difference"},
+	"sugar":         {"sugars_100g", "sugar", "sugars", "sugars,_total"},
+	"fiber":         {"fiber_100g", "fiber", "fibre", "fiber,_total_dietary"},
+	"salt":          {"salt_100g", "salt"},
+	"sodium":        {"sodium_100g"},
+}
+
+// usdaNutrients - USDA nutrient numbers of each field. Sodium comes in mg.
+var usdaNutrients = map[string]string{
+	"208": "energy",
+	"957": "energy",
+	"203": "protein",
+	"204": "fat",
+	"606": "saturatedFat",
+	"205": "carbohydrates",
+	"269": "sugar",
+	"291": "fiber",
+	"307": "sodium",
+}
+
+// usdaFoods - the USDA FoodData Central JSON dump, one list per data type
+type usdaFoods struct {
+	FoundationFoods []usdaFood `json:"FoundationFoods"`
+	SRLegacyFoods   []usdaFood `json:"SRLegacyFoods"`
+	SurveyFoods     []usdaFood `json:"SurveyFoods"`
+	BrandedFoods    []usdaFood `json:"BrandedFoods"`
+}
+
+type usdaFood struct {
+	FdcID         int    `json:"fdcId"`
+	Description   string `json:"description"`
+	FoodNutrients []struct {
+		Nutrient struct {
+			Number string `json:"number"`
+		} `json:"nutrient"`
+		Amount float64 `json:"amount"`
+	} `json:"foodNutrients"`
+}
+
+// Read - the foods of a dataset: a USDA FoodData Central JSON dump, an
+// Open Food Facts JSON or JSONL export, a JSON array of flat objects or a
+// CSV (or tab separated) file with a header row. source prefixes the ids of
+// flat datasets. Entries without a name are skipped.
+func Read(r io.Reader, source string) ([]Food, error) {
+	br := bufio.NewReader(r)
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			return nil, fmt.Errorf("%w: empty dataset", ErrFormat)
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n', 0xEF, 0xBB, 0xBF:
+			br.ReadByte()
+			continue
+		case '[', '{':
+			return readJSON(br, source)
+		default:
+			return readCSV(br, source)
+		}
+	}
+}
+
+/** PRIVATE METHODS **/
+
+// readJSON - reads a USDA dump, an array of objects or a stream of them
+func readJSON(r io.Reader, source string) ([]Food, error) {
+	foods := []Food{}
+	dec := json.NewDecoder(r)
+
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err == io.EOF {
+			return foods, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrFormat, err.Error())
+		}
+
+		var objects []json.RawMessage
+		if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("[")) {
+			if err := json.Unmarshal(raw, &objects); err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrFormat, err.Error())
+			}
+		} else {
+			objects = []json.RawMessage{raw}
+		}
+
+		for i, object := range objects {
+			read, err := readObject(object, source, len(foods)+i+1)
+			if err != nil {
+				return nil, err
+			}
+			foods = append(foods, read...)
+		}
+	}
+}
+
+// readObject - the foods of one JSON object: a whole USDA dump, an Open
+// Food Facts product or a flat record
+func readObject(data json.RawMessage, source string, n int) ([]Food, error) {
+	var object map[string]interface{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrFormat, err.Error())
+	}
+
+	if _, ok := object["foodNutrients"]; ok {
+		var food usdaFood
+		if err := json.Unmarshal(data, &food); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrFormat, err.Error())
+		}
+		return usdaToFoods(food), nil
+	}
+	for key := range object {
+		if strings.HasSuffix(key, "Foods") {
+			return readUSDA(data)
+		}
+	}
+
+	if products, ok := object["products"].([]interface{}); ok {
+		foods := []Food{}
+		for i, product := range products {
+			if p, ok := product.(map[string]interface{}); ok {
+				if food, ok := fromRecord(flatten(p), "off", i+1); ok {
+					foods = append(foods, food)
+				}
+			}
+		}
+		return foods, nil
+	}
+
+	if nutriments, ok := object["nutriments"].(map[string]interface{}); ok {
+		for key, value := range nutriments {
+			object[key] = value
+		}
+		source = "off"
+	}
+	if food, ok := fromRecord(flatten(object), source, n); ok {
+		return []Food{food}, nil
+	}
+	return []Food{}, nil
+}
+
+// readUSDA - the foods of a USDA FoodData Central dump
+func readUSDA(data []byte) ([]Food, error) {
+	var dump usdaFoods
+	if err := json.Unmarshal(data, &dump); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrFormat, err.Error())
+	}
+
+	foods := []Food{}
+	for _, list := range [][]usdaFood{dump.FoundationFoods, dump.SRLegacyFoods, dump.SurveyFoods, dump.BrandedFoods} {
+		foods = append(foods, usdaToFoods(list...)...)
+	}
+	return foods, nil
+}
+
+// usdaToFoods - the named USDA foods
+func usdaToFoods(list ...usdaFood) []Food {
+	foods := []Food{}
+	for _, f := range list {
+		if f.Description == "" {
+			continue
+		}
+		values := map[string]float64{}
+		for _, fn := range f.FoodNutrients {
+			if field, ok := usdaNutrients[fn.Nutrient.Number]; ok {
+				if _, seen := values[field]; !seen {
+					values[field] = fn.Amount
+				}
+			}
+		}
+		values["sodium"] /= 1000
+
+		foods = append(foods, Food{
+			Source:    fmt.Sprintf("usda:%d", f.FdcID),
+			Name:      f.Description,
+			Nutrition: nutrition(values),
+		})
+	}
+	return foods
+}
+
+// readCSV - reads a comma or tab separated file with a header row
+func readCSV(r *bufio.Reader, source string) ([]Food, error) {
+	header, err := r.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	cr := csv.NewReader(io.MultiReader(strings.NewReader(header), r))
+	if strings.Count(header, "\t") > strings.Count(header, ",") {
+		cr.Comma = '\t'
+		cr.LazyQuotes = true
+	}
+	cr.FieldsPerRecord = -1
+
+	names, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrFormat, err.Error())
+	}
+
+	foods := []Food{}
+	for n := 1; ; n++ {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return foods, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrFormat, err.Error())
+		}
+
+		record := map[string]string{}
+		for i, value := range row {
+			if i < len(names) {
+				record[columnKey(names[i])] = value
+			}
+		}
+		if food, ok := fromRecord(record, source, n); ok {
+			foods = append(foods, food)
+		}
+	}
+}
+
+// flatten - the scalar values of a JSON object as strings
+func flatten(object map[string]interface{}) map[string]string {
+	record := map[string]string{}
+	for key, value := range object {
+		switch v := value.(type) {
+		case string:
+			record[columnKey(key)] = v
+		case float64:
+			record[columnKey(key)] = strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	}
+	return record
+}
+
+// fromRecord - the food of a flat record, false when it has no name
+func fromRecord(record map[string]string, source string, n int) (Food, bool) {
+	value := func(field string) string {
+		for _, column := range columns[field] {
+			if v := strings.TrimSpace(record[column]); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+
+	name := value("name")
+	if name == "" {
+		return Food{}, false
+	}
+	id := value("id")
+	if id == "" {
+		id = strconv.Itoa(n)
+	}
+
+	values := map[string]float64{}
+	for field := range columns {
+		if v, err := strconv.ParseFloat(strings.Replace(value(field), ",", ".", 1), 64); err == nil {
+			values[field] = v
+		}
+	}
+	return Food{Source: source + ":" + id, Name: name, Nutrition: nutrition(values)}, true
+}
+
+// nutrition - the facts out of the field values, salt from sodium when
+// missing
+func nutrition(values map[string]float64) hrsmodel.Nutrition {
+	salt, ok := values["salt"]
+	if !ok {
+		salt = values["sodium"] * 2.5
+	}
+	return hrsmodel.Nutrition{
+		Energy:        values["energy"],
+		Protein:       values["protein"],
+		Fat:           values["fat"],
+		SaturatedFat:  values["saturatedFat"],
+		Carbohydrates: values["carbohydrates"],
+		Sugar:         values["sugar"],
+		Fiber:         values["fiber"],
+		Salt:          salt,
+	}
+}
+
+// columnKey - a column name as looked up in columns
+func columnKey(name string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), " ", "_")
+}
@@ -0,0 +1,102 @@
+package hrsfood
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		source string
+		food   string
+		energy float64
+		salt   float64
+	}{
+		{
+			name:   "csv",
+			data:   "name,energy,protein,fat,salt\n\"Flour, wheat\",364,10.3,1,0.01\n,1,1,1,1\n",
+			source: "bedca:1",
+			food:   "Flour, wheat",
+			energy: 364,
+			salt:   0.01,
+		},
+		{
+			name:   "off tsv",
+			data:   "code\tproduct_name\tenergy-kcal_100g\tproteins_100g\tsodium_100g\n8410000810004\tHarina de trigo\t340\t10\t0.4\n",
+			source: "bedca:8410000810004",
+			food:   "Harina de trigo",
+			energy: 340,
+			salt:   1,
+		},
+		{
+			name:   "off jsonl",
+			data:   `{"code": "123", "product_name": "Garbanzos", "nutriments": {"energy-kcal_100g": 364, "salt_100g": "0,05"}}` + "\n" + `{"code": "124"}`,
+			source: "off:123",
+			food:   "Garbanzos",
+			energy: 364,
+			salt:   0.05,
+		},
+		{
+			name: "usda",
+			data: `{"SRLegacyFoods": [{"fdcId": 168936, "description": "Flour, wheat, all-purpose",
+				"foodNutrients": [{"nutrient": {"number": "208"}, "amount": 364}, {"nutrient": {"number": "307"}, "amount": 2}]}]}`,
+			source: "usda:168936",
+			food:   "Flour, wheat, all-purpose",
+			energy: 364,
+			salt:   0.005,
+		},
+		{
+			name:   "json array",
+			data:   "\ufeff" + `[{"id": "a", "description": "Egg, whole", "calories": 143}]`,
+			source: "bedca:a",
+			food:   "Egg, whole",
+			energy: 143,
+		},
+	}
+	for _, tt := range tests {
+		foods, err := Read(strings.NewReader(tt.data), "bedca")
+		if err != nil {
+			t.Errorf("Read(%s) error = %v", tt.name, err)
+			continue
+		}
+		if len(foods) != 1 {
+			t.Errorf("Read(%s) = %+v, want 1 food", tt.name, foods)
+			continue
+		}
+		f := foods[0]
+		if f.Source != tt.source || f.Name != tt.food || f.Nutrition.Energy != tt.energy || f.Nutrition.Salt != tt.salt {
+			t.Errorf("Read(%s) = %+v", tt.name, f)
+		}
+	}
+
+	if _, err := Read(strings.NewReader("  "), "x"); err == nil {
+		t.Errorf("Read() empty error = nil")
+	}
+}
+
+func TestMatcher_Best(t *testing.T) {
+	m := NewMatcher([]Food{
+		{Source: "usda:1", Name: "Egg, white, raw"},
+		{Source: "usda:2", Name: "Egg, whole"},
+		{Source: "usda:3", Name: "Flour, wheat, all-purpose, enriched"},
+		{Source: "usda:4", Name: "Flour, rice, white"},
+	})
+
+	tests := []struct {
+		name   string
+		source string
+		found  bool
+	}{
+		{"Eggs", "usda:2", true},
+		{"Wheat flour", "usda:3", true},
+		{"Harina de arroz", "", false},
+	}
+	for _, tt := range tests {
+		best, ok := m.Best(tt.name)
+		if ok != tt.found || (ok && best.Food.Source != tt.source) {
+			t.Errorf("Best(%q) = %+v %v, want %s", tt.name, best, ok, tt.source)
+		}
+	}
+}
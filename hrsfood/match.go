@@ -0,0 +1,70 @@
+package hrsfood
+
+import (
+	"github.com/ninh0gauch0/homerecipes/hrssearch"
+)
+
+// Candidate - a food an ingredient name may be, Score from 0 to 1
+type Candidate struct {
+	Food  *Food
+	Score float64
+}
+
+// Matcher - finds the foods of a dataset by ingredient name
+type Matcher struct {
+	foods []Food
+	terms []map[string]bool
+}
+
+// NewMatcher - a matcher over the foods, their names split in terms once
+func NewMatcher(foods []Food) *Matcher {
+	m := &Matcher{foods: foods, terms: make([]map[string]bool, len(foods))}
+	for i := range foods {
+		m.terms[i] = termSet(foods[i].Name)
+	}
+	return m
+}
+
+// Best - the food most like the name. Dataset names qualify the food
+// ("Flour, wheat, all-purpose"), so covering every term of the name weighs
+// more than the extra words; ties go to the shortest name. ok is false when
+// nothing shares a term.
+func (m *Matcher) Best(name string) (best Candidate, ok bool) {
+	terms := termSet(name)
+	if len(terms) == 0 {
+		return best, false
+	}
+
+	bestTerms := 0
+	for i, food := range m.terms {
+		shared := 0
+		for term := range terms {
+			if food[term] {
+				shared++
+			}
+		}
+		if shared == 0 {
+			continue
+		}
+
+		coverage := float64(shared) / float64(len(terms))
+		jaccard := float64(shared) / float64(len(terms)+len(food)-shared)
+		score := 0.75*coverage + 0.25*jaccard
+
+		if !ok || score > best.Score || (score == best.Score && len(food) < bestTerms) {
+			best, ok = Candidate{Food: &m.foods[i], Score: score}, true
+			bestTerms = len(food)
+		}
+	}
+	return best, ok
+}
+
+/** PRIVATE METHODS **/
+
+func termSet(text string) map[string]bool {
+	set := map[string]bool{}
+	for _, term := range hrssearch.Terms(text) {
+		set[term] = true
+	}
+	return set
+}
@@ -0,0 +1,321 @@
+package hrshtml
+
+import (
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/ninh0gauch0/homerecipes/hrsjsonld"
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/homerecipes/hrssearch"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	// JSONLD Constant
+	JSONLD = "json-ld"
+	// MICRODATA Constant
+	MICRODATA = "microdata"
+	// HEURISTIC Constant
+	HEURISTIC = "heuristic"
+)
+
+// ErrNoRecipe - the page has no recipe that can be told apart
+var ErrNoRecipe = errors.New("no recipe found in the page")
+
+// ingredientHeadings - folded words of the heading over the ingredients
+var ingredientHeadings = []string{"ingredient"}
+
+// stepHeadings - folded words of the heading over the steps
+var stepHeadings = []string{
+	"instruction", "direction", "method", "preparation", "step",
+	"preparacion", "elaboracion", "modo de hacer", "pasos", "procedimiento",
+}
+
+// Extract - the recipes of a saved web page and how they were found: its
+// schema.org JSON-LD, else its microdata, else the lists under ingredient
+// and step headings. Ingredient lines are left as free text.
+func Extract(r io.Reader) ([]*hrsmodel.Recipe, string, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if recipes := linkedData(doc); len(recipes) > 0 {
+		return recipes, JSONLD, nil
+	}
+	if recipes := microdata(doc); len(recipes) > 0 {
+		return recipes, MICRODATA, nil
+	}
+	if recipe := lists(doc); recipe != nil {
+		return []*hrsmodel.Recipe{recipe}, HEURISTIC, nil
+	}
+	return nil, "", ErrNoRecipe
+}
+
+/** PRIVATE METHODS **/
+
+// linkedData - the recipes of the JSON-LD scripts; scripts that can't be
+// read are skipped
+func linkedData(doc *html.Node) []*hrsmodel.Recipe {
+	recipes := []*hrsmodel.Recipe{}
+
+	walk(doc, func(n *html.Node) bool {
+		if n.DataAtom != atom.Script || !strings.EqualFold(attr(n, "type"), hrsjsonld.MEDIATYPE) {
+			return true
+		}
+		if n.FirstChild == nil {
+			return false
+		}
+		if found, err := hrsjsonld.Parse([]byte(n.FirstChild.Data)); err == nil {
+			recipes = append(recipes, found...)
+		}
+		return false
+	})
+	return recipes
+}
+
+// microdata - the recipes of the schema.org microdata items, read as the
+// JSON-LD nodes they stand for
+func microdata(doc *html.Node) []*hrsmodel.Recipe {
+	recipes := []*hrsmodel.Recipe{}
+
+	walk(doc, func(n *html.Node) bool {
+		if !hasAttr(n, "itemscope") || !isRecipeType(attr(n, "itemtype")) {
+			return true
+		}
+		recipes = append(recipes, hrsjsonld.ToRecipe(item(n)))
+		return false
+	})
+	return recipes
+}
+
+// item - the properties of a microdata item; repeated ones become lists
+func item(scope *html.Node) map[string]interface{} {
+	node := map[string]interface{}{}
+	if t := attr(scope, "itemtype"); t != "" {
+		node["@type"] = t
+	}
+
+	var props func(n *html.Node)
+	props = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+
+			names := strings.Fields(attr(c, "itemprop"))
+			if len(names) == 0 {
+				if !hasAttr(c, "itemscope") {
+					props(c)
+				}
+				continue
+			}
+
+			var value interface{}
+			if hasAttr(c, "itemscope") {
+				value = item(c)
+			} else {
+				value = propValue(c)
+				props(c)
+			}
+			for _, name := range names {
+				switch current := node[name].(type) {
+				case nil:
+					node[name] = value
+				case []interface{}:
+					node[name] = append(current, value)
+				default:
+					node[name] = []interface{}{current, value}
+				}
+			}
+		}
+	}
+	props(scope)
+	return node
+}
+
+// propValue - the value of a microdata property element; the items of
+// the lists it holds, like instructions written as a list
+func propValue(n *html.Node) interface{} {
+	items := []interface{}{}
+	walk(n, func(c *html.Node) bool {
+		if c.DataAtom == atom.Li {
+			if text := textContent(c); text != "" {
+				items = append(items, text)
+			}
+			return false
+		}
+		return true
+	})
+	if len(items) > 0 && n.DataAtom != atom.Li {
+		return items
+	}
+
+	switch {
+	case hasAttr(n, "content"):
+		return attr(n, "content")
+	case n.DataAtom == atom.Img || n.DataAtom == atom.Source:
+		return attr(n, "src")
+	case n.DataAtom == atom.A || n.DataAtom == atom.Link:
+		return attr(n, "href")
+	case n.DataAtom == atom.Time && hasAttr(n, "datetime"):
+		return attr(n, "datetime")
+	case n.DataAtom == atom.Meta:
+		return attr(n, "content")
+	default:
+		return textContent(n)
+	}
+}
+
+// lists - a recipe made of the page title and the lists under the
+// ingredient and step headings; without headings the first unordered list
+// holds the ingredients and the first ordered one the steps
+func lists(doc *html.Node) *hrsmodel.Recipe {
+	var title, heading string
+	var ingredients, steps, firstUL, firstOL []string
+
+	walk(doc, func(n *html.Node) bool {
+		switch {
+		case n.DataAtom == atom.Title && title == "":
+			title = textContent(n)
+		case n.DataAtom == atom.H1:
+			title = textContent(n)
+		case isHeading(n):
+			heading = hrssearch.Fold(textContent(n))
+		case n.DataAtom == atom.Ul || n.DataAtom == atom.Ol:
+			items := listItems(n)
+			switch {
+			case len(items) == 0:
+			case ingredients == nil && containsAny(heading, ingredientHeadings):
+				ingredients = items
+			case steps == nil && containsAny(heading, stepHeadings):
+				steps = items
+			case n.DataAtom == atom.Ul && firstUL == nil:
+				firstUL = items
+			case n.DataAtom == atom.Ol && firstOL == nil:
+				firstOL = items
+			}
+			return false
+		}
+		return true
+	})
+
+	if ingredients == nil {
+		ingredients = firstUL
+	}
+	if steps == nil {
+		steps = firstOL
+	}
+	if len(ingredients) == 0 || len(steps) == 0 {
+		return nil
+	}
+
+	recipe := &hrsmodel.Recipe{}
+	recipe.Name = title
+	recipe.Steps = steps
+	for _, text := range ingredients {
+		recipe.Lines = append(recipe.Lines, hrsmodel.IngredientLine{Text: text})
+	}
+	return recipe
+}
+
+// listItems - the texts of the items of a list
+func listItems(list *html.Node) []string {
+	items := []string{}
+	for c := list.FirstChild; c != nil; c = c.NextSibling {
+		if c.DataAtom != atom.Li {
+			continue
+		}
+		if text := textContent(c); text != "" {
+			items = append(items, text)
+		}
+	}
+	return items
+}
+
+// walk - visits the nodes of the tree in document order; fn returns false
+// to skip the children of a node
+func walk(n *html.Node, fn func(n *html.Node) bool) {
+	if n.Type == html.ElementNode && !fn(n) {
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walk(c, fn)
+	}
+}
+
+// textContent - the text of a node and its children, spaces collapsed and
+// blocks apart
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	var collect func(n *html.Node)
+	collect = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			b.WriteString(n.Data)
+		case n.DataAtom == atom.Script || n.DataAtom == atom.Style:
+			return
+		case isBlock(n):
+			b.WriteString(" ")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			collect(c)
+		}
+	}
+	collect(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasAttr(n *html.Node, name string) bool {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return true
+		}
+	}
+	return false
+}
+
+func isHeading(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		return true
+	}
+	return false
+}
+
+func isBlock(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.Br, atom.P, atom.Div, atom.Li, atom.Td, atom.Th, atom.Tr, atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		return true
+	}
+	return false
+}
+
+func isRecipeType(itemtype string) bool {
+	for _, t := range strings.Fields(itemtype) {
+		if strings.HasSuffix(strings.TrimSuffix(t, "/"), "schema.org/Recipe") {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(text string, words []string) bool {
+	for _, word := range words {
+		if strings.Contains(text, word) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package hrshtml
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name   string
+		page   string
+		method string
+		recipe string
+		lines  int
+		steps  []string
+	}{
+		{
+			name: "json-ld",
+			page: `<html><head><script type="application/ld+json">
+				{"@context": "https://schema.org", "@type": "Recipe", "name": "Pisto",
+				 "recipeIngredient": ["2 calabacines", "1 cebolla"],
+				 "recipeInstructions": [{"@type": "HowToStep", "text": "Pochar   la cebolla."}]}
+				</script></head><body><h1>Otro título</h1></body></html>`,
+			method: JSONLD,
+			recipe: "Pisto",
+			lines:  2,
+			steps:  []string{"Pochar   la cebolla."},
+		},
+		{
+			name: "microdata",
+			page: `<div itemscope itemtype="http://schema.org/Recipe">
+				<h1 itemprop="name">Gazpacho</h1>
+				<meta itemprop="totalTime" content="PT15M">
+				<img itemprop="image" src="gazpacho.jpg">
+				<ul><li itemprop="recipeIngredient">1 kg de tomates</li><li itemprop="recipeIngredient">1 pepino</li></ul>
+				<div itemprop="recipeInstructions"><ol><li>Triturar.</li><li>Enfriar.</li></ol></div>
+				</div>`,
+			method: MICRODATA,
+			recipe: "Gazpacho",
+			lines:  2,
+			steps:  []string{"Triturar.", "Enfriar."},
+		},
+		{
+			name: "lists",
+			page: `<html><head><title>Blog de cocina</title></head><body>
+				<nav><ul><li>Inicio</li><li>Recetas</li></ul></nav>
+				<h1>Lentejas de la abuela</h1>
+				<h2>Ingredientes</h2><ul><li>300 g de lentejas</li><li>1 chorizo</li><li>Sal</li></ul>
+				<h2>Preparación</h2><ol><li>Poner en remojo.</li><li>Cocer <b>40 minutos</b>.</li></ol>
+				</body></html>`,
+			method: HEURISTIC,
+			recipe: "Lentejas de la abuela",
+			lines:  3,
+			steps:  []string{"Poner en remojo.", "Cocer 40 minutos."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recipes, method, err := Extract(strings.NewReader(tt.page))
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			r := recipes[0]
+			if method != tt.method || r.Name != tt.recipe || len(r.Lines) != tt.lines || !reflect.DeepEqual(r.Steps, tt.steps) {
+				t.Errorf("Extract() = %s %q %d lines %q", method, r.Name, len(r.Lines), r.Steps)
+			}
+		})
+	}
+
+	if _, _, err := Extract(strings.NewReader(`<p>Nada</p>`)); err != ErrNoRecipe {
+		t.Errorf("Extract() without recipe error = %v", err)
+	}
+}
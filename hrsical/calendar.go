@@ -0,0 +1,96 @@
+package hrsical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// LOCALTIME Constant
+	LOCALTIME = "20060102T150405"
+	// UTCTIME Constant
+	UTCTIME = "20060102T150405Z"
+	// MAXLINE Constant
+	MAXLINE = 75
+)
+
+// Event - a calendar event. Times are floating: they are written without a
+// zone, so calendars show them at that hour wherever they are.
+type Event struct {
+	UID         string
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Description string
+	URL         string
+}
+
+// Calendar - an iCalendar (RFC 5545) document
+type Calendar struct {
+	Name   string
+	Events []Event
+}
+
+// escaper - text value escaping of RFC 5545
+var escaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// Write - writes the calendar as text/calendar, lines folded and ended
+// with CRLF
+func (c *Calendar) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	stamp := time.Now().UTC().Format(UTCTIME)
+
+	line := func(name string, value string) {
+		writeFolded(bw, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//homerecipes//Home Recipes Service//EN")
+	line("CALSCALE", "GREGORIAN")
+	if c.Name != "" {
+		line("X-WR-CALNAME", escaper.Replace(c.Name))
+	}
+
+	for _, e := range c.Events {
+		line("BEGIN", "VEVENT")
+		line("UID", e.UID)
+		line("DTSTAMP", stamp)
+		line("DTSTART", e.Start.Format(LOCALTIME))
+		line("DTEND", e.End.Format(LOCALTIME))
+		line("SUMMARY", escaper.Replace(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION", escaper.Replace(e.Description))
+		}
+		if e.URL != "" {
+			line("URL", e.URL)
+		}
+		line("END", "VEVENT")
+	}
+
+	line("END", "VCALENDAR")
+	return bw.Flush()
+}
+
+/** PRIVATE METHODS **/
+
+// writeFolded - writes a content line split in lines of at most MAXLINE
+// octets, continuation lines starting with a space. Runes aren't split.
+func writeFolded(w *bufio.Writer, text string) {
+	limit := MAXLINE
+	for len(text) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		w.WriteString(text[:cut])
+		w.WriteString("\r\n ")
+		text = text[cut:]
+		limit = MAXLINE - 1
+	}
+	w.WriteString(text)
+	w.WriteString("\r\n")
+}
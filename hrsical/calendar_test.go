@@ -0,0 +1,49 @@
+package hrsical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalendar_Write(t *testing.T) {
+	start := time.Date(2024, 3, 4, 14, 0, 0, 0, time.UTC)
+	c := &Calendar{Name: "Casa", Events: []Event{{
+		UID:         "e1@homerecipes",
+		Start:       start,
+		End:         start.Add(time.Hour),
+		Summary:     "Lentejas, con chorizo; receta de la abuela",
+		Description: strings.Repeat("Pochar la cebolla a fuego lento. ", 4) + "\nServir",
+		URL:         "http://localhost/hrs/recipes/r1",
+	}}}
+
+	var buf bytes.Buffer
+	if err := c.Write(&buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"DTSTART:20240304T140000\r\n",
+		"DTEND:20240304T150000\r\n",
+		`SUMMARY:Lentejas\, con chorizo\; receta de la abuela` + "\r\n",
+		"URL:http://localhost/hrs/recipes/r1\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Write() lacks %q:\n%s", want, out)
+		}
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		if len(line) > MAXLINE {
+			t.Errorf("Write() line longer than %d octets: %q", MAXLINE, line)
+		}
+	}
+	unfolded := strings.ReplaceAll(out, "\r\n ", "")
+	if !strings.Contains(unfolded, `lento. \nServir`) {
+		t.Errorf("Write() description not folded back: %s", unfolded)
+	}
+}
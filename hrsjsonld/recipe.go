@@ -0,0 +1,333 @@
+package hrsjsonld
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/homerecipes/hrssearch"
+)
+
+const (
+	// MEDIATYPE Constant
+	MEDIATYPE = "application/ld+json"
+	// CONTEXT Constant
+	CONTEXT = "https://schema.org"
+)
+
+// ErrNoRecipe - the document has no schema.org Recipe
+var ErrNoRecipe = errors.New("no schema.org Recipe found")
+
+// servingsYield - a yield that is a number of people: "4", "serves 4",
+// "4 servings", "para 4 personas"
+var servingsYield = regexp.MustCompile(`^(?:serves|makes|for|para|sirve)?\s*(\d+)\s*(?:servings?|people|persons|portions?|raciones|personas|porciones|comensales)?$`)
+
+// Recipe - a schema.org Recipe as JSON-LD
+type Recipe struct {
+	Context            string        `json:"@context"`
+	Type               string        `json:"@type"`
+	Name               string        `json:"name"`
+	Description        string        `json:"description,omitempty"`
+	Image              []string      `json:"image,omitempty"`
+	RecipeYield        []string      `json:"recipeYield,omitempty"`
+	PrepTime           string        `json:"prepTime,omitempty"`
+	CookTime           string        `json:"cookTime,omitempty"`
+	TotalTime          string        `json:"totalTime,omitempty"`
+	RecipeIngredient   []string      `json:"recipeIngredient"`
+	RecipeInstructions []interface{} `json:"recipeInstructions"`
+}
+
+// HowToStep - one instruction
+type HowToStep struct {
+	Type string `json:"@type"`
+	Text string `json:"text"`
+}
+
+// HowToSection - the instructions under a heading
+type HowToSection struct {
+	Type            string      `json:"@type"`
+	Name            string      `json:"name"`
+	ItemListElement []HowToStep `json:"itemListElement"`
+}
+
+// FromRecipe - the recipe as a schema.org Recipe. Lines referencing an
+// ingredient are written with its name.
+func FromRecipe(recipe *hrsmodel.Recipe, ingredients map[string]*hrsmodel.Ingredient) *Recipe {
+	doc := &Recipe{
+		Context:            CONTEXT,
+		Type:               "Recipe",
+		Name:               recipe.Name,
+		Description:        recipe.Description,
+		Image:              recipe.Images,
+		PrepTime:           FormatDuration(recipe.PrepTime),
+		CookTime:           FormatDuration(recipe.CookTime),
+		TotalTime:          FormatDuration(recipe.TotalTime),
+		RecipeIngredient:   []string{},
+		RecipeInstructions: []interface{}{},
+	}
+
+	if recipe.Servings > 0 {
+		doc.RecipeYield = append(doc.RecipeYield, strconv.Itoa(recipe.Servings))
+	}
+	if recipe.Yield != "" {
+		doc.RecipeYield = append(doc.RecipeYield, recipe.Yield)
+	}
+
+	for _, line := range recipe.Lines {
+		if ingredient := ingredients[line.Ingredient]; line.Name == "" && ingredient != nil {
+			line.Name = ingredient.Name
+		}
+		doc.RecipeIngredient = append(doc.RecipeIngredient, line.String())
+	}
+
+	var section *HowToSection
+	for i, step := range recipe.Steps {
+		group := recipe.StepGroup(i)
+		if group == "" {
+			section = nil
+			doc.RecipeInstructions = append(doc.RecipeInstructions, HowToStep{Type: "HowToStep", Text: step})
+			continue
+		}
+		if section == nil || section.Name != group {
+			section = &HowToSection{Type: "HowToSection", Name: group}
+			doc.RecipeInstructions = append(doc.RecipeInstructions, section)
+		}
+		section.ItemListElement = append(section.ItemListElement, HowToStep{Type: "HowToStep", Text: step})
+	}
+	return doc
+}
+
+// Parse - the schema.org Recipes of a JSON-LD document, found at its top,
+// in a list, in a @graph or nested in other nodes. Ingredient lines are
+// left as free text.
+func Parse(data []byte) ([]*hrsmodel.Recipe, error) {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("wrong JSON-LD: %w", err)
+	}
+
+	recipes := []*hrsmodel.Recipe{}
+	collect(doc, &recipes)
+	if len(recipes) == 0 {
+		return nil, ErrNoRecipe
+	}
+	return recipes, nil
+}
+
+// ToRecipe - maps a schema.org Recipe node
+func ToRecipe(node map[string]interface{}) *hrsmodel.Recipe {
+	recipe := &hrsmodel.Recipe{}
+	recipe.Name = text(node["name"])
+	recipe.Description = text(node["description"])
+	recipe.Images = images(node["image"])
+	recipe.PrepTime = ParseDuration(text(node["prepTime"]))
+	recipe.CookTime = ParseDuration(text(node["cookTime"]))
+	recipe.TotalTime = ParseDuration(text(node["totalTime"]))
+
+	for _, value := range list(node["recipeYield"]) {
+		yield := text(value)
+		if m := servingsYield.FindStringSubmatch(hrssearch.Fold(yield)); m != nil && recipe.Servings == 0 {
+			recipe.Servings, _ = strconv.Atoi(m[1])
+		} else if recipe.Yield == "" {
+			recipe.Yield = yield
+		}
+	}
+
+	ingredients := node["recipeIngredient"]
+	if ingredients == nil {
+		ingredients = node["ingredients"]
+	}
+	for _, value := range list(ingredients) {
+		if line := text(value); line != "" {
+			recipe.Lines = append(recipe.Lines, hrsmodel.IngredientLine{Text: line})
+		}
+	}
+
+	grouped := false
+	instructions(node["recipeInstructions"], "", func(group string, step string) {
+		recipe.Steps = append(recipe.Steps, step)
+		recipe.StepGroups = append(recipe.StepGroups, group)
+		grouped = grouped || group != ""
+	})
+	if !grouped {
+		recipe.StepGroups = nil
+	}
+	return recipe
+}
+
+// ParseDuration - the minutes of an ISO 8601 duration like PT1H30M, zero
+// when it isn't one
+func ParseDuration(value string) int {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if !strings.HasPrefix(value, "P") {
+		return 0
+	}
+
+	minutes, number, inTime := 0.0, "", false
+	for _, r := range value[1:] {
+		switch {
+		case r == 'T':
+			inTime = true
+		case r >= '0' && r <= '9', r == '.', r == ',':
+			number += string(r)
+		default:
+			n, err := strconv.ParseFloat(strings.Replace(number, ",", ".", 1), 64)
+			if err != nil {
+				return 0
+			}
+			number = ""
+			switch {
+			case r == 'D':
+				minutes += n * 24 * 60
+			case r == 'H' && inTime:
+				minutes += n * 60
+			case r == 'M' && inTime:
+				minutes += n
+			case r == 'S' && inTime:
+				minutes += n / 60
+			default:
+				return 0
+			}
+		}
+	}
+	return int(minutes + 0.5)
+}
+
+// FormatDuration - minutes as an ISO 8601 duration, none for zero
+func FormatDuration(minutes int) string {
+	if minutes <= 0 {
+		return ""
+	}
+
+	duration := "PT"
+	if hours := minutes / 60; hours > 0 {
+		duration += strconv.Itoa(hours) + "H"
+	}
+	if minutes%60 > 0 {
+		duration += strconv.Itoa(minutes%60) + "M"
+	}
+	return duration
+}
+
+/** PRIVATE METHODS **/
+
+// collect - walks the document looking for Recipe nodes
+func collect(node interface{}, recipes *[]*hrsmodel.Recipe) {
+	switch n := node.(type) {
+	case []interface{}:
+		for _, item := range n {
+			collect(item, recipes)
+		}
+	case map[string]interface{}:
+		if isType(n["@type"], "Recipe") {
+			*recipes = append(*recipes, ToRecipe(n))
+			return
+		}
+		for key, value := range n {
+			if key != "@context" {
+				collect(value, recipes)
+			}
+		}
+	}
+}
+
+// isType - whether the @type value, a name or a list of them, is name in
+// any of its spellings: Recipe, schema:Recipe, http://schema.org/Recipe
+func isType(value interface{}, name string) bool {
+	for _, t := range list(value) {
+		s, _ := t.(string)
+		if i := strings.LastIndexAny(s, "/:"); i >= 0 {
+			s = s[i+1:]
+		}
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+// instructions - calls fn with every step of recipeInstructions: a text, a
+// list of texts, HowToSteps, HowToSections or ItemLists of them
+func instructions(value interface{}, group string, fn func(group string, step string)) {
+	switch v := value.(type) {
+	case string:
+		for _, step := range strings.Split(html.UnescapeString(v), "\n") {
+			if step = strings.TrimSpace(step); step != "" {
+				fn(group, step)
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			instructions(item, group, fn)
+		}
+	case map[string]interface{}:
+		if items, ok := v["itemListElement"]; ok {
+			if isType(v["@type"], "HowToSection") {
+				group = text(v["name"])
+			}
+			instructions(items, group, fn)
+			return
+		}
+		step := text(v["text"])
+		if step == "" {
+			step = text(v["name"])
+		}
+		instructions(step, group, fn)
+	}
+}
+
+// images - the urls of an image value: a url, an ImageObject or a list
+func images(value interface{}) []string {
+	urls := []string{}
+	for _, item := range list(value) {
+		url := text(item)
+		if object, ok := item.(map[string]interface{}); ok {
+			url = text(object["url"])
+			if url == "" {
+				url = text(object["contentUrl"])
+			}
+		}
+		if url != "" {
+			urls = append(urls, url)
+		}
+	}
+	if len(urls) == 0 {
+		return nil
+	}
+	return urls
+}
+
+// list - the value as a list, a single value being a list of one
+func list(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return v
+	default:
+		return []interface{}{v}
+	}
+}
+
+// text - a text value without HTML entities; the first of a list, numbers
+// written as text
+func text(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(html.UnescapeString(v))
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []interface{}:
+		if len(v) > 0 {
+			return text(v[0])
+		}
+	case map[string]interface{}:
+		return text(v["@value"])
+	}
+	return ""
+}
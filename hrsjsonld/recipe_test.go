@@ -0,0 +1,106 @@
+package hrsjsonld
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/hrstypes"
+)
+
+const page = `{
+	"@context": "https://schema.org",
+	"@graph": [
+		{"@type": "WebPage", "name": "Recetas"},
+		{
+			"@type": ["Recipe", "NewsArticle"],
+			"name": "Tarta de queso &amp; frambuesas",
+			"description": "Sin horno",
+			"image": [{"@type": "ImageObject", "url": "https://example.com/tarta.jpg"}, "https://example.com/tarta2.jpg"],
+			"recipeYield": ["8", "1 tarta de 24 cm"],
+			"prepTime": "PT20M",
+			"cookTime": "PT1H",
+			"totalTime": "P0DT4H20M",
+			"recipeIngredient": ["200 g galletas", "100 g mantequilla"],
+			"recipeInstructions": [
+				{"@type": "HowToSection", "name": "Base", "itemListElement": [
+					{"@type": "HowToStep", "text": "Triturar las galletas."},
+					{"@type": "HowToStep", "text": "Mezclar con la mantequilla."}
+				]},
+				{"@type": "HowToSection", "name": "Relleno", "itemListElement": [
+					{"@type": "HowToStep", "name": "Batir el queso."}
+				]}
+			]
+		}
+	]
+}`
+
+func TestParse(t *testing.T) {
+	recipes, err := Parse([]byte(page))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(recipes) != 1 {
+		t.Fatalf("Parse() = %d recipes", len(recipes))
+	}
+
+	r := recipes[0]
+	if r.Name != "Tarta de queso & frambuesas" || r.Description != "Sin horno" || r.Servings != 8 || r.Yield != "1 tarta de 24 cm" {
+		t.Errorf("Parse() = %+v", r)
+	}
+	if r.PrepTime != 20 || r.CookTime != 60 || r.TotalTime != 260 {
+		t.Errorf("Parse() times = %d %d %d", r.PrepTime, r.CookTime, r.TotalTime)
+	}
+	if !reflect.DeepEqual(r.Images, []string{"https://example.com/tarta.jpg", "https://example.com/tarta2.jpg"}) {
+		t.Errorf("Parse() images = %v", r.Images)
+	}
+	if len(r.Lines) != 2 || !r.Lines[0].IsRaw() || r.Lines[0].Text != "200 g galletas" {
+		t.Errorf("Parse() lines = %+v", r.Lines)
+	}
+	if !reflect.DeepEqual(r.Steps, []string{"Triturar las galletas.", "Mezclar con la mantequilla.", "Batir el queso."}) ||
+		!reflect.DeepEqual(r.StepGroups, []string{"Base", "Base", "Relleno"}) {
+		t.Errorf("Parse() steps = %v %v", r.Steps, r.StepGroups)
+	}
+
+	if _, err = Parse([]byte(`{"@type": "WebPage"}`)); err != ErrNoRecipe {
+		t.Errorf("Parse() without recipe error = %v", err)
+	}
+}
+
+func TestFromRecipe(t *testing.T) {
+	recipe := &hrsmodel.Recipe{
+		Recipe:     hrstypes.Recipe{Name: "Gazpacho", Steps: []string{"Trocear.", "Triturar.", "Enfriar."}},
+		Servings:   4,
+		TotalTime:  135,
+		StepGroups: []string{"", "", "Servir"},
+		Lines: []hrsmodel.IngredientLine{
+			{Ingredient: "i-tomato", Quantity: &hrsmodel.Quantity{Value: 1}, Unit: "kg", Note: "maduros"},
+			{Name: "pepino", Quantity: &hrsmodel.Quantity{Value: 1}, Optional: true},
+			{Text: "sal al gusto"},
+		},
+	}
+	ingredients := map[string]*hrsmodel.Ingredient{"i-tomato": {Ingredient: hrstypes.Ingredient{Name: "tomates"}}}
+
+	doc := FromRecipe(recipe, ingredients)
+
+	if !reflect.DeepEqual(doc.RecipeIngredient, []string{"1 kg tomates, maduros", "1 pepino (optional)", "sal al gusto"}) {
+		t.Errorf("FromRecipe() ingredients = %q", doc.RecipeIngredient)
+	}
+	if doc.TotalTime != "PT2H15M" || !reflect.DeepEqual(doc.RecipeYield, []string{"4"}) {
+		t.Errorf("FromRecipe() = %+v", doc)
+	}
+	if len(doc.RecipeInstructions) != 3 {
+		t.Fatalf("FromRecipe() instructions = %+v", doc.RecipeInstructions)
+	}
+
+	data, _ := json.Marshal(doc)
+	back, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if back[0].Servings != 4 || back[0].TotalTime != 135 || !reflect.DeepEqual(back[0].Steps, recipe.Steps) ||
+		!reflect.DeepEqual(back[0].StepGroups, recipe.StepGroups) {
+		t.Errorf("Parse(FromRecipe()) = %+v", back[0])
+	}
+}
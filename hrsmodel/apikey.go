@@ -0,0 +1,62 @@
+package hrsmodel
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// RECIPESREAD Constant
+	RECIPESREAD = "recipes:read"
+	// RECIPESWRITE Constant
+	RECIPESWRITE = "recipes:write"
+	// INGREDIENTSWRITE Constant
+	INGREDIENTSWRITE = "ingredients:write"
+)
+
+// scopes - what API keys may be allowed to do
+var scopes = []string{RECIPESREAD, RECIPESWRITE, INGREDIENTSWRITE}
+
+// APIKey - a key scripts authenticate with as their user, only for what
+// its scopes allow. Code is the id the key starts with and Hash the hash
+// of the whole key, which is never kept; no Expires means it doesn't.
+type APIKey struct {
+	Code    string    `json:"code" bson:"code"`
+	User    string    `json:"user" bson:"user"`
+	Name    string    `json:"name" bson:"name"`
+	Hash    string    `json:"hash,omitempty" bson:"hash"`
+	Scopes  []string  `json:"scopes" bson:"scopes"`
+	Created time.Time `json:"created" bson:"created"`
+	Expires time.Time `json:"expires,omitempty" bson:"expires,omitempty"`
+}
+
+// GetObjectInfo - Interface DTOObject Implementation
+func (k *APIKey) GetObjectInfo() string {
+	return fmt.Sprintf("API key %s (%s) with scopes %v", k.Name, k.Code, k.Scopes)
+}
+
+// Validate - checks the name and the scopes
+func (k *APIKey) Validate() error {
+	if k.Name == "" {
+		return fmt.Errorf("%w: the API key needs a name", ErrInvalid)
+	}
+	if len(k.Scopes) == 0 {
+		return fmt.Errorf("%w: the API key needs some of the scopes %v", ErrInvalid, scopes)
+	}
+	for _, scope := range k.Scopes {
+		if !contains(scopes, scope) {
+			return fmt.Errorf("%w: unknown scope %q, the scopes are %v", ErrInvalid, scope, scopes)
+		}
+	}
+	return nil
+}
+
+// Allows - whether the key has the scope
+func (k *APIKey) Allows(scope string) bool {
+	return contains(k.Scopes, scope)
+}
+
+// Expired - whether the key can't be used any more
+func (k *APIKey) Expired(now time.Time) bool {
+	return !k.Expires.IsZero() && !now.Before(k.Expires)
+}
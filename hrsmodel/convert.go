@@ -0,0 +1,52 @@
+package hrsmodel
+
+import (
+	"fmt"
+
+	"github.com/ninh0gauch0/homerecipes/hrsunits"
+)
+
+// Convert - a copy of the recipe written in the given measurement system.
+// Densities, in g/ml by ingredient reference, let volumes be weighed.
+// Temperatures in the steps are converted too.
+func (r *Recipe) Convert(system string, densities map[string]float64) (*Recipe, error) {
+	if system != hrsunits.METRIC && system != hrsunits.IMPERIAL {
+		return nil, fmt.Errorf("%w: unknown measurement system %q", ErrInvalid, system)
+	}
+
+	converted := *r
+	converted.Lines = make([]IngredientLine, len(r.Lines))
+
+	for i, line := range r.Lines {
+		if line.Quantity != nil && line.Unit != "" {
+			line.Quantity, line.Unit = convertQuantity(line.Quantity, line.Unit, system, densities[line.Ref()])
+		}
+		converted.Lines[i] = line
+	}
+	if r.Steps != nil {
+		converted.Steps = make([]string, len(r.Steps))
+		for i, step := range r.Steps {
+			converted.Steps[i] = hrsunits.ConvertTemperatures(step, system)
+		}
+	}
+	return &converted, nil
+}
+
+// convertQuantity - converts q, the upper end of a range into the unit the
+// lower one takes
+func convertQuantity(q *Quantity, unit string, system string, density float64) (*Quantity, string) {
+	value, to := hrsunits.ToSystem(q.Value, unit, system, density)
+	if to == unit {
+		return q, unit
+	}
+	converted := &Quantity{Value: value}
+
+	if q.IsRange() {
+		max, err := hrsunits.ConvertWith(q.Max, unit, to, density)
+		if err != nil {
+			max = q.Max
+		}
+		converted.Max = hrsunits.Round(max, to)
+	}
+	return converted, to
+}
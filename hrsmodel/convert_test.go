@@ -0,0 +1,41 @@
+package hrsmodel
+
+import (
+	"testing"
+
+	"github.com/ninh0gauch0/hrstypes"
+)
+
+func TestRecipe_Convert(t *testing.T) {
+	recipe := &Recipe{
+		Recipe: hrstypes.Recipe{Code: "r1", Name: "Bizcocho", Steps: []string{"Hornear a 180 °C"}},
+		Lines: []IngredientLine{
+			{Ingredient: "flour", Quantity: &Quantity{Value: 2}, Unit: "cup"},
+			{Ingredient: "milk", Quantity: &Quantity{Value: 1, Max: 2}, Unit: "cup"},
+			{Ingredient: "eggs", Quantity: &Quantity{Value: 3}},
+		},
+	}
+
+	converted, err := recipe.Convert("metric", map[string]float64{"flour": 0.53})
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+	if q := converted.Lines[0].Quantity; q.Value != 250 || converted.Lines[0].Unit != "g" {
+		t.Errorf("Convert() flour = %+v %s, want 250 g", *q, converted.Lines[0].Unit)
+	}
+	if q := converted.Lines[1].Quantity; q.Value != 235 || q.Max != 475 || converted.Lines[1].Unit != "ml" {
+		t.Errorf("Convert() milk = %+v %s, want 235-475 ml", *q, converted.Lines[1].Unit)
+	}
+	if converted.Lines[2].Quantity.Value != 3 || recipe.Lines[0].Unit != "cup" {
+		t.Errorf("Convert() = %+v", converted.Lines)
+	}
+
+	converted, _ = recipe.Convert("imperial", nil)
+	if converted.Steps[0] != "Hornear a 350 °F" || recipe.Steps[0] != "Hornear a 180 °C" {
+		t.Errorf("Convert() steps = %v", converted.Steps)
+	}
+
+	if _, err = recipe.Convert("cubits", nil); err == nil {
+		t.Errorf("Convert() unknown system error = nil")
+	}
+}
@@ -0,0 +1,136 @@
+package hrsmodel
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// OWNER Constant
+	OWNER = "owner"
+	// EDITOR Constant
+	EDITOR = "editor"
+	// VIEWER Constant
+	VIEWER = "viewer"
+)
+
+// roles - what a household member may do, from more to less
+var roles = []string{OWNER, EDITOR, VIEWER}
+
+// Household - a family sharing recipes, ingredients, lists, plans and
+// pantry, which nobody else sees. Version counts its saves, so that one
+// read before another save can't undo it.
+type Household struct {
+	Code    string   `json:"code" bson:"code"`
+	Name    string   `json:"name" bson:"name"`
+	Members []Member `json:"members" bson:"members"`
+	Version int      `json:"version" bson:"version"`
+}
+
+// Member - a user of a household and their role: owners manage the
+// household, editors change its data and viewers only read it
+type Member struct {
+	User     string `json:"user" bson:"user"`
+	Username string `json:"username" bson:"username"`
+	Role     string `json:"role" bson:"role"`
+}
+
+// Invitation - lets whoever holds its code join a household with a role,
+// only the user named Username when it is set
+type Invitation struct {
+	Code      string    `json:"code" bson:"code"`
+	Household string    `json:"household" bson:"household"`
+	Role      string    `json:"role" bson:"role"`
+	Username  string    `json:"username,omitempty" bson:"username,omitempty"`
+	InvitedBy string    `json:"invitedBy" bson:"invitedBy"`
+	Expires   time.Time `json:"expires" bson:"expires"`
+}
+
+// GetObjectInfo - Interface DTOObject Implementation
+func (h *Household) GetObjectInfo() string {
+	return fmt.Sprintf("household %s (%s) of %d members", h.Name, h.Code, len(h.Members))
+}
+
+// Validate - checks the name and the members: known roles, each user once
+// and an owner at least
+func (h *Household) Validate() error {
+	if h.Name == "" {
+		return fmt.Errorf("%w: the household needs a name", ErrInvalid)
+	}
+
+	owners := 0
+	seen := map[string]bool{}
+	for _, member := range h.Members {
+		if !contains(roles, member.Role) {
+			return fmt.Errorf("%w: the role must be one of %v", ErrInvalid, roles)
+		}
+		if seen[member.User] {
+			return fmt.Errorf("%w: %s is a member already", ErrInvalid, member.Username)
+		}
+		seen[member.User] = true
+		if member.Role == OWNER {
+			owners++
+		}
+	}
+	if owners == 0 {
+		return fmt.Errorf("%w: the household needs an owner", ErrInvalid)
+	}
+	return nil
+}
+
+// Role - the role of a user in the household, empty when not a member
+func (h *Household) Role(user string) string {
+	if member := h.Member(user); member != nil {
+		return member.Role
+	}
+	return ""
+}
+
+// Member - the membership of a user, nil when not a member
+func (h *Household) Member(user string) *Member {
+	for i := range h.Members {
+		if h.Members[i].User == user {
+			return &h.Members[i]
+		}
+	}
+	return nil
+}
+
+// RemoveMember - takes a user out of the household, whether they were in
+func (h *Household) RemoveMember(user string) bool {
+	for i := range h.Members {
+		if h.Members[i].User == user {
+			h.Members = append(h.Members[:i], h.Members[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// GetObjectInfo - Interface DTOObject Implementation
+func (i *Invitation) GetObjectInfo() string {
+	return fmt.Sprintf("invitation %s to household %s as %s", i.Code, i.Household, i.Role)
+}
+
+// Validate - checks the role
+func (i *Invitation) Validate() error {
+	if !contains(roles, i.Role) {
+		return fmt.Errorf("%w: the role must be one of %v", ErrInvalid, roles)
+	}
+	return nil
+}
+
+// Expired - whether the invitation can't be accepted any more
+func (i *Invitation) Expired(now time.Time) bool {
+	return !now.Before(i.Expires)
+}
+
+// CanRead - whether the role lets its members read the household data
+func CanRead(role string) bool {
+	return contains(roles, role)
+}
+
+// CanWrite - whether the role lets its members change the household data
+func CanWrite(role string) bool {
+	return role == OWNER || role == EDITOR
+}
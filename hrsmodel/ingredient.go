@@ -0,0 +1,47 @@
+package hrsmodel
+
+import (
+	"fmt"
+
+	"github.com/ninh0gauch0/hrstypes"
+)
+
+// Ingredient - a hrstypes.Ingredient with the data needed to convert its
+// amounts. Density is grams per millilitre, so a cup of flour can be
+// weighed; UnitWeight is what a piece weighs in grams, an egg or a lemon.
+// Both are zero when unknown. Nutrition is per 100 g; NutritionSource is
+// the dataset entry it was taken from, like "usda:171413". Category is
+// the store aisle it is bought in, shopping lists are grouped by it.
+type Ingredient struct {
+	hrstypes.Ingredient `bson:",inline"`
+	Density             float64    `json:"density,omitempty" bson:"density,omitempty"`
+	UnitWeight          float64    `json:"unitWeight,omitempty" bson:"unitWeight,omitempty"`
+	Nutrition           *Nutrition `json:"nutrition,omitempty" bson:"nutrition,omitempty"`
+	NutritionSource     string     `json:"nutritionSource,omitempty" bson:"nutritionSource,omitempty"`
+	Category            string     `json:"category,omitempty" bson:"category,omitempty"`
+}
+
+// FromLegacyIngredient - wraps an ingredient stored without conversion data
+func FromLegacyIngredient(legacy *hrstypes.Ingredient) *Ingredient {
+	return &Ingredient{Ingredient: *legacy}
+}
+
+// Validate - checks the conversion and nutrition data
+func (i *Ingredient) Validate() error {
+	if i.Density < 0 || i.UnitWeight < 0 {
+		return fmt.Errorf("%w: density and unit weight can't be negative", ErrInvalid)
+	}
+
+	if n := i.Nutrition; n != nil {
+		for _, v := range []float64{n.Energy, n.Protein, n.Fat, n.SaturatedFat, n.Carbohydrates, n.Sugar, n.Fiber, n.Salt} {
+			if v < 0 {
+				return fmt.Errorf("%w: nutrition facts can't be negative", ErrInvalid)
+			}
+		}
+		// Sources round their figures, leave them some slack
+		if n.Protein+n.Fat+n.Carbohydrates+n.Salt > 101 {
+			return fmt.Errorf("%w: nutrition facts don't add up for 100 g", ErrInvalid)
+		}
+	}
+	return nil
+}
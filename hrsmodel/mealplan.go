@@ -0,0 +1,94 @@
+package hrsmodel
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	// BREAKFAST Constant
+	BREAKFAST = "breakfast"
+	// LUNCH Constant
+	LUNCH = "lunch"
+	// DINNER Constant
+	DINNER = "dinner"
+	// DATEFORMAT Constant
+	DATEFORMAT = "2006-01-02"
+)
+
+// slots - the meals of a day, in order
+var slots = []string{BREAKFAST, LUNCH, DINNER}
+
+// MealPlanEntry - a recipe planned for a meal of a day. Date is written
+// as 2006-01-02; no servings means the recipe ones.
+type MealPlanEntry struct {
+	Code     string `json:"code" bson:"code"`
+	Date     string `json:"date" bson:"date"`
+	Slot     string `json:"slot" bson:"slot"`
+	Recipe   string `json:"recipe" bson:"recipe"`
+	Servings int    `json:"servings,omitempty" bson:"servings,omitempty"`
+}
+
+// GetObjectInfo - Interface DTOObject Implementation
+func (e *MealPlanEntry) GetObjectInfo() string {
+	return fmt.Sprintf("%s %s: recipe %s", e.Date, e.Slot, e.Recipe)
+}
+
+// Validate - checks the date, the slot and the servings
+func (e *MealPlanEntry) Validate() error {
+	if _, err := ParseDate(e.Date); err != nil {
+		return err
+	}
+	if slotOrder(e.Slot) < 0 {
+		return fmt.Errorf("%w: the slot must be one of %v", ErrInvalid, slots)
+	}
+	if e.Recipe == "" {
+		return fmt.Errorf("%w: the entry needs a recipe", ErrInvalid)
+	}
+	if e.Servings < 0 {
+		return fmt.Errorf("%w: servings can't be negative", ErrInvalid)
+	}
+	return nil
+}
+
+// Shifted - a copy of the entry moved the given number of days, without
+// code
+func (e *MealPlanEntry) Shifted(days int) *MealPlanEntry {
+	date, _ := ParseDate(e.Date)
+	shifted := *e
+	shifted.Code = ""
+	shifted.Date = date.AddDate(0, 0, days).Format(DATEFORMAT)
+	return &shifted
+}
+
+// ParseDate - reads a 2006-01-02 date
+func ParseDate(value string) (time.Time, error) {
+	date, err := time.Parse(DATEFORMAT, value)
+	if err != nil {
+		return date, fmt.Errorf("%w: %q isn't a YYYY-MM-DD date", ErrInvalid, value)
+	}
+	return date, nil
+}
+
+// SortMealPlan - orders entries by date and meal
+func SortMealPlan(entries []*MealPlanEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Date != b.Date {
+			return a.Date < b.Date
+		}
+		return slotOrder(a.Slot) < slotOrder(b.Slot)
+	})
+}
+
+/** PRIVATE METHODS **/
+
+func slotOrder(slot string) int {
+	for i, s := range slots {
+		if s == slot {
+			return i
+		}
+	}
+	return -1
+}
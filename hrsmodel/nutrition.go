@@ -0,0 +1,153 @@
+package hrsmodel
+
+import (
+	"math"
+
+	"github.com/ninh0gauch0/homerecipes/hrsunits"
+)
+
+const (
+	// NOINGREDIENT Constant
+	NOINGREDIENT = "the line isn't linked to an ingredient"
+	// UNKNOWNINGREDIENT Constant
+	UNKNOWNINGREDIENT = "the linked ingredient doesn't exist"
+	// NONUTRITION Constant
+	NONUTRITION = "the ingredient has no nutrition data"
+	// NOQUANTITY Constant
+	NOQUANTITY = "the line has no quantity"
+	// NOWEIGHT Constant
+	NOWEIGHT = "the amount can't be converted to grams"
+)
+
+// Nutrition - nutrition facts. On an ingredient they are per 100 g; energy
+// is in kcal and the rest in grams.
+type Nutrition struct {
+	Energy        float64 `json:"energy" bson:"energy"`
+	Protein       float64 `json:"protein" bson:"protein"`
+	Fat           float64 `json:"fat" bson:"fat"`
+	SaturatedFat  float64 `json:"saturatedFat" bson:"saturatedFat"`
+	Carbohydrates float64 `json:"carbohydrates" bson:"carbohydrates"`
+	Sugar         float64 `json:"sugar" bson:"sugar"`
+	Fiber         float64 `json:"fiber" bson:"fiber"`
+	Salt          float64 `json:"salt" bson:"salt"`
+}
+
+// NutritionIssue - an ingredient line left out of the totals, and why
+type NutritionIssue struct {
+	Line       int    `json:"line"`
+	Ingredient string `json:"ingredient"`
+	Reason     string `json:"reason"`
+}
+
+// NutritionReport - the nutrition facts of a recipe. PerServing is nil
+// when the recipe has no servings. Totals are complete only when there
+// are no issues.
+type NutritionReport struct {
+	Total      Nutrition        `json:"total"`
+	PerServing *Nutrition       `json:"perServing,omitempty"`
+	Servings   int              `json:"servings,omitempty"`
+	Complete   bool             `json:"complete"`
+	Issues     []NutritionIssue `json:"issues"`
+}
+
+// Scaled - every fact multiplied by factor
+func (n Nutrition) Scaled(factor float64) Nutrition {
+	return Nutrition{
+		Energy:        n.Energy * factor,
+		Protein:       n.Protein * factor,
+		Fat:           n.Fat * factor,
+		SaturatedFat:  n.SaturatedFat * factor,
+		Carbohydrates: n.Carbohydrates * factor,
+		Sugar:         n.Sugar * factor,
+		Fiber:         n.Fiber * factor,
+		Salt:          n.Salt * factor,
+	}
+}
+
+// Plus - the sum of both facts
+func (n Nutrition) Plus(o Nutrition) Nutrition {
+	return Nutrition{
+		Energy:        n.Energy + o.Energy,
+		Protein:       n.Protein + o.Protein,
+		Fat:           n.Fat + o.Fat,
+		SaturatedFat:  n.SaturatedFat + o.SaturatedFat,
+		Carbohydrates: n.Carbohydrates + o.Carbohydrates,
+		Sugar:         n.Sugar + o.Sugar,
+		Fiber:         n.Fiber + o.Fiber,
+		Salt:          n.Salt + o.Salt,
+	}
+}
+
+// Rounded - the facts rounded to one decimal
+func (n Nutrition) Rounded() Nutrition {
+	round := func(v float64) float64 { return math.Round(v*10) / 10 }
+	return Nutrition{
+		Energy:        round(n.Energy),
+		Protein:       round(n.Protein),
+		Fat:           round(n.Fat),
+		SaturatedFat:  round(n.SaturatedFat),
+		Carbohydrates: round(n.Carbohydrates),
+		Sugar:         round(n.Sugar),
+		Fiber:         round(n.Fiber),
+		Salt:          round(n.Salt),
+	}
+}
+
+// Grams - what an amount of the ingredient weighs. Masses convert, volumes
+// need the density and pieces the unit weight. Ranges count as their
+// middle.
+func (i *Ingredient) Grams(q *Quantity, unit string) (float64, error) {
+	value := q.Value
+	if q.IsRange() {
+		value = (q.Value + q.Max) / 2
+	}
+
+	if (unit == "" || unit == "piece") && i.UnitWeight > 0 {
+		return value * i.UnitWeight, nil
+	}
+	return hrsunits.ConvertWith(value, unit, "g", i.Density)
+}
+
+// Nutrition - adds up the nutrition facts of the recipe lines. Ingredients
+// are looked up by reference; lines that can't be counted are reported as
+// issues instead of failing.
+func (r *Recipe) Nutrition(ingredients map[string]*Ingredient) *NutritionReport {
+	report := &NutritionReport{Servings: r.Servings, Issues: []NutritionIssue{}}
+
+	for i, line := range r.Lines {
+		issue := NutritionIssue{Line: i + 1, Ingredient: line.Ref()}
+		ingredient := ingredients[line.Ingredient]
+
+		switch {
+		case line.Ingredient == "":
+			issue.Reason = NOINGREDIENT
+		case ingredient == nil:
+			issue.Reason = UNKNOWNINGREDIENT
+		case ingredient.Nutrition == nil:
+			issue.Reason = NONUTRITION
+		case line.Quantity == nil:
+			issue.Reason = NOQUANTITY
+		}
+
+		if issue.Reason == "" {
+			grams, err := ingredient.Grams(line.Quantity, line.Unit)
+			if err != nil {
+				issue.Reason = NOWEIGHT
+			} else {
+				report.Total = report.Total.Plus(ingredient.Nutrition.Scaled(grams / 100))
+			}
+		}
+
+		if issue.Reason != "" {
+			report.Issues = append(report.Issues, issue)
+		}
+	}
+
+	if r.Servings > 0 {
+		perServing := report.Total.Scaled(1 / float64(r.Servings)).Rounded()
+		report.PerServing = &perServing
+	}
+	report.Total = report.Total.Rounded()
+	report.Complete = len(report.Issues) == 0
+	return report
+}
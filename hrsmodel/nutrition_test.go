@@ -0,0 +1,58 @@
+package hrsmodel
+
+import (
+	"testing"
+
+	"github.com/ninh0gauch0/hrstypes"
+)
+
+func TestRecipe_Nutrition(t *testing.T) {
+	ingredients := map[string]*Ingredient{
+		"flour": {Density: 0.53, Nutrition: &Nutrition{Energy: 364, Protein: 10, Fat: 1, Carbohydrates: 76}},
+		"egg":   {UnitWeight: 50, Nutrition: &Nutrition{Energy: 143, Protein: 12.6, Fat: 9.5}},
+		"oil":   {Nutrition: &Nutrition{Energy: 884, Fat: 100}},
+		"sugar": {},
+	}
+	recipe := &Recipe{
+		Recipe:   hrstypes.Recipe{Code: "r1", Name: "Bizcocho"},
+		Servings: 4,
+		Lines: []IngredientLine{
+			{Ingredient: "flour", Quantity: &Quantity{Value: 2}, Unit: "cup"},
+			{Ingredient: "egg", Quantity: &Quantity{Value: 2}},
+			{Ingredient: "sugar", Quantity: &Quantity{Value: 100}, Unit: "g"},
+			{Name: "sal"},
+			{Ingredient: "oil", Quantity: &Quantity{Value: 1}, Unit: "dash"},
+			{Ingredient: "ghost", Quantity: &Quantity{Value: 1}, Unit: "g"},
+			{Ingredient: "egg"},
+		},
+	}
+
+	report := recipe.Nutrition(ingredients)
+	if report.Total.Energy != 1055.9 || report.Total.Protein != 37.7 {
+		t.Errorf("Nutrition() total = %+v", report.Total)
+	}
+	if report.PerServing == nil || report.PerServing.Energy != 264 {
+		t.Errorf("Nutrition() per serving = %+v", report.PerServing)
+	}
+
+	want := []NutritionIssue{
+		{3, "sugar", NONUTRITION},
+		{4, "sal", NOINGREDIENT},
+		{5, "oil", NOWEIGHT},
+		{6, "ghost", UNKNOWNINGREDIENT},
+		{7, "egg", NOQUANTITY},
+	}
+	if report.Complete || len(report.Issues) != len(want) {
+		t.Fatalf("Nutrition() issues = %+v", report.Issues)
+	}
+	for i, issue := range want {
+		if report.Issues[i] != issue {
+			t.Errorf("Nutrition() issue %d = %+v, want %+v", i, report.Issues[i], issue)
+		}
+	}
+
+	recipe.Servings = 0
+	if report = recipe.Nutrition(ingredients); report.PerServing != nil {
+		t.Errorf("Nutrition() without servings per serving = %+v", report.PerServing)
+	}
+}
@@ -0,0 +1,276 @@
+package hrsmodel
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/ninh0gauch0/homerecipes/hrsunits"
+)
+
+const (
+	// FRIDGE Constant
+	FRIDGE = "fridge"
+	// FREEZER Constant
+	FREEZER = "freezer"
+	// CUPBOARD Constant
+	CUPBOARD = "cupboard"
+	// NOTINPANTRY Constant
+	NOTINPANTRY = "not in the pantry"
+	// NOTENOUGH Constant
+	NOTENOUGH = "not enough in the pantry"
+	// NOTCONVERTIBLE Constant
+	NOTCONVERTIBLE = "pantry amount in other units"
+)
+
+// locations - where pantry items are kept
+var locations = []string{FRIDGE, FREEZER, CUPBOARD}
+
+// PantryItem - an amount of an ingredient we have at home. BestBefore is
+// written as 2006-01-02; no unit means pieces.
+type PantryItem struct {
+	Code       string  `json:"code" bson:"code"`
+	Ingredient string  `json:"ingredient" bson:"ingredient"`
+	Quantity   float64 `json:"quantity" bson:"quantity"`
+	Unit       string  `json:"unit,omitempty" bson:"unit,omitempty"`
+	Location   string  `json:"location" bson:"location"`
+	BestBefore string  `json:"bestBefore,omitempty" bson:"bestBefore,omitempty"`
+}
+
+// GetObjectInfo - Interface DTOObject Implementation
+func (p *PantryItem) GetObjectInfo() string {
+	return fmt.Sprintf("pantry item %s: %s %s of %s in the %s", p.Code, FormatAmount(p.Quantity), p.Unit, p.Ingredient, p.Location)
+}
+
+// Validate - checks the ingredient, the amount, the location and the date
+func (p *PantryItem) Validate() error {
+	if p.Ingredient == "" {
+		return fmt.Errorf("%w: the item needs an ingredient", ErrInvalid)
+	}
+	if p.Quantity <= 0 {
+		return fmt.Errorf("%w: the quantity must be positive", ErrInvalid)
+	}
+	if p.Unit != "" {
+		if _, ok := hrsunits.Get(p.Unit); !ok {
+			return fmt.Errorf("%w: unknown unit %q", ErrInvalid, p.Unit)
+		}
+	}
+	if !contains(locations, p.Location) {
+		return fmt.Errorf("%w: the location must be one of %v", ErrInvalid, locations)
+	}
+	if p.BestBefore != "" {
+		if _, err := ParseDate(p.BestBefore); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Expires - whether the item is past its best-before date on the given
+// 2006-01-02 day. Items without date don't expire.
+func (p *PantryItem) Expires(date string) bool {
+	return p.BestBefore != "" && p.BestBefore <= date
+}
+
+// SortPantry - orders items by best-before date, undated ones last
+func SortPantry(items []*PantryItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if (a.BestBefore == "") != (b.BestBefore == "") {
+			return b.BestBefore == ""
+		}
+		if a.BestBefore != b.BestBefore {
+			return a.BestBefore < b.BestBefore
+		}
+		return a.Code < b.Code
+	})
+}
+
+// PantryUse - what cooking takes from a pantry item, in its units
+type PantryUse struct {
+	Item       string  `json:"item"`
+	Ingredient string  `json:"ingredient"`
+	Quantity   float64 `json:"quantity"`
+	Unit       string  `json:"unit,omitempty"`
+	Left       float64 `json:"left"`
+}
+
+// PantryShortage - what a recipe line needs and the pantry doesn't have, in
+// the line units
+type PantryShortage struct {
+	Line       int     `json:"line"`
+	Ingredient string  `json:"ingredient"`
+	Quantity   float64 `json:"quantity"`
+	Unit       string  `json:"unit,omitempty"`
+	Reason     string  `json:"reason"`
+}
+
+// PantryDeduction - what cooking a recipe takes from the pantry
+type PantryDeduction struct {
+	Recipe   string           `json:"recipe"`
+	Servings int              `json:"servings,omitempty"`
+	Deducted bool             `json:"deducted"`
+	Used     []PantryUse      `json:"used"`
+	Short    []PantryShortage `json:"short"`
+}
+
+// GetObjectInfo - Interface DTOObject Implementation
+func (d *PantryDeduction) GetObjectInfo() string {
+	return fmt.Sprintf("cooking %s: %d pantry items used, %d lines short", d.Recipe, len(d.Used), len(d.Short))
+}
+
+// Deduct - takes the recipe line amounts from the pantry items of their
+// ingredients, the ones expiring first before. Items are changed in place.
+// Lines without ingredient or quantity are left out; ranges count their
+// midpoint.
+func (r *Recipe) Deduct(items []*PantryItem, ingredients map[string]*Ingredient) *PantryDeduction {
+	deduction := &PantryDeduction{Recipe: r.Code, Servings: r.Servings, Used: []PantryUse{}, Short: []PantryShortage{}}
+
+	stock := append([]*PantryItem{}, items...)
+	SortPantry(stock)
+
+	for i, line := range r.Lines {
+		if line.Ingredient == "" || line.Quantity == nil {
+			continue
+		}
+		need := line.Quantity.Value
+		if line.Quantity.IsRange() {
+			need = (line.Quantity.Value + line.Quantity.Max) / 2
+		}
+		ingredient := ingredients[line.Ingredient]
+		if ingredient == nil {
+			ingredient = &Ingredient{}
+		}
+
+		reason := NOTINPANTRY
+		for _, item := range stock {
+			if item.Ingredient != line.Ingredient || item.Quantity <= 0 || need <= 0 {
+				continue
+			}
+			have, err := ingredient.convert(item.Quantity, item.Unit, line.Unit)
+			if err != nil || have <= 0 {
+				reason = NOTCONVERTIBLE
+				continue
+			}
+			reason = NOTENOUGH
+
+			take := math.Min(need, have)
+			used := item.Quantity * take / have
+			item.Quantity = math.Max(amount(item.Quantity-used), 0)
+			if take == have {
+				item.Quantity = 0
+			}
+			need -= take
+
+			deduction.Used = append(deduction.Used, PantryUse{
+				Item:       item.Code,
+				Ingredient: item.Ingredient,
+				Quantity:   amount(used),
+				Unit:       item.Unit,
+				Left:       item.Quantity,
+			})
+		}
+
+		if amount(need) > 0 {
+			deduction.Short = append(deduction.Short, PantryShortage{
+				Line:       i + 1,
+				Ingredient: line.Ingredient,
+				Quantity:   amount(need),
+				Unit:       line.Unit,
+				Reason:     reason,
+			})
+		}
+	}
+	return deduction
+}
+
+// PantrySuggestion - a recipe using pantry items about to expire. Missing
+// are the ingredients of the recipe we don't have at all.
+type PantrySuggestion struct {
+	Recipe     string   `json:"recipe"`
+	Name       string   `json:"name"`
+	Expiring   []string `json:"expiring"`
+	BestBefore string   `json:"bestBefore"`
+	Missing    []string `json:"missing"`
+}
+
+// Suggest - what the recipe would use of the expiring items, nil when it
+// uses none of them. pantry has every ingredient we have.
+func (r *Recipe) Suggest(expiring []*PantryItem, pantry map[string]bool) *PantrySuggestion {
+	suggestion := &PantrySuggestion{Recipe: r.Code, Name: r.Name, Expiring: []string{}, Missing: []string{}}
+
+	for _, ref := range r.Refs() {
+		for _, item := range expiring {
+			if item.Ingredient != ref {
+				continue
+			}
+			if !contains(suggestion.Expiring, ref) {
+				suggestion.Expiring = append(suggestion.Expiring, ref)
+			}
+			if suggestion.BestBefore == "" || item.BestBefore < suggestion.BestBefore {
+				suggestion.BestBefore = item.BestBefore
+			}
+		}
+		if !pantry[ref] {
+			suggestion.Missing = append(suggestion.Missing, ref)
+		}
+	}
+
+	if len(suggestion.Expiring) == 0 {
+		return nil
+	}
+	return suggestion
+}
+
+// SortSuggestions - the recipes using more expiring ingredients first, then
+// the ones using the soonest to expire and the ones missing less
+func SortSuggestions(suggestions []*PantrySuggestion) {
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if len(a.Expiring) != len(b.Expiring) {
+			return len(a.Expiring) > len(b.Expiring)
+		}
+		if a.BestBefore != b.BestBefore {
+			return a.BestBefore < b.BestBefore
+		}
+		if len(a.Missing) != len(b.Missing) {
+			return len(a.Missing) < len(b.Missing)
+		}
+		return a.Name < b.Name
+	})
+}
+
+/** PRIVATE METHODS **/
+
+// convert - the amount in from units expressed in to units, weighing
+// pieces with the unit weight of the ingredient. No unit means pieces.
+func (i *Ingredient) convert(value float64, from string, to string) (float64, error) {
+	if from == "" {
+		from = "piece"
+	}
+	if to == "" {
+		to = "piece"
+	}
+	if from == to {
+		return value, nil
+	}
+
+	converted, err := hrsunits.ConvertWith(value, from, to, i.Density)
+	if err == nil || i.UnitWeight <= 0 {
+		return converted, err
+	}
+	switch {
+	case from == "piece":
+		return hrsunits.ConvertWith(value*i.UnitWeight, "g", to, i.Density)
+	case to == "piece":
+		grams, err := hrsunits.ConvertWith(value, from, "g", i.Density)
+		return grams / i.UnitWeight, err
+	default:
+		return converted, err
+	}
+}
+
+// amount - a quantity without floating point noise
+func amount(value float64) float64 {
+	return math.Round(value*1000) / 1000
+}
@@ -0,0 +1,72 @@
+package hrsmodel
+
+import (
+	"testing"
+
+	"github.com/ninh0gauch0/hrstypes"
+)
+
+func TestRecipe_Deduct(t *testing.T) {
+	recipe := &Recipe{
+		Recipe: hrstypes.Recipe{Code: "tortilla", Name: "Tortilla"},
+		Lines: []IngredientLine{
+			{Ingredient: "eggs", Quantity: &Quantity{Value: 6}},
+			{Ingredient: "potatoes", Quantity: &Quantity{Value: 500}, Unit: "g"},
+			{Ingredient: "oil", Quantity: &Quantity{Value: 1}, Unit: "cup"},
+			{Ingredient: "onion", Quantity: &Quantity{Value: 1}},
+			{Ingredient: "salt"},
+		},
+	}
+	items := []*PantryItem{
+		{Code: "p1", Ingredient: "eggs", Quantity: 12, Location: FRIDGE, BestBefore: "2024-03-20"},
+		{Code: "p2", Ingredient: "eggs", Quantity: 2, Location: FRIDGE, BestBefore: "2024-03-10"},
+		{Code: "p3", Ingredient: "potatoes", Quantity: 4, Location: CUPBOARD},
+		{Code: "p4", Ingredient: "oil", Quantity: 1, Unit: "l", Location: CUPBOARD},
+	}
+	ingredients := map[string]*Ingredient{"potatoes": {UnitWeight: 200}}
+
+	deduction := recipe.Deduct(items, ingredients)
+
+	want := map[string]float64{"p1": 8, "p2": 0, "p3": 1.5, "p4": 0.763}
+	for _, item := range items {
+		if item.Quantity != want[item.Code] {
+			t.Errorf("Deduct() left %v of %s, want %v", item.Quantity, item.Code, want[item.Code])
+		}
+	}
+	if len(deduction.Used) != 4 || deduction.Used[0].Item != "p2" {
+		t.Errorf("Deduct() used = %+v", deduction.Used)
+	}
+	if len(deduction.Short) != 1 || deduction.Short[0].Ingredient != "onion" || deduction.Short[0].Reason != NOTINPANTRY {
+		t.Errorf("Deduct() short = %+v", deduction.Short)
+	}
+
+	short := recipe.Deduct([]*PantryItem{{Code: "p5", Ingredient: "potatoes", Quantity: 200, Unit: "g"}}, nil)
+	if s := short.Short[1]; s.Ingredient != "potatoes" || s.Quantity != 300 || s.Reason != NOTENOUGH {
+		t.Errorf("Deduct() short = %+v", short.Short)
+	}
+}
+
+func TestRecipe_Suggest(t *testing.T) {
+	expiring := []*PantryItem{
+		{Ingredient: "spinach", BestBefore: "2024-03-05"},
+		{Ingredient: "cream", BestBefore: "2024-03-06"},
+	}
+	pantry := map[string]bool{"spinach": true, "cream": true, "pasta": true}
+
+	lasagna := &Recipe{Recipe: hrstypes.Recipe{Code: "lasagna", Name: "Lasagna"}, Lines: []IngredientLine{
+		{Ingredient: "pasta"}, {Ingredient: "spinach"}, {Ingredient: "cream"}, {Ingredient: "ricotta"},
+	}}
+	salad := &Recipe{Recipe: hrstypes.Recipe{Code: "salad", Name: "Salad"}, Lines: []IngredientLine{{Ingredient: "spinach"}}}
+	soup := &Recipe{Recipe: hrstypes.Recipe{Code: "soup", Name: "Soup"}, Lines: []IngredientLine{{Ingredient: "leek"}}}
+
+	if soup.Suggest(expiring, pantry) != nil {
+		t.Errorf("Suggest() suggests a recipe without expiring items")
+	}
+
+	suggestions := []*PantrySuggestion{salad.Suggest(expiring, pantry), lasagna.Suggest(expiring, pantry)}
+	SortSuggestions(suggestions)
+	if first := suggestions[0]; first.Recipe != "lasagna" || len(first.Expiring) != 2 || first.BestBefore != "2024-03-05" ||
+		len(first.Missing) != 1 || first.Missing[0] != "ricotta" {
+		t.Errorf("Suggest() = %+v", first)
+	}
+}
@@ -0,0 +1,180 @@
+package hrsmodel
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ErrQuantity - the text is not a quantity
+var ErrQuantity = errors.New("invalid quantity")
+
+// unicodeFractions - vulgar fraction characters and their value
+var unicodeFractions = map[rune]float64{
+	'½': 1.0 / 2, '⅓': 1.0 / 3, '⅔': 2.0 / 3, '¼': 1.0 / 4, '¾': 3.0 / 4,
+	'⅕': 1.0 / 5, '⅖': 2.0 / 5, '⅗': 3.0 / 5, '⅘': 4.0 / 5, '⅙': 1.0 / 6,
+	'⅚': 5.0 / 6, '⅛': 1.0 / 8, '⅜': 3.0 / 8, '⅝': 5.0 / 8, '⅞': 7.0 / 8,
+}
+
+// Quantity - an amount; Max is set for ranges like "2-3"
+type Quantity struct {
+	Value float64 `json:"value"`
+	Max   float64 `json:"max,omitempty"`
+}
+
+// IsRange - true for "from Value to Max" quantities
+func (q *Quantity) IsRange() bool {
+	return q.Max > q.Value
+}
+
+// String - the quantity as a kitchen friendly text: "1 1/2", "2-3"
+func (q *Quantity) String() string {
+	if q.IsRange() {
+		return FormatAmount(q.Value) + "-" + FormatAmount(q.Max)
+	}
+	return FormatAmount(q.Value)
+}
+
+// UnmarshalJSON - accepts a number, a text ("1 1/2", "½", "2-3", "0,5")
+// or the {value, max} object
+func (q *Quantity) UnmarshalJSON(data []byte) error {
+	var number float64
+	if err := json.Unmarshal(data, &number); err == nil {
+		*q = Quantity{Value: number}
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		parsed, err := ParseQuantity(text)
+		if err != nil {
+			return err
+		}
+		*q = *parsed
+		return nil
+	}
+
+	type plain Quantity
+	return json.Unmarshal(data, (*plain)(q))
+}
+
+// ParseQuantity - reads integers, decimals (point or comma), fractions,
+// mixed numbers, unicode fractions and ranges joined by "-", "to" or "a"
+func ParseQuantity(text string) (*Quantity, error) {
+	text = strings.TrimSpace(text)
+
+	for _, sep := range []string{"-", "–", " to ", " a "} {
+		parts := strings.SplitN(text, sep, 2)
+		if len(parts) != 2 {
+			continue
+		}
+		from, err := ParseAmount(parts[0])
+		if err != nil {
+			continue
+		}
+		to, err := ParseAmount(parts[1])
+		if err != nil {
+			continue
+		}
+		if to < from {
+			return nil, fmt.Errorf("%w: %q", ErrQuantity, text)
+		}
+		return &Quantity{Value: from, Max: to}, nil
+	}
+
+	value, err := ParseAmount(text)
+	if err != nil {
+		return nil, err
+	}
+	return &Quantity{Value: value}, nil
+}
+
+// ParseAmount - reads a single amount: "2", "0,5", "1/2", "1 1/2", "1½"
+func ParseAmount(text string) (float64, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, fmt.Errorf("%w: empty", ErrQuantity)
+	}
+
+	total := 0.0
+	for _, part := range strings.Fields(splitUnicodeFractions(text)) {
+		value, err := parseAmountPart(part)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q", ErrQuantity, text)
+		}
+		total += value
+	}
+	return total, nil
+}
+
+// FormatAmount - writes an amount with the closest kitchen fraction when
+// it is near enough: 1.5 -> "1 1/2", 0.33 -> "1/3", 2.2 -> "2.2"
+func FormatAmount(value float64) string {
+	whole := math.Floor(value)
+	rest := value - whole
+
+	for _, denominator := range []float64{2, 3, 4, 8} {
+		numerator := math.Round(rest * denominator)
+		if math.Abs(rest-numerator/denominator) > 0.01 {
+			continue
+		}
+		if numerator == 0 {
+			return strconv.FormatFloat(whole, 'f', -1, 64)
+		}
+		if numerator == denominator {
+			return strconv.FormatFloat(whole+1, 'f', -1, 64)
+		}
+		fraction := fmt.Sprintf("%d/%d", int(numerator), int(denominator))
+		if whole == 0 {
+			return fraction
+		}
+		return fmt.Sprintf("%d %s", int(whole), fraction)
+	}
+	return strconv.FormatFloat(math.Round(value*100)/100, 'f', -1, 64)
+}
+
+/** PRIVATE METHODS **/
+
+// splitUnicodeFractions - "1½" -> "1 ½" so fields can be parsed one by one
+func splitUnicodeFractions(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if _, ok := unicodeFractions[r]; ok {
+			b.WriteRune(' ')
+			b.WriteRune(r)
+			b.WriteRune(' ')
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func parseAmountPart(part string) (float64, error) {
+	if runes := []rune(part); len(runes) == 1 {
+		if value, ok := unicodeFractions[runes[0]]; ok {
+			return value, nil
+		}
+	}
+
+	if fraction := strings.SplitN(part, "/", 2); len(fraction) == 2 {
+		numerator, err := strconv.ParseFloat(fraction[0], 64)
+		if err != nil {
+			return 0, err
+		}
+		denominator, err := strconv.ParseFloat(fraction[1], 64)
+		if err != nil || denominator == 0 {
+			return 0, ErrQuantity
+		}
+		return numerator / denominator, nil
+	}
+
+	value, err := strconv.ParseFloat(strings.Replace(part, ",", ".", 1), 64)
+	if err != nil || value < 0 {
+		return 0, ErrQuantity
+	}
+	return value, nil
+}
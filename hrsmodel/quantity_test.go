@@ -0,0 +1,72 @@
+package hrsmodel
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseQuantity(t *testing.T) {
+	tests := []struct {
+		text string
+		want Quantity
+	}{
+		{"2", Quantity{Value: 2}},
+		{"0,5", Quantity{Value: 0.5}},
+		{"1/2", Quantity{Value: 0.5}},
+		{"1 1/2", Quantity{Value: 1.5}},
+		{"1½", Quantity{Value: 1.5}},
+		{"¾", Quantity{Value: 0.75}},
+		{"2-3", Quantity{Value: 2, Max: 3}},
+		{"1 a 2", Quantity{Value: 1, Max: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := ParseQuantity(tt.text)
+			if err != nil {
+				t.Fatalf("ParseQuantity() error = %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("ParseQuantity() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+
+	for _, text := range []string{"", "some", "3-1", "1/0"} {
+		if _, err := ParseQuantity(text); err == nil {
+			t.Errorf("ParseQuantity(%q) error = nil", text)
+		}
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	tests := map[float64]string{1.5: "1 1/2", 0.333: "1/3", 2: "2", 2.2: "2.2", 0.125: "1/8"}
+	for value, want := range tests {
+		if got := FormatAmount(value); got != want {
+			t.Errorf("FormatAmount(%v) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestRecipe_UnmarshalJSON(t *testing.T) {
+	data := `{"code":"r1","name":"Bizcocho","ingredients":[
+		"i-egg",
+		{"ingredient":"i-flour","quantity":"1 1/2","unit":"cup","note":"sifted"},
+		{"name":"icing sugar","optional":true,"group":"For the glaze"}]}`
+
+	recipe := &Recipe{}
+	if err := json.Unmarshal([]byte(data), recipe); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if recipe.Name != "Bizcocho" || len(recipe.Lines) != 3 {
+		t.Fatalf("Unmarshal() = %+v", recipe)
+	}
+	if !recipe.Lines[0].IsRaw() || recipe.Lines[0].Ref() != "i-egg" || recipe.Lines[1].Quantity.Value != 1.5 {
+		t.Errorf("Unmarshal() lines = %+v", recipe.Lines)
+	}
+
+	recipe.Sync()
+	refs := recipe.Recipe.Ingredients
+	if len(refs) != 3 || refs[2] != "icing sugar" {
+		t.Errorf("Sync() refs = %v", refs)
+	}
+}
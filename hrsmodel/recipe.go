@@ -0,0 +1,221 @@
+package hrsmodel
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ninh0gauch0/homerecipes/hrssearch"
+	"github.com/ninh0gauch0/hrstypes"
+)
+
+// ErrInvalid - the element doesn't pass validation
+var ErrInvalid = errors.New("invalid element")
+
+const (
+	// INGREDIENTMENTION Constant
+	INGREDIENTMENTION = "ingredient"
+	// COOKWAREMENTION Constant
+	COOKWAREMENTION = "cookware"
+	// TIMERMENTION Constant
+	TIMERMENTION = "timer"
+)
+
+// prepAhead - folded words of steps done the day before cooking
+var prepAhead = []string{
+	"overnight", "the night before", "the day before", "12 hours", "24 hours",
+	"toda la noche", "la noche anterior", "el dia anterior", "la vispera", "12 horas", "24 horas",
+}
+
+// IngredientLine - one line of the ingredient list of a recipe: "200 g
+// flour, sifted". Ingredient references the ingredients collection; Name
+// is what the line says when there is no reference. Group is the heading
+// the line sits under, like "For the sauce". Text holds a line given as
+// free text which hasn't been parsed yet.
+type IngredientLine struct {
+	Text       string    `json:"text,omitempty" bson:"text,omitempty"`
+	Ingredient string    `json:"ingredient,omitempty" bson:"ingredient,omitempty"`
+	Name       string    `json:"name,omitempty" bson:"name,omitempty"`
+	Quantity   *Quantity `json:"quantity,omitempty" bson:"quantity,omitempty"`
+	Unit       string    `json:"unit,omitempty" bson:"unit,omitempty"`
+	Optional   bool      `json:"optional,omitempty" bson:"optional,omitempty"`
+	Note       string    `json:"note,omitempty" bson:"note,omitempty"`
+	Group      string    `json:"group,omitempty" bson:"group,omitempty"`
+}
+
+// UnmarshalJSON - a bare string is a free text line, or a legacy
+// ingredient reference
+func (l *IngredientLine) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*l = IngredientLine{Text: text}
+		return nil
+	}
+
+	type plain IngredientLine
+	return json.Unmarshal(data, (*plain)(l))
+}
+
+// IsRaw - true for a free text line not parsed yet
+func (l *IngredientLine) IsRaw() bool {
+	return l.Text != "" && l.Ingredient == "" && l.Name == "" && l.Quantity == nil && l.Unit == ""
+}
+
+// Ref - the ingredient reference, the name (or the raw text) when the line
+// has none
+func (l *IngredientLine) Ref() string {
+	switch {
+	case l.Ingredient != "":
+		return l.Ingredient
+	case l.Name != "":
+		return l.Name
+	default:
+		return l.Text
+	}
+}
+
+// String - the line as a recipe would write it: "200 g flour, sifted
+// (optional)". Free text lines are given back as they came.
+func (l *IngredientLine) String() string {
+	if l.IsRaw() || (l.Name == "" && l.Text != "") {
+		return l.Text
+	}
+
+	parts := []string{}
+	if l.Quantity != nil {
+		parts = append(parts, l.Quantity.String())
+	}
+	if l.Unit != "" {
+		parts = append(parts, l.Unit)
+	}
+	if l.Name != "" {
+		parts = append(parts, l.Name)
+	} else {
+		parts = append(parts, l.Ref())
+	}
+
+	text := strings.Join(parts, " ")
+	if l.Note != "" {
+		text += ", " + l.Note
+	}
+	if l.Optional {
+		text += " (optional)"
+	}
+	return text
+}
+
+// Mention - where a step names an ingredient line, a cookware or a timer,
+// the way Cooklang marks them. Start is the byte offset of Text in the
+// step; Line indexes the ingredient lines. Amount keeps what the mark
+// says that the model has no place for, like "a pinch" or a timer "10%min".
+type Mention struct {
+	Step   int    `json:"step" bson:"step"`
+	Start  int    `json:"start" bson:"start"`
+	Text   string `json:"text" bson:"text"`
+	Kind   string `json:"kind" bson:"kind"`
+	Line   int    `json:"line,omitempty" bson:"line,omitempty"`
+	Name   string `json:"name,omitempty" bson:"name,omitempty"`
+	Amount string `json:"amount,omitempty" bson:"amount,omitempty"`
+}
+
+// Recipe - a hrstypes.Recipe whose ingredients are structured lines. The
+// embedded Ingredients keep the bare references, for legacy readers.
+// Servings is how many people the quantities feed; Yield is what the recipe
+// makes when servings don't fit, like "1 loaf". Times are in minutes.
+// StepGroups, when set, has the heading of every step, the way Group does
+// for lines. Mentions mark the ingredients, cookware and timers in the
+// steps.
+type Recipe struct {
+	hrstypes.Recipe `bson:",inline"`
+	Lines           []IngredientLine `json:"ingredients" bson:"lines,omitempty"`
+	Servings        int              `json:"servings,omitempty" bson:"servings,omitempty"`
+	Yield           string           `json:"yield,omitempty" bson:"yield,omitempty"`
+	PrepTime        int              `json:"prepTime,omitempty" bson:"prepTime,omitempty"`
+	CookTime        int              `json:"cookTime,omitempty" bson:"cookTime,omitempty"`
+	TotalTime       int              `json:"totalTime,omitempty" bson:"totalTime,omitempty"`
+	Images          []string         `json:"images,omitempty" bson:"images,omitempty"`
+	StepGroups      []string         `json:"stepGroups,omitempty" bson:"stepGroups,omitempty"`
+	Mentions        []Mention        `json:"mentions,omitempty" bson:"mentions,omitempty"`
+}
+
+// FromLegacy - converts a recipe whose ingredients are bare references
+func FromLegacy(legacy *hrstypes.Recipe) *Recipe {
+	recipe := &Recipe{Recipe: *legacy}
+	for _, ref := range legacy.Ingredients {
+		recipe.Lines = append(recipe.Lines, IngredientLine{Ingredient: ref})
+	}
+	return recipe
+}
+
+// Refs - the ingredient references of the recipe lines
+func (r *Recipe) Refs() []string {
+	refs := []string{}
+	for _, line := range r.Lines {
+		if ref := line.Ref(); ref != "" {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// Sync - copies the line references to the legacy Ingredients field
+func (r *Recipe) Sync() {
+	r.Recipe.Ingredients = r.Refs()
+}
+
+// StepGroup - the heading the i-th step sits under; none when the groups
+// don't match the steps
+func (r *Recipe) StepGroup(i int) string {
+	if len(r.StepGroups) != len(r.Steps) || i < 0 || i >= len(r.StepGroups) {
+		return ""
+	}
+	return r.StepGroups[i]
+}
+
+// PrepAheadSteps - the steps to start the day before, like soaking the
+// beans overnight
+func (r *Recipe) PrepAheadSteps() []string {
+	steps := []string{}
+	for _, step := range r.Steps {
+		folded := hrssearch.Fold(step)
+		for _, words := range prepAhead {
+			if strings.Contains(folded, words) {
+				steps = append(steps, step)
+				break
+			}
+		}
+	}
+	return steps
+}
+
+// Validate - checks the servings and every ingredient line
+func (r *Recipe) Validate() error {
+	if r.Servings < 0 {
+		return fmt.Errorf("%w: servings can't be negative", ErrInvalid)
+	}
+	if r.PrepTime < 0 || r.CookTime < 0 || r.TotalTime < 0 {
+		return fmt.Errorf("%w: times can't be negative", ErrInvalid)
+	}
+	if len(r.StepGroups) > 0 && len(r.StepGroups) != len(r.Steps) {
+		return fmt.Errorf("%w: there must be a step group for every step", ErrInvalid)
+	}
+	for i, m := range r.Mentions {
+		if m.Step < 0 || m.Start < 0 || m.Line < 0 {
+			return fmt.Errorf("%w: mention %d has a wrong position", ErrInvalid, i+1)
+		}
+		if m.Kind != INGREDIENTMENTION && m.Kind != COOKWAREMENTION && m.Kind != TIMERMENTION {
+			return fmt.Errorf("%w: mention %d has an unknown kind", ErrInvalid, i+1)
+		}
+	}
+
+	for i, line := range r.Lines {
+		if line.Ref() == "" {
+			return fmt.Errorf("%w: ingredient line %d has no ingredient", ErrInvalid, i+1)
+		}
+		if q := line.Quantity; q != nil && (q.Value < 0 || (q.Max != 0 && q.Max < q.Value)) {
+			return fmt.Errorf("%w: ingredient line %d has a wrong quantity", ErrInvalid, i+1)
+		}
+	}
+	return nil
+}
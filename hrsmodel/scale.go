@@ -0,0 +1,55 @@
+package hrsmodel
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ninh0gauch0/homerecipes/hrsunits"
+)
+
+// Scale - a copy of the recipe for the given servings, every quantity
+// multiplied, rounded and written in its handiest unit
+func (r *Recipe) Scale(servings int) (*Recipe, error) {
+	if r.Servings <= 0 {
+		return nil, fmt.Errorf("%w: the recipe has no servings to scale from", ErrInvalid)
+	}
+	if servings <= 0 {
+		return nil, fmt.Errorf("%w: servings must be positive", ErrInvalid)
+	}
+
+	scaled := r.ScaleBy(float64(servings) / float64(r.Servings))
+	scaled.Servings = servings
+	return scaled, nil
+}
+
+// ScaleBy - a copy of the recipe with every quantity multiplied by factor,
+// rounded and written in its handiest unit
+func (r *Recipe) ScaleBy(factor float64) *Recipe {
+	scaled := *r
+	scaled.Servings = int(math.Round(float64(r.Servings) * factor))
+	scaled.Lines = make([]IngredientLine, len(r.Lines))
+
+	for i, line := range r.Lines {
+		if line.Quantity != nil {
+			line.Quantity, line.Unit = scaleQuantity(line.Quantity, line.Unit, factor)
+		}
+		scaled.Lines[i] = line
+	}
+	return &scaled
+}
+
+// scaleQuantity - multiplies q, both ends of a range promoted to the unit
+// the lower one takes
+func scaleQuantity(q *Quantity, unit string, factor float64) (*Quantity, string) {
+	value, to := hrsunits.Promote(q.Value*factor, unit)
+	scaled := &Quantity{Value: hrsunits.Round(value, to)}
+
+	if q.IsRange() {
+		max, err := hrsunits.Convert(q.Max*factor, unit, to)
+		if err != nil {
+			max = q.Max * factor
+		}
+		scaled.Max = hrsunits.Round(max, to)
+	}
+	return scaled, to
+}
@@ -0,0 +1,60 @@
+package hrsmodel
+
+import (
+	"testing"
+
+	"github.com/ninh0gauch0/hrstypes"
+)
+
+func TestRecipe_Scale(t *testing.T) {
+	recipe := &Recipe{
+		Recipe:   hrstypes.Recipe{Code: "r1", Name: "Lentejas"},
+		Servings: 2,
+		Lines: []IngredientLine{
+			{Ingredient: "lentils", Quantity: &Quantity{Value: 300}, Unit: "g"},
+			{Ingredient: "paprika", Quantity: &Quantity{Value: 9.6}, Unit: "tsp"},
+			{Ingredient: "cumin", Quantity: &Quantity{Value: 0.25}, Unit: "cup"},
+			{Ingredient: "garlic", Quantity: &Quantity{Value: 1, Max: 2}, Unit: "clove"},
+			{Ingredient: "salt"},
+		},
+	}
+
+	scaled, err := recipe.Scale(10)
+	if err != nil {
+		t.Fatalf("Scale() error = %v", err)
+	}
+
+	want := []struct {
+		value float64
+		max   float64
+		unit  string
+	}{
+		{1.5, 0, "kg"},
+		{1, 0, "cup"},
+		{1.25, 0, "cup"},
+		{5, 10, "clove"},
+	}
+	for i, w := range want {
+		q := scaled.Lines[i].Quantity
+		if q.Value != w.value || q.Max != w.max || scaled.Lines[i].Unit != w.unit {
+			t.Errorf("Scale() line %d = %+v %s, want %v-%v %s", i, *q, scaled.Lines[i].Unit, w.value, w.max, w.unit)
+		}
+	}
+	if scaled.Lines[4].Quantity != nil || scaled.Servings != 10 {
+		t.Errorf("Scale() = %+v", scaled)
+	}
+	if recipe.Lines[0].Quantity.Value != 300 {
+		t.Errorf("Scale() changed the original recipe")
+	}
+
+	// Scaling down demotes to smaller units
+	scaled, _ = recipe.Scale(1)
+	if scaled.Lines[2].Unit != "tbsp" || scaled.Lines[2].Quantity.Value != 2 {
+		t.Errorf("Scale() down = %+v %s, want 2 tbsp", *scaled.Lines[2].Quantity, scaled.Lines[2].Unit)
+	}
+
+	recipe.Servings = 0
+	if _, err = recipe.Scale(4); err == nil {
+		t.Errorf("Scale() without servings error = nil")
+	}
+}
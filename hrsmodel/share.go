@@ -0,0 +1,29 @@
+package hrsmodel
+
+import (
+	"fmt"
+	"time"
+)
+
+// Share - a public link to one recipe of a household: whoever holds the
+// Code reads that recipe, and nothing else, until the link expires or is
+// revoked. Unlike API keys codes are kept as they are, links are meant
+// to be passed around again; no Expires means it doesn't.
+type Share struct {
+	Code      string    `json:"code" bson:"code"`
+	Household string    `json:"household" bson:"household"`
+	Recipe    string    `json:"recipe" bson:"recipe"`
+	CreatedBy string    `json:"createdBy" bson:"createdBy"`
+	Created   time.Time `json:"created" bson:"created"`
+	Expires   time.Time `json:"expires,omitempty" bson:"expires,omitempty"`
+}
+
+// GetObjectInfo - Interface DTOObject Implementation
+func (s *Share) GetObjectInfo() string {
+	return fmt.Sprintf("share of recipe %s of household %s", s.Recipe, s.Household)
+}
+
+// Expired - whether the link can't be used any more
+func (s *Share) Expired(now time.Time) bool {
+	return !s.Expires.IsZero() && !now.Before(s.Expires)
+}
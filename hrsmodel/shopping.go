@@ -0,0 +1,239 @@
+package hrsmodel
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/ninh0gauch0/homerecipes/hrssearch"
+	"github.com/ninh0gauch0/homerecipes/hrsunits"
+)
+
+// ShoppingRecipe - a recipe to shop for. Servings scales it to that many
+// people, otherwise Multiplier scales it (once when zero).
+type ShoppingRecipe struct {
+	Recipe     string  `json:"recipe" bson:"recipe"`
+	Servings   int     `json:"servings,omitempty" bson:"servings,omitempty"`
+	Multiplier float64 `json:"multiplier,omitempty" bson:"multiplier,omitempty"`
+}
+
+// ShoppingItem - what to buy of an ingredient, summed over the recipes
+// using it. Quantity is nil when no recipe gives one, like salt to taste.
+type ShoppingItem struct {
+	ID         string    `json:"id" bson:"id"`
+	Ingredient string    `json:"ingredient,omitempty" bson:"ingredient,omitempty"`
+	Name       string    `json:"name" bson:"name"`
+	Quantity   *Quantity `json:"quantity,omitempty" bson:"quantity,omitempty"`
+	Unit       string    `json:"unit,omitempty" bson:"unit,omitempty"`
+	Recipes    []string  `json:"recipes" bson:"recipes"`
+	Checked    bool      `json:"checked" bson:"checked"`
+}
+
+// ShoppingGroup - the items of a store aisle, the ingredient category.
+// Ingredients without one go in a last group with no category.
+type ShoppingGroup struct {
+	Category string         `json:"category" bson:"category"`
+	Items    []ShoppingItem `json:"items" bson:"items"`
+}
+
+// ShoppingList - the consolidated ingredients of a set of recipes
+type ShoppingList struct {
+	Code    string           `json:"code" bson:"code"`
+	Name    string           `json:"name,omitempty" bson:"name,omitempty"`
+	Recipes []ShoppingRecipe `json:"recipes" bson:"recipes"`
+	Groups  []ShoppingGroup  `json:"groups" bson:"groups"`
+}
+
+// GetObjectInfo - Interface DTOObject Implementation
+func (l *ShoppingList) GetObjectInfo() string {
+	items := 0
+	for _, group := range l.Groups {
+		items += len(group.Items)
+	}
+	return fmt.Sprintf("shopping list %s: %d recipes, %d items", l.Code, len(l.Recipes), items)
+}
+
+// Validate - checks the recipes to shop for
+func (l *ShoppingList) Validate() error {
+	if len(l.Recipes) == 0 {
+		return fmt.Errorf("%w: the list needs some recipe", ErrInvalid)
+	}
+	for i, r := range l.Recipes {
+		if r.Recipe == "" || r.Servings < 0 || r.Multiplier < 0 {
+			return fmt.Errorf("%w: recipe %d needs an id and positive servings or multiplier", ErrInvalid, i+1)
+		}
+	}
+	return nil
+}
+
+// Item - the item with the given id, nil when there is none
+func (l *ShoppingList) Item(id string) *ShoppingItem {
+	for g := range l.Groups {
+		for i := range l.Groups[g].Items {
+			if l.Groups[g].Items[i].ID == id {
+				return &l.Groups[g].Items[i]
+			}
+		}
+	}
+	return nil
+}
+
+// Consolidate - fills the groups of the list with the lines of the recipes,
+// already scaled. Lines of the same ingredient are merged: masses and
+// volumes are summed in grams and millilitres (grams when the density is
+// known), other units each on their own. Ingredients are looked up by
+// reference for their name, density and category.
+func (l *ShoppingList) Consolidate(recipes []*Recipe, ingredients map[string]*Ingredient) {
+	sums := map[string]*shoppingSum{}
+	order := []string{}
+
+	for _, recipe := range recipes {
+		for _, line := range recipe.Lines {
+			ingredient := ingredients[line.Ingredient]
+			if ingredient == nil {
+				ingredient = &Ingredient{}
+			}
+
+			ref := line.Ingredient
+			if ref == "" {
+				ref = "~" + hrssearch.Fold(line.Ref())
+			}
+			value, max, unit := normalize(line, ingredient.Density)
+			key := ref + "|" + unit
+
+			sum, ok := sums[key]
+			if !ok {
+				name := ingredient.Name
+				if name == "" {
+					name = line.Name
+				}
+				if name == "" {
+					name = line.Ref()
+				}
+				sum = &shoppingSum{ref: ref, category: ingredient.Category, unit: unit}
+				sum.item = ShoppingItem{Ingredient: line.Ingredient, Name: name, Recipes: []string{}}
+				sums[key] = sum
+				order = append(order, key)
+			}
+
+			sum.counted = sum.counted || line.Quantity != nil
+			sum.value += value
+			sum.max += max
+			sum.ranged = sum.ranged || (line.Quantity != nil && line.Quantity.IsRange())
+			if !contains(sum.item.Recipes, recipe.Code) {
+				sum.item.Recipes = append(sum.item.Recipes, recipe.Code)
+			}
+		}
+	}
+
+	counted := map[string]bool{}
+	for _, sum := range sums {
+		counted[sum.ref] = counted[sum.ref] || sum.counted
+	}
+
+	groups := map[string][]ShoppingItem{}
+	for _, key := range order {
+		sum := sums[key]
+		if !sum.counted && counted[sum.ref] {
+			continue
+		}
+		groups[sum.category] = append(groups[sum.category], sum.toItem())
+	}
+
+	l.Groups = []ShoppingGroup{}
+	for category, items := range groups {
+		sort.SliceStable(items, func(i, j int) bool {
+			return hrssearch.Fold(items[i].Name) < hrssearch.Fold(items[j].Name)
+		})
+		l.Groups = append(l.Groups, ShoppingGroup{Category: category, Items: items})
+	}
+	sort.Slice(l.Groups, func(i, j int) bool {
+		a, b := l.Groups[i].Category, l.Groups[j].Category
+		if a == "" || b == "" {
+			return b == ""
+		}
+		return hrssearch.Fold(a) < hrssearch.Fold(b)
+	})
+
+	n := 0
+	for g := range l.Groups {
+		for i := range l.Groups[g].Items {
+			n++
+			l.Groups[g].Items[i].ID = strconv.Itoa(n)
+		}
+	}
+}
+
+/** PRIVATE METHODS **/
+
+// shoppingSum - the running total of an ingredient in a unit
+type shoppingSum struct {
+	ref      string
+	category string
+	unit     string
+	counted  bool
+	ranged   bool
+	value    float64
+	max      float64
+	item     ShoppingItem
+}
+
+// toItem - the item with the total written in its handiest unit
+func (s *shoppingSum) toItem() ShoppingItem {
+	item := s.item
+	if !s.counted {
+		return item
+	}
+
+	value, unit := hrsunits.Promote(s.value, s.unit)
+	item.Quantity = &Quantity{Value: hrsunits.Round(value, unit)}
+	item.Unit = unit
+
+	if s.ranged {
+		max, err := hrsunits.Convert(s.max, s.unit, unit)
+		if err != nil {
+			max = s.max
+		}
+		item.Quantity.Max = hrsunits.Round(max, unit)
+	}
+	return item
+}
+
+// normalize - the amounts of a line in the unit it is summed in: grams for
+// masses, and volumes with a density, millilitres for other volumes
+func normalize(line IngredientLine, density float64) (value float64, max float64, unit string) {
+	q := line.Quantity
+	if q == nil {
+		return 0, 0, ""
+	}
+
+	value, max, unit = q.Value, q.Value, line.Unit
+	if q.IsRange() {
+		max = q.Max
+	}
+
+	u, ok := hrsunits.Get(line.Unit)
+	switch {
+	case !ok:
+		return value, max, unit
+	case u.Dimension == hrsunits.MASS || (u.Dimension == hrsunits.VOLUME && density > 0):
+		unit = "g"
+	case u.Dimension == hrsunits.VOLUME:
+		unit = "ml"
+	default:
+		return value, max, unit
+	}
+
+	value, _ = hrsunits.ConvertWith(value, line.Unit, unit, density)
+	max, _ = hrsunits.ConvertWith(max, line.Unit, unit, density)
+	return value, max, unit
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
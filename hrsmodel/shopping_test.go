@@ -0,0 +1,80 @@
+package hrsmodel
+
+import (
+	"testing"
+
+	"github.com/ninh0gauch0/hrstypes"
+)
+
+func TestShoppingList_Consolidate(t *testing.T) {
+	ingredients := map[string]*Ingredient{
+		"flour": {Ingredient: hrstypes.Ingredient{Code: "flour", Name: "Harina"}, Density: 0.53, Category: "Panadería"},
+		"milk":  {Ingredient: hrstypes.Ingredient{Code: "milk", Name: "Leche"}, Category: "Lácteos"},
+		"egg":   {Ingredient: hrstypes.Ingredient{Code: "egg", Name: "Huevo"}, Category: "Lácteos"},
+	}
+	recipes := []*Recipe{
+		{
+			Recipe: hrstypes.Recipe{Code: "r1"},
+			Lines: []IngredientLine{
+				{Ingredient: "flour", Quantity: &Quantity{Value: 500}, Unit: "g"},
+				{Ingredient: "milk", Quantity: &Quantity{Value: 1}, Unit: "cup"},
+				{Ingredient: "egg", Quantity: &Quantity{Value: 2}},
+				{Name: "sal"},
+			},
+		},
+		{
+			Recipe: hrstypes.Recipe{Code: "r2"},
+			Lines: []IngredientLine{
+				{Ingredient: "flour", Quantity: &Quantity{Value: 1}, Unit: "cup"},
+				{Ingredient: "milk", Quantity: &Quantity{Value: 750}, Unit: "ml"},
+				{Ingredient: "egg", Quantity: &Quantity{Value: 1, Max: 2}},
+				{Name: "Sal", Quantity: &Quantity{Value: 1}, Unit: "pinch"},
+				{Name: "pimienta"},
+			},
+		},
+	}
+
+	list := &ShoppingList{}
+	list.Consolidate(recipes, ingredients)
+
+	want := []struct {
+		category string
+		name     string
+		value    float64
+		max      float64
+		unit     string
+	}{
+		{"Lácteos", "Huevo", 3, 4, ""},
+		{"Lácteos", "Leche", 985, 0, "ml"},
+		{"Panadería", "Harina", 625, 0, "g"},
+		{"", "pimienta", 0, 0, ""},
+		{"", "Sal", 1, 0, "pinch"},
+	}
+
+	items := []ShoppingItem{}
+	categories := []string{}
+	for _, group := range list.Groups {
+		for _, item := range group.Items {
+			items = append(items, item)
+			categories = append(categories, group.Category)
+		}
+	}
+	if len(items) != len(want) {
+		t.Fatalf("Consolidate() = %+v", list.Groups)
+	}
+
+	for i, w := range want {
+		item := items[i]
+		value, max := 0.0, 0.0
+		if item.Quantity != nil {
+			value, max = item.Quantity.Value, item.Quantity.Max
+		}
+		if categories[i] != w.category || item.Name != w.name || value != w.value || max != w.max || item.Unit != w.unit {
+			t.Errorf("Consolidate() item %d = %s %+v, want %+v", i, categories[i], item, w)
+		}
+	}
+
+	if items[0].ID != "1" || len(items[2].Recipes) != 2 || list.Item("5") == nil || list.Item("6") != nil {
+		t.Errorf("Consolidate() ids and recipes = %+v", items)
+	}
+}
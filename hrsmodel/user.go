@@ -0,0 +1,46 @@
+package hrsmodel
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// MAXUSERNAME Constant
+const MAXUSERNAME = 64
+
+// User - someone who can log in. PasswordHash is the bcrypt hash of the
+// password and is never sent out; tokens issued before PasswordChanged
+// are no longer good. Users logging in through an OpenID Connect provider
+// have no password but the Issuer and the Subject it knows them as.
+type User struct {
+	Code            string    `json:"code" bson:"code"`
+	Username        string    `json:"username" bson:"username"`
+	PasswordHash    string    `json:"passwordHash" bson:"passwordHash"`
+	PasswordChanged time.Time `json:"passwordChanged" bson:"passwordChanged"`
+	Issuer          string    `json:"issuer,omitempty" bson:"issuer,omitempty"`
+	Subject         string    `json:"subject,omitempty" bson:"subject,omitempty"`
+}
+
+// GetObjectInfo - Interface DTOObject Implementation
+func (u *User) GetObjectInfo() string {
+	return fmt.Sprintf("user %s (%s)", u.Username, u.Code)
+}
+
+// Validate - checks the username: up to MAXUSERNAME characters, no spaces
+func (u *User) Validate() error {
+	if u.Username == "" || len([]rune(u.Username)) > MAXUSERNAME {
+		return fmt.Errorf("%w: the username must have 1 to %d characters", ErrInvalid, MAXUSERNAME)
+	}
+	if strings.IndexFunc(u.Username, unicode.IsSpace) >= 0 {
+		return fmt.Errorf("%w: the username can't have spaces", ErrInvalid)
+	}
+	return nil
+}
+
+// TokenRevoked - whether a token issued at the given time is not newer
+// than the current password; one issued as it changed is revoked too
+func (u *User) TokenRevoked(issued time.Time) bool {
+	return !issued.After(u.PasswordChanged)
+}
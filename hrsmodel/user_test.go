@@ -0,0 +1,27 @@
+package hrsmodel
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUser_TokenRevoked(t *testing.T) {
+	user := &User{PasswordChanged: time.Date(2024, 5, 1, 12, 0, 0, 500e6, time.UTC)}
+
+	tests := map[time.Duration]bool{
+		-time.Hour:              true,
+		-400 * time.Millisecond: true,
+		0:                       true,
+		time.Millisecond:        false,
+		400 * time.Millisecond:  false,
+	}
+	for offset, want := range tests {
+		if got := user.TokenRevoked(user.PasswordChanged.Add(offset)); got != want {
+			t.Errorf("TokenRevoked() of a token issued %v from the password = %v, want %v", offset, got, want)
+		}
+	}
+
+	if (&User{}).TokenRevoked(time.Now()) {
+		t.Errorf("TokenRevoked() of a user without password = true")
+	}
+}
@@ -0,0 +1,395 @@
+package hrsoidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DISCOVERYPATH Constant
+	DISCOVERYPATH = "/.well-known/openid-configuration"
+	// LEEWAY Constant
+	LEEWAY = time.Minute
+	// MAXRESPONSE Constant
+	MAXRESPONSE = 1 << 20
+)
+
+// ErrLogin - the identity provider turned the login down or vouched for it
+// with a token which isn't good
+var ErrLogin = errors.New("the identity provider didn't vouch for the login")
+
+// defaultScopes - what is asked for when no scopes are configured
+var defaultScopes = []string{"openid", "profile", "email"}
+
+// defaultUsernameClaims - the claims the username is taken from when none
+// are configured, the first one given wins
+var defaultUsernameClaims = []string{"preferred_username", "email"}
+
+// Config - the client registered at the identity provider. RedirectURL is
+// where the provider sends the user back with the code; the username is
+// the first of UsernameClaims the ID token has.
+type Config struct {
+	Issuer         string
+	ClientID       string
+	ClientSecret   string
+	RedirectURL    string
+	Scopes         []string
+	UsernameClaims []string
+}
+
+// Identity - whom the identity provider vouched for
+type Identity struct {
+	Issuer   string
+	Subject  string
+	Username string
+}
+
+// Provider - a relying party of an OpenID Connect provider, doing the
+// authorization code flow with PKCE. The provider is discovered on first
+// use and its keys fetched again when a token is signed with a new one.
+type Provider struct {
+	config   Config
+	client   *http.Client
+	now      func() time.Time
+	mu       sync.Mutex
+	metadata *metadata
+	keys     map[string]*rsa.PublicKey
+}
+
+// metadata - the part of the discovery document the flow needs
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// claims - the ID token claims checked; the username ones are read apart
+type claims struct {
+	Issuer    string          `json:"iss"`
+	Subject   string          `json:"sub"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt int64           `json:"exp"`
+	Nonce     string          `json:"nonce"`
+}
+
+// NewProvider - a relying party with the client configuration; client is
+// the HTTP client talking to the provider, a default one when nil
+func NewProvider(config Config, client *http.Client) (*Provider, error) {
+	if config.Issuer == "" || config.ClientID == "" || config.RedirectURL == "" {
+		return nil, errors.New("the issuer, the client id and the redirect URL are mandatory")
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = defaultScopes
+	} else if !contains(config.Scopes, "openid") {
+		config.Scopes = append([]string{"openid"}, config.Scopes...)
+	}
+	if len(config.UsernameClaims) == 0 {
+		config.UsernameClaims = defaultUsernameClaims
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Provider{config: config, client: client, now: time.Now}, nil
+}
+
+// NewSecret - a random value good as state, nonce or PKCE code verifier
+func NewSecret() (string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(random), nil
+}
+
+// Challenge - the S256 PKCE code challenge of a code verifier
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL - where the user is sent to log in at the provider. The
+// state comes back with the code, the nonce inside the ID token and the
+// verifier is handed to Exchange.
+func (p *Provider) AuthCodeURL(ctx context.Context, state string, nonce string, verifier string) (string, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {p.config.RedirectURL},
+		"scope":                 {strings.Join(p.config.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {Challenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return meta.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange - trades the code the provider sent the user back with for an
+// ID token and returns whom it vouches for. ErrLogin when the provider
+// turns the code down or the token isn't good.
+func (p *Provider) Exchange(ctx context.Context, code string, verifier string, nonce string) (*Identity, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"code_verifier": {verifier},
+	}
+	if p.config.ClientSecret == "" {
+		form.Set("client_id", p.config.ClientID)
+	}
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		r.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	var tokens struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := p.fetch(r, &tokens)
+	if err != nil {
+		return nil, err
+	}
+	if tokens.Error != "" {
+		return nil, fmt.Errorf("%w: %s %s", ErrLogin, tokens.Error, tokens.ErrorDescription)
+	}
+	if status != http.StatusOK || tokens.IDToken == "" {
+		return nil, fmt.Errorf("%w: the token endpoint answered %d without ID token", ErrLogin, status)
+	}
+	return p.verify(ctx, meta, tokens.IDToken, nonce)
+}
+
+/** PRIVATE METHODS **/
+
+// discover - the provider metadata, fetched once; the issuer it tells must
+// be the configured one
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(p.config.Issuer, "/")+DISCOVERYPATH, nil)
+	if err != nil {
+		return nil, err
+	}
+	meta := &metadata{}
+	status, err := p.fetch(r, meta)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("discovery of %s answered %d", p.config.Issuer, status)
+	}
+	if meta.Issuer != p.config.Issuer {
+		return nil, fmt.Errorf("discovery of %s tells the issuer %s", p.config.Issuer, meta.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, fmt.Errorf("discovery of %s lacks endpoints", p.config.Issuer)
+	}
+	p.metadata = meta
+	return meta, nil
+}
+
+// verify - the identity of an RS256 ID token signed by the provider, issued
+// for this client with the nonce and not expired
+func (p *Provider) verify(ctx context.Context, meta *metadata, token string, nonce string) (*Identity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed ID token", ErrLogin)
+	}
+	var header struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil || header.Algorithm != "RS256" {
+		return nil, fmt.Errorf("%w: ID tokens must be signed with RS256", ErrLogin)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed ID token signature", ErrLogin)
+	}
+	key, err := p.key(ctx, meta, header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if rsa.VerifyPKCS1v15(key, crypto.SHA256, sum[:], signature) != nil {
+		return nil, fmt.Errorf("%w: bad ID token signature", ErrLogin)
+	}
+
+	registered := claims{}
+	all := map[string]interface{}{}
+	if decodeSegment(parts[1], &registered) != nil || decodeSegment(parts[1], &all) != nil {
+		return nil, fmt.Errorf("%w: malformed ID token claims", ErrLogin)
+	}
+	switch {
+	case registered.Issuer != meta.Issuer:
+		return nil, fmt.Errorf("%w: ID token of another issuer", ErrLogin)
+	case !audience(registered.Audience, p.config.ClientID):
+		return nil, fmt.Errorf("%w: ID token for another client", ErrLogin)
+	case p.now().Add(-LEEWAY).Unix() >= registered.ExpiresAt:
+		return nil, fmt.Errorf("%w: expired ID token", ErrLogin)
+	case registered.Nonce != nonce:
+		return nil, fmt.Errorf("%w: ID token of another login", ErrLogin)
+	case registered.Subject == "":
+		return nil, fmt.Errorf("%w: ID token without subject", ErrLogin)
+	}
+
+	identity := &Identity{Issuer: registered.Issuer, Subject: registered.Subject}
+	for _, claim := range p.config.UsernameClaims {
+		if value, ok := all[claim].(string); ok && value != "" {
+			identity.Username = value
+			break
+		}
+	}
+	if identity.Username == "" {
+		return nil, fmt.Errorf("%w: the ID token has none of the claims %v", ErrLogin, p.config.UsernameClaims)
+	}
+	return identity, nil
+}
+
+// key - the provider key with the id, fetching the keys again when it is
+// unknown; an empty id is good when the provider has a single key
+func (p *Provider) key(ctx context.Context, meta *metadata, id string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for refreshed := false; ; refreshed = true {
+		if key, ok := p.keys[id]; ok {
+			return key, nil
+		}
+		if id == "" && len(p.keys) == 1 {
+			for _, key := range p.keys {
+				return key, nil
+			}
+		}
+		if refreshed {
+			return nil, fmt.Errorf("%w: ID token signed with an unknown key", ErrLogin)
+		}
+		if err := p.fetchKeys(ctx, meta); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// fetchKeys - replaces the known keys by the RSA signing keys of the
+// provider key set
+func (p *Provider) fetchKeys(ctx context.Context, meta *metadata) error {
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, meta.JWKSURI, nil)
+	if err != nil {
+		return err
+	}
+	var set struct {
+		Keys []struct {
+			Type     string `json:"kty"`
+			Use      string `json:"use"`
+			KeyID    string `json:"kid"`
+			Modulus  string `json:"n"`
+			Exponent string `json:"e"`
+		} `json:"keys"`
+	}
+	status, err := p.fetch(r, &set)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("the key set of %s answered %d", p.config.Issuer, status)
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, jwk := range set.Keys {
+		if jwk.Type != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(jwk.Modulus)
+		e, errE := base64.RawURLEncoding.DecodeString(jwk.Exponent)
+		exponent := new(big.Int).SetBytes(e)
+		if errN != nil || errE != nil || !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			continue
+		}
+		keys[jwk.KeyID] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+	}
+	p.keys = keys
+	return nil
+}
+
+// fetch - decodes the JSON answer to the request, whatever its status
+func (p *Provider) fetch(r *http.Request, v interface{}) (int, error) {
+	rsp, err := p.client.Do(r)
+	if err != nil {
+		return 0, err
+	}
+	defer rsp.Body.Close()
+
+	if err = json.NewDecoder(io.LimitReader(rsp.Body, MAXRESPONSE)).Decode(v); err != nil && rsp.StatusCode == http.StatusOK {
+		return 0, fmt.Errorf("bad answer from %s: %w", r.URL.Host, err)
+	}
+	return rsp.StatusCode, nil
+}
+
+// decodeSegment - decodes a base64url JSON segment of a token
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// audience - whether the aud claim, a string or a list of them, has the
+// client
+func audience(aud json.RawMessage, clientID string) bool {
+	var one string
+	if json.Unmarshal(aud, &one) == nil {
+		return one == clientID
+	}
+	var many []string
+	return json.Unmarshal(aud, &many) == nil && contains(many, clientID)
+}
+
+// contains - whether the value is in the list
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
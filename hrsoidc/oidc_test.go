@@ -0,0 +1,130 @@
+package hrsoidc_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ninh0gauch0/homerecipes/hrsoidc"
+	"github.com/ninh0gauch0/homerecipes/hrsoidc/oidctest"
+)
+
+const callback = "http://hrs.test/hrs/oidc/callback"
+
+// login - goes through the provider with the state, nonce and verifier,
+// returning the code sent back
+func login(t *testing.T, idp *oidctest.IdP, p *hrsoidc.Provider, nonce string, verifier string) string {
+	authURL, err := p.AuthCodeURL(context.Background(), "the-state", nonce, verifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	if !strings.HasPrefix(authURL, idp.URL+"/authorize?") || !strings.Contains(authURL, "code_challenge_method=S256") {
+		t.Fatalf("AuthCodeURL = %s", authURL)
+	}
+
+	back, err := idp.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if back.Scheme+"://"+back.Host+back.Path != callback || back.Query().Get("state") != "the-state" {
+		t.Fatalf("sent back to %s", back)
+	}
+	return back.Query().Get("code")
+}
+
+func TestProvider_Exchange(t *testing.T) {
+	idp, err := oidctest.NewIdP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idp.Close()
+	idp.Claims = map[string]interface{}{"sub": "1234", "email": "ana@example.com"}
+
+	p, err := hrsoidc.NewProvider(idp.Config(callback), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	verifier, _ := hrsoidc.NewSecret()
+	code := login(t, idp, p, "the-nonce", verifier)
+	identity, err := p.Exchange(ctx, code, verifier, "the-nonce")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if identity.Issuer != idp.URL || identity.Subject != "1234" || identity.Username != "ana@example.com" {
+		t.Errorf("identity = %+v", identity)
+	}
+	if _, err = p.Exchange(ctx, code, verifier, "the-nonce"); !errors.Is(err, hrsoidc.ErrLogin) {
+		t.Errorf("Exchange of a used code: %v", err)
+	}
+
+	code = login(t, idp, p, "the-nonce", verifier)
+	if _, err = p.Exchange(ctx, code, "another verifier", "the-nonce"); !errors.Is(err, hrsoidc.ErrLogin) {
+		t.Errorf("Exchange with another verifier: %v", err)
+	}
+	code = login(t, idp, p, "the-nonce", verifier)
+	if _, err = p.Exchange(ctx, code, verifier, "another nonce"); !errors.Is(err, hrsoidc.ErrLogin) {
+		t.Errorf("Exchange with another nonce: %v", err)
+	}
+
+	idp.Claims = map[string]interface{}{"sub": "1234", "preferred_username": "ana", "email": "ana@example.com"}
+	code = login(t, idp, p, "the-nonce", verifier)
+	if identity, err = p.Exchange(ctx, code, verifier, "the-nonce"); err != nil || identity.Username != "ana" {
+		t.Errorf("Exchange with preferred_username = %+v, %v", identity, err)
+	}
+
+	config := idp.Config(callback)
+	config.UsernameClaims = []string{"nickname"}
+	if p, err = hrsoidc.NewProvider(config, nil); err != nil {
+		t.Fatal(err)
+	}
+	code = login(t, idp, p, "the-nonce", verifier)
+	if _, err = p.Exchange(ctx, code, verifier, "the-nonce"); !errors.Is(err, hrsoidc.ErrLogin) {
+		t.Errorf("Exchange without the username claim: %v", err)
+	}
+
+	config = idp.Config(callback)
+	config.ClientSecret = "wrong"
+	if p, err = hrsoidc.NewProvider(config, nil); err != nil {
+		t.Fatal(err)
+	}
+	code = login(t, idp, p, "the-nonce", verifier)
+	if _, err = p.Exchange(ctx, code, verifier, "the-nonce"); !errors.Is(err, hrsoidc.ErrLogin) {
+		t.Errorf("Exchange with a wrong secret: %v", err)
+	}
+}
+
+func TestProvider_discovery(t *testing.T) {
+	idp, err := oidctest.NewIdP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idp.Close()
+
+	config := idp.Config(callback)
+	config.Issuer = idp.URL + "/"
+	p, err := hrsoidc.NewProvider(config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = p.AuthCodeURL(context.Background(), "state", "nonce", "verifier"); err == nil {
+		t.Error("AuthCodeURL of a provider telling another issuer")
+	}
+
+	if _, err = hrsoidc.NewProvider(hrsoidc.Config{Issuer: idp.URL}, nil); err == nil {
+		t.Error("NewProvider without client")
+	}
+
+	p, _ = hrsoidc.NewProvider(idp.Config(callback), nil)
+	authURL, err := p.AuthCodeURL(context.Background(), "state", "nonce", "verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	query, _ := url.ParseQuery(strings.SplitN(authURL, "?", 2)[1])
+	if query.Get("scope") != "openid profile email" || query.Get("code_challenge") != hrsoidc.Challenge("verifier") {
+		t.Errorf("AuthCodeURL query = %v", query)
+	}
+}
@@ -0,0 +1,211 @@
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ninh0gauch0/homerecipes/hrsoidc"
+)
+
+const (
+	// CLIENTID Constant
+	CLIENTID = "hrs"
+	// CLIENTSECRET Constant
+	CLIENTSECRET = "hrs-secret"
+	// KEYID Constant
+	KEYID = "test-key"
+)
+
+// IdP - a stand-in OpenID Connect provider for tests: it knows a single
+// client and logs in, without asking, whoever Claims says, issuing RS256
+// ID tokens. Authorize plays the browser going to the provider and back.
+type IdP struct {
+	*httptest.Server
+	// Claims - the claims of the next ID tokens, besides iss, aud, exp,
+	// iat and nonce; sub must be among them
+	Claims map[string]interface{}
+	key    *rsa.PrivateKey
+	mu     sync.Mutex
+	codes  map[string]grant
+}
+
+// grant - an authorization code handed out and what it was asked with
+type grant struct {
+	redirectURI string
+	challenge   string
+	nonce       string
+	claims      map[string]interface{}
+}
+
+// NewIdP - a started stand-in provider, to be closed by the test
+func NewIdP() (*IdP, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	idp := &IdP{key: key, codes: map[string]grant{}, Claims: map[string]interface{}{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(hrsoidc.DISCOVERYPATH, idp.discovery)
+	mux.HandleFunc("/jwks", idp.jwks)
+	mux.HandleFunc("/authorize", idp.authorize)
+	mux.HandleFunc("/token", idp.token)
+	idp.Server = httptest.NewServer(mux)
+	return idp, nil
+}
+
+// Config - the client configuration of the relying party of the provider
+func (i *IdP) Config(redirectURL string) hrsoidc.Config {
+	return hrsoidc.Config{Issuer: i.URL, ClientID: CLIENTID, ClientSecret: CLIENTSECRET, RedirectURL: redirectURL}
+}
+
+// Authorize - follows the authorization URL as the browser does and
+// returns where the provider sends the user back to
+func (i *IdP) Authorize(authURL string) (*url.URL, error) {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	rsp, err := client.Get(authURL)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusFound {
+		return nil, errors.New("the provider turned the authorization down: " + rsp.Status)
+	}
+	return rsp.Location()
+}
+
+/** PRIVATE METHODS **/
+
+// discovery - the provider metadata
+func (i *IdP) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                i.URL,
+		"authorization_endpoint":                i.URL + "/authorize",
+		"token_endpoint":                        i.URL + "/token",
+		"jwks_uri":                              i.URL + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+// jwks - the public key the ID tokens are signed with
+func (i *IdP) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"keys": []map[string]string{{
+		"kty": "RSA",
+		"use": "sig",
+		"alg": "RS256",
+		"kid": KEYID,
+		"n":   base64.RawURLEncoding.EncodeToString(i.key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(i.key.E)).Bytes()),
+	}}})
+}
+
+// authorize - logs in whoever Claims says and sends them back with a code
+func (i *IdP) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != CLIENTID || query.Get("response_type") != "code" ||
+		query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" || query.Get("redirect_uri") == "" {
+		http.Error(w, "bad authorization request", http.StatusBadRequest)
+		return
+	}
+
+	code, err := hrsoidc.NewSecret()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	i.mu.Lock()
+	claims := map[string]interface{}{}
+	for name, value := range i.Claims {
+		claims[name] = value
+	}
+	i.codes[code] = grant{redirectURI: query.Get("redirect_uri"), challenge: query.Get("code_challenge"),
+		nonce: query.Get("nonce"), claims: claims}
+	i.mu.Unlock()
+
+	back, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	values := back.Query()
+	values.Set("code", code)
+	values.Set("state", query.Get("state"))
+	back.RawQuery = values.Encode()
+	http.Redirect(w, r, back.String(), http.StatusFound)
+}
+
+// token - trades a code, once, for an ID token when the client, the
+// redirect URI and the PKCE verifier are the ones it was handed out for
+func (i *IdP) token(w http.ResponseWriter, r *http.Request) {
+	id, secret, _ := r.BasicAuth()
+	id, _ = url.QueryUnescape(id)
+	secret, _ = url.QueryUnescape(secret)
+	if id != CLIENTID || secret != CLIENTSECRET {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	i.mu.Lock()
+	code, ok := i.codes[r.PostFormValue("code")]
+	delete(i.codes, r.PostFormValue("code"))
+	i.mu.Unlock()
+	if !ok || r.PostFormValue("grant_type") != "authorization_code" || r.PostFormValue("redirect_uri") != code.redirectURI ||
+		hrsoidc.Challenge(r.PostFormValue("code_verifier")) != code.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	code.claims["iss"] = i.URL
+	code.claims["aud"] = CLIENTID
+	code.claims["iat"] = now.Unix()
+	code.claims["exp"] = now.Add(time.Hour).Unix()
+	code.claims["nonce"] = code.nonce
+	token, err := i.sign(code.claims)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"access_token": "unused", "token_type": "Bearer", "id_token": token})
+}
+
+// sign - an RS256 JWT with the claims
+func (i *IdP) sign(claims map[string]interface{}) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": KEYID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	sum := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, i.key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// writeJSON - writes the value as the JSON answer
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
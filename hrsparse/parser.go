@@ -0,0 +1,164 @@
+package hrsparse
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/homerecipes/hrssearch"
+	"github.com/ninh0gauch0/homerecipes/hrsunits"
+)
+
+// numberWords - words standing for an amount at the start of a line
+var numberWords = map[string]float64{
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "half": 0.5,
+	"un": 1, "una": 1, "uno": 1, "dos": 2, "tres": 3, "medio": 0.5, "media": 0.5,
+}
+
+// rangeWords - words joining the two ends of a range
+var rangeWords = map[string]bool{"-": true, "–": true, "to": true, "a": true, "or": true, "o": true}
+
+// connectors - words between the unit and the ingredient name
+var connectors = map[string]bool{"de": true, "of": true}
+
+// optionalWords - marks of an optional ingredient
+var optionalWords = []string{"optional", "opcional"}
+
+// ParseLine - reads a free text ingredient line like "1 1/2 tazas de
+// harina de trigo, tamizada" or "2-3 cloves garlic, minced" into its
+// quantity, unit, name and note. The name is not resolved.
+func ParseLine(text string) hrsmodel.IngredientLine {
+	line := hrsmodel.IngredientLine{}
+	text = strings.TrimLeft(strings.TrimSpace(text), "-*•· ")
+
+	text, notes := extractNotes(text)
+	for _, note := range notes {
+		if isOptional(note) {
+			line.Optional = true
+			continue
+		}
+		if line.Note != "" {
+			line.Note += "; "
+		}
+		line.Note += note
+	}
+
+	words := strings.Fields(text)
+	quantity, rest := parseQuantity(words)
+	line.Quantity = quantity
+
+	if unit, n := parseUnit(rest); unit != nil {
+		line.Unit = unit.Code
+		rest = rest[n:]
+	}
+	if len(rest) > 1 && connectors[hrssearch.Fold(rest[0])] && (line.Quantity != nil || line.Unit != "") {
+		rest = rest[1:]
+	}
+
+	line.Name = strings.TrimSpace(strings.Join(rest, " "))
+	return line
+}
+
+/** PRIVATE METHODS **/
+
+// extractNotes - takes out what goes between parentheses and after the
+// first comma
+func extractNotes(text string) (string, []string) {
+	notes := []string{}
+
+	for {
+		open := strings.Index(text, "(")
+		end := strings.Index(text, ")")
+		if open < 0 || end < open {
+			break
+		}
+		if note := strings.TrimSpace(text[open+1 : end]); note != "" {
+			notes = append(notes, note)
+		}
+		text = text[:open] + " " + text[end+1:]
+	}
+
+	if comma := strings.Index(text, ","); comma >= 0 && !isDecimalComma(text, comma) {
+		if note := strings.TrimSpace(text[comma+1:]); note != "" {
+			notes = append(notes, note)
+		}
+		text = text[:comma]
+	}
+	return strings.TrimSpace(text), notes
+}
+
+// isDecimalComma - true for the comma of "0,5"
+func isDecimalComma(text string, i int) bool {
+	return i > 0 && i+1 < len(text) && unicode.IsDigit(rune(text[i-1])) && unicode.IsDigit(rune(text[i+1]))
+}
+
+func isOptional(note string) bool {
+	folded := hrssearch.Fold(note)
+	for _, word := range optionalWords {
+		if folded == word {
+			return true
+		}
+	}
+	return false
+}
+
+// parseQuantity - the longest run of leading words that is a quantity
+func parseQuantity(words []string) (*hrsmodel.Quantity, []string) {
+	if len(words) == 0 {
+		return nil, words
+	}
+
+	if value, ok := numberWords[hrssearch.Fold(words[0])]; ok && len(words) > 1 {
+		if _, isNumber := parseAmounts(words[1:2]); !isNumber {
+			return &hrsmodel.Quantity{Value: value}, words[1:]
+		}
+	}
+
+	var best *hrsmodel.Quantity
+	used := 0
+	for n := 1; n <= len(words) && n <= 5; n++ {
+		if q, ok := parseAmounts(words[:n]); ok {
+			best, used = q, n
+		}
+	}
+	return best, words[used:]
+}
+
+// parseAmounts - the words as a single amount or a range
+func parseAmounts(words []string) (*hrsmodel.Quantity, bool) {
+	for i, word := range words {
+		if !rangeWords[hrssearch.Fold(word)] || i == 0 || i == len(words)-1 {
+			continue
+		}
+		from, err := hrsmodel.ParseAmount(strings.Join(words[:i], " "))
+		if err != nil {
+			return nil, false
+		}
+		to, err := hrsmodel.ParseAmount(strings.Join(words[i+1:], " "))
+		if err != nil || to < from {
+			return nil, false
+		}
+		return &hrsmodel.Quantity{Value: from, Max: to}, true
+	}
+
+	q, err := hrsmodel.ParseQuantity(strings.Join(words, " "))
+	if err != nil {
+		return nil, false
+	}
+	return q, true
+}
+
+// parseUnit - the unit written by the first one or two words
+func parseUnit(words []string) (*hrsunits.Unit, int) {
+	if len(words) > 1 {
+		if unit, ok := hrsunits.Lookup(words[0] + " " + words[1]); ok {
+			return unit, 2
+		}
+	}
+	if len(words) > 0 {
+		if unit, ok := hrsunits.Lookup(words[0]); ok {
+			return unit, 1
+		}
+	}
+	return nil, 0
+}
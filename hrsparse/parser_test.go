@@ -0,0 +1,42 @@
+package hrsparse
+
+import (
+	"testing"
+
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		text string
+		want hrsmodel.IngredientLine
+	}{
+		{"1 1/2 tazas de harina de trigo, tamizada", hrsmodel.IngredientLine{
+			Quantity: &hrsmodel.Quantity{Value: 1.5}, Unit: "cup", Name: "harina de trigo", Note: "tamizada"}},
+		{"2-3 cloves garlic, minced", hrsmodel.IngredientLine{
+			Quantity: &hrsmodel.Quantity{Value: 2, Max: 3}, Unit: "clove", Name: "garlic", Note: "minced"}},
+		{"0,5 l de leche entera", hrsmodel.IngredientLine{
+			Quantity: &hrsmodel.Quantity{Value: 0.5}, Unit: "l", Name: "leche entera"}},
+		{"½ cdta. de sal", hrsmodel.IngredientLine{
+			Quantity: &hrsmodel.Quantity{Value: 0.5}, Unit: "tsp", Name: "sal"}},
+		{"un diente de ajo", hrsmodel.IngredientLine{
+			Quantity: &hrsmodel.Quantity{Value: 1}, Unit: "clove", Name: "ajo"}},
+		{"200 g flour (optional)", hrsmodel.IngredientLine{
+			Quantity: &hrsmodel.Quantity{Value: 200}, Unit: "g", Name: "flour", Optional: true}},
+		{"3 huevos", hrsmodel.IngredientLine{
+			Quantity: &hrsmodel.Quantity{Value: 3}, Name: "huevos"}},
+		{"- Sal al gusto", hrsmodel.IngredientLine{Name: "Sal al gusto"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got := ParseLine(tt.text)
+			if (got.Quantity == nil) != (tt.want.Quantity == nil) || (got.Quantity != nil && *got.Quantity != *tt.want.Quantity) {
+				t.Errorf("ParseLine() quantity = %+v, want %+v", got.Quantity, tt.want.Quantity)
+			}
+			got.Quantity, tt.want.Quantity = nil, nil
+			if got != tt.want {
+				t.Errorf("ParseLine() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package hrssearch
+
+import (
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// SNIPPETRADIUS Constant
+	SNIPPETRADIUS = 40
+	// MARKOPEN Constant
+	MARKOPEN = "<mark>"
+	// MARKCLOSE Constant
+	MARKCLOSE = "</mark>"
+)
+
+// Field - a named piece of text of a document; Boost weights its matches
+type Field struct {
+	Name  string
+	Text  string
+	Boost float64
+}
+
+// Hit - a matching document. Snippets holds, per matched field, an excerpt
+// with the matches wrapped in <mark> tags.
+type Hit struct {
+	ID       string            `json:"id"`
+	Score    float64           `json:"score"`
+	Snippets map[string]string `json:"snippets"`
+}
+
+// Index - a concurrency safe in-memory inverted index. Postings count the
+// occurrences of a term per document and field.
+type Index struct {
+	mu       sync.RWMutex
+	docs     map[string]document
+	postings map[string]map[string]map[int]int
+}
+
+// document - the indexed fields and their length in terms
+type document struct {
+	fields  []Field
+	lengths []int
+}
+
+// NewIndex - creates an empty index
+func NewIndex() *Index {
+	return &Index{
+		docs:     map[string]document{},
+		postings: map[string]map[string]map[int]int{},
+	}
+}
+
+// Add - indexes a document, replacing any previous version
+func (idx *Index) Add(id string, fields []Field) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(id)
+	doc := document{fields: fields, lengths: make([]int, len(fields))}
+
+	for f, field := range fields {
+		tokens := tokenize(field.Text)
+		doc.lengths[f] = len(tokens)
+
+		for _, tok := range tokens {
+			if idx.postings[tok.term] == nil {
+				idx.postings[tok.term] = map[string]map[int]int{}
+			}
+			if idx.postings[tok.term][id] == nil {
+				idx.postings[tok.term][id] = map[int]int{}
+			}
+			idx.postings[tok.term][id][f]++
+		}
+	}
+	idx.docs[id] = doc
+}
+
+// Remove - drops a document from the index
+func (idx *Index) Remove(id string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(id)
+}
+
+// Len - number of indexed documents
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	return len(idx.docs)
+}
+
+// Search - returns the documents matching any term of query, best first
+func (idx *Index) Search(query string, limit int) []Hit {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	terms := map[string]bool{}
+	for _, term := range Terms(query) {
+		terms[term] = true
+	}
+
+	scores := map[string]float64{}
+	for term := range terms {
+		docs := idx.postings[term]
+		idf := math.Log(1 + float64(len(idx.docs))/float64(len(docs)+1))
+
+		for id, fields := range docs {
+			doc := idx.docs[id]
+			for f, tf := range fields {
+				scores[id] += idf * doc.fields[f].Boost * float64(tf) / math.Sqrt(float64(doc.lengths[f]))
+			}
+		}
+	}
+
+	hits := []Hit{}
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	for i := range hits {
+		hits[i].Snippets = idx.snippets(hits[i].ID, terms)
+	}
+	return hits
+}
+
+/** PRIVATE METHODS **/
+
+func (idx *Index) remove(id string) {
+	doc, ok := idx.docs[id]
+	if !ok {
+		return
+	}
+
+	for _, field := range doc.fields {
+		for _, tok := range tokenize(field.Text) {
+			delete(idx.postings[tok.term], id)
+			if len(idx.postings[tok.term]) == 0 {
+				delete(idx.postings, tok.term)
+			}
+		}
+	}
+	delete(idx.docs, id)
+}
+
+// snippets - an excerpt per field around its first match
+func (idx *Index) snippets(id string, terms map[string]bool) map[string]string {
+	snippets := map[string]string{}
+
+	for _, field := range idx.docs[id].fields {
+		matches := []token{}
+		for _, tok := range tokenize(field.Text) {
+			if terms[tok.term] {
+				matches = append(matches, tok)
+			}
+		}
+		if len(matches) > 0 {
+			snippets[field.Name] = highlight(field.Text, matches)
+		}
+	}
+	return snippets
+}
+
+// highlight - cuts text around the first match and marks the matches
+// inside; the text is escaped, snippets are HTML
+func highlight(text string, matches []token) string {
+	from := wordBoundary(text, matches[0].start-SNIPPETRADIUS, -1)
+	to := wordBoundary(text, matches[0].end+SNIPPETRADIUS, 1)
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("...")
+	}
+
+	pos := from
+	for _, m := range matches {
+		if m.start < pos || m.end > to {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:m.start]))
+		b.WriteString(MARKOPEN)
+		b.WriteString(html.EscapeString(text[m.start:m.end]))
+		b.WriteString(MARKCLOSE)
+		pos = m.end
+	}
+	b.WriteString(html.EscapeString(text[pos:to]))
+
+	if to < len(text) {
+		b.WriteString("...")
+	}
+	return b.String()
+}
+
+// wordBoundary - moves i towards dir until it sits on a space or an end
+func wordBoundary(text string, i int, dir int) int {
+	if i <= 0 {
+		return 0
+	}
+	if i >= len(text) {
+		return len(text)
+	}
+	for i > 0 && i < len(text) && text[i] != ' ' {
+		i += dir
+	}
+	return i
+}
@@ -0,0 +1,94 @@
+package hrssearch
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIndex_Search(t *testing.T) {
+	idx := NewIndex()
+	idx.Add("r1", []Field{
+		{Name: "name", Text: "Pulpo a la gallega", Boost: 3},
+		{Name: "steps", Text: "Cocer el pulpo y espolvorear con pimentón y sal", Boost: 1},
+	})
+	idx.Add("r2", []Field{
+		{Name: "name", Text: "Patatas con pimentón", Boost: 3},
+	})
+	idx.Add("r3", []Field{
+		{Name: "name", Text: "Baked onions", Boost: 3},
+	})
+
+	hits := idx.Search("PIMENTON", 10)
+	if len(hits) != 2 {
+		t.Fatalf("Search() = %+v, want 2 hits", hits)
+	}
+	if hits[0].ID != "r2" {
+		t.Errorf("Search() best hit = %s, want the one matching in the name", hits[0].ID)
+	}
+	if !strings.Contains(hits[1].Snippets["steps"], "<mark>pimentón</mark>") {
+		t.Errorf("Search() snippet = %q", hits[1].Snippets["steps"])
+	}
+
+	if hits = idx.Search("onion bake", 10); len(hits) != 1 || hits[0].ID != "r3" {
+		t.Errorf("Search() stemmed = %+v", hits)
+	}
+
+	idx.Remove("r2")
+	if hits = idx.Search("pimentón", 10); len(hits) != 1 || hits[0].ID != "r1" {
+		t.Errorf("Search() after Remove = %+v", hits)
+	}
+}
+
+func TestIndex_Search_escapes(t *testing.T) {
+	idx := NewIndex()
+	idx.Add("r1", []Field{
+		{Name: "steps", Text: `Servir <img src=x onerror="alert(1)"> con pimentón & sal`, Boost: 1},
+	})
+
+	hits := idx.Search("pimentón", 10)
+	if len(hits) != 1 {
+		t.Fatalf("Search() = %+v, want 1 hit", hits)
+	}
+	want := `Servir &lt;img src=x onerror=&#34;alert(1)&#34;&gt; con <mark>pimentón</mark> &amp; sal`
+	if snippet := hits[0].Snippets["steps"]; snippet != want {
+		t.Errorf("Search() snippet = %q, want %q", snippet, want)
+	}
+}
+
+func TestIndex_Search_ranking(t *testing.T) {
+	idx := NewIndex()
+	idx.Add("r1", []Field{
+		{Name: "name", Text: "Cordero de un año", Boost: 3},
+	})
+	idx.Add("r2", []Field{
+		{Name: "name", Text: "Pasta con tomates", Boost: 3},
+	})
+	idx.Add("r3", []Field{
+		{Name: "name", Text: "Tomate de la Peña", Boost: 3},
+		{Name: "steps", Text: "Pelar el tomate", Boost: 1},
+	})
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"año", []string{"r1"}},
+		{"ano", []string{}},
+		{"peña", []string{"r3"}},
+		{"pena", []string{}},
+		{"pastas", []string{"r2"}},
+		{"pasto", []string{}},
+		{"tomate", []string{"r3", "r2"}},
+		{"TOMATES", []string{"r3", "r2"}},
+	}
+	for _, tt := range tests {
+		hits := idx.Search(tt.query, 10)
+		got := []string{}
+		for _, hit := range hits {
+			got = append(got, hit.ID)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package hrssearch
+
+import (
+	"sort"
+	"sync"
+)
+
+// Match - how well the available ingredients cover a recipe
+type Match struct {
+	ID       string   `json:"id"`
+	Coverage float64  `json:"coverage"`
+	Have     []string `json:"have"`
+	Missing  []string `json:"missing"`
+}
+
+// IngredientIndex - concurrency safe index of the ingredient references
+// of every recipe, references are compared folded
+type IngredientIndex struct {
+	mu      sync.RWMutex
+	recipes map[string][]string
+	users   map[string]map[string]bool
+}
+
+// NewIngredientIndex - creates an empty index
+func NewIngredientIndex() *IngredientIndex {
+	return &IngredientIndex{
+		recipes: map[string][]string{},
+		users:   map[string]map[string]bool{},
+	}
+}
+
+// Add - indexes the references of a recipe, replacing previous ones
+func (idx *IngredientIndex) Add(id string, refs []string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(id)
+
+	unique := []string{}
+	seen := map[string]bool{}
+	for _, ref := range refs {
+		if ref == "" || seen[Fold(ref)] {
+			continue
+		}
+		seen[Fold(ref)] = true
+		unique = append(unique, ref)
+
+		if idx.users[Fold(ref)] == nil {
+			idx.users[Fold(ref)] = map[string]bool{}
+		}
+		idx.users[Fold(ref)][id] = true
+	}
+	idx.recipes[id] = unique
+}
+
+// Remove - drops a recipe from the index
+func (idx *IngredientIndex) Remove(id string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(id)
+}
+
+// Match - recipes using at least one of the available references and
+// missing at most maxMissing (no limit when negative), best covered first
+func (idx *IngredientIndex) Match(available []string, maxMissing int) []Match {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	have := map[string]bool{}
+	for _, ref := range available {
+		have[Fold(ref)] = true
+	}
+
+	// Only the recipes sharing an ingredient are candidates
+	candidates := map[string]bool{}
+	for ref := range have {
+		for id := range idx.users[ref] {
+			candidates[id] = true
+		}
+	}
+
+	matches := []Match{}
+	for id := range candidates {
+		m := Match{ID: id, Have: []string{}, Missing: []string{}}
+		for _, ref := range idx.recipes[id] {
+			if have[Fold(ref)] {
+				m.Have = append(m.Have, ref)
+			} else {
+				m.Missing = append(m.Missing, ref)
+			}
+		}
+		if maxMissing >= 0 && len(m.Missing) > maxMissing {
+			continue
+		}
+		m.Coverage = float64(len(m.Have)) / float64(len(idx.recipes[id]))
+		matches = append(matches, m)
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.Coverage != b.Coverage {
+			return a.Coverage > b.Coverage
+		}
+		if len(a.Missing) != len(b.Missing) {
+			return len(a.Missing) < len(b.Missing)
+		}
+		return a.ID < b.ID
+	})
+	return matches
+}
+
+/** PRIVATE METHODS **/
+
+func (idx *IngredientIndex) remove(id string) {
+	for _, ref := range idx.recipes[id] {
+		delete(idx.users[Fold(ref)], id)
+		if len(idx.users[Fold(ref)]) == 0 {
+			delete(idx.users, Fold(ref))
+		}
+	}
+	delete(idx.recipes, id)
+}
@@ -0,0 +1,30 @@
+package hrssearch
+
+import "testing"
+
+func TestIngredientIndex_Match(t *testing.T) {
+	idx := NewIngredientIndex()
+	idx.Add("tortilla", []string{"egg", "potato", "onion"})
+	idx.Add("fried-egg", []string{"egg", "oil"})
+	idx.Add("salad", []string{"lettuce", "tomato", "onion"})
+
+	matches := idx.Match([]string{"EGG", "potato", "oil"}, 1)
+	if len(matches) != 2 {
+		t.Fatalf("Match() = %+v, want 2 matches", matches)
+	}
+	if matches[0].ID != "fried-egg" || matches[0].Coverage != 1 {
+		t.Errorf("Match() best = %+v, want fried-egg fully covered", matches[0])
+	}
+	if matches[1].ID != "tortilla" || len(matches[1].Missing) != 1 || matches[1].Missing[0] != "onion" {
+		t.Errorf("Match() second = %+v, want tortilla missing onion", matches[1])
+	}
+
+	if matches = idx.Match([]string{"onion"}, 0); len(matches) != 0 {
+		t.Errorf("Match() with no missing allowed = %+v", matches)
+	}
+
+	idx.Remove("fried-egg")
+	if matches = idx.Match([]string{"oil"}, -1); len(matches) != 0 {
+		t.Errorf("Match() after Remove = %+v", matches)
+	}
+}
@@ -0,0 +1,137 @@
+package hrssearch
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// stopWords - Spanish and English words not worth indexing
+var stopWords = map[string]bool{
+	"a": true, "al": true, "con": true, "de": true, "del": true, "el": true,
+	"en": true, "la": true, "las": true, "lo": true, "los": true, "o": true,
+	"para": true, "por": true, "se": true, "un": true, "una": true, "y": true,
+	"an": true, "and": true, "for": true, "in": true, "of": true, "on": true,
+	"or": true, "the": true, "to": true, "with": true,
+}
+
+// keptLetters - accented letters Fold keeps, as they tell words apart:
+// "año" isn't "ano" nor "peña" "pena"
+var keptLetters = map[rune]bool{'ñ': true}
+
+// suffixes - plural, adverb and verb endings stem replaces, longest first.
+// Genders are kept, as "pasta" and "pasto" or "casa" and "caso" are
+// different words
+var suffixes = []struct {
+	suffix  string
+	replace string
+}{
+	{"mente", ""}, {"ces", "z"}, {"ies", "y"}, {"ing", ""},
+	{"es", ""}, {"ed", ""}, {"ly", ""}, {"s", ""},
+}
+
+// token - a term and its byte offsets in the original text
+type token struct {
+	term  string
+	start int
+	end   int
+}
+
+// Fold - lowercases s and removes its accents but the keptLetters ones:
+// "Pimentón" -> "pimenton", "Piñón" -> "piñon"
+func Fold(s string) string {
+	var folded strings.Builder
+	for _, r := range norm.NFC.String(strings.ToLower(s)) {
+		if keptLetters[r] {
+			folded.WriteRune(r)
+			continue
+		}
+		for _, d := range norm.NFD.String(string(r)) {
+			if !unicode.Is(unicode.Mn, d) {
+				folded.WriteRune(d)
+			}
+		}
+	}
+	return folded.String()
+}
+
+// Terms - the index terms of a text, in order
+func Terms(text string) []string {
+	terms := []string{}
+	for _, tok := range tokenize(text) {
+		terms = append(terms, tok.term)
+	}
+	return terms
+}
+
+// Similarity - how alike two short texts are, from 0 to 1, by their
+// shared terms: "harina de trigo" and "Harinas" share half of them
+func Similarity(a string, b string) float64 {
+	ta, tb := map[string]bool{}, map[string]bool{}
+	for _, term := range Terms(a) {
+		ta[term] = true
+	}
+	for _, term := range Terms(b) {
+		tb[term] = true
+	}
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for term := range ta {
+		if tb[term] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(ta)+len(tb)-shared)
+}
+
+// tokenize - splits text in words, folds and stems them, stop words skipped
+func tokenize(text string) []token {
+	tokens := []token{}
+	start := -1
+
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		word := Fold(text[start:end])
+		if !stopWords[word] {
+			tokens = append(tokens, token{term: stem(word), start: start, end: end})
+		}
+		start = -1
+	}
+
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		flush(i)
+	}
+	flush(len(text))
+	return tokens
+}
+
+// stem - a light suffix stripper good enough for Spanish and English
+// plurals and verb forms: "cebollas" -> "cebolla", "limones", "limón" ->
+// "limon", "tomates", "tomate" -> "tomat", "baked", "bake" -> "bak"
+func stem(word string) string {
+	for _, s := range suffixes {
+		if strings.HasSuffix(word, s.suffix) && len(word)-len(s.suffix) >= 3 {
+			word = strings.TrimSuffix(word, s.suffix) + s.replace
+			break
+		}
+	}
+	switch {
+	case len(word) > 3 && strings.HasSuffix(word, "ce"):
+		word = strings.TrimSuffix(word, "ce") + "z"
+	case len(word) > 3 && strings.HasSuffix(word, "e"):
+		word = strings.TrimSuffix(word, "e")
+	}
+	return word
+}
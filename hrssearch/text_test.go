@@ -0,0 +1,47 @@
+package hrssearch
+
+import "testing"
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Pimentón", "pimenton"},
+		{"AÑO", "año"},
+		{"Piñón", "piñon"},
+		{"ñandú", "ñandu"},
+		{"Crème brûlée", "creme brulee"},
+	}
+	for _, tt := range tests {
+		if got := Fold(tt.in); got != tt.want {
+			t.Errorf("Fold(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTerms_stems(t *testing.T) {
+	tests := []struct {
+		a    string
+		b    string
+		same bool
+	}{
+		{"cebollas", "cebolla", true},
+		{"limones", "limón", true},
+		{"tomates", "tomate", true},
+		{"nueces", "nuez", true},
+		{"berries", "berry", true},
+		{"baked", "bake", true},
+		{"pasta", "pasto", false},
+		{"casa", "caso", false},
+		{"pera", "pero", false},
+		{"año", "ano", false},
+		{"peña", "pena", false},
+	}
+	for _, tt := range tests {
+		a, b := Terms(tt.a), Terms(tt.b)
+		if same := len(a) == 1 && len(b) == 1 && a[0] == b[0]; same != tt.same {
+			t.Errorf("Terms(%q) = %v, Terms(%q) = %v, same = %v", tt.a, a, tt.b, b, same)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package hrsstore
+
+import (
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+)
+
+// APIKeyStore - API keys persistence operations
+type APIKeyStore interface {
+	InsertAPIKey(key *hrsmodel.APIKey) error
+	GetAPIKey(id string) (*hrsmodel.APIKey, error)
+	DeleteAPIKey(id string) error
+	ListAPIKeys(user string) ([]*hrsmodel.APIKey, error)
+}
+
+// InsertAPIKey - inserts an API key, its code must be free
+func (d *docStore) InsertAPIKey(key *hrsmodel.APIKey) error {
+	return d.eng.update(func(t tx) error {
+		return insertDoc(t, APIKEYCOLL, key.Code, key)
+	})
+}
+
+// GetAPIKey - returns an API key by id
+func (d *docStore) GetAPIKey(id string) (key *hrsmodel.APIKey, err error) {
+	err = d.eng.view(func(t tx) error {
+		key = &hrsmodel.APIKey{}
+		return getDoc(t, APIKEYCOLL, id, key)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// DeleteAPIKey - removes an API key by id
+func (d *docStore) DeleteAPIKey(id string) error {
+	return d.eng.update(func(t tx) error {
+		return deleteDoc(t, APIKEYCOLL, id)
+	})
+}
+
+// ListAPIKeys - the API keys of a user
+func (d *docStore) ListAPIKeys(user string) ([]*hrsmodel.APIKey, error) {
+	keys := []*hrsmodel.APIKey{}
+
+	err := d.eng.view(func(t tx) error {
+		return t.each(APIKEYCOLL, func(id string, data []byte) error {
+			key := &hrsmodel.APIKey{}
+			if _, err := readRecord(data, key); err != nil {
+				return err
+			}
+			if key.User == user {
+				keys = append(keys, key)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// InsertAPIKey - the connector only maps recipes and ingredients
+func (m *MongoStore) InsertAPIKey(key *hrsmodel.APIKey) error {
+	return ErrUnsupported
+}
+
+// GetAPIKey - the connector only maps recipes and ingredients
+func (m *MongoStore) GetAPIKey(id string) (*hrsmodel.APIKey, error) {
+	return nil, ErrUnsupported
+}
+
+// DeleteAPIKey - the connector only maps recipes and ingredients
+func (m *MongoStore) DeleteAPIKey(id string) error {
+	return ErrUnsupported
+}
+
+// ListAPIKeys - the connector only maps recipes and ingredients
+func (m *MongoStore) ListAPIKeys(user string) ([]*hrsmodel.APIKey, error) {
+	return nil, ErrUnsupported
+}
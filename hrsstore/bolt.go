@@ -0,0 +1,105 @@
+package hrsstore
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltStore - persistent store kept in a single bbolt file, one bucket per
+// collection
+type BoltStore struct {
+	docStore
+}
+
+// NewBoltStore - opens (or creates) the database file
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("%w: %s is open in another process, stop the server before running commands on it", ErrUnavailable, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &BoltStore{docStore{eng: &boltEngine{db: db}}}, nil
+}
+
+// boltEngine - engine over a bbolt database
+type boltEngine struct {
+	db *bolt.DB
+}
+
+func (b *boltEngine) view(fn func(tx) error) error {
+	return b.db.View(func(t *bolt.Tx) error {
+		return fn(&boltTx{t: t})
+	})
+}
+
+func (b *boltEngine) update(fn func(tx) error) error {
+	return b.db.Update(func(t *bolt.Tx) error {
+		return fn(&boltTx{t: t})
+	})
+}
+
+func (b *boltEngine) close() error {
+	return b.db.Close()
+}
+
+// boltTx - tx over a bbolt transaction, buckets are created on first write
+type boltTx struct {
+	t *bolt.Tx
+}
+
+func (b *boltTx) get(coll string, id string) []byte {
+	bucket := b.t.Bucket([]byte(coll))
+	if bucket == nil {
+		return nil
+	}
+	return bucket.Get([]byte(id))
+}
+
+func (b *boltTx) put(coll string, id string, data []byte) error {
+	bucket, err := b.t.CreateBucketIfNotExists([]byte(coll))
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(id), data)
+}
+
+func (b *boltTx) del(coll string, id string) error {
+	bucket := b.t.Bucket([]byte(coll))
+	if bucket == nil {
+		return nil
+	}
+	return bucket.Delete([]byte(id))
+}
+
+// each - visits the documents of a collection sorted by id
+func (b *boltTx) each(coll string, fn func(id string, data []byte) error) error {
+	return b.eachAfter(coll, "", 0, fn)
+}
+
+// eachAfter - visits up to limit documents, all with 0, whose id sorts
+// after the given one, sorted by id; the cursor seeks right to them
+func (b *boltTx) eachAfter(coll string, after string, limit int, fn func(id string, data []byte) error) error {
+	bucket := b.t.Bucket([]byte(coll))
+	if bucket == nil {
+		return nil
+	}
+
+	c := bucket.Cursor()
+	k, v := c.Seek([]byte(after))
+	if k != nil && string(k) == after {
+		k, v = c.Next()
+	}
+	for n := 0; k != nil && (limit == 0 || n < limit); n++ {
+		if err := fn(string(k), v); err != nil {
+			return err
+		}
+		k, v = c.Next()
+	}
+	return nil
+}
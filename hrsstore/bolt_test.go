@@ -0,0 +1,84 @@
+package hrsstore
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/hrstypes"
+)
+
+func TestBoltStore_Transaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hrs.db")
+
+	store, err := NewBoltStore(path)
+	if err != nil {
+		t.Fatalf("NewBoltStore() error = %v", err)
+	}
+
+	// A failing transaction leaves both collections untouched
+	failure := errors.New("rollback")
+	err = store.RunInTransaction(func(tx Tx) error {
+		if err := tx.InsertIngredient(&hrsmodel.Ingredient{Ingredient: hrstypes.Ingredient{Code: "i1", Name: "Harina"}}); err != nil {
+			return err
+		}
+		if err := tx.InsertRecipe(newRecipe("r1", "Pan", "i1")); err != nil {
+			return err
+		}
+		return failure
+	})
+	if err != failure {
+		t.Fatalf("RunInTransaction() error = %v, want %v", err, failure)
+	}
+	if _, err = store.GetIngredient("i1"); err != ErrNotFound {
+		t.Errorf("GetIngredient() after rollback error = %v, want %v", err, ErrNotFound)
+	}
+
+	err = store.RunInTransaction(func(tx Tx) error {
+		if err := tx.InsertIngredient(&hrsmodel.Ingredient{Ingredient: hrstypes.Ingredient{Code: "i1", Name: "Harina"}}); err != nil {
+			return err
+		}
+		return tx.InsertRecipe(newRecipe("r1", "Pan", "i1"))
+	})
+	if err != nil {
+		t.Fatalf("RunInTransaction() error = %v", err)
+	}
+	if err = store.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// Everything survives a reopen
+	store, err = NewBoltStore(path)
+	if err != nil {
+		t.Fatalf("NewBoltStore() reopen error = %v", err)
+	}
+	defer store.Close()
+
+	recipe, err := store.GetRecipe("r1")
+	if err != nil {
+		t.Fatalf("GetRecipe() error = %v", err)
+	}
+	if recipe.Name != "Pan" || len(recipe.Lines) != 1 || recipe.Lines[0].Ingredient != "i1" {
+		t.Errorf("GetRecipe() = %+v", recipe)
+	}
+	if err = store.InsertRecipe(recipe); err != ErrConflict {
+		t.Errorf("InsertRecipe() duplicated error = %v, want %v", err, ErrConflict)
+	}
+}
+
+func TestNewBoltStore_locked(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hrs.db")
+	store, err := NewBoltStore(path)
+	if err != nil {
+		t.Fatalf("NewBoltStore() error = %v", err)
+	}
+	defer store.Close()
+
+	// The file stays locked by whoever opened it first, the server
+	_, err = NewBoltStore(path)
+	if !errors.Is(err, ErrUnavailable) || !strings.Contains(err.Error(), "stop the server") {
+		t.Errorf("NewBoltStore() of a file in use error = %v", err)
+	}
+}
@@ -0,0 +1,280 @@
+package hrsstore
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+)
+
+// tx - primitive document operations, valid inside a view or an update
+type tx interface {
+	get(coll string, id string) []byte
+	put(coll string, id string, data []byte) error
+	del(coll string, id string) error
+	each(coll string, fn func(id string, data []byte) error) error
+	eachAfter(coll string, after string, limit int, fn func(id string, data []byte) error) error
+}
+
+// engine - a key/value backend holding JSON documents per collection
+type engine interface {
+	view(fn func(tx) error) error
+	update(fn func(tx) error) error
+	close() error
+}
+
+// docStore - Store implementation shared by the embedded backends
+type docStore struct {
+	eng engine
+}
+
+// InsertRecipe - inserts a recipe, its code must be free
+func (d *docStore) InsertRecipe(recipe *hrsmodel.Recipe) error {
+	return d.eng.update(func(t tx) error {
+		return docTx{t}.InsertRecipe(recipe)
+	})
+}
+
+// GetRecipe - returns a recipe by id
+func (d *docStore) GetRecipe(id string) (recipe *hrsmodel.Recipe, err error) {
+	err = d.eng.view(func(t tx) error {
+		recipe, err = docTx{t}.GetRecipe(id)
+		return err
+	})
+	return recipe, err
+}
+
+// UpdateRecipe - patches the non empty fields of a recipe
+func (d *docStore) UpdateRecipe(id string, recipe *hrsmodel.Recipe) (stored *hrsmodel.Recipe, err error) {
+	err = d.eng.update(func(t tx) error {
+		stored, err = docTx{t}.UpdateRecipe(id, recipe)
+		return err
+	})
+	return stored, err
+}
+
+// DeleteRecipe - removes a recipe by id
+func (d *docStore) DeleteRecipe(id string) error {
+	return d.eng.update(func(t tx) error {
+		return docTx{t}.DeleteRecipe(id)
+	})
+}
+
+// InsertIngredient - inserts an ingredient, its code must be free
+func (d *docStore) InsertIngredient(ingredient *hrsmodel.Ingredient) error {
+	return d.eng.update(func(t tx) error {
+		return docTx{t}.InsertIngredient(ingredient)
+	})
+}
+
+// GetIngredient - returns an ingredient by id
+func (d *docStore) GetIngredient(id string) (ingredient *hrsmodel.Ingredient, err error) {
+	err = d.eng.view(func(t tx) error {
+		ingredient, err = docTx{t}.GetIngredient(id)
+		return err
+	})
+	return ingredient, err
+}
+
+// UpdateIngredient - patches the non empty fields of an ingredient
+func (d *docStore) UpdateIngredient(id string, ingredient *hrsmodel.Ingredient) (stored *hrsmodel.Ingredient, err error) {
+	err = d.eng.update(func(t tx) error {
+		stored, err = docTx{t}.UpdateIngredient(id, ingredient)
+		return err
+	})
+	return stored, err
+}
+
+// DeleteIngredient - removes an ingredient by id
+func (d *docStore) DeleteIngredient(id string) error {
+	return d.eng.update(func(t tx) error {
+		return docTx{t}.DeleteIngredient(id)
+	})
+}
+
+// ListRecipes - returns a page of the recipes matching q
+func (d *docStore) ListRecipes(q Query) (list *RecipeList, err error) {
+	err = d.eng.view(func(t tx) error {
+		list, err = docTx{t}.ListRecipes(q)
+		return err
+	})
+	return list, err
+}
+
+// ListIngredients - returns a page of the ingredients matching q
+func (d *docStore) ListIngredients(q Query) (list *IngredientList, err error) {
+	err = d.eng.view(func(t tx) error {
+		list, err = docTx{t}.ListIngredients(q)
+		return err
+	})
+	return list, err
+}
+
+// RunInTransaction - runs fn atomically, nothing is written if it fails
+func (d *docStore) RunInTransaction(fn func(Tx) error) error {
+	return d.eng.update(func(t tx) error {
+		return fn(docTx{t})
+	})
+}
+
+// Close - releases the engine
+func (d *docStore) Close() error {
+	return d.eng.close()
+}
+
+// docTx - Tx implementation over the engine primitives
+type docTx struct {
+	t tx
+}
+
+// InsertRecipe - inserts a recipe, its code must be free
+func (d docTx) InsertRecipe(recipe *hrsmodel.Recipe) error {
+	return insertDoc(d.t, RECIPECOLL, recipe.Code, recipe)
+}
+
+// GetRecipe - returns a recipe by id
+func (d docTx) GetRecipe(id string) (*hrsmodel.Recipe, error) {
+	recipe := &hrsmodel.Recipe{}
+	if err := getDoc(d.t, RECIPECOLL, id, recipe); err != nil {
+		return nil, err
+	}
+	return recipe, nil
+}
+
+// UpdateRecipe - patches the non empty fields of a recipe
+func (d docTx) UpdateRecipe(id string, recipe *hrsmodel.Recipe) (*hrsmodel.Recipe, error) {
+	stored, err := d.GetRecipe(id)
+	if err != nil {
+		return nil, err
+	}
+	patch(stored, recipe)
+	stored.Code = id
+	return stored, putDoc(d.t, RECIPECOLL, id, stored)
+}
+
+// DeleteRecipe - removes a recipe by id
+func (d docTx) DeleteRecipe(id string) error {
+	return deleteDoc(d.t, RECIPECOLL, id)
+}
+
+// InsertIngredient - inserts an ingredient, its code must be free
+func (d docTx) InsertIngredient(ingredient *hrsmodel.Ingredient) error {
+	return insertDoc(d.t, INGREDIENTCOLL, ingredient.Code, ingredient)
+}
+
+// GetIngredient - returns an ingredient by id
+func (d docTx) GetIngredient(id string) (*hrsmodel.Ingredient, error) {
+	ingredient := &hrsmodel.Ingredient{}
+	if err := getDoc(d.t, INGREDIENTCOLL, id, ingredient); err != nil {
+		return nil, err
+	}
+	return ingredient, nil
+}
+
+// UpdateIngredient - patches the non empty fields of an ingredient
+func (d docTx) UpdateIngredient(id string, ingredient *hrsmodel.Ingredient) (*hrsmodel.Ingredient, error) {
+	stored, err := d.GetIngredient(id)
+	if err != nil {
+		return nil, err
+	}
+	patch(stored, ingredient)
+	stored.Code = id
+	return stored, putDoc(d.t, INGREDIENTCOLL, id, stored)
+}
+
+// DeleteIngredient - removes an ingredient by id
+func (d docTx) DeleteIngredient(id string) error {
+	return deleteDoc(d.t, INGREDIENTCOLL, id)
+}
+
+/** PRIVATE METHODS **/
+
+// record - how a document is kept, along with the metadata the DTOs lack
+type record struct {
+	Created time.Time       `json:"created"`
+	Doc     json.RawMessage `json:"doc"`
+}
+
+func insertDoc(t tx, coll string, id string, doc interface{}) error {
+	if t.get(coll, id) != nil {
+		return ErrConflict
+	}
+	return writeRecord(t, coll, id, time.Now().UTC(), doc)
+}
+
+func getDoc(t tx, coll string, id string, doc interface{}) error {
+	data := t.get(coll, id)
+	if data == nil {
+		return ErrNotFound
+	}
+	_, err := readRecord(data, doc)
+	return err
+}
+
+// putDoc - replaces a document keeping its creation date
+func putDoc(t tx, coll string, id string, doc interface{}) error {
+	created := time.Now().UTC()
+	if data := t.get(coll, id); data != nil {
+		rec := record{}
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return err
+		}
+		created = rec.Created
+	}
+	return writeRecord(t, coll, id, created, doc)
+}
+
+func deleteDoc(t tx, coll string, id string) error {
+	if t.get(coll, id) == nil {
+		return ErrNotFound
+	}
+	return t.del(coll, id)
+}
+
+func writeRecord(t tx, coll string, id string, created time.Time, doc interface{}) error {
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(record{Created: created, Doc: raw})
+	if err != nil {
+		return err
+	}
+	return t.put(coll, id, data)
+}
+
+// readRecord - decodes the document into doc and returns its creation date
+func readRecord(data []byte, doc interface{}) (time.Time, error) {
+	rec := record{}
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return time.Time{}, err
+	}
+	return rec.Created, json.Unmarshal(rec.Doc, doc)
+}
+
+// patch - copies every non zero field of src into dst, the way the mongo
+// connector patches documents. Both must be pointers to the same struct type.
+func patch(dst interface{}, src interface{}) {
+	patchValue(reflect.ValueOf(dst).Elem(), reflect.ValueOf(src).Elem())
+}
+
+func patchValue(dst reflect.Value, src reflect.Value) {
+	typeOfT := src.Type()
+
+	for i := 0; i < src.NumField(); i++ {
+		field := typeOfT.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		f := src.Field(i)
+		if field.Anonymous && f.Kind() == reflect.Struct {
+			patchValue(dst.Field(i), f)
+			continue
+		}
+		if !f.IsZero() {
+			dst.Field(i).Set(f)
+		}
+	}
+}
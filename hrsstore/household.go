@@ -0,0 +1,416 @@
+package hrsstore
+
+import (
+	"fmt"
+
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+)
+
+// householdColls - the collections every household has its own of
+var householdColls = []string{RECIPECOLL, INGREDIENTCOLL, SHOPPINGLISTCOLL, MEALPLANCOLL, PANTRYCOLL}
+
+// HouseholdStore - households and invitations persistence operations
+type HouseholdStore interface {
+	InsertHousehold(household *hrsmodel.Household) error
+	GetHousehold(id string) (*hrsmodel.Household, error)
+	SaveHousehold(household *hrsmodel.Household) error
+	DeleteHousehold(household string) error
+	ListHouseholds(user string) ([]*hrsmodel.Household, error)
+	InsertInvitation(invitation *hrsmodel.Invitation) error
+	GetInvitation(id string) (*hrsmodel.Invitation, error)
+	DeleteInvitation(id string) error
+	ListInvitations(household string) ([]*hrsmodel.Invitation, error)
+	AcceptInvitation(id string, member hrsmodel.Member) (*hrsmodel.Household, error)
+	AddMember(id string, member hrsmodel.Member) (*hrsmodel.Household, error)
+	AdoptUnscoped(household string) (int, error)
+	Scope(household string) (Store, error)
+}
+
+// InsertHousehold - inserts a household, its code must be free
+func (d *docStore) InsertHousehold(household *hrsmodel.Household) error {
+	return d.eng.update(func(t tx) error {
+		return insertDoc(t, HOUSEHOLDCOLL, household.Code, household)
+	})
+}
+
+// GetHousehold - returns a household by id
+func (d *docStore) GetHousehold(id string) (household *hrsmodel.Household, err error) {
+	err = d.eng.view(func(t tx) error {
+		household = &hrsmodel.Household{}
+		return getDoc(t, HOUSEHOLDCOLL, id, household)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return household, nil
+}
+
+// SaveHousehold - replaces a stored household, members included, and
+// bumps its version; ErrConflict when it was saved since it was read
+func (d *docStore) SaveHousehold(household *hrsmodel.Household) error {
+	saved := *household
+	err := d.eng.update(func(t tx) error {
+		stored := &hrsmodel.Household{}
+		if err := getDoc(t, HOUSEHOLDCOLL, household.Code, stored); err != nil {
+			return err
+		}
+		if stored.Version != household.Version {
+			return ErrConflict
+		}
+		saved.Version++
+		return putDoc(t, HOUSEHOLDCOLL, household.Code, &saved)
+	})
+	if err != nil {
+		return err
+	}
+	household.Version = saved.Version
+	return nil
+}
+
+// DeleteHousehold - removes a household along with its invitations, share
+// links and everything stored in it
+func (d *docStore) DeleteHousehold(household string) error {
+	return d.eng.update(func(t tx) error {
+		if err := deleteDoc(t, HOUSEHOLDCOLL, household); err != nil {
+			return err
+		}
+
+		scoped := scopedTx{tx: unscoped(t), household: household}
+		for _, coll := range householdColls {
+			if err := deleteAll(scoped, coll, func(id string, data []byte) (bool, error) { return true, nil }); err != nil {
+				return err
+			}
+		}
+		err := deleteAll(t, INVITATIONCOLL, func(id string, data []byte) (bool, error) {
+			invitation := &hrsmodel.Invitation{}
+			_, err := readRecord(data, invitation)
+			return invitation.Household == household, err
+		})
+		if err != nil {
+			return err
+		}
+		return deleteAll(t, SHARECOLL, func(id string, data []byte) (bool, error) {
+			share := &hrsmodel.Share{}
+			_, err := readRecord(data, share)
+			return share.Household == household, err
+		})
+	})
+}
+
+// ListHouseholds - the households the user is a member of, all of them
+// when user is empty
+func (d *docStore) ListHouseholds(user string) ([]*hrsmodel.Household, error) {
+	households := []*hrsmodel.Household{}
+
+	err := d.eng.view(func(t tx) error {
+		return t.each(HOUSEHOLDCOLL, func(id string, data []byte) error {
+			household := &hrsmodel.Household{}
+			if _, err := readRecord(data, household); err != nil {
+				return err
+			}
+			if user == "" || household.Member(user) != nil {
+				households = append(households, household)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return households, nil
+}
+
+// InsertInvitation - inserts an invitation, its code must be free
+func (d *docStore) InsertInvitation(invitation *hrsmodel.Invitation) error {
+	return d.eng.update(func(t tx) error {
+		return insertDoc(t, INVITATIONCOLL, invitation.Code, invitation)
+	})
+}
+
+// GetInvitation - returns an invitation by id
+func (d *docStore) GetInvitation(id string) (invitation *hrsmodel.Invitation, err error) {
+	err = d.eng.view(func(t tx) error {
+		invitation = &hrsmodel.Invitation{}
+		return getDoc(t, INVITATIONCOLL, id, invitation)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// DeleteInvitation - removes an invitation by id
+func (d *docStore) DeleteInvitation(id string) error {
+	return d.eng.update(func(t tx) error {
+		return deleteDoc(t, INVITATIONCOLL, id)
+	})
+}
+
+// ListInvitations - the pending invitations to a household
+func (d *docStore) ListInvitations(household string) ([]*hrsmodel.Invitation, error) {
+	invitations := []*hrsmodel.Invitation{}
+
+	err := d.eng.view(func(t tx) error {
+		return t.each(INVITATIONCOLL, func(id string, data []byte) error {
+			invitation := &hrsmodel.Invitation{}
+			if _, err := readRecord(data, invitation); err != nil {
+				return err
+			}
+			if invitation.Household == household {
+				invitations = append(invitations, invitation)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+// AcceptInvitation - uses up an invitation adding the member to its
+// household, with the invitation role; ErrConflict when already a member
+func (d *docStore) AcceptInvitation(id string, member hrsmodel.Member) (household *hrsmodel.Household, err error) {
+	err = d.eng.update(func(t tx) error {
+		invitation := &hrsmodel.Invitation{}
+		if err := getDoc(t, INVITATIONCOLL, id, invitation); err != nil {
+			return err
+		}
+		household = &hrsmodel.Household{}
+		if err := getDoc(t, HOUSEHOLDCOLL, invitation.Household, household); err != nil {
+			return err
+		}
+		if household.Member(member.User) != nil {
+			return ErrConflict
+		}
+
+		member.Role = invitation.Role
+		household.Members = append(household.Members, member)
+		household.Version++
+		if err := putDoc(t, HOUSEHOLDCOLL, household.Code, household); err != nil {
+			return err
+		}
+		return t.del(INVITATIONCOLL, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return household, nil
+}
+
+// AddMember - adds the member to the household in one go, so that no
+// concurrent change is lost; ErrConflict when already a member
+func (d *docStore) AddMember(id string, member hrsmodel.Member) (household *hrsmodel.Household, err error) {
+	err = d.eng.update(func(t tx) error {
+		household = &hrsmodel.Household{}
+		if err := getDoc(t, HOUSEHOLDCOLL, id, household); err != nil {
+			return err
+		}
+		if household.Member(member.User) != nil {
+			return ErrConflict
+		}
+
+		household.Members = append(household.Members, member)
+		if err := household.Validate(); err != nil {
+			return err
+		}
+		household.Version++
+		return putDoc(t, HOUSEHOLDCOLL, household.Code, household)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return household, nil
+}
+
+// AdoptUnscoped - moves the documents stored before there were households
+// into one, returning how many
+func (d *docStore) AdoptUnscoped(household string) (moved int, err error) {
+	err = d.eng.update(func(t tx) error {
+		if t.get(HOUSEHOLDCOLL, household) == nil {
+			return ErrNotFound
+		}
+
+		t = unscoped(t)
+		scoped := scopedTx{tx: t, household: household}
+		for _, coll := range householdColls {
+			err := deleteAll(t, coll, func(id string, data []byte) (bool, error) {
+				moved++
+				return true, scoped.put(coll, id, data)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return moved, err
+}
+
+// Scope - the store of a household: its collections are its own, users,
+// households and invitations are shared. Closing it closes the store.
+func (d *docStore) Scope(household string) (Store, error) {
+	eng := d.eng
+	if scoped, ok := eng.(*scopedEngine); ok {
+		eng = scoped.engine
+	}
+	return &docStore{eng: &scopedEngine{engine: eng, household: household}}, nil
+}
+
+// InsertHousehold - the connector only maps recipes and ingredients
+func (m *MongoStore) InsertHousehold(household *hrsmodel.Household) error {
+	return ErrUnsupported
+}
+
+// GetHousehold - the connector only maps recipes and ingredients
+func (m *MongoStore) GetHousehold(id string) (*hrsmodel.Household, error) {
+	return nil, ErrUnsupported
+}
+
+// SaveHousehold - the connector only maps recipes and ingredients
+func (m *MongoStore) SaveHousehold(household *hrsmodel.Household) error {
+	return ErrUnsupported
+}
+
+// DeleteHousehold - the connector only maps recipes and ingredients
+func (m *MongoStore) DeleteHousehold(id string) error {
+	return ErrUnsupported
+}
+
+// ListHouseholds - the connector only maps recipes and ingredients
+func (m *MongoStore) ListHouseholds(user string) ([]*hrsmodel.Household, error) {
+	return nil, ErrUnsupported
+}
+
+// InsertInvitation - the connector only maps recipes and ingredients
+func (m *MongoStore) InsertInvitation(invitation *hrsmodel.Invitation) error {
+	return ErrUnsupported
+}
+
+// GetInvitation - the connector only maps recipes and ingredients
+func (m *MongoStore) GetInvitation(id string) (*hrsmodel.Invitation, error) {
+	return nil, ErrUnsupported
+}
+
+// DeleteInvitation - the connector only maps recipes and ingredients
+func (m *MongoStore) DeleteInvitation(id string) error {
+	return ErrUnsupported
+}
+
+// ListInvitations - the connector only maps recipes and ingredients
+func (m *MongoStore) ListInvitations(household string) ([]*hrsmodel.Invitation, error) {
+	return nil, ErrUnsupported
+}
+
+// AcceptInvitation - the connector only maps recipes and ingredients
+func (m *MongoStore) AcceptInvitation(id string, member hrsmodel.Member) (*hrsmodel.Household, error) {
+	return nil, ErrUnsupported
+}
+
+// AddMember - the connector only maps recipes and ingredients
+func (m *MongoStore) AddMember(id string, member hrsmodel.Member) (*hrsmodel.Household, error) {
+	return nil, ErrUnsupported
+}
+
+// AdoptUnscoped - the connector only maps recipes and ingredients
+func (m *MongoStore) AdoptUnscoped(household string) (int, error) {
+	return 0, ErrUnsupported
+}
+
+// Scope - the connector has no households, it can't isolate them
+func (m *MongoStore) Scope(household string) (Store, error) {
+	return nil, ErrUnsupported
+}
+
+/** PRIVATE METHODS **/
+
+// scopedEngine - engine whose transactions see the collections of a
+// household
+type scopedEngine struct {
+	engine
+	household string
+}
+
+func (s *scopedEngine) view(fn func(tx) error) error {
+	return s.engine.view(func(t tx) error {
+		return fn(scopedTx{tx: t, household: s.household})
+	})
+}
+
+// update - runs fn while the household is there, its collections would
+// outlive it otherwise
+func (s *scopedEngine) update(fn func(tx) error) error {
+	return s.engine.update(func(t tx) error {
+		if t.get(HOUSEHOLDCOLL, s.household) == nil {
+			return fmt.Errorf("%w: household %s", ErrNotFound, s.household)
+		}
+		return fn(scopedTx{tx: t, household: s.household})
+	})
+}
+
+// scopedTx - tx renaming the household collections after the household
+type scopedTx struct {
+	tx
+	household string
+}
+
+func (s scopedTx) get(coll string, id string) []byte {
+	return s.tx.get(s.coll(coll), id)
+}
+
+func (s scopedTx) put(coll string, id string, data []byte) error {
+	return s.tx.put(s.coll(coll), id, data)
+}
+
+func (s scopedTx) del(coll string, id string) error {
+	return s.tx.del(s.coll(coll), id)
+}
+
+func (s scopedTx) each(coll string, fn func(id string, data []byte) error) error {
+	return s.tx.each(s.coll(coll), fn)
+}
+
+func (s scopedTx) eachAfter(coll string, after string, limit int, fn func(id string, data []byte) error) error {
+	return s.tx.eachAfter(s.coll(coll), after, limit, fn)
+}
+
+// coll - the name the collection has in the household
+func (s scopedTx) coll(coll string) string {
+	for _, c := range householdColls {
+		if c == coll {
+			return coll + "@" + s.household
+		}
+	}
+	return coll
+}
+
+// unscoped - the tx under the household one, if it is
+func unscoped(t tx) tx {
+	if scoped, ok := t.(scopedTx); ok {
+		return scoped.tx
+	}
+	return t
+}
+
+// deleteAll - removes the documents of a collection matching, once they
+// have all been visited
+func deleteAll(t tx, coll string, match func(id string, data []byte) (bool, error)) error {
+	ids := []string{}
+	err := t.each(coll, func(id string, data []byte) error {
+		ok, err := match(id, data)
+		if ok {
+			ids = append(ids, id)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := t.del(coll, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package hrsstore
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+)
+
+func TestDocStore_Scope(t *testing.T) {
+	store, err := NewMemoryStore("")
+	if err != nil {
+		t.Fatalf("NewMemoryStore() error = %v", err)
+	}
+	store.InsertRecipe(newRecipe("r-old", "Gazpacho", "i-tomato"))
+	for _, code := range []string{"h1", "h2"} {
+		household := &hrsmodel.Household{Code: code, Name: code, Members: []hrsmodel.Member{{User: "u-" + code, Role: hrsmodel.OWNER}}}
+		if err = store.InsertHousehold(household); err != nil {
+			t.Fatalf("InsertHousehold() error = %v", err)
+		}
+	}
+
+	h1, _ := store.Scope("h1")
+	h2, _ := store.Scope("h2")
+	if err = h1.InsertRecipe(newRecipe("r1", "Tortilla", "i-egg")); err != nil {
+		t.Fatalf("InsertRecipe() error = %v", err)
+	}
+
+	// Neither the other households nor the shared store see the recipe
+	for name, other := range map[string]Store{"h2": h2, "unscoped": store} {
+		if _, err = other.GetRecipe("r1"); err != ErrNotFound {
+			t.Errorf("GetRecipe() from %s error = %v", name, err)
+		}
+		if _, err = other.UpdateRecipe("r1", newRecipe("", "Other", "i-egg")); err != ErrNotFound {
+			t.Errorf("UpdateRecipe() from %s error = %v", name, err)
+		}
+	}
+	if list, _ := h2.ListRecipes(Query{}); list.Total != 0 {
+		t.Errorf("ListRecipes() from h2 = %+v", list.Items)
+	}
+	if household, err := h2.GetHousehold("h1"); err != nil || household.Name != "h1" {
+		t.Errorf("GetHousehold() from h2 = %v, %v", household, err)
+	}
+
+	if moved, err := h2.AdoptUnscoped("h2"); err != nil || moved != 1 {
+		t.Errorf("AdoptUnscoped() = %d, %v", moved, err)
+	}
+	if _, err = h2.GetRecipe("r-old"); err != nil {
+		t.Errorf("GetRecipe() of an adopted recipe error = %v", err)
+	}
+	if _, err = store.GetRecipe("r-old"); err != ErrNotFound {
+		t.Errorf("GetRecipe() of an adopted recipe from the unscoped store error = %v", err)
+	}
+
+	store.InsertInvitation(&hrsmodel.Invitation{Code: "inv", Household: "h1", Role: hrsmodel.VIEWER})
+	store.InsertShare(&hrsmodel.Share{Code: "link", Household: "h1", Recipe: "r1"})
+	if err = store.DeleteHousehold("h1"); err != nil {
+		t.Fatalf("DeleteHousehold() error = %v", err)
+	}
+	if _, err = h1.GetRecipe("r1"); err != ErrNotFound {
+		t.Errorf("GetRecipe() of a deleted household error = %v", err)
+	}
+	if err = h1.InsertRecipe(newRecipe("r2", "Pisto")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("InsertRecipe() into a deleted household error = %v", err)
+	}
+	if _, err = h1.GetRecipe("r2"); err != ErrNotFound {
+		t.Errorf("GetRecipe() of a recipe written after the household was deleted error = %v", err)
+	}
+	if _, err = store.GetInvitation("inv"); err != ErrNotFound {
+		t.Errorf("GetInvitation() of a deleted household error = %v", err)
+	}
+	if _, err = store.GetShare("link"); err != ErrNotFound {
+		t.Errorf("GetShare() of a deleted household error = %v", err)
+	}
+}
+
+func TestDocStore_AcceptInvitation(t *testing.T) {
+	store, err := NewMemoryStore("")
+	if err != nil {
+		t.Fatalf("NewMemoryStore() error = %v", err)
+	}
+	store.InsertHousehold(&hrsmodel.Household{Code: "h1", Name: "Casa", Members: []hrsmodel.Member{{User: "u1", Role: hrsmodel.OWNER}}})
+	store.InsertInvitation(&hrsmodel.Invitation{Code: "inv", Household: "h1", Role: hrsmodel.EDITOR})
+
+	household, err := store.AcceptInvitation("inv", hrsmodel.Member{User: "u2", Username: "bea"})
+	if err != nil || household.Role("u2") != hrsmodel.EDITOR {
+		t.Fatalf("AcceptInvitation() = %+v, %v", household, err)
+	}
+	if _, err = store.AcceptInvitation("inv", hrsmodel.Member{User: "u3"}); err != ErrNotFound {
+		t.Errorf("AcceptInvitation() twice error = %v", err)
+	}
+	if households, _ := store.ListHouseholds("u2"); len(households) != 1 {
+		t.Errorf("ListHouseholds() = %+v", households)
+	}
+}
+
+func TestDocStore_AddMember(t *testing.T) {
+	store, err := NewMemoryStore("")
+	if err != nil {
+		t.Fatalf("NewMemoryStore() error = %v", err)
+	}
+	store.InsertHousehold(&hrsmodel.Household{Code: "h1", Name: "Casa", Members: []hrsmodel.Member{{User: "u1", Role: hrsmodel.OWNER}}})
+
+	// Members joining at once are all kept
+	var wg sync.WaitGroup
+	for i := 2; i <= 20; i++ {
+		wg.Add(1)
+		go func(user string) {
+			defer wg.Done()
+			if _, err := store.AddMember("h1", hrsmodel.Member{User: user, Role: hrsmodel.VIEWER}); err != nil {
+				t.Errorf("AddMember(%s) error = %v", user, err)
+			}
+		}(fmt.Sprintf("u%d", i))
+	}
+	wg.Wait()
+	household, _ := store.GetHousehold("h1")
+	if len(household.Members) != 20 {
+		t.Errorf("AddMember() kept %d members, want 20", len(household.Members))
+	}
+
+	if _, err = store.AddMember("h1", hrsmodel.Member{User: "u2", Role: hrsmodel.EDITOR}); err != ErrConflict {
+		t.Errorf("AddMember() of a member error = %v", err)
+	}
+	if _, err = store.AddMember("h1", hrsmodel.Member{User: "u99", Role: "chef"}); !errors.Is(err, hrsmodel.ErrInvalid) {
+		t.Errorf("AddMember() with an unknown role error = %v", err)
+	}
+
+	// A household saved from before another change is turned down
+	stale, _ := store.GetHousehold("h1")
+	household.Members[1].Role = hrsmodel.EDITOR
+	if err = store.SaveHousehold(household); err != nil {
+		t.Fatalf("SaveHousehold() error = %v", err)
+	}
+	stale.RemoveMember("u3")
+	if err = store.SaveHousehold(stale); err != ErrConflict {
+		t.Errorf("SaveHousehold() of a stale household error = %v", err)
+	}
+	if household, _ = store.GetHousehold("h1"); household.Members[1].Role != hrsmodel.EDITOR || len(household.Members) != 20 {
+		t.Errorf("GetHousehold() after a stale save = %+v", household)
+	}
+}
@@ -0,0 +1,113 @@
+package hrsstore
+
+import (
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+)
+
+// MealPlanStore - meal plan persistence operations
+type MealPlanStore interface {
+	InsertMealPlanEntries(entries ...*hrsmodel.MealPlanEntry) error
+	GetMealPlanEntry(id string) (*hrsmodel.MealPlanEntry, error)
+	UpdateMealPlanEntry(id string, entry *hrsmodel.MealPlanEntry) (*hrsmodel.MealPlanEntry, error)
+	DeleteMealPlanEntry(id string) error
+	ListMealPlan(from string, to string) ([]*hrsmodel.MealPlanEntry, error)
+}
+
+// InsertMealPlanEntries - inserts the entries at once, their codes must be
+// free
+func (d *docStore) InsertMealPlanEntries(entries ...*hrsmodel.MealPlanEntry) error {
+	return d.eng.update(func(t tx) error {
+		for _, entry := range entries {
+			if err := insertDoc(t, MEALPLANCOLL, entry.Code, entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetMealPlanEntry - returns a meal plan entry by id
+func (d *docStore) GetMealPlanEntry(id string) (entry *hrsmodel.MealPlanEntry, err error) {
+	err = d.eng.view(func(t tx) error {
+		entry = &hrsmodel.MealPlanEntry{}
+		return getDoc(t, MEALPLANCOLL, id, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// UpdateMealPlanEntry - patches the non empty fields of an entry
+func (d *docStore) UpdateMealPlanEntry(id string, entry *hrsmodel.MealPlanEntry) (stored *hrsmodel.MealPlanEntry, err error) {
+	err = d.eng.update(func(t tx) error {
+		stored = &hrsmodel.MealPlanEntry{}
+		if err := getDoc(t, MEALPLANCOLL, id, stored); err != nil {
+			return err
+		}
+		patch(stored, entry)
+		stored.Code = id
+		return putDoc(t, MEALPLANCOLL, id, stored)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// DeleteMealPlanEntry - removes a meal plan entry by id
+func (d *docStore) DeleteMealPlanEntry(id string) error {
+	return d.eng.update(func(t tx) error {
+		return deleteDoc(t, MEALPLANCOLL, id)
+	})
+}
+
+// ListMealPlan - the entries from one date to another, both included,
+// ordered by date and meal
+func (d *docStore) ListMealPlan(from string, to string) ([]*hrsmodel.MealPlanEntry, error) {
+	entries := []*hrsmodel.MealPlanEntry{}
+
+	err := d.eng.view(func(t tx) error {
+		return t.each(MEALPLANCOLL, func(id string, data []byte) error {
+			entry := &hrsmodel.MealPlanEntry{}
+			if _, err := readRecord(data, entry); err != nil {
+				return err
+			}
+			if entry.Date >= from && entry.Date <= to {
+				entries = append(entries, entry)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	hrsmodel.SortMealPlan(entries)
+	return entries, nil
+}
+
+// InsertMealPlanEntries - the connector only maps recipes and ingredients
+func (m *MongoStore) InsertMealPlanEntries(entries ...*hrsmodel.MealPlanEntry) error {
+	return ErrUnsupported
+}
+
+// GetMealPlanEntry - the connector only maps recipes and ingredients
+func (m *MongoStore) GetMealPlanEntry(id string) (*hrsmodel.MealPlanEntry, error) {
+	return nil, ErrUnsupported
+}
+
+// UpdateMealPlanEntry - the connector only maps recipes and ingredients
+func (m *MongoStore) UpdateMealPlanEntry(id string, entry *hrsmodel.MealPlanEntry) (*hrsmodel.MealPlanEntry, error) {
+	return nil, ErrUnsupported
+}
+
+// DeleteMealPlanEntry - the connector only maps recipes and ingredients
+func (m *MongoStore) DeleteMealPlanEntry(id string) error {
+	return ErrUnsupported
+}
+
+// ListMealPlan - the connector only maps recipes and ingredients
+func (m *MongoStore) ListMealPlan(from string, to string) ([]*hrsmodel.MealPlanEntry, error) {
+	return nil, ErrUnsupported
+}
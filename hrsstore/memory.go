@@ -0,0 +1,196 @@
+package hrsstore
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// MemoryStore - in-process store, optionally snapshotted to a JSON file
+type MemoryStore struct {
+	docStore
+}
+
+// NewMemoryStore - creates a memory store. If snapshot is not empty, its
+// content is loaded (when the file exists) and rewritten on Close.
+func NewMemoryStore(snapshot string) (*MemoryStore, error) {
+	eng := &memEngine{
+		snapshot: snapshot,
+		colls:    map[string]map[string][]byte{},
+	}
+
+	if snapshot != "" {
+		if err := eng.load(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &MemoryStore{docStore{eng: eng}}, nil
+}
+
+// memEngine - collections of encoded documents guarded by a RWMutex.
+// Documents are kept encoded so callers never share memory with the store.
+type memEngine struct {
+	mu       sync.RWMutex
+	snapshot string
+	colls    map[string]map[string][]byte
+}
+
+func (m *memEngine) view(fn func(tx) error) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return fn(&memTx{eng: m})
+}
+
+// update - runs fn over a write overlay which is only applied when fn succeeds
+func (m *memEngine) update(fn func(tx) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := &memTx{eng: m, writes: map[string]map[string][]byte{}}
+	if err := fn(t); err != nil {
+		return err
+	}
+
+	for coll, docs := range t.writes {
+		for id, data := range docs {
+			if data == nil {
+				delete(m.colls[coll], id)
+				continue
+			}
+			if m.colls[coll] == nil {
+				m.colls[coll] = map[string][]byte{}
+			}
+			m.colls[coll][id] = data
+		}
+	}
+	return nil
+}
+
+// close - dumps the collections to the snapshot file
+func (m *memEngine) close() error {
+	if m.snapshot == "" {
+		return nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	dump := map[string]map[string]json.RawMessage{}
+	for coll, docs := range m.colls {
+		dump[coll] = map[string]json.RawMessage{}
+		for id, data := range docs {
+			dump[coll][id] = data
+		}
+	}
+
+	data, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write and rename, a crash never leaves a half written snapshot
+	tmp, err := ioutil.TempFile(filepath.Dir(m.snapshot), filepath.Base(m.snapshot)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), m.snapshot)
+}
+
+// load - reads the snapshot file, a missing file means an empty store
+func (m *memEngine) load() error {
+	data, err := ioutil.ReadFile(m.snapshot)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	dump := map[string]map[string]json.RawMessage{}
+	if err = json.Unmarshal(data, &dump); err != nil {
+		return err
+	}
+
+	for coll, docs := range dump {
+		m.colls[coll] = map[string][]byte{}
+		for id, doc := range docs {
+			m.colls[coll][id] = doc
+		}
+	}
+	return nil
+}
+
+// memTx - a view of the engine plus the pending writes of an update
+type memTx struct {
+	eng    *memEngine
+	writes map[string]map[string][]byte
+}
+
+func (t *memTx) get(coll string, id string) []byte {
+	if data, ok := t.writes[coll][id]; ok {
+		return data
+	}
+	return t.eng.colls[coll][id]
+}
+
+func (t *memTx) put(coll string, id string, data []byte) error {
+	if t.writes[coll] == nil {
+		t.writes[coll] = map[string][]byte{}
+	}
+	t.writes[coll][id] = data
+	return nil
+}
+
+// del - records a deletion as a nil document
+func (t *memTx) del(coll string, id string) error {
+	return t.put(coll, id, nil)
+}
+
+// each - visits the documents of a collection sorted by id
+func (t *memTx) each(coll string, fn func(id string, data []byte) error) error {
+	return t.eachAfter(coll, "", 0, fn)
+}
+
+// eachAfter - visits up to limit documents, all with 0, whose id sorts
+// after the given one, sorted by id
+func (t *memTx) eachAfter(coll string, after string, limit int, fn func(id string, data []byte) error) error {
+	ids := []string{}
+	for id := range t.eng.colls[coll] {
+		if _, ok := t.writes[coll][id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	for id, data := range t.writes[coll] {
+		if data != nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	start := sort.Search(len(ids), func(i int) bool {
+		return ids[i] > after
+	})
+	for n, id := range ids[start:] {
+		if limit != 0 && n == limit {
+			break
+		}
+		if err := fn(id, t.get(coll, id)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package hrsstore
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/hrstypes"
+)
+
+func TestMemoryStore_Recipes(t *testing.T) {
+	store, err := NewMemoryStore("")
+	if err != nil {
+		t.Fatalf("NewMemoryStore() error = %v", err)
+	}
+
+	recipe := newRecipe("r1", "Tortilla", "i-egg")
+	recipe.Steps = []string{"Beat the eggs"}
+	if err = store.InsertRecipe(recipe); err != nil {
+		t.Fatalf("InsertRecipe() error = %v", err)
+	}
+	if err = store.InsertRecipe(recipe); err != ErrConflict {
+		t.Errorf("InsertRecipe() duplicated error = %v, want %v", err, ErrConflict)
+	}
+
+	patched, err := store.UpdateRecipe("r1", &hrsmodel.Recipe{Recipe: hrstypes.Recipe{Description: "Spanish omelette"}})
+	if err != nil {
+		t.Fatalf("UpdateRecipe() error = %v", err)
+	}
+	if patched.Name != "Tortilla" || patched.Description != "Spanish omelette" || len(patched.Steps) != 1 || len(patched.Lines) != 1 {
+		t.Errorf("UpdateRecipe() = %+v, want untouched name and steps", patched)
+	}
+
+	if err = store.DeleteRecipe("r1"); err != nil {
+		t.Fatalf("DeleteRecipe() error = %v", err)
+	}
+	if _, err = store.GetRecipe("r1"); err != ErrNotFound {
+		t.Errorf("GetRecipe() after delete error = %v, want %v", err, ErrNotFound)
+	}
+	if err = store.DeleteRecipe("r1"); err != ErrNotFound {
+		t.Errorf("DeleteRecipe() twice error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestMemoryStore_Snapshot(t *testing.T) {
+	snapshot := filepath.Join(t.TempDir(), "hrs.json")
+
+	store, err := NewMemoryStore(snapshot)
+	if err != nil {
+		t.Fatalf("NewMemoryStore() error = %v", err)
+	}
+	if err = store.InsertIngredient(&hrsmodel.Ingredient{Ingredient: hrstypes.Ingredient{Code: "i1", Name: "Pimentón"}}); err != nil {
+		t.Fatalf("InsertIngredient() error = %v", err)
+	}
+	if err = store.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	reloaded, err := NewMemoryStore(snapshot)
+	if err != nil {
+		t.Fatalf("NewMemoryStore() reload error = %v", err)
+	}
+	ingredient, err := reloaded.GetIngredient("i1")
+	if err != nil {
+		t.Fatalf("GetIngredient() error = %v", err)
+	}
+	if ingredient.Name != "Pimentón" {
+		t.Errorf("GetIngredient() name = %q, want %q", ingredient.Name, "Pimentón")
+	}
+}
@@ -0,0 +1,190 @@
+package hrsstore
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/hrstypes"
+	mongo "github.com/ninh0gauch0/mongoconnector"
+)
+
+// metadataObject - what the connector expects to persist
+type metadataObject interface {
+	GetObjectInfo() string
+}
+
+// MongoStore - Store adapter over the mongoconnector library. The
+// connector reads and writes documents by id; lists are queried on a
+// session of their own to the database config/mongo.json names.
+type MongoStore struct {
+	Ctx context.Context
+}
+
+// InsertRecipe - inserts a recipe
+func (m *MongoStore) InsertRecipe(recipe *hrsmodel.Recipe) error {
+	return m.insert(RECIPECOLL, recipe)
+}
+
+// GetRecipe - returns a recipe by id
+func (m *MongoStore) GetRecipe(id string) (*hrsmodel.Recipe, error) {
+	res, err := m.searchByID(RECIPECOLL, id)
+	if err != nil {
+		return nil, err
+	}
+	return asRecipe(res)
+}
+
+// UpdateRecipe - patches a recipe by id
+func (m *MongoStore) UpdateRecipe(id string, recipe *hrsmodel.Recipe) (*hrsmodel.Recipe, error) {
+	res, err := m.update(RECIPECOLL, id, recipe)
+	if err != nil {
+		return nil, err
+	}
+	return asRecipe(res)
+}
+
+// DeleteRecipe - removes a recipe by id
+func (m *MongoStore) DeleteRecipe(id string) error {
+	return m.delete(RECIPECOLL, id)
+}
+
+// InsertIngredient - inserts an ingredient
+func (m *MongoStore) InsertIngredient(ingredient *hrsmodel.Ingredient) error {
+	return m.insert(INGREDIENTCOLL, ingredient)
+}
+
+// GetIngredient - returns an ingredient by id
+func (m *MongoStore) GetIngredient(id string) (*hrsmodel.Ingredient, error) {
+	res, err := m.searchByID(INGREDIENTCOLL, id)
+	if err != nil {
+		return nil, err
+	}
+	return asIngredient(res)
+}
+
+// UpdateIngredient - patches an ingredient by id
+func (m *MongoStore) UpdateIngredient(id string, ingredient *hrsmodel.Ingredient) (*hrsmodel.Ingredient, error) {
+	res, err := m.update(INGREDIENTCOLL, id, ingredient)
+	if err != nil {
+		return nil, err
+	}
+	return asIngredient(res)
+}
+
+// DeleteIngredient - removes an ingredient by id
+func (m *MongoStore) DeleteIngredient(id string) error {
+	return m.delete(INGREDIENTCOLL, id)
+}
+
+// Close - nothing to release, every operation opens its own session
+func (m *MongoStore) Close() error {
+	return nil
+}
+
+/** PRIVATE METHODS **/
+
+func (m *MongoStore) manager() (*mongo.Manager, error) {
+	manager := &mongo.Manager{
+		Ctx: m.Ctx,
+	}
+
+	if !manager.Init() {
+		return nil, ErrUnavailable
+	}
+	return manager, nil
+}
+
+func (m *MongoStore) insert(coll string, obj metadataObject) error {
+	manager, err := m.manager()
+	if err != nil {
+		return err
+	}
+
+	res, err := manager.ExecuteInsert(coll, obj)
+	if err != nil {
+		return err
+	}
+	if res != 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+func (m *MongoStore) searchByID(coll string, id string) (interface{}, error) {
+	manager, err := m.manager()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := manager.ExecuteSearchByID(coll, id)
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, ErrNotFound
+	}
+	return res, nil
+}
+
+func (m *MongoStore) update(coll string, id string, obj metadataObject) (interface{}, error) {
+	manager, err := m.manager()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := manager.ExecuteUpdate(coll, id, obj)
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, ErrNotFound
+	}
+	return res, nil
+}
+
+func (m *MongoStore) delete(coll string, id string) error {
+	manager, err := m.manager()
+	if err != nil {
+		return err
+	}
+
+	res, err := manager.ExecuteDelete(coll, id)
+	if err != nil {
+		return err
+	}
+	if res != 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// asRecipe - type assertion over the connector result. The connector may
+// decode recipes as hrstypes.Recipe, whose bare references become lines.
+func asRecipe(res interface{}) (*hrsmodel.Recipe, error) {
+	switch r := res.(type) {
+	case *hrsmodel.Recipe:
+		return r, nil
+	case *hrstypes.Recipe:
+		return hrsmodel.FromLegacy(r), nil
+	case hrstypes.Recipe:
+		return hrsmodel.FromLegacy(&r), nil
+	default:
+		return nil, fmt.Errorf("unexpected recipe type %T", res)
+	}
+}
+
+// asIngredient - type assertion over the connector result. The connector
+// may decode ingredients as hrstypes.Ingredient, without conversion data.
+func asIngredient(res interface{}) (*hrsmodel.Ingredient, error) {
+	switch i := res.(type) {
+	case *hrsmodel.Ingredient:
+		return i, nil
+	case *hrstypes.Ingredient:
+		return hrsmodel.FromLegacyIngredient(i), nil
+	case hrstypes.Ingredient:
+		return hrsmodel.FromLegacyIngredient(&i), nil
+	default:
+		return nil, fmt.Errorf("unexpected ingredient type %T", res)
+	}
+}
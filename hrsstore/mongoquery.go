@@ -0,0 +1,240 @@
+package hrsstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	// MONGOCONFIG Constant
+	MONGOCONFIG = "config/mongo.json"
+	// MONGOTIMEOUT Constant
+	MONGOTIMEOUT = 10 * time.Second
+)
+
+// mongoConfig - where the database is, the way config/mongo.json says
+type mongoConfig struct {
+	Host string `json:"host"`
+	Port string `json:"port"`
+	DB   string `json:"db"`
+}
+
+// ListRecipes - returns a page of the recipes matching q. Mongo documents
+// carry no creation date, they can only be sorted by name.
+func (m *MongoStore) ListRecipes(q Query) (*RecipeList, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	list := &RecipeList{Items: []*hrsmodel.Recipe{}}
+	err := m.list(RECIPECOLL, q, &list.Total, &list.Cursor, func(raw bson.Raw) error {
+		recipe, err := mongoRecipe(raw)
+		if err == nil {
+			list.Items = append(list.Items, recipe)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// ListIngredients - returns a page of the ingredients matching q, sorted
+// by name as ListRecipes does
+func (m *MongoStore) ListIngredients(q Query) (*IngredientList, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	q.Ingredients = nil
+
+	list := &IngredientList{Items: []*hrsmodel.Ingredient{}}
+	err := m.list(INGREDIENTCOLL, q, &list.Total, &list.Cursor, func(raw bson.Raw) error {
+		ingredient := &hrsmodel.Ingredient{}
+		if err := raw.Unmarshal(ingredient); err != nil {
+			return err
+		}
+		list.Items = append(list.Items, ingredient)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+/** PRIVATE METHODS **/
+
+// session - a session to the database the connector uses
+func (m *MongoStore) session() (*mgo.Session, error) {
+	data, err := ioutil.ReadFile(MONGOCONFIG)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnavailable, err.Error())
+	}
+	config := mongoConfig{}
+	if err = json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("%w: %s: %s", ErrUnavailable, MONGOCONFIG, err.Error())
+	}
+
+	session, err := mgo.DialWithInfo(&mgo.DialInfo{
+		Addrs:    []string{net.JoinHostPort(config.Host, config.Port)},
+		Database: config.DB,
+		Timeout:  MONGOTIMEOUT,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnavailable, err.Error())
+	}
+	return session, nil
+}
+
+// list - counts the documents of the collection matching q and calls fn
+// with the ones of the page after q.Cursor, setting the cursor of the next
+// one. Documents are sorted by their lower case name, added as "k", and
+// their id, the same cursor the embedded stores page with.
+func (m *MongoStore) list(coll string, q Query, total *int, next *string, fn func(raw bson.Raw) error) error {
+	if strings.TrimPrefix(q.Sort, "-") != SORTNAME {
+		return fmt.Errorf("%w: the mongo store can only sort by %s", ErrInvalidQuery, SORTNAME)
+	}
+	session, err := m.session()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+	c := session.DB("").C(coll)
+
+	filter := mongoFilter(q)
+	if *total, err = c.Find(filter).Count(); err != nil {
+		return err
+	}
+
+	page := []bson.Raw{}
+	if err = c.Pipe(mongoPage(filter, q)).All(&page); err != nil {
+		return err
+	}
+	if len(page) > q.Limit {
+		page = page[:q.Limit]
+		last := cursor{}
+		if err = page[len(page)-1].Unmarshal(&mongoPosition{Key: &last.Key, ID: &last.ID}); err != nil {
+			return err
+		}
+		*next = encodeCursor(last)
+	}
+
+	for _, raw := range page {
+		if err = fn(raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walk - calls fn with every document of the collection in id order, a
+// page at a time, each one found from the last id of the previous
+func (m *MongoStore) walk(coll string, fn func(raw bson.Raw) error) error {
+	session, err := m.session()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+	c := session.DB("").C(coll)
+
+	filter := bson.M{}
+	for {
+		page := []bson.Raw{}
+		if err = c.Find(filter).Sort("_id").Limit(MAXLIMIT).All(&page); err != nil {
+			return err
+		}
+		for _, raw := range page {
+			if err = fn(raw); err != nil {
+				return err
+			}
+		}
+		if len(page) < MAXLIMIT {
+			return nil
+		}
+		last := struct {
+			ID interface{} `bson:"_id"`
+		}{}
+		if err = page[len(page)-1].Unmarshal(&last); err != nil {
+			return err
+		}
+		filter = bson.M{"_id": bson.M{"$gt": last.ID}}
+	}
+}
+
+// mongoPosition - where a listed document sits
+type mongoPosition struct {
+	Key *string `bson:"k"`
+	ID  *string `bson:"_id"`
+}
+
+// mongoFilter - the documents q lists: names starting with the prefix,
+// whatever the case, and recipes with every ingredient, referenced by
+// their lines or their legacy ingredients
+func mongoFilter(q Query) bson.M {
+	filter := bson.M{}
+	if q.NamePrefix != "" {
+		filter["name"] = bson.RegEx{Pattern: "^" + regexp.QuoteMeta(q.NamePrefix), Options: "i"}
+	}
+	if len(q.Ingredients) > 0 {
+		all := []bson.M{}
+		for _, id := range q.Ingredients {
+			all = append(all, bson.M{"$or": []bson.M{
+				{"ingredients": id},
+				{"lines.ingredient": id},
+				{"lines.name": id},
+				{"lines.text": id},
+			}})
+		}
+		filter["$and"] = all
+	}
+	return filter
+}
+
+// mongoPage - the pipeline of the page after q.Cursor, one document more
+// than the page to tell whether there is another
+func mongoPage(filter bson.M, q Query) []bson.M {
+	order, after := 1, "$gt"
+	if strings.HasPrefix(q.Sort, "-") {
+		order, after = -1, "$lt"
+	}
+
+	pipeline := []bson.M{
+		{"$match": filter},
+		{"$addFields": bson.M{"k": bson.M{"$toLower": "$name"}}},
+	}
+	if q.Cursor != "" {
+		c, _ := decodeCursor(q.Cursor)
+		pipeline = append(pipeline, bson.M{"$match": bson.M{"$or": []bson.M{
+			{"k": bson.M{after: c.Key}},
+			{"k": c.Key, "_id": bson.M{after: c.ID}},
+		}}})
+	}
+	return append(pipeline,
+		bson.M{"$sort": bson.D{{Name: "k", Value: order}, {Name: "_id", Value: order}}},
+		bson.M{"$limit": q.Limit + 1},
+	)
+}
+
+// mongoRecipe - decodes a stored recipe; the ones stored before there
+// were lines get theirs from the bare references
+func mongoRecipe(raw bson.Raw) (*hrsmodel.Recipe, error) {
+	recipe := &hrsmodel.Recipe{}
+	if err := raw.Unmarshal(recipe); err != nil {
+		return nil, err
+	}
+	if len(recipe.Lines) == 0 && len(recipe.Recipe.Ingredients) > 0 {
+		legacy := recipe.Recipe
+		recipe.Lines = hrsmodel.FromLegacy(&legacy).Lines
+	}
+	return recipe, nil
+}
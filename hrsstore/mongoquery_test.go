@@ -0,0 +1,74 @@
+package hrsstore
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestMongoFilter(t *testing.T) {
+	filter := mongoFilter(Query{NamePrefix: "pa.", Ingredients: []string{"rice"}})
+
+	name, ok := filter["name"].(bson.RegEx)
+	if !ok || name.Pattern != `^pa\.` || name.Options != "i" {
+		t.Errorf("mongoFilter() name = %#v, want a case insensitive prefix", filter["name"])
+	}
+	all, ok := filter["$and"].([]bson.M)
+	if !ok || len(all) != 1 {
+		t.Fatalf("mongoFilter() $and = %#v, want one clause", filter["$and"])
+	}
+	if refs := all[0]["$or"].([]bson.M); len(refs) != 4 || refs[0]["ingredients"] != "rice" {
+		t.Errorf("mongoFilter() refs = %#v, want the legacy and the line references", refs)
+	}
+
+	if filter = mongoFilter(Query{}); len(filter) != 0 {
+		t.Errorf("mongoFilter() = %#v, want every document", filter)
+	}
+}
+
+func TestMongoPage(t *testing.T) {
+	q := Query{Limit: 2, Sort: "-" + SORTNAME, Cursor: encodeCursor(cursor{Key: "paella", ID: "r1"})}
+	pipeline := mongoPage(bson.M{}, q)
+	if len(pipeline) != 5 {
+		t.Fatalf("mongoPage() = %d stages, want 5", len(pipeline))
+	}
+
+	after := bson.M{"$or": []bson.M{
+		{"k": bson.M{"$lt": "paella"}},
+		{"k": "paella", "_id": bson.M{"$lt": "r1"}},
+	}}
+	if !reflect.DeepEqual(pipeline[2]["$match"], after) {
+		t.Errorf("mongoPage() cursor = %#v, want %#v", pipeline[2]["$match"], after)
+	}
+	order := bson.D{{Name: "k", Value: -1}, {Name: "_id", Value: -1}}
+	if !reflect.DeepEqual(pipeline[3]["$sort"], order) {
+		t.Errorf("mongoPage() sort = %#v, want %#v", pipeline[3]["$sort"], order)
+	}
+	if pipeline[4]["$limit"] != 3 {
+		t.Errorf("mongoPage() limit = %v, want one past the page", pipeline[4]["$limit"])
+	}
+}
+
+func TestMongoStore_ListRecipes_created(t *testing.T) {
+	m := &MongoStore{}
+	if _, err := m.ListRecipes(Query{Sort: SORTCREATED}); err == nil {
+		t.Error("ListRecipes() by creation date should fail on mongo")
+	}
+}
+
+func TestMongoRecipe(t *testing.T) {
+	data, _ := bson.Marshal(bson.M{"_id": "r1", "name": "Paella", "ingredients": []string{"rice"}})
+	recipe, err := mongoRecipe(bson.Raw{Kind: 0x03, Data: data})
+	if err != nil {
+		t.Fatalf("mongoRecipe() error = %v", err)
+	}
+	if recipe.Code != "r1" || len(recipe.Lines) != 1 || recipe.Lines[0].Ingredient != "rice" {
+		t.Errorf("mongoRecipe() = %+v, want the legacy ingredient as a line", recipe)
+	}
+
+	data, _ = bson.Marshal(bson.M{"_id": "r2", "name": "Pisto", "lines": []bson.M{{"text": "2 tomatoes"}}})
+	if recipe, err = mongoRecipe(bson.Raw{Kind: 0x03, Data: data}); err != nil || len(recipe.Lines) != 1 || recipe.Lines[0].Text != "2 tomatoes" {
+		t.Errorf("mongoRecipe() = %+v, %v, want the stored lines", recipe, err)
+	}
+}
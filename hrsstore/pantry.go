@@ -0,0 +1,131 @@
+package hrsstore
+
+import (
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+)
+
+// PantryStore - pantry persistence operations
+type PantryStore interface {
+	InsertPantryItem(item *hrsmodel.PantryItem) error
+	GetPantryItem(id string) (*hrsmodel.PantryItem, error)
+	UpdatePantryItem(id string, item *hrsmodel.PantryItem) (*hrsmodel.PantryItem, error)
+	DeletePantryItem(id string) error
+	ListPantry(location string, ingredient string) ([]*hrsmodel.PantryItem, error)
+	SavePantryItems(items ...*hrsmodel.PantryItem) error
+}
+
+// InsertPantryItem - inserts a pantry item, its code must be free
+func (d *docStore) InsertPantryItem(item *hrsmodel.PantryItem) error {
+	return d.eng.update(func(t tx) error {
+		return insertDoc(t, PANTRYCOLL, item.Code, item)
+	})
+}
+
+// GetPantryItem - returns a pantry item by id
+func (d *docStore) GetPantryItem(id string) (item *hrsmodel.PantryItem, err error) {
+	err = d.eng.view(func(t tx) error {
+		item = &hrsmodel.PantryItem{}
+		return getDoc(t, PANTRYCOLL, id, item)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// UpdatePantryItem - patches the non empty fields of a pantry item
+func (d *docStore) UpdatePantryItem(id string, item *hrsmodel.PantryItem) (stored *hrsmodel.PantryItem, err error) {
+	err = d.eng.update(func(t tx) error {
+		stored = &hrsmodel.PantryItem{}
+		if err := getDoc(t, PANTRYCOLL, id, stored); err != nil {
+			return err
+		}
+		patch(stored, item)
+		stored.Code = id
+		return putDoc(t, PANTRYCOLL, id, stored)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// DeletePantryItem - removes a pantry item by id
+func (d *docStore) DeletePantryItem(id string) error {
+	return d.eng.update(func(t tx) error {
+		return deleteDoc(t, PANTRYCOLL, id)
+	})
+}
+
+// ListPantry - the items kept in a location and of an ingredient, any when
+// empty, ordered by best-before date
+func (d *docStore) ListPantry(location string, ingredient string) ([]*hrsmodel.PantryItem, error) {
+	items := []*hrsmodel.PantryItem{}
+
+	err := d.eng.view(func(t tx) error {
+		return t.each(PANTRYCOLL, func(id string, data []byte) error {
+			item := &hrsmodel.PantryItem{}
+			if _, err := readRecord(data, item); err != nil {
+				return err
+			}
+			if (location == "" || item.Location == location) && (ingredient == "" || item.Ingredient == ingredient) {
+				items = append(items, item)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	hrsmodel.SortPantry(items)
+	return items, nil
+}
+
+// SavePantryItems - stores the items at once, removing the used up ones
+func (d *docStore) SavePantryItems(items ...*hrsmodel.PantryItem) error {
+	return d.eng.update(func(t tx) error {
+		for _, item := range items {
+			var err error
+			if item.Quantity > 0 {
+				err = putDoc(t, PANTRYCOLL, item.Code, item)
+			} else {
+				err = deleteDoc(t, PANTRYCOLL, item.Code)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// InsertPantryItem - the connector only maps recipes and ingredients
+func (m *MongoStore) InsertPantryItem(item *hrsmodel.PantryItem) error {
+	return ErrUnsupported
+}
+
+// GetPantryItem - the connector only maps recipes and ingredients
+func (m *MongoStore) GetPantryItem(id string) (*hrsmodel.PantryItem, error) {
+	return nil, ErrUnsupported
+}
+
+// UpdatePantryItem - the connector only maps recipes and ingredients
+func (m *MongoStore) UpdatePantryItem(id string, item *hrsmodel.PantryItem) (*hrsmodel.PantryItem, error) {
+	return nil, ErrUnsupported
+}
+
+// DeletePantryItem - the connector only maps recipes and ingredients
+func (m *MongoStore) DeletePantryItem(id string) error {
+	return ErrUnsupported
+}
+
+// ListPantry - the connector only maps recipes and ingredients
+func (m *MongoStore) ListPantry(location string, ingredient string) ([]*hrsmodel.PantryItem, error) {
+	return nil, ErrUnsupported
+}
+
+// SavePantryItems - the connector only maps recipes and ingredients
+func (m *MongoStore) SavePantryItems(items ...*hrsmodel.PantryItem) error {
+	return ErrUnsupported
+}
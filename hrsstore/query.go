@@ -0,0 +1,367 @@
+package hrsstore
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	// DEFAULTLIMIT Constant
+	DEFAULTLIMIT = 20
+	// MAXLIMIT Constant
+	MAXLIMIT = 100
+	// SORTNAME Constant
+	SORTNAME = "name"
+	// SORTCREATED Constant
+	SORTCREATED = "created"
+)
+
+// Query - list criteria. Sort is a field name, prefixed with "-" for
+// descending order. Cursor is the value returned by the previous page.
+type Query struct {
+	Limit       int
+	Cursor      string
+	Sort        string
+	NamePrefix  string
+	Ingredients []string
+}
+
+// RecipeList - a page of recipes
+type RecipeList struct {
+	Items  []*hrsmodel.Recipe `json:"items"`
+	Total  int                `json:"total"`
+	Cursor string             `json:"cursor,omitempty"`
+}
+
+// GetObjectInfo - Interface DTOObject Implementation
+func (l *RecipeList) GetObjectInfo() string {
+	return fmt.Sprintf("%d of %d recipes", len(l.Items), l.Total)
+}
+
+// IngredientList - a page of ingredients
+type IngredientList struct {
+	Items  []*hrsmodel.Ingredient `json:"items"`
+	Total  int                    `json:"total"`
+	Cursor string                 `json:"cursor,omitempty"`
+}
+
+// GetObjectInfo - Interface DTOObject Implementation
+func (l *IngredientList) GetObjectInfo() string {
+	return fmt.Sprintf("%d of %d ingredients", len(l.Items), l.Total)
+}
+
+// Validate - checks the query and fills the defaults
+func (q *Query) Validate() error {
+	if q.Limit < 0 {
+		return fmt.Errorf("%w: negative limit", ErrInvalidQuery)
+	}
+	if q.Limit == 0 {
+		q.Limit = DEFAULTLIMIT
+	}
+	if q.Limit > MAXLIMIT {
+		q.Limit = MAXLIMIT
+	}
+
+	if q.Sort == "" {
+		q.Sort = SORTNAME
+	}
+	switch strings.TrimPrefix(q.Sort, "-") {
+	case SORTNAME, SORTCREATED:
+	default:
+		return fmt.Errorf("%w: can't sort by %q", ErrInvalidQuery, q.Sort)
+	}
+
+	if q.Cursor != "" {
+		if _, err := decodeCursor(q.Cursor); err != nil {
+			return fmt.Errorf("%w: bad cursor", ErrInvalidQuery)
+		}
+	}
+	return nil
+}
+
+// ListRecipes - returns a page of the recipes matching q. Only the
+// fields filtered and sorted by are read of every recipe, the page ones
+// are read whole.
+func (d docTx) ListRecipes(q Query) (*RecipeList, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	entries := []listEntry{}
+	err := d.t.each(RECIPECOLL, func(id string, data []byte) error {
+		listed := &listedRecipe{}
+		created, err := readRecord(data, listed)
+		if err != nil {
+			return err
+		}
+		refs := (&hrsmodel.Recipe{Lines: listed.Lines}).Refs()
+		if hasPrefix(listed.Name, q.NamePrefix) && containsAll(refs, q.Ingredients) {
+			entries = append(entries, listEntry{id: id, name: listed.Name, created: created})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	page, cursor := paginate(entries, q)
+	list := &RecipeList{Items: []*hrsmodel.Recipe{}, Total: len(entries), Cursor: cursor}
+	for _, entry := range page {
+		recipe := &hrsmodel.Recipe{}
+		if err = getDoc(d.t, RECIPECOLL, entry.id, recipe); err != nil {
+			return nil, err
+		}
+		list.Items = append(list.Items, recipe)
+	}
+	return list, nil
+}
+
+// ListIngredients - returns a page of the ingredients matching q. Only the
+// names are read of every ingredient, the page ones are read whole.
+func (d docTx) ListIngredients(q Query) (*IngredientList, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	entries := []listEntry{}
+	err := d.t.each(INGREDIENTCOLL, func(id string, data []byte) error {
+		listed := &listedName{}
+		created, err := readRecord(data, listed)
+		if err != nil {
+			return err
+		}
+		if hasPrefix(listed.Name, q.NamePrefix) {
+			entries = append(entries, listEntry{id: id, name: listed.Name, created: created})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	page, cursor := paginate(entries, q)
+	list := &IngredientList{Items: []*hrsmodel.Ingredient{}, Total: len(entries), Cursor: cursor}
+	for _, entry := range page {
+		ingredient := &hrsmodel.Ingredient{}
+		if err = getDoc(d.t, INGREDIENTCOLL, entry.id, ingredient); err != nil {
+			return nil, err
+		}
+		list.Items = append(list.Items, ingredient)
+	}
+	return list, nil
+}
+
+// EachRecipe - calls fn with every stored recipe, page after page. The
+// embedded stores and mongo read them in id order, each page right after
+// the previous one; the others are listed.
+func EachRecipe(store RecipeStore, fn func(recipe *hrsmodel.Recipe) error) error {
+	if d, ok := store.(*docStore); ok {
+		return d.walk(RECIPECOLL, func(data []byte) error {
+			recipe := &hrsmodel.Recipe{}
+			if _, err := readRecord(data, recipe); err != nil {
+				return err
+			}
+			return fn(recipe)
+		})
+	}
+	if m, ok := store.(*MongoStore); ok {
+		return m.walk(RECIPECOLL, func(raw bson.Raw) error {
+			recipe, err := mongoRecipe(raw)
+			if err != nil {
+				return err
+			}
+			return fn(recipe)
+		})
+	}
+
+	q := Query{Limit: MAXLIMIT}
+	for {
+		list, err := store.ListRecipes(q)
+		if err != nil {
+			return err
+		}
+		for _, recipe := range list.Items {
+			if err = fn(recipe); err != nil {
+				return err
+			}
+		}
+		if list.Cursor == "" {
+			return nil
+		}
+		q.Cursor = list.Cursor
+	}
+}
+
+// EachIngredient - calls fn with every stored ingredient, page after page,
+// the way EachRecipe does
+func EachIngredient(store IngredientStore, fn func(ingredient *hrsmodel.Ingredient) error) error {
+	if d, ok := store.(*docStore); ok {
+		return d.walk(INGREDIENTCOLL, func(data []byte) error {
+			ingredient := &hrsmodel.Ingredient{}
+			if _, err := readRecord(data, ingredient); err != nil {
+				return err
+			}
+			return fn(ingredient)
+		})
+	}
+	if m, ok := store.(*MongoStore); ok {
+		return m.walk(INGREDIENTCOLL, func(raw bson.Raw) error {
+			ingredient := &hrsmodel.Ingredient{}
+			if err := raw.Unmarshal(ingredient); err != nil {
+				return err
+			}
+			return fn(ingredient)
+		})
+	}
+
+	q := Query{Limit: MAXLIMIT}
+	for {
+		list, err := store.ListIngredients(q)
+		if err != nil {
+			return err
+		}
+		for _, ingredient := range list.Items {
+			if err = fn(ingredient); err != nil {
+				return err
+			}
+		}
+		if list.Cursor == "" {
+			return nil
+		}
+		q.Cursor = list.Cursor
+	}
+}
+
+/** PRIVATE METHODS **/
+
+// listEntry - the id of a matching document plus its sort keys
+type listEntry struct {
+	id      string
+	name    string
+	created time.Time
+}
+
+// listedRecipe - the fields of a recipe lists filter and sort by
+type listedRecipe struct {
+	Name  string                    `json:"name"`
+	Lines []hrsmodel.IngredientLine `json:"ingredients"`
+}
+
+// listedName - the field ingredient and shopping lists filter and sort by
+type listedName struct {
+	Name string `json:"name"`
+}
+
+// walk - calls fn with every document of the collection in id order. Each
+// page is read in a transaction of its own, seeking past the last id of
+// the previous one, so fn may use the store.
+func (d *docStore) walk(coll string, fn func(data []byte) error) error {
+	after := ""
+	for {
+		page := [][]byte{}
+		err := d.eng.view(func(t tx) error {
+			return t.eachAfter(coll, after, MAXLIMIT, func(id string, data []byte) error {
+				after = id
+				page = append(page, append([]byte(nil), data...))
+				return nil
+			})
+		})
+		if err != nil {
+			return err
+		}
+		for _, data := range page {
+			if err = fn(data); err != nil {
+				return err
+			}
+		}
+		if len(page) < MAXLIMIT {
+			return nil
+		}
+	}
+}
+
+// key - the value the entry is sorted by, ids break the ties
+func (e listEntry) key(sortBy string) string {
+	if strings.TrimPrefix(sortBy, "-") == SORTCREATED {
+		return fmt.Sprintf("%020d", e.created.UnixNano())
+	}
+	return strings.ToLower(e.name)
+}
+
+// cursor - position of the last element of a page
+type cursor struct {
+	Key string `json:"k"`
+	ID  string `json:"i"`
+}
+
+func encodeCursor(c cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	c := cursor{}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	return c, json.Unmarshal(data, &c)
+}
+
+// paginate - sorts the entries and returns the page after q.Cursor along
+// with the cursor of the next one, empty on the last page
+func paginate(entries []listEntry, q Query) ([]listEntry, string) {
+	desc := strings.HasPrefix(q.Sort, "-")
+	less := func(a cursor, b cursor) bool {
+		if a.Key != b.Key {
+			return (a.Key < b.Key) != desc
+		}
+		return (a.ID < b.ID) != desc
+	}
+	position := func(e listEntry) cursor {
+		return cursor{Key: e.key(q.Sort), ID: e.id}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return less(position(entries[i]), position(entries[j]))
+	})
+
+	start := 0
+	if q.Cursor != "" {
+		after, _ := decodeCursor(q.Cursor)
+		start = sort.Search(len(entries), func(i int) bool {
+			return less(after, position(entries[i]))
+		})
+	}
+
+	end := start + q.Limit
+	if end >= len(entries) {
+		return entries[start:], ""
+	}
+	return entries[start:end], encodeCursor(position(entries[end-1]))
+}
+
+func hasPrefix(name string, prefix string) bool {
+	return strings.HasPrefix(strings.ToLower(name), strings.ToLower(prefix))
+}
+
+// containsAll - true when every wanted id is in ids
+func containsAll(ids []string, wanted []string) bool {
+	set := map[string]bool{}
+	for _, id := range ids {
+		set[id] = true
+	}
+	for _, id := range wanted {
+		if !set[id] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,120 @@
+package hrsstore
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/hrstypes"
+)
+
+// newRecipe - a recipe referencing the given ingredients
+func newRecipe(code string, name string, refs ...string) *hrsmodel.Recipe {
+	return hrsmodel.FromLegacy(&hrstypes.Recipe{Code: code, Name: name, Ingredients: refs})
+}
+
+func TestDocStore_ListRecipes(t *testing.T) {
+	store, _ := NewMemoryStore("")
+	recipes := []*hrsmodel.Recipe{
+		newRecipe("r1", "Paella", "rice", "saffron"),
+		newRecipe("r2", "Pisto", "tomato", "pepper"),
+		newRecipe("r3", "Patatas bravas", "potato", "pepper"),
+		newRecipe("r4", "Gazpacho", "tomato", "pepper"),
+	}
+	for _, recipe := range recipes {
+		if err := store.InsertRecipe(recipe); err != nil {
+			t.Fatalf("InsertRecipe() error = %v", err)
+		}
+	}
+
+	// Walk every page of the "P" recipes, two at a time
+	names := []string{}
+	q := Query{Limit: 2, NamePrefix: "p"}
+	for {
+		list, err := store.ListRecipes(q)
+		if err != nil {
+			t.Fatalf("ListRecipes() error = %v", err)
+		}
+		if list.Total != 3 {
+			t.Errorf("ListRecipes() total = %d, want 3", list.Total)
+		}
+		for _, recipe := range list.Items {
+			names = append(names, recipe.Name)
+		}
+		if list.Cursor == "" {
+			break
+		}
+		q.Cursor = list.Cursor
+	}
+	want := []string{"Paella", "Patatas bravas", "Pisto"}
+	if len(names) != len(want) {
+		t.Fatalf("ListRecipes() names = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("ListRecipes() names = %v, want %v", names, want)
+			break
+		}
+	}
+
+	list, err := store.ListRecipes(Query{Sort: "-name", Ingredients: []string{"tomato", "pepper"}})
+	if err != nil {
+		t.Fatalf("ListRecipes() error = %v", err)
+	}
+	if list.Total != 2 || list.Items[0].Name != "Pisto" || list.Items[1].Name != "Gazpacho" {
+		t.Errorf("ListRecipes() by ingredients = %+v", list.Items)
+	}
+
+	if _, err = store.ListRecipes(Query{Sort: "steps"}); err == nil {
+		t.Errorf("ListRecipes() unknown sort error = nil")
+	}
+}
+
+func TestEachRecipe(t *testing.T) {
+	memory, _ := NewMemoryStore("")
+	bolt, err := NewBoltStore(filepath.Join(t.TempDir(), "hrs.db"))
+	if err != nil {
+		t.Fatalf("NewBoltStore() error = %v", err)
+	}
+	defer bolt.Close()
+	bolt.InsertHousehold(&hrsmodel.Household{Code: "h1", Name: "h1", Members: []hrsmodel.Member{{User: "u1", Role: hrsmodel.OWNER}}})
+	scoped, _ := bolt.Scope("h1")
+
+	for name, store := range map[string]Store{"memory": memory, "bolt": bolt, "scoped": scoped} {
+		count := 2*MAXLIMIT + 1
+		for i := 0; i < count; i++ {
+			if err := store.InsertRecipe(newRecipe(fmt.Sprintf("r%03d", i), "Receta")); err != nil {
+				t.Fatalf("%s: InsertRecipe() error = %v", name, err)
+			}
+		}
+
+		// Every recipe once, in id order, while the callback writes
+		codes := []string{}
+		err := EachRecipe(store, func(recipe *hrsmodel.Recipe) error {
+			codes = append(codes, recipe.Code)
+			return store.InsertIngredient(&hrsmodel.Ingredient{Ingredient: hrstypes.Ingredient{Code: recipe.Code}})
+		})
+		if err != nil {
+			t.Fatalf("%s: EachRecipe() error = %v", name, err)
+		}
+		if len(codes) != count || codes[0] != "r000" || codes[count-1] != fmt.Sprintf("r%03d", count-1) {
+			t.Errorf("%s: EachRecipe() visited %d recipes, %v...", name, len(codes), codes[:3])
+		}
+		for i := 1; i < len(codes); i++ {
+			if codes[i-1] >= codes[i] {
+				t.Errorf("%s: EachRecipe() visited %s after %s", name, codes[i], codes[i-1])
+				break
+			}
+		}
+
+		ingredients := 0
+		EachIngredient(store, func(ingredient *hrsmodel.Ingredient) error {
+			ingredients++
+			return nil
+		})
+		if ingredients != count {
+			t.Errorf("%s: EachIngredient() visited %d ingredients, want %d", name, ingredients, count)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package hrsstore
+
+import (
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+)
+
+// ShareStore - recipe share links persistence operations
+type ShareStore interface {
+	InsertShare(share *hrsmodel.Share) error
+	GetShare(code string) (*hrsmodel.Share, error)
+	DeleteShare(code string) error
+	ListShares(household string, recipe string) ([]*hrsmodel.Share, error)
+	DeleteShares(household string, recipe string) error
+}
+
+// InsertShare - inserts a share link, its code must be free
+func (d *docStore) InsertShare(share *hrsmodel.Share) error {
+	return d.eng.update(func(t tx) error {
+		return insertDoc(t, SHARECOLL, share.Code, share)
+	})
+}
+
+// GetShare - returns a share link by code
+func (d *docStore) GetShare(code string) (share *hrsmodel.Share, err error) {
+	err = d.eng.view(func(t tx) error {
+		share = &hrsmodel.Share{}
+		return getDoc(t, SHARECOLL, code, share)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return share, nil
+}
+
+// DeleteShare - removes a share link by code
+func (d *docStore) DeleteShare(code string) error {
+	return d.eng.update(func(t tx) error {
+		return deleteDoc(t, SHARECOLL, code)
+	})
+}
+
+// ListShares - the share links of a recipe of the household
+func (d *docStore) ListShares(household string, recipe string) ([]*hrsmodel.Share, error) {
+	shares := []*hrsmodel.Share{}
+
+	err := d.eng.view(func(t tx) error {
+		return t.each(SHARECOLL, func(id string, data []byte) error {
+			share := &hrsmodel.Share{}
+			if _, err := readRecord(data, share); err != nil {
+				return err
+			}
+			if share.Household == household && share.Recipe == recipe {
+				shares = append(shares, share)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
+
+// DeleteShares - removes the share links of a recipe of the household
+func (d *docStore) DeleteShares(household string, recipe string) error {
+	return d.eng.update(func(t tx) error {
+		return deleteAll(t, SHARECOLL, func(id string, data []byte) (bool, error) {
+			share := &hrsmodel.Share{}
+			_, err := readRecord(data, share)
+			return share.Household == household && share.Recipe == recipe, err
+		})
+	})
+}
+
+// InsertShare - the connector only maps recipes and ingredients
+func (m *MongoStore) InsertShare(share *hrsmodel.Share) error {
+	return ErrUnsupported
+}
+
+// GetShare - the connector only maps recipes and ingredients
+func (m *MongoStore) GetShare(code string) (*hrsmodel.Share, error) {
+	return nil, ErrUnsupported
+}
+
+// DeleteShare - the connector only maps recipes and ingredients
+func (m *MongoStore) DeleteShare(code string) error {
+	return ErrUnsupported
+}
+
+// ListShares - the connector only maps recipes and ingredients
+func (m *MongoStore) ListShares(household string, recipe string) ([]*hrsmodel.Share, error) {
+	return nil, ErrUnsupported
+}
+
+// DeleteShares - the connector only maps recipes and ingredients
+func (m *MongoStore) DeleteShares(household string, recipe string) error {
+	return ErrUnsupported
+}
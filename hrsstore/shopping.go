@@ -0,0 +1,141 @@
+package hrsstore
+
+import (
+	"fmt"
+
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+)
+
+// ShoppingListStore - shopping lists persistence operations
+type ShoppingListStore interface {
+	InsertShoppingList(list *hrsmodel.ShoppingList) error
+	GetShoppingList(id string) (*hrsmodel.ShoppingList, error)
+	CheckShoppingItem(id string, item string, checked bool) (*hrsmodel.ShoppingList, error)
+	DeleteShoppingList(id string) error
+	ListShoppingLists(q Query) (*ShoppingLists, error)
+}
+
+// ShoppingLists - a page of shopping lists
+type ShoppingLists struct {
+	Items  []*hrsmodel.ShoppingList `json:"items"`
+	Total  int                      `json:"total"`
+	Cursor string                   `json:"cursor,omitempty"`
+}
+
+// GetObjectInfo - Interface DTOObject Implementation
+func (l *ShoppingLists) GetObjectInfo() string {
+	return fmt.Sprintf("%d of %d shopping lists", len(l.Items), l.Total)
+}
+
+// InsertShoppingList - inserts a shopping list, its code must be free
+func (d *docStore) InsertShoppingList(list *hrsmodel.ShoppingList) error {
+	return d.eng.update(func(t tx) error {
+		return insertDoc(t, SHOPPINGLISTCOLL, list.Code, list)
+	})
+}
+
+// GetShoppingList - returns a shopping list by id
+func (d *docStore) GetShoppingList(id string) (list *hrsmodel.ShoppingList, err error) {
+	err = d.eng.view(func(t tx) error {
+		list = &hrsmodel.ShoppingList{}
+		return getDoc(t, SHOPPINGLISTCOLL, id, list)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// CheckShoppingItem - ticks or unticks an item of a shopping list, in one
+// write so phones checking items at once don't undo each other
+func (d *docStore) CheckShoppingItem(id string, item string, checked bool) (list *hrsmodel.ShoppingList, err error) {
+	err = d.eng.update(func(t tx) error {
+		list = &hrsmodel.ShoppingList{}
+		if err := getDoc(t, SHOPPINGLISTCOLL, id, list); err != nil {
+			return err
+		}
+		found := list.Item(item)
+		if found == nil {
+			return ErrNotFound
+		}
+		found.Checked = checked
+		return putDoc(t, SHOPPINGLISTCOLL, id, list)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// DeleteShoppingList - removes a shopping list by id
+func (d *docStore) DeleteShoppingList(id string) error {
+	return d.eng.update(func(t tx) error {
+		return deleteDoc(t, SHOPPINGLISTCOLL, id)
+	})
+}
+
+// ListShoppingLists - returns a page of the shopping lists matching q,
+// reading only the names of the ones out of the page
+func (d *docStore) ListShoppingLists(q Query) (list *ShoppingLists, err error) {
+	if err = q.Validate(); err != nil {
+		return nil, err
+	}
+
+	err = d.eng.view(func(t tx) error {
+		entries := []listEntry{}
+		err := t.each(SHOPPINGLISTCOLL, func(id string, data []byte) error {
+			listed := &listedName{}
+			created, err := readRecord(data, listed)
+			if err != nil {
+				return err
+			}
+			if hasPrefix(listed.Name, q.NamePrefix) {
+				entries = append(entries, listEntry{id: id, name: listed.Name, created: created})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		page, cursor := paginate(entries, q)
+		list = &ShoppingLists{Items: []*hrsmodel.ShoppingList{}, Total: len(entries), Cursor: cursor}
+		for _, entry := range page {
+			shopping := &hrsmodel.ShoppingList{}
+			if err = getDoc(t, SHOPPINGLISTCOLL, entry.id, shopping); err != nil {
+				return err
+			}
+			list.Items = append(list.Items, shopping)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// InsertShoppingList - the connector only maps recipes and ingredients
+func (m *MongoStore) InsertShoppingList(list *hrsmodel.ShoppingList) error {
+	return ErrUnsupported
+}
+
+// GetShoppingList - the connector only maps recipes and ingredients
+func (m *MongoStore) GetShoppingList(id string) (*hrsmodel.ShoppingList, error) {
+	return nil, ErrUnsupported
+}
+
+// CheckShoppingItem - the connector only maps recipes and ingredients
+func (m *MongoStore) CheckShoppingItem(id string, item string, checked bool) (*hrsmodel.ShoppingList, error) {
+	return nil, ErrUnsupported
+}
+
+// DeleteShoppingList - the connector only maps recipes and ingredients
+func (m *MongoStore) DeleteShoppingList(id string) error {
+	return ErrUnsupported
+}
+
+// ListShoppingLists - the connector only maps recipes and ingredients
+func (m *MongoStore) ListShoppingLists(q Query) (*ShoppingLists, error) {
+	return nil, ErrUnsupported
+}
@@ -0,0 +1,135 @@
+package hrsstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+)
+
+const (
+	// INGREDIENTCOLL Constant
+	INGREDIENTCOLL = "ingredients"
+	// RECIPECOLL Constant
+	RECIPECOLL = "recipes"
+	// SHOPPINGLISTCOLL Constant
+	SHOPPINGLISTCOLL = "shoppingLists"
+	// MEALPLANCOLL Constant
+	MEALPLANCOLL = "mealPlans"
+	// PANTRYCOLL Constant
+	PANTRYCOLL = "pantry"
+	// USERCOLL Constant
+	USERCOLL = "users"
+	// HOUSEHOLDCOLL Constant
+	HOUSEHOLDCOLL = "households"
+	// INVITATIONCOLL Constant
+	INVITATIONCOLL = "invitations"
+	// APIKEYCOLL Constant
+	APIKEYCOLL = "apiKeys"
+	// SHARECOLL Constant
+	SHARECOLL = "shares"
+	// MONGO Constant
+	MONGO = "mongo"
+	// MEMORY Constant
+	MEMORY = "memory"
+	// BOLT Constant
+	BOLT = "bolt"
+)
+
+var (
+	// ErrUnavailable - the backend can't be reached
+	ErrUnavailable = errors.New("storage backend unavailable")
+	// ErrNotFound - there is no element with the given id
+	ErrNotFound = errors.New("element not found")
+	// ErrConflict - the operation clashes with an existing element
+	ErrConflict = errors.New("element already exists")
+	// ErrUnsupported - the backend can't perform the operation
+	ErrUnsupported = errors.New("operation not supported by the store")
+	// ErrInvalidQuery - the list criteria are wrong
+	ErrInvalidQuery = errors.New("invalid query")
+)
+
+// RecipeStore - recipes persistence operations
+type RecipeStore interface {
+	InsertRecipe(recipe *hrsmodel.Recipe) error
+	GetRecipe(id string) (*hrsmodel.Recipe, error)
+	UpdateRecipe(id string, recipe *hrsmodel.Recipe) (*hrsmodel.Recipe, error)
+	DeleteRecipe(id string) error
+	ListRecipes(q Query) (*RecipeList, error)
+}
+
+// IngredientStore - ingredients persistence operations
+type IngredientStore interface {
+	InsertIngredient(ingredient *hrsmodel.Ingredient) error
+	GetIngredient(id string) (*hrsmodel.Ingredient, error)
+	UpdateIngredient(id string, ingredient *hrsmodel.Ingredient) (*hrsmodel.Ingredient, error)
+	DeleteIngredient(id string) error
+	ListIngredients(q Query) (*IngredientList, error)
+}
+
+// Store - a storage backend, the worker only talks to it
+type Store interface {
+	RecipeStore
+	IngredientStore
+	ShoppingListStore
+	MealPlanStore
+	PantryStore
+	UserStore
+	HouseholdStore
+	APIKeyStore
+	ShareStore
+	Close() error
+}
+
+// Tx - recipes and ingredients operations run atomically
+type Tx interface {
+	RecipeStore
+	IngredientStore
+}
+
+// Transactional - a store able to run several operations atomically
+type Transactional interface {
+	RunInTransaction(fn func(Tx) error) error
+}
+
+// New - returns the store selected by config["store"]; mongo by default.
+// The memory store snapshots to config["snapshot"] when it is set and the
+// bolt store keeps everything in the config["db"] file. With
+// config["household"] the store is the one of that household.
+func New(ctx context.Context, config map[string]string) (Store, error) {
+	store, err := open(ctx, config)
+	if err != nil || config["household"] == "" {
+		return store, err
+	}
+
+	if _, err = store.GetHousehold(config["household"]); err == nil {
+		var scoped Store
+		if scoped, err = store.Scope(config["household"]); err == nil {
+			return scoped, nil
+		}
+	}
+	store.Close()
+	return nil, fmt.Errorf("household %s: %w", config["household"], err)
+}
+
+/** PRIVATE METHODS **/
+
+// open - the store selected by config["store"]
+func open(ctx context.Context, config map[string]string) (Store, error) {
+	kind, ok := config["store"]
+	if !ok || kind == "" {
+		kind = MONGO
+	}
+
+	switch kind {
+	case MONGO:
+		return &MongoStore{Ctx: ctx}, nil
+	case MEMORY:
+		return NewMemoryStore(config["snapshot"])
+	case BOLT:
+		return NewBoltStore(config["db"])
+	default:
+		return nil, fmt.Errorf("unknown store %q", kind)
+	}
+}
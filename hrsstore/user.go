@@ -0,0 +1,160 @@
+package hrsstore
+
+import (
+	"strings"
+
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+)
+
+// UserStore - users persistence operations
+type UserStore interface {
+	InsertUser(user *hrsmodel.User) error
+	GetUser(id string) (*hrsmodel.User, error)
+	GetUserByName(username string) (*hrsmodel.User, error)
+	GetUserBySubject(issuer string, subject string) (*hrsmodel.User, error)
+	UpdateUser(id string, user *hrsmodel.User) (*hrsmodel.User, error)
+	DeleteUser(id string) error
+}
+
+// InsertUser - inserts a user, its code and its username must be free.
+// Usernames are told apart regardless of case.
+func (d *docStore) InsertUser(user *hrsmodel.User) error {
+	return d.eng.update(func(t tx) error {
+		if _, err := userByName(t, user.Username); err != ErrNotFound {
+			if err == nil {
+				return ErrConflict
+			}
+			return err
+		}
+		return insertDoc(t, USERCOLL, user.Code, user)
+	})
+}
+
+// GetUser - returns a user by id
+func (d *docStore) GetUser(id string) (user *hrsmodel.User, err error) {
+	err = d.eng.view(func(t tx) error {
+		user = &hrsmodel.User{}
+		return getDoc(t, USERCOLL, id, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// GetUserByName - returns a user by username, regardless of case
+func (d *docStore) GetUserByName(username string) (user *hrsmodel.User, err error) {
+	err = d.eng.view(func(t tx) error {
+		user, err = userByName(t, username)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// GetUserBySubject - returns the user an OpenID Connect provider knows by
+// the subject
+func (d *docStore) GetUserBySubject(issuer string, subject string) (*hrsmodel.User, error) {
+	var found *hrsmodel.User
+
+	err := d.eng.view(func(t tx) error {
+		return t.each(USERCOLL, func(id string, data []byte) error {
+			user := &hrsmodel.User{}
+			if _, err := readRecord(data, user); err != nil {
+				return err
+			}
+			if found == nil && subject != "" && user.Issuer == issuer && user.Subject == subject {
+				found = user
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, ErrNotFound
+	}
+	return found, nil
+}
+
+// UpdateUser - patches the non empty fields of a user
+func (d *docStore) UpdateUser(id string, user *hrsmodel.User) (stored *hrsmodel.User, err error) {
+	err = d.eng.update(func(t tx) error {
+		stored = &hrsmodel.User{}
+		if err := getDoc(t, USERCOLL, id, stored); err != nil {
+			return err
+		}
+		patch(stored, user)
+		stored.Code = id
+		return putDoc(t, USERCOLL, id, stored)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// DeleteUser - removes a user by id
+func (d *docStore) DeleteUser(id string) error {
+	return d.eng.update(func(t tx) error {
+		return deleteDoc(t, USERCOLL, id)
+	})
+}
+
+// InsertUser - the connector only maps recipes and ingredients
+func (m *MongoStore) InsertUser(user *hrsmodel.User) error {
+	return ErrUnsupported
+}
+
+// GetUser - the connector only maps recipes and ingredients
+func (m *MongoStore) GetUser(id string) (*hrsmodel.User, error) {
+	return nil, ErrUnsupported
+}
+
+// GetUserByName - the connector only maps recipes and ingredients
+func (m *MongoStore) GetUserByName(username string) (*hrsmodel.User, error) {
+	return nil, ErrUnsupported
+}
+
+// GetUserBySubject - the connector only maps recipes and ingredients
+func (m *MongoStore) GetUserBySubject(issuer string, subject string) (*hrsmodel.User, error) {
+	return nil, ErrUnsupported
+}
+
+// UpdateUser - the connector only maps recipes and ingredients
+func (m *MongoStore) UpdateUser(id string, user *hrsmodel.User) (*hrsmodel.User, error) {
+	return nil, ErrUnsupported
+}
+
+// DeleteUser - the connector only maps recipes and ingredients
+func (m *MongoStore) DeleteUser(id string) error {
+	return ErrUnsupported
+}
+
+/** PRIVATE METHODS **/
+
+// userByName - looks the user up among all of them, there are a few
+func userByName(t tx, username string) (*hrsmodel.User, error) {
+	var found *hrsmodel.User
+
+	err := t.each(USERCOLL, func(id string, data []byte) error {
+		user := &hrsmodel.User{}
+		if _, err := readRecord(data, user); err != nil {
+			return err
+		}
+		if found == nil && strings.EqualFold(user.Username, username) {
+			found = user
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, ErrNotFound
+	}
+	return found, nil
+}
@@ -0,0 +1,186 @@
+package hrsunits
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrConversion - the units can't be converted into each other
+var ErrConversion = errors.New("units can't be converted")
+
+// step - a unit of a ladder, used from Min amounts on
+type step struct {
+	Code string
+	Min  float64
+}
+
+// ladder - the units an amount may be written in, Members are converted
+// into the Steps, largest first
+type ladder struct {
+	Members []string
+	Steps   []step
+}
+
+// ladders - how amounts get promoted and demoted in each family of units
+var ladders = []ladder{
+	{Members: []string{"mg", "g", "kg"}, Steps: []step{{"kg", 1}, {"g", 1}, {"mg", 0}}},
+	{Members: []string{"ml", "cl", "dl", "l"}, Steps: []step{{"l", 1}, {"ml", 0}}},
+	{Members: []string{"oz", "lb"}, Steps: []step{{"lb", 1}, {"oz", 0}}},
+	{Members: []string{"tsp", "tbsp", "fl oz", "cup", "pint", "quart", "gallon"}, Steps: []step{{"cup", 0.25}, {"tbsp", 1}, {"tsp", 0}}},
+}
+
+// Convert - the value in from units, expressed in to units
+func Convert(value float64, from string, to string) (float64, error) {
+	if from == to {
+		return value, nil
+	}
+
+	f, ok := Get(from)
+	t, ok2 := Get(to)
+	if !ok || !ok2 || f.Dimension != t.Dimension || f.Dimension == COUNT {
+		return 0, fmt.Errorf("%w: %q to %q", ErrConversion, from, to)
+	}
+	if f.Dimension == TEMPERATURE {
+		if f.Code == "C" {
+			return value*9/5 + 32, nil
+		}
+		return (value - 32) * 5 / 9, nil
+	}
+	return value * f.Factor / t.Factor, nil
+}
+
+// ConvertWith - like Convert, bridging mass and volume with the density of
+// the ingredient in g/ml: 1 cup of flour at 0.53 -> 125 g
+func ConvertWith(value float64, from string, to string, density float64) (float64, error) {
+	converted, err := Convert(value, from, to)
+	if err == nil || density <= 0 {
+		return converted, err
+	}
+
+	f, _ := Get(from)
+	t, _ := Get(to)
+	switch {
+	case f == nil || t == nil:
+	case f.Dimension == VOLUME && t.Dimension == MASS:
+		return value * f.Factor * density / t.Factor, nil
+	case f.Dimension == MASS && t.Dimension == VOLUME:
+		return value * f.Factor / density / t.Factor, nil
+	}
+	return 0, err
+}
+
+// ToSystem - writes an amount in the units of the given system, in its
+// handiest unit and rounded. Volumes become grams in the metric system when
+// the density is known. Count units, unknown units and units already in
+// the system are kept as written.
+func ToSystem(value float64, unit string, system string, density float64) (float64, string) {
+	u, ok := Get(unit)
+	if !ok || u.Dimension == COUNT || u.System == system {
+		return value, unit
+	}
+
+	base := baseUnit(u.Dimension, system, density)
+	converted, err := ConvertWith(value, unit, base, density)
+	if err != nil {
+		return value, unit
+	}
+
+	if u.Dimension == TEMPERATURE {
+		return RoundTemperature(converted, base), base
+	}
+	converted, to := Promote(converted, base)
+	return Round(converted, to), to
+}
+
+// Promote - writes the amount in the handiest unit of its family:
+// 48 tsp -> 1 cup, 1500 g -> 1.5 kg, 0.125 cup -> 2 tbsp. Units outside a
+// family are kept.
+func Promote(value float64, unit string) (float64, string) {
+	l := ladderOf(unit)
+	if l == nil {
+		return value, unit
+	}
+
+	for _, s := range l.Steps {
+		converted, err := Convert(value, unit, s.Code)
+		if err == nil && converted >= s.Min {
+			return converted, s.Code
+		}
+	}
+	return value, unit
+}
+
+// Round - rounds an amount to what can be measured in a kitchen: grams and
+// millilitres to units (fives above 100), kilos and litres to 0.05, the
+// rest to quarters (eighths under 1)
+func Round(value float64, unit string) float64 {
+	var step float64
+
+	switch unit {
+	case "g", "ml", "mg":
+		step = 1
+		if value >= 100 {
+			step = 5
+		}
+	case "kg", "l", "cl", "dl":
+		step = 0.05
+	default:
+		step = 0.25
+		if value < 1 {
+			step = 0.125
+		}
+	}
+
+	rounded := math.Round(value/step) * step
+	if rounded == 0 && value > 0 {
+		rounded = step
+	}
+	return math.Round(rounded*1000) / 1000
+}
+
+// RoundTemperature - rounds oven temperatures the way dials are marked, to
+// tens of Celsius and quarters of a hundred Fahrenheit. Lower ones, like
+// sous vide or sugar work, are kept to the degree.
+func RoundTemperature(value float64, unit string) float64 {
+	switch {
+	case unit == "C" && value >= 100:
+		return math.Round(value/10) * 10
+	case unit == "F" && value >= 212:
+		return math.Round(value/25) * 25
+	default:
+		return math.Round(value)
+	}
+}
+
+/** PRIVATE METHODS **/
+
+// baseUnit - the smallest unit of a dimension in a system, amounts are
+// promoted from it
+func baseUnit(dimension string, system string, density float64) string {
+	switch {
+	case dimension == TEMPERATURE && system == METRIC:
+		return "C"
+	case dimension == TEMPERATURE:
+		return "F"
+	case system == METRIC && (dimension == MASS || density > 0):
+		return "g"
+	case system == METRIC:
+		return "ml"
+	case dimension == MASS:
+		return "oz"
+	default:
+		return "tsp"
+	}
+}
+
+func ladderOf(unit string) *ladder {
+	for i := range ladders {
+		for _, member := range ladders[i].Members {
+			if member == unit {
+				return &ladders[i]
+			}
+		}
+	}
+	return nil
+}
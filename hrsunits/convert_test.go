@@ -0,0 +1,80 @@
+package hrsunits
+
+import (
+	"math"
+	"testing"
+)
+
+func TestConvertWith(t *testing.T) {
+	tests := []struct {
+		value   float64
+		from    string
+		to      string
+		density float64
+		want    float64
+		wantErr bool
+	}{
+		{1, "lb", "g", 0, 453.592, false},
+		{1, "cup", "g", 0.53, 125.392, false},
+		{125, "g", "cup", 0.53, 0.997, false},
+		{100, "C", "F", 0, 212, false},
+		{1, "cup", "g", 0, 0, true},
+		{1, "clove", "g", 1, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ConvertWith(tt.value, tt.from, tt.to, tt.density)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ConvertWith(%v %s -> %s) error = %v", tt.value, tt.from, tt.to, err)
+			continue
+		}
+		if math.Abs(got-tt.want) > 0.001 {
+			t.Errorf("ConvertWith(%v %s -> %s) = %v, want %v", tt.value, tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestToSystem(t *testing.T) {
+	tests := []struct {
+		value    float64
+		unit     string
+		system   string
+		density  float64
+		want     float64
+		wantUnit string
+	}{
+		{2, "cup", METRIC, 0.53, 250, "g"},
+		{1, "cup", METRIC, 0, 235, "ml"},
+		{500, "g", IMPERIAL, 0, 1, "lb"},
+		{250, "ml", IMPERIAL, 0, 1, "cup"},
+		{180, "C", IMPERIAL, 0, 350, "F"},
+		{350, "F", METRIC, 0, 180, "C"},
+		{200, "g", METRIC, 0, 200, "g"},
+		{3, "clove", METRIC, 0, 3, "clove"},
+	}
+	for _, tt := range tests {
+		got, unit := ToSystem(tt.value, tt.unit, tt.system, tt.density)
+		if got != tt.want || unit != tt.wantUnit {
+			t.Errorf("ToSystem(%v %s, %s) = %v %s, want %v %s", tt.value, tt.unit, tt.system, got, unit, tt.want, tt.wantUnit)
+		}
+	}
+}
+
+func TestConvertTemperatures(t *testing.T) {
+	tests := []struct {
+		text   string
+		system string
+		want   string
+	}{
+		{"Hornear a 180 °C durante 20 minutos", IMPERIAL, "Hornear a 350 °F durante 20 minutos"},
+		{"Bake at 350 degrees F until golden", METRIC, "Bake at 180 °C until golden"},
+		{"Horno a 200 grados centígrados", IMPERIAL, "Horno a 400 °F"},
+		{"Cocinar a 63ºC", IMPERIAL, "Cocinar a 145 °F"},
+		{"Hornear a 180 °C", METRIC, "Hornear a 180 °C"},
+		{"Bake at 350 degrees", METRIC, "Bake at 350 degrees"},
+	}
+	for _, tt := range tests {
+		if got := ConvertTemperatures(tt.text, tt.system); got != tt.want {
+			t.Errorf("ConvertTemperatures(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
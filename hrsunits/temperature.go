@@ -0,0 +1,37 @@
+package hrsunits
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// temperatureRe - a temperature written in a step: "180 °C", "180ºC",
+// "350 degrees F", "200 grados centígrados". The scale must be explicit, a
+// bare "180 degrees" is left alone.
+var temperatureRe = regexp.MustCompile(`(?i)(\d+(?:[.,]\d+)?)\s*(?:[°º]\s*|degrees?\s+|grados?\s+)(celsius|fahrenheit|cent[ií]grados?|c|f)\b`)
+
+// ConvertTemperatures - rewrites the temperatures of a text in the given
+// system: "Bake at 180 °C" -> "Bake at 350 °F"
+func ConvertTemperatures(text string, system string) string {
+	target := baseUnit(TEMPERATURE, system, 0)
+
+	return temperatureRe.ReplaceAllStringFunc(text, func(match string) string {
+		parts := temperatureRe.FindStringSubmatch(match)
+		from := "C"
+		if strings.HasPrefix(strings.ToLower(parts[2]), "f") {
+			from = "F"
+		}
+		if from == target {
+			return match
+		}
+
+		value, err := strconv.ParseFloat(strings.Replace(parts[1], ",", ".", 1), 64)
+		if err != nil {
+			return match
+		}
+		converted, _ := Convert(value, from, target)
+		return fmt.Sprintf("%s °%s", strconv.FormatFloat(RoundTemperature(converted, target), 'f', -1, 64), target)
+	})
+}
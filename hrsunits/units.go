@@ -0,0 +1,93 @@
+package hrsunits
+
+import (
+	"strings"
+
+	"github.com/ninh0gauch0/homerecipes/hrssearch"
+)
+
+const (
+	// MASS Constant
+	MASS = "mass"
+	// VOLUME Constant
+	VOLUME = "volume"
+	// COUNT Constant
+	COUNT = "count"
+	// TEMPERATURE Constant
+	TEMPERATURE = "temperature"
+	// METRIC Constant
+	METRIC = "metric"
+	// IMPERIAL Constant
+	IMPERIAL = "imperial"
+)
+
+// Unit - a kitchen unit, its code is what recipes store. Factor converts
+// mass units to grams and volume units to millilitres; temperatures aren't
+// proportional and convert on their own. Aliases are the
+// Spanish and English ways of writing it, compared folded.
+type Unit struct {
+	Code      string
+	Dimension string
+	System    string
+	Factor    float64
+	Aliases   []string
+}
+
+// units - the known units
+var units = []Unit{
+	{Code: "mg", Dimension: MASS, System: METRIC, Factor: 0.001, Aliases: []string{"mg", "miligramo", "miligramos", "milligram", "milligrams"}},
+	{Code: "g", Dimension: MASS, System: METRIC, Factor: 1, Aliases: []string{"g", "gr", "grs", "gramo", "gramos", "gram", "grams", "gramme", "grammes"}},
+	{Code: "kg", Dimension: MASS, System: METRIC, Factor: 1000, Aliases: []string{"kg", "kgs", "kilo", "kilos", "kilogramo", "kilogramos", "kilogram", "kilograms"}},
+	{Code: "oz", Dimension: MASS, System: IMPERIAL, Factor: 28.349523125, Aliases: []string{"oz", "onza", "onzas", "ounce", "ounces"}},
+	{Code: "lb", Dimension: MASS, System: IMPERIAL, Factor: 453.59237, Aliases: []string{"lb", "lbs", "libra", "libras", "pound", "pounds"}},
+	{Code: "ml", Dimension: VOLUME, System: METRIC, Factor: 1, Aliases: []string{"ml", "mililitro", "mililitros", "milliliter", "milliliters", "millilitre", "millilitres"}},
+	{Code: "cl", Dimension: VOLUME, System: METRIC, Factor: 10, Aliases: []string{"cl", "centilitro", "centilitros", "centiliter", "centiliters"}},
+	{Code: "dl", Dimension: VOLUME, System: METRIC, Factor: 100, Aliases: []string{"dl", "decilitro", "decilitros", "deciliter", "deciliters"}},
+	{Code: "l", Dimension: VOLUME, System: METRIC, Factor: 1000, Aliases: []string{"l", "lt", "litro", "litros", "liter", "liters", "litre", "litres"}},
+	{Code: "tsp", Dimension: VOLUME, System: IMPERIAL, Factor: 4.92892159375, Aliases: []string{"tsp", "tsps", "teaspoon", "teaspoons", "cucharadita", "cucharaditas", "cdta", "cdtas", "cdita", "cditas", "cta"}},
+	{Code: "tbsp", Dimension: VOLUME, System: IMPERIAL, Factor: 14.78676478125, Aliases: []string{"tbsp", "tbsps", "tbs", "tablespoon", "tablespoons", "cucharada", "cucharadas", "cda", "cdas", "cs"}},
+	{Code: "fl oz", Dimension: VOLUME, System: IMPERIAL, Factor: 29.5735295625, Aliases: []string{"fl oz", "fl. oz", "fluid ounce", "fluid ounces", "onza liquida", "onzas liquidas"}},
+	{Code: "cup", Dimension: VOLUME, System: IMPERIAL, Factor: 236.5882365, Aliases: []string{"cup", "cups", "taza", "tazas", "tz"}},
+	{Code: "pint", Dimension: VOLUME, System: IMPERIAL, Factor: 473.176473, Aliases: []string{"pint", "pints", "pt", "pinta", "pintas"}},
+	{Code: "quart", Dimension: VOLUME, System: IMPERIAL, Factor: 946.352946, Aliases: []string{"quart", "quarts", "qt", "cuarto de galon"}},
+	{Code: "gallon", Dimension: VOLUME, System: IMPERIAL, Factor: 3785.411784, Aliases: []string{"gallon", "gallons", "gal", "galon", "galones"}},
+	{Code: "C", Dimension: TEMPERATURE, System: METRIC, Aliases: []string{"°c", "ºc", "celsius", "centigrado", "centigrados"}},
+	{Code: "F", Dimension: TEMPERATURE, System: IMPERIAL, Aliases: []string{"°f", "ºf", "fahrenheit"}},
+	{Code: "pinch", Dimension: COUNT, Factor: 1, Aliases: []string{"pinch", "pinches", "pizca", "pizcas", "pellizco", "pellizcos"}},
+	{Code: "dash", Dimension: COUNT, Factor: 1, Aliases: []string{"dash", "dashes", "chorro", "chorros", "chorrito", "chorritos"}},
+	{Code: "clove", Dimension: COUNT, Factor: 1, Aliases: []string{"clove", "cloves", "diente", "dientes"}},
+	{Code: "slice", Dimension: COUNT, Factor: 1, Aliases: []string{"slice", "slices", "rebanada", "rebanadas", "loncha", "lonchas", "rodaja", "rodajas"}},
+	{Code: "can", Dimension: COUNT, Factor: 1, Aliases: []string{"can", "cans", "tin", "tins", "lata", "latas", "bote", "botes"}},
+	{Code: "package", Dimension: COUNT, Factor: 1, Aliases: []string{"package", "packages", "pack", "packs", "paquete", "paquetes", "sobre", "sobres"}},
+	{Code: "bunch", Dimension: COUNT, Factor: 1, Aliases: []string{"bunch", "bunches", "manojo", "manojos", "ramillete", "ramilletes"}},
+	{Code: "sprig", Dimension: COUNT, Factor: 1, Aliases: []string{"sprig", "sprigs", "ramita", "ramitas", "rama", "ramas"}},
+	{Code: "handful", Dimension: COUNT, Factor: 1, Aliases: []string{"handful", "handfuls", "puñado", "puñados"}},
+	{Code: "piece", Dimension: COUNT, Factor: 1, Aliases: []string{"piece", "pieces", "pc", "pcs", "unidad", "unidades", "ud", "uds", "pieza", "piezas"}},
+}
+
+// aliases - folded alias -> unit
+var aliases = map[string]*Unit{}
+
+func init() {
+	for i := range units {
+		for _, alias := range units[i].Aliases {
+			aliases[hrssearch.Fold(alias)] = &units[i]
+		}
+	}
+}
+
+// Lookup - the unit written as word: "Tazas", "cdas.", "fl oz"
+func Lookup(word string) (*Unit, bool) {
+	unit, ok := aliases[strings.TrimSuffix(hrssearch.Fold(strings.TrimSpace(word)), ".")]
+	return unit, ok
+}
+
+// Get - the unit with the given code
+func Get(code string) (*Unit, bool) {
+	for i := range units {
+		if units[i].Code == code {
+			return &units[i], true
+		}
+	}
+	return nil, false
+}
@@ -14,7 +14,7 @@ import (
 )
 
 const (
-	version = "1.0.0"
+	version = "1.1.0"
 )
 
 var (
@@ -69,13 +69,14 @@ func main() {
 	serverCommnad.Usage = "Starts the HR Server"
 
 	// Additional command flags
-	serverCommnad.Flags = []cli.Flag{
+	serverCommnad.Flags = append([]cli.Flag{
 		cli.StringFlag{
 			Name:  "port, p",
 			Value: "8089",
 			Usage: "Server port",
 		},
-	}
+	}, hrscli.StoreFlags()...)
+	serverCommnad.Flags = append(serverCommnad.Flags, hrscli.AuthFlags()...)
 
 	// Starts the server with a given configuration
 	serverCommnad.Action = func(c *cli.Context) {
@@ -102,8 +103,10 @@ func main() {
 		defer cancelFunc()
 
 		// Config definition
-		config := map[string]string{
-			"addr": fmt.Sprintf(":%s", c.String("port")),
+		config := hrscli.StoreConfig(c)
+		config["addr"] = fmt.Sprintf(":%s", c.String("port"))
+		for key, value := range hrscli.AuthConfig(c) {
+			config[key] = value
 		}
 		// Init the server
 		if s.Init() {
@@ -124,6 +127,8 @@ func main() {
 					fallthrough
 				case syscall.SIGTERM:
 					exitChan <- true
+					// Waiting for the server to release its resources
+					<-exitChan
 				}
 			}
 		} else {
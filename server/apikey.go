@@ -0,0 +1,233 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/ninh0gauch0/homerecipes/hrsauth"
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/homerecipes/hrsstore"
+	"github.com/ninh0gauch0/hrstypes"
+)
+
+// apiKeyKey - the API key a request was authenticated with in the request
+// context
+const apiKeyKey = contextKey("apiKey")
+
+// addAPIKeyRoutes - the API keys of the user
+func (s *Server) addAPIKeyRoutes(accountRoutes *mux.Router) {
+	accountRoutes.HandleFunc("/apikeys", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("creating API key...")
+
+		var key hrsmodel.APIKey
+		if s.member(w, r) && s.decodeBody(w, r, &key) {
+			s.writeResponse(w, s.worker.CreateAPIKey(currentUser(r), &key), "API key created")
+		}
+	}).Methods("POST")
+
+	accountRoutes.HandleFunc("/apikeys", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("listing API keys...")
+		if s.member(w, r) {
+			s.writeResponse(w, s.worker.ListAPIKeys(currentUser(r)), "API keys returned")
+		}
+	}).Methods("GET")
+
+	accountRoutes.HandleFunc("/apikeys/{id}", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("revoking API key...")
+		if s.member(w, r) {
+			s.writeResponse(w, s.worker.RevokeAPIKey(currentUser(r), mux.Vars(r)["id"]), "API key revoked")
+		}
+	}).Methods("DELETE")
+}
+
+// authorize - lets through the requests authenticated with an API key
+// only when the key has the scopes the request needs
+func (s *Server) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key := currentAPIKey(r); key != nil {
+			for _, scope := range requiredScopes(r) {
+				if !key.Allows(scope) {
+					funcErr := hrstypes.FunctionalError{}
+					s.writeResponse(w, generateErrorResponse(FORBIDDEN, "The API key lacks the "+scope+" scope", funcErr, http.StatusForbidden), "")
+					return
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// personal - keeps API keys away from managing accounts, households and
+// keys; they can only tell whose they are
+func (s *Server) personal(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		template, _ := mux.CurrentRoute(r).GetPathTemplate()
+		if currentAPIKey(r) != nil && !(r.Method == http.MethodGet && template == "/hrs/me") {
+			funcErr := hrstypes.FunctionalError{}
+			s.writeResponse(w, generateErrorResponse(FORBIDDEN, "API keys can't manage accounts", funcErr, http.StatusForbidden), "")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// currentAPIKey - the API key the request was authenticated with, nil when
+// it wasn't
+func currentAPIKey(r *http.Request) *hrsmodel.APIKey {
+	key, _ := r.Context().Value(apiKeyKey).(*hrsmodel.APIKey)
+	return key
+}
+
+// KeyUser - the user of an API key and the key; hrsauth.ErrToken when the
+// key isn't good, expired, was revoked or its user is gone
+func (w *Worker) KeyUser(secret string) (*hrsmodel.User, *hrsmodel.APIKey, error) {
+	id, ok := hrsauth.APIKeyID(secret)
+	if !ok {
+		return nil, nil, hrsauth.ErrToken
+	}
+
+	key, err := w.store.GetAPIKey(id)
+	if err == nil && (!hrsauth.CheckAPIKey(key.Hash, secret) || key.Expired(time.Now())) {
+		err = hrsauth.ErrToken
+	}
+	var user *hrsmodel.User
+	if err == nil {
+		user, err = w.store.GetUser(key.User)
+	}
+	if errors.Is(err, hrsstore.ErrNotFound) {
+		return nil, nil, hrsauth.ErrToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, key, nil
+}
+
+// CreateAPIKey - Creates an API key of the user; the key itself is only
+// returned now, it is kept hashed
+func (w *Worker) CreateAPIKey(user *hrsmodel.User, key *hrsmodel.APIKey) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - CreateAPIKey [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	if err := key.Validate(); err != nil {
+		funcErr := hrstypes.FunctionalError{}
+		return generateErrorResponse(FAIL, err.Error(), funcErr, http.StatusConflict)
+	}
+	now := time.Now().Truncate(time.Second)
+	if !key.Expires.IsZero() && !key.Expires.After(now) {
+		funcErr := hrstypes.FunctionalError{}
+		return generateErrorResponse(FAIL, "The API key would be expired already", funcErr, http.StatusConflict)
+	}
+
+	secret, id, err := hrsauth.NewAPIKey()
+	if err != nil {
+		return generateErrorResponse(TECHNICAL, "Fatal error generating key: "+err.Error(), err, http.StatusInternalServerError)
+	}
+	key.Code, key.User, key.Hash, key.Created = id, user.Code, hrsauth.HashAPIKey(secret), now
+
+	if err = w.store.InsertAPIKey(key); err != nil {
+		w.logger.Errorf("Worker - CreateAPIKey - Error: " + err.Error())
+		return storeErrorResponse(err, "Insertion can't be accomplished", "Fatal error trying to insert: ")
+	}
+	key.Hash = ""
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusCreated,
+		Description: CREATED,
+	}
+	rsp.RespObj = &NewAPIKey{Key: secret, APIKey: key}
+	rsp.SetError(nil)
+
+	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
+	w.logger.Debugf("Worker - CreateAPIKey [OUT]")
+	return rsp
+}
+
+// ListAPIKeys - Returns the API keys of the user, without their hashes
+func (w *Worker) ListAPIKeys(user *hrsmodel.User) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - ListAPIKeys [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	keys, err := w.store.ListAPIKeys(user.Code)
+	if err != nil {
+		w.logger.Errorf("Worker - ListAPIKeys - Error: " + err.Error())
+		return storeErrorResponse(err, "Query can't be accomplished", "Fatal error trying to list: ")
+	}
+	for _, key := range keys {
+		key.Hash = ""
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: QUERIED,
+	}
+	rsp.RespObj = &APIKeys{Items: keys}
+	rsp.SetError(nil)
+
+	w.logger.Debugf("Worker - ListAPIKeys [OUT]")
+	return rsp
+}
+
+// RevokeAPIKey - Removes an API key of the user, it stops working
+func (w *Worker) RevokeAPIKey(user *hrsmodel.User, id string) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - RevokeAPIKey [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	key, err := w.store.GetAPIKey(id)
+	if err == nil && key.User != user.Code {
+		err = hrsstore.ErrNotFound
+	}
+	if err == nil {
+		err = w.store.DeleteAPIKey(id)
+	}
+	if err != nil {
+		w.logger.Errorf("Worker - RevokeAPIKey - Error: " + err.Error())
+		return storeErrorResponse(err, "Deletion can't be accomplished", "Fatal error trying to delete: ")
+	}
+	key.Hash = ""
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: REMOVED,
+	}
+	rsp.RespObj = key
+	rsp.SetError(nil)
+
+	w.logger.Debugf("Worker - RevokeAPIKey [OUT]")
+	return rsp
+}
+
+/** PRIVATE METHODS **/
+
+// requiredScopes - the scopes an API key needs for the request: reading
+// takes recipes:read, changing ingredients ingredients:write, importing
+// both writing scopes and changing anything else recipes:write
+func requiredScopes(r *http.Request) []string {
+	if readOnly(r) {
+		return []string{hrsmodel.RECIPESREAD}
+	}
+
+	template, _ := mux.CurrentRoute(r).GetPathTemplate()
+	switch {
+	case strings.HasPrefix(template, "/hrs/ingredients"):
+		return []string{hrsmodel.INGREDIENTSWRITE}
+	case template == "/hrs/import":
+		return []string{hrsmodel.RECIPESWRITE, hrsmodel.INGREDIENTSWRITE}
+	default:
+		return []string{hrsmodel.RECIPESWRITE}
+	}
+}
+
+// deleteAPIKeys - revokes every API key of the user
+func (w *Worker) deleteAPIKeys(user *hrsmodel.User) error {
+	keys, err := w.store.ListAPIKeys(user.Code)
+	for _, key := range keys {
+		if err == nil {
+			err = w.store.DeleteAPIKey(key.Code)
+		}
+	}
+	return err
+}
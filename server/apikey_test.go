@@ -0,0 +1,94 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+)
+
+func TestServer_apiKeys(t *testing.T) {
+	s := newTestServer(t)
+	token := s.worker.Login("ana", "correct horse").RespObj.(*Session).Token
+	user, _ := s.worker.TokenUser(token)
+	s.worker.CreateHousehold(user, "Casa")
+
+	newKey := func(body string) *NewAPIKey {
+		rec := serve(s, "POST", "/hrs/apikeys", body, token)
+		var rsp struct {
+			RespObj NewAPIKey `json:"respObj"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &rsp); err != nil || rec.Code != http.StatusCreated {
+			t.Fatalf("POST /apikeys = %d %s", rec.Code, rec.Body.String())
+		}
+		return &rsp.RespObj
+	}
+	if rec := serve(s, "POST", "/hrs/apikeys", `{"name":"bad","scopes":["recipes:delete"]}`, token); rec.Code != http.StatusConflict {
+		t.Errorf("POST /apikeys with an unknown scope = %d", rec.Code)
+	}
+	reader := newKey(`{"name":"backup","scopes":["recipes:read"]}`)
+	writer := newKey(`{"name":"stock","scopes":["recipes:read","ingredients:write"]}`)
+	stock := newKey(`{"name":"stock only","scopes":["ingredients:write"]}`)
+	calendar := "/hrs/mealplans/calendar.ics?key="
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		key    string
+		status int
+	}{
+		{"read", "GET", "/hrs/recipes", "", reader.Key, http.StatusOK},
+		{"whoami", "GET", "/hrs/me", "", reader.Key, http.StatusOK},
+		{"write without scope", "POST", "/hrs/ingredients", `{"code":"i-egg","name":"huevo"}`, reader.Key, http.StatusForbidden},
+		{"write", "POST", "/hrs/ingredients", `{"code":"i-egg","name":"huevo"}`, writer.Key, http.StatusCreated},
+		{"write recipes", "POST", "/hrs/recipes", `{"name":"Tortilla"}`, writer.Key, http.StatusForbidden},
+		{"manage keys", "GET", "/hrs/apikeys", "", writer.Key, http.StatusForbidden},
+		{"manage households", "POST", "/hrs/households", `{"name":"Otra"}`, writer.Key, http.StatusForbidden},
+		{"forged", "GET", "/hrs/recipes", "", reader.Key + "x", http.StatusUnauthorized},
+		{"calendar feed", "GET", calendar + reader.Key, "", "", http.StatusOK},
+		{"calendar feed without scope", "GET", calendar + stock.Key, "", "", http.StatusForbidden},
+		{"calendar feed forged", "GET", calendar + reader.Key + "x", "", "", http.StatusUnauthorized},
+		{"calendar feed token", "GET", calendar + token, "", "", http.StatusUnauthorized},
+		{"key parameter elsewhere", "GET", "/hrs/recipes?key=" + reader.Key, "", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		if rec := serve(s, tt.method, tt.path, tt.body, tt.key); rec.Code != tt.status {
+			t.Errorf("%s: %s %s = %d %s", tt.name, tt.method, tt.path, rec.Code, rec.Body.String())
+		}
+	}
+
+	// Calendar apps pick the household in the url too
+	other := s.worker.CreateHousehold(user, "Pueblo").RespObj.(*hrsmodel.Household)
+	if rec := serve(s, "GET", calendar+reader.Key, "", ""); rec.Code == http.StatusOK {
+		t.Errorf("calendar feed of a user of several households = %d", rec.Code)
+	}
+	if rec := serve(s, "GET", calendar+reader.Key+"&household="+other.Code, "", ""); rec.Code != http.StatusOK {
+		t.Errorf("calendar feed of a household = %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := serve(s, "GET", "/hrs/recipes?household="+other.Code, "", reader.Key); rec.Code == http.StatusOK {
+		t.Errorf("household parameter elsewhere = %d", rec.Code)
+	}
+
+	keys := s.worker.ListAPIKeys(user).RespObj.(*APIKeys).Items
+	if len(keys) != 3 || keys[0].Hash != "" {
+		t.Errorf("ListAPIKeys() = %+v", keys)
+	}
+
+	if rec := serve(s, "DELETE", "/hrs/apikeys/"+reader.Code, "", token); rec.Code != http.StatusOK {
+		t.Errorf("DELETE /apikeys = %d", rec.Code)
+	}
+	if rec := serve(s, "GET", "/hrs/recipes", "", reader.Key); rec.Code != http.StatusUnauthorized {
+		t.Errorf("GET with a revoked key = %d", rec.Code)
+	}
+
+	expired := &hrsmodel.APIKey{Name: "old", Scopes: []string{hrsmodel.RECIPESREAD}, Expires: time.Now().Add(time.Second)}
+	key := s.worker.CreateAPIKey(user, expired).RespObj.(*NewAPIKey).Key
+	time.Sleep(time.Second)
+	if rec := serve(s, "GET", "/hrs/recipes", "", key); rec.Code != http.StatusUnauthorized {
+		t.Errorf("GET with an expired key = %d", rec.Code)
+	}
+}
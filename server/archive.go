@@ -0,0 +1,419 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/ninh0gauch0/homerecipes/hrsarchive"
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/homerecipes/hrsstore"
+	"github.com/ninh0gauch0/hrstypes"
+)
+
+const (
+	// CONFLICTSKIP Constant
+	CONFLICTSKIP = "skip"
+	// CONFLICTOVERWRITE Constant
+	CONFLICTOVERWRITE = "overwrite"
+	// CONFLICTFAIL Constant
+	CONFLICTFAIL = "fail"
+	// MAXFAILURES Constant
+	MAXFAILURES = 100
+	// MAXIMAGESIZE Constant
+	MAXIMAGESIZE = 10 << 20
+	// IMAGETIMEOUT Constant
+	IMAGETIMEOUT = 30 * time.Second
+	// MAXIMPORTSIZE Constant
+	MAXIMPORTSIZE = 1 << 30
+)
+
+// reservedNets - the address blocks which aren't global, besides the ones
+// the net package tells apart: shared, benchmarking, documentation and
+// protocol ranges, and the IPv6 translation and tunnel prefixes able to
+// reach any IPv4 address
+var reservedNets = parseNets(
+	"0.0.0.0/8", "100.64.0.0/10", "192.0.0.0/24", "192.0.2.0/24", "192.88.99.0/24", "198.18.0.0/15",
+	"198.51.100.0/24", "203.0.113.0/24", "240.0.0.0/4",
+	"64:ff9b::/96", "64:ff9b:1::/48", "100::/64", "2001::/23", "2001:db8::/32", "2002::/16", "fec0::/10",
+)
+
+// imageClient - fetches the recipe images kept in archives. The image
+// urls come from the users, so it only dials public addresses and follows
+// no redirects, which would get around that.
+var imageClient = &http.Client{
+	Timeout: IMAGETIMEOUT,
+	Transport: &http.Transport{
+		DialContext:         (&net.Dialer{Timeout: IMAGETIMEOUT, Control: publicAddress}).DialContext,
+		TLSHandshakeTimeout: IMAGETIMEOUT,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// addArchiveRoutes - bulk export and import endpoints
+func (s *Server) addArchiveRoutes(hrsRoutes *mux.Router) {
+	hrsRoutes.HandleFunc("/export", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("exporting collection...")
+
+		format := r.URL.Query().Get("format")
+		if format == "" && accepts(r, hrsarchive.ARCHIVETYPE) {
+			format = hrsarchive.ARCHIVE
+		} else if format == "" {
+			format = hrsarchive.NDJSON
+		}
+
+		// Nothing is written until the first record, so a store failing
+		// right away still gets an error response
+		body := &countingWriter{w: w}
+		out, err := hrsarchive.NewWriter(body, format)
+		if err != nil {
+			s.writeResponse(w, generateErrorResponse(FAIL, err.Error(), hrstypes.FunctionalError{}, http.StatusConflict), "")
+			return
+		}
+
+		w.Header().Set("Content-Type", hrsarchive.MediaType(format))
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"hrs-%s.%s\"", time.Now().Format("20060102"), format))
+
+		images := format == hrsarchive.ARCHIVE && r.URL.Query().Get("images") == "true"
+		if err = s.tenant(r).ExportCollection(out, images); err != nil && body.n == 0 {
+			w.Header().Del("Content-Disposition")
+			w.Header().Set("Content-Type", "application/json")
+			s.writeResponse(w, storeErrorResponse(err, "Export can't be accomplished", "Fatal error trying to export: "), "")
+			return
+		}
+		if err != nil {
+			// Too late for an error response, the export is left cut short
+			s.customErrorLogger("Export cut short - error: %s", err.Error())
+			return
+		}
+		if err = out.Close(); err != nil {
+			s.customErrorLogger("Export not closed - error: %s", err.Error())
+			return
+		}
+		s.customInfoLogger("Collection exported as %s, %d bytes", format, body.n)
+	}).Methods("GET")
+
+	hrsRoutes.HandleFunc("/import", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("importing collection...")
+		defer r.Body.Close()
+
+		in, err := hrsarchive.NewReader(http.MaxBytesReader(w, r.Body, MAXIMPORTSIZE))
+		if err != nil {
+			s.writeResponse(w, generateErrorResponse(FAIL, err.Error(), hrstypes.FunctionalError{}, http.StatusConflict), "")
+			return
+		}
+		s.writeResponse(w, s.tenant(r).ImportCollection(in, r.URL.Query().Get("mode")), "Collection imported")
+	}).Methods("POST")
+}
+
+// ExportCollection - Writes every ingredient and then every recipe, page
+// after page, so the collection is never held whole. With images, a writer
+// keeping images gets the recipe images too; the ones which can't be
+// fetched are left out.
+func (w *Worker) ExportCollection(out hrsarchive.Writer, images bool) error {
+	w.logger.Debugf("Worker - ExportCollection [IN]")
+	ingredients, recipes := 0, 0
+
+	err := hrsstore.EachIngredient(w.store, func(ingredient *hrsmodel.Ingredient) error {
+		ingredients++
+		return out.Write(&hrsarchive.Record{Kind: hrsarchive.INGREDIENT, Ingredient: ingredient})
+	})
+	if err != nil {
+		w.logger.Errorf("Worker - ExportCollection - Error: " + err.Error())
+		return err
+	}
+
+	imageWriter, keepsImages := out.(hrsarchive.ImageWriter)
+	err = hrsstore.EachRecipe(w.store, func(recipe *hrsmodel.Recipe) error {
+		recipes++
+		if err := out.Write(&hrsarchive.Record{Kind: hrsarchive.RECIPE, Recipe: recipe}); err != nil {
+			return err
+		}
+		if !images || !keepsImages {
+			return nil
+		}
+		for i, url := range recipe.Images {
+			if err := w.exportImage(imageWriter, recipe.Code, i, url); err != nil {
+				w.logger.Warnf("Worker - ExportCollection - image %s of recipe %s left out: %s", url, recipe.Code, err.Error())
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		w.logger.Errorf("Worker - ExportCollection - Error: " + err.Error())
+		return err
+	}
+
+	w.logger.Debugf("%d ingredients and %d recipes exported", ingredients, recipes)
+	w.logger.Debugf("Worker - ExportCollection [OUT]")
+	return nil
+}
+
+// ImportCollection - Imports the records read, keeping their codes. An
+// element whose code is taken is skipped, overwritten or ends the import,
+// as the mode says (skip by default); with fail the records before it stay
+// imported. Records which can't be read or don't validate are reported and
+// left out.
+func (w *Worker) ImportCollection(in hrsarchive.Reader, mode string) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - ImportCollection [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	if mode == "" {
+		mode = CONFLICTSKIP
+	}
+	if mode != CONFLICTSKIP && mode != CONFLICTOVERWRITE && mode != CONFLICTFAIL {
+		funcErr := hrstypes.FunctionalError{}
+		return generateErrorResponse(FAIL, fmt.Sprintf("mode must be %s, %s or %s", CONFLICTSKIP, CONFLICTOVERWRITE, CONFLICTFAIL), funcErr, http.StatusConflict)
+	}
+
+	res := &CollectionImport{Mode: mode, Failures: []ImportFailure{}}
+	for {
+		record, err := in.Next()
+		if err == io.EOF {
+			break
+		}
+
+		var recordErr *hrsarchive.RecordError
+		if errors.As(err, &recordErr) {
+			res.Unreadable++
+			res.fail(ImportFailure{Position: recordErr.Position, Error: recordErr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			w.logger.Errorf("Worker - ImportCollection - Error: " + err.Error())
+			funcErr := hrstypes.FunctionalError{}
+			rsp = generateErrorResponse(FAIL, "the import can't be read: "+err.Error(), funcErr, http.StatusConflict)
+			rsp.RespObj = res
+			return rsp
+		}
+
+		counts := &res.Ingredients
+		if record.Kind == hrsarchive.RECIPE {
+			counts = &res.Recipes
+		}
+
+		overwritten, err := w.importRecord(record, mode == CONFLICTOVERWRITE)
+		switch {
+		case err == nil && overwritten:
+			counts.Overwritten++
+		case err == nil:
+			counts.Created++
+		case errors.Is(err, hrsstore.ErrConflict) && mode == CONFLICTSKIP:
+			counts.Skipped++
+		case errors.Is(err, hrsmodel.ErrInvalid):
+			counts.Failed++
+			res.fail(ImportFailure{Position: in.Position(), Kind: record.Kind, Code: record.Code(), Error: err.Error()})
+		default:
+			w.logger.Errorf("Worker - ImportCollection - Error: " + err.Error())
+			counts.Failed++
+			res.fail(ImportFailure{Position: in.Position(), Kind: record.Kind, Code: record.Code(), Error: err.Error()})
+			rsp = storeErrorResponse(err, "Import stopped at "+record.Kind+" "+record.Code(), "Fatal error trying to import: ")
+			rsp.RespObj = res
+			return rsp
+		}
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: CREATED,
+	}
+	rsp.RespObj = res
+	rsp.SetError(nil)
+
+	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
+	w.logger.Debugf("Worker - ImportCollection [OUT]")
+	return rsp
+}
+
+/** PRIVATE METHODS **/
+
+// fail - records a failure, up to MAXFAILURES of them
+func (ci *CollectionImport) fail(failure ImportFailure) {
+	if len(ci.Failures) < MAXFAILURES {
+		ci.Failures = append(ci.Failures, failure)
+	}
+}
+
+// importRecord - stores the element of the record with its code; with
+// overwrite an element already there with the code is replaced
+func (w *Worker) importRecord(record *hrsarchive.Record, overwrite bool) (overwritten bool, err error) {
+	if record.Kind == hrsarchive.INGREDIENT {
+		ingredient := record.Ingredient
+		if ingredient.Code == "" {
+			if ingredient.Code, err = newUUID(); err != nil {
+				return false, err
+			}
+		}
+		if err = ingredient.Validate(); err != nil {
+			return false, err
+		}
+
+		err = w.store.InsertIngredient(ingredient)
+		if errors.Is(err, hrsstore.ErrConflict) && overwrite {
+			overwritten, err = true, w.replaceIngredient(ingredient)
+		}
+		if err == nil {
+			w.catalog.put(ingredient)
+		}
+		return overwritten, err
+	}
+
+	recipe := record.Recipe
+	if recipe.Code == "" {
+		if recipe.Code, err = newUUID(); err != nil {
+			return false, err
+		}
+	}
+	// The lines are read as POST /recipes reads them
+	if err = w.parseRawLines(recipe, false); err != nil {
+		return false, err
+	}
+	if err = recipe.Validate(); err != nil {
+		return false, err
+	}
+	recipe.Sync()
+
+	err = w.store.InsertRecipe(recipe)
+	if errors.Is(err, hrsstore.ErrConflict) && overwrite {
+		overwritten, err = true, w.replaceRecipe(recipe)
+	}
+	if err == nil {
+		w.indexRecipe(recipe)
+	}
+	return overwritten, err
+}
+
+// replaceIngredient - puts the ingredient in place of the stored one with
+// its code, atomically when the store can; else it is patched
+func (w *Worker) replaceIngredient(ingredient *hrsmodel.Ingredient) error {
+	if t, ok := w.store.(hrsstore.Transactional); ok {
+		return t.RunInTransaction(func(tx hrsstore.Tx) error {
+			if err := tx.DeleteIngredient(ingredient.Code); err != nil {
+				return err
+			}
+			return tx.InsertIngredient(ingredient)
+		})
+	}
+	_, err := w.store.UpdateIngredient(ingredient.Code, ingredient)
+	return err
+}
+
+// replaceRecipe - puts the recipe in place of the stored one with its
+// code, atomically when the store can; else it is patched
+func (w *Worker) replaceRecipe(recipe *hrsmodel.Recipe) error {
+	if t, ok := w.store.(hrsstore.Transactional); ok {
+		return t.RunInTransaction(func(tx hrsstore.Tx) error {
+			if err := tx.DeleteRecipe(recipe.Code); err != nil {
+				return err
+			}
+			return tx.InsertRecipe(recipe)
+		})
+	}
+	_, err := w.store.UpdateRecipe(recipe.Code, recipe)
+	return err
+}
+
+// exportImage - fetches an image of a recipe and adds it to the writer as
+// <index><ext>. The image is read whole first: an entry can't be taken
+// back once started.
+func (w *Worker) exportImage(out hrsarchive.ImageWriter, recipe string, index int, url string) error {
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return errors.New("not an http url")
+	}
+
+	resp, err := imageClient.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(resp.Status)
+	}
+	if t, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err != nil || !strings.HasPrefix(t, "image/") {
+		return fmt.Errorf("not an image but %q", resp.Header.Get("Content-Type"))
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, MAXIMAGESIZE+1))
+	if err != nil {
+		return err
+	}
+	if len(data) > MAXIMAGESIZE {
+		return fmt.Errorf("bigger than %d bytes", MAXIMAGESIZE)
+	}
+
+	name := fmt.Sprintf("%d%s", index+1, imageExt(resp.Request.URL.Path, resp.Header.Get("Content-Type")))
+	return out.WriteImage(recipe, name, int64(len(data)), bytes.NewReader(data))
+}
+
+// publicAddress - refuses to dial the loopback, private, link local,
+// multicast, unspecified and reserved addresses, the ones of the server
+// network or which may lead to it
+func publicAddress(network string, address string, conn syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("%s is not a public address", host)
+	}
+	for _, reserved := range reservedNets {
+		if reserved.Contains(ip) {
+			return fmt.Errorf("%s is not a public address", host)
+		}
+	}
+	return nil
+}
+
+// parseNets - the address blocks in CIDR notation
+func parseNets(cidrs ...string) []*net.IPNet {
+	nets := []*net.IPNet{}
+	for _, cidr := range cidrs {
+		_, block, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, block)
+	}
+	return nets
+}
+
+// imageExt - the extension of an image, from its url or its media type
+func imageExt(urlPath string, contentType string) string {
+	switch ext := strings.ToLower(path.Ext(urlPath)); ext {
+	case ".jpg", ".jpeg", ".png", ".gif", ".webp", ".avif", ".svg":
+		return ext
+	}
+
+	if t, _, err := mime.ParseMediaType(contentType); err == nil {
+		if exts, _ := mime.ExtensionsByType(t); len(exts) > 0 {
+			return exts[0]
+		}
+	}
+	return ""
+}
+
+// countingWriter - counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ninh0gauch0/homerecipes/hrsarchive"
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/hrstypes"
+)
+
+func TestWorker_ExportImportCollection(t *testing.T) {
+	w := newTestWorker(t)
+	w.CreateIngredient(&hrsmodel.Ingredient{Ingredient: hrstypes.Ingredient{Code: "i-flour", Name: "harina"}})
+	w.CreateRecipe(newRecipe("Pan", "i-flour"))
+
+	var buf bytes.Buffer
+	out, _ := hrsarchive.NewWriter(&buf, hrsarchive.NDJSON)
+	if err := w.ExportCollection(out, false); err != nil {
+		t.Fatalf("ExportCollection() error = %v", err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 2 {
+		t.Fatalf("ExportCollection() = %d lines:\n%s", lines, buf.String())
+	}
+	export := buf.String()
+
+	// Same codes again: skipped, overwritten or the end of the import
+	tests := []struct {
+		mode   string
+		status int
+		counts ImportCounts
+	}{
+		{"", http.StatusOK, ImportCounts{Skipped: 1}},
+		{CONFLICTOVERWRITE, http.StatusOK, ImportCounts{Overwritten: 1}},
+		{CONFLICTFAIL, http.StatusConflict, ImportCounts{Failed: 1}},
+	}
+	for _, tt := range tests {
+		in, _ := hrsarchive.NewReader(strings.NewReader(export))
+		rsp := w.ImportCollection(in, tt.mode)
+		if rsp.Status.Code != tt.status {
+			t.Errorf("ImportCollection(%q) = %+v", tt.mode, rsp)
+			continue
+		}
+		if res := rsp.RespObj.(*CollectionImport); res.Ingredients != tt.counts {
+			t.Errorf("ImportCollection(%q) ingredients = %+v", tt.mode, res.Ingredients)
+		}
+	}
+
+	other := newTestWorker(t)
+	data := export + "{\"kind\":\"ingredient\",\"ingredient\":{\"code\":\"i-bad\",\"density\":-1}}\nnot json\n"
+	in, _ := hrsarchive.NewReader(strings.NewReader(data))
+	rsp := other.ImportCollection(in, CONFLICTFAIL)
+	if rsp.Status.Code != http.StatusOK {
+		t.Fatalf("ImportCollection() = %+v", rsp)
+	}
+	res := rsp.RespObj.(*CollectionImport)
+	if res.Ingredients.Created != 1 || res.Recipes.Created != 1 || res.Ingredients.Failed != 1 || res.Unreadable != 1 || len(res.Failures) != 2 {
+		t.Errorf("ImportCollection() = %+v", res)
+	}
+	if res.Failures[0].Position != "line 3" || res.Failures[0].Code != "i-bad" {
+		t.Errorf("ImportCollection() failures = %+v", res.Failures)
+	}
+	if found := other.SearchRecipes("pan", 10); len(found.RespObj.(*RecipeSearch).Items) != 1 {
+		t.Errorf("SearchRecipes() after import = %+v", found.RespObj)
+	}
+
+	// Free text lines are parsed and resolved as POST /recipes does
+	data = `{"kind":"recipe","recipe":{"code":"r-bread","name":"Pan de pueblo","ingredients":["500 g de harina"]}}` + "\n"
+	in, _ = hrsarchive.NewReader(strings.NewReader(data))
+	if rsp = other.ImportCollection(in, ""); rsp.Error != nil {
+		t.Fatalf("ImportCollection() = %+v", rsp)
+	}
+	recipe, _ := other.store.GetRecipe("r-bread")
+	if line := recipe.Lines[0]; line.Ingredient != "i-flour" || line.Quantity == nil || line.Unit != "g" {
+		t.Errorf("imported line = %+v", line)
+	}
+}
+
+// imageNames - an image writer recording the names of the images
+type imageNames []string
+
+func (n *imageNames) WriteImage(recipe string, name string, size int64, data io.Reader) error {
+	*n = append(*n, recipe+"/"+name)
+	return nil
+}
+
+func TestWorker_exportImage(t *testing.T) {
+	w := newTestWorker(t)
+	origin := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/photo.jpg":
+			rw.Header().Set("Content-Type", "image/jpeg")
+			rw.Write([]byte("jpg"))
+		case "/moved.jpg":
+			http.Redirect(rw, r, "/photo.jpg", http.StatusFound)
+		default:
+			rw.Header().Set("Content-Type", "text/html")
+			rw.Write([]byte("<html></html>"))
+		}
+	}))
+	defer origin.Close()
+
+	// The server network is never dialed
+	var images imageNames
+	if err := w.exportImage(&images, "r1", 0, origin.URL+"/photo.jpg"); err == nil || len(images) != 0 {
+		t.Errorf("exportImage() of a loopback url = %v, %v", err, images)
+	}
+	for _, address := range []string{"127.0.0.1:80", "[::1]:80", "10.0.0.1:80", "192.168.1.1:80", "169.254.169.254:80", "[fe80::1]:80", "0.0.0.0:80",
+		"100.64.0.1:80", "192.0.0.8:80", "198.18.0.1:80", "[64:ff9b::a00:1]:80", "[2002:a00:1::]:80", "[::ffff:127.0.0.1]:80"} {
+		if err := publicAddress("tcp", address, nil); err == nil {
+			t.Errorf("publicAddress(%s) = nil", address)
+		}
+	}
+	for _, address := range []string{"93.184.216.34:443", "[2606:2800:220:1:248:1893:25c8:1946]:443"} {
+		if err := publicAddress("tcp", address, nil); err != nil {
+			t.Errorf("publicAddress(%s) of a public address = %v", address, err)
+		}
+	}
+
+	// Through a client reaching it, only images are kept and redirects not followed
+	transport := imageClient.Transport
+	imageClient.Transport = http.DefaultTransport
+	defer func() { imageClient.Transport = transport }()
+	for _, url := range []string{origin.URL + "/moved.jpg", origin.URL + "/page.jpg"} {
+		if err := w.exportImage(&images, "r1", 0, url); err == nil {
+			t.Errorf("exportImage(%s) = nil", url)
+		}
+	}
+	if err := w.exportImage(&images, "r1", 0, origin.URL+"/photo.jpg"); err != nil || len(images) != 1 || images[0] != "r1/1.jpg" {
+		t.Errorf("exportImage() = %v, %v", err, images)
+	}
+}
@@ -0,0 +1,409 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/ninh0gauch0/homerecipes/hrsauth"
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/homerecipes/hrsstore"
+	"github.com/ninh0gauch0/hrstypes"
+)
+
+const (
+	// UNAUTHORIZED Constant
+	UNAUTHORIZED = "Not authorized"
+	// REALM Constant
+	REALM = `Bearer realm="hrs"`
+)
+
+// contextKey - keys of the values put in the request context
+type contextKey string
+
+// userKey - the authenticated user in the request context
+const userKey = contextKey("user")
+
+// feedRoutes - the routes calendar apps subscribe to. They can't send
+// headers, so these take an API key in the "key" query parameter and the
+// household in the "household" one.
+var feedRoutes = map[string]bool{
+	"/hrs/mealplans/calendar.ics": true,
+}
+
+// configureAuth - the token signer and whether anonymous users may read,
+// and which household, from the "token-secret", "token-ttl",
+// "anonymous-read" and "anonymous-household" keys
+func (s *Server) configureAuth(config map[string]string) error {
+	var ttl time.Duration
+	if value := config["token-ttl"]; value != "" {
+		var err error
+		if ttl, err = time.ParseDuration(value); err != nil {
+			return err
+		}
+	}
+	if config["token-secret"] == "" {
+		s.logger.Warnf("No token secret given, tokens won't outlive the server")
+	}
+
+	signer, err := hrsauth.NewSigner(config["token-secret"], ttl)
+	if err != nil {
+		return err
+	}
+	s.worker.signer = signer
+
+	if value := config["anonymous-read"]; value != "" {
+		if s.anonymousRead, err = strconv.ParseBool(value); err != nil {
+			return err
+		}
+	}
+	s.anonymousHousehold = config["anonymous-household"]
+	if s.anonymousRead && s.anonymousHousehold == "" {
+		return errors.New("anonymous reads need the household they read")
+	}
+	return nil
+}
+
+// addAuthRoutes - login, on the main router so that it needs no token, and
+// the authenticated user
+func (s *Server) addAuthRoutes(accountRoutes *mux.Router) {
+	s.router.HandleFunc("/hrs/login", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("logging in...")
+
+		var credentials Credentials
+		if s.decodeBody(w, r, &credentials) {
+			s.writeResponse(w, s.worker.Login(credentials.Username, credentials.Password), "Logged in")
+		}
+	}).Methods("POST")
+
+	accountRoutes.HandleFunc("/me", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("returning the authenticated user...")
+
+		user := currentUser(r)
+		if user == nil {
+			s.unauthorized(w)
+			return
+		}
+		rsp := hrstypes.HRAResponse{}
+		rsp.Status = hrstypes.Status{
+			Code:        http.StatusOK,
+			Description: QUERIED,
+		}
+		rsp.RespObj = userInfo(user)
+		rsp.SetError(nil)
+		s.writeResponse(w, rsp, "User returned")
+	}).Methods("GET")
+}
+
+// authenticate - lets through the requests carrying a good bearer token,
+// an API key, in the "key" parameter on the feed routes too, or the
+// session cookie of an OpenID Connect login, and the reading ones without
+// any when anonymous reads are allowed. Every request goes through when the
+// store keeps no users.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.accountless {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token := bearerToken(r)
+		if token == "" {
+			token = feedKey(r)
+		}
+		if token == "" {
+			token = sessionToken(r)
+		}
+		if token == "" && s.anonymousRead && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var user *hrsmodel.User
+		var key *hrsmodel.APIKey
+		var err error
+		if _, ok := hrsauth.APIKeyID(token); ok {
+			user, key, err = s.worker.KeyUser(token)
+		} else {
+			user, err = s.worker.TokenUser(token)
+		}
+		if errors.Is(err, hrsauth.ErrToken) {
+			s.unauthorized(w)
+			return
+		}
+		if err != nil {
+			s.writeResponse(w, storeErrorResponse(err, "Authentication can't be accomplished", "Fatal error trying to authenticate: "), "")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), userKey, user)
+		if key != nil {
+			ctx = context.WithValue(ctx, apiKeyKey, key)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// currentUser - the user the request was authenticated as, nil when
+// anonymous
+func currentUser(r *http.Request) *hrsmodel.User {
+	user, _ := r.Context().Value(userKey).(*hrsmodel.User)
+	return user
+}
+
+// Login - Checks the credentials of a user and issues a token for them
+func (w *Worker) Login(username string, password string) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - Login [IN]")
+
+	user, err := w.store.GetUserByName(username)
+	if err != nil && !errors.Is(err, hrsstore.ErrNotFound) {
+		w.logger.Errorf("Worker - Login - Error: " + err.Error())
+		return storeErrorResponse(err, "Login can't be accomplished", "Fatal error trying to login: ")
+	}
+
+	hash := ""
+	if user != nil {
+		hash = user.PasswordHash
+	}
+	if !hrsauth.CheckPassword(hash, password) {
+		funcErr := hrstypes.FunctionalError{}
+		return generateErrorResponse(UNAUTHORIZED, "Wrong username or password", funcErr, http.StatusUnauthorized)
+	}
+
+	rsp := w.session(user)
+	w.logger.Debugf("Worker - Login [OUT]")
+	return rsp
+}
+
+// TokenUser - the user a token was issued for; hrsauth.ErrToken when the
+// token isn't good, its user is gone or changed the password after
+func (w *Worker) TokenUser(token string) (*hrsmodel.User, error) {
+	claims, err := w.signer.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := w.store.GetUser(claims.Subject)
+	if errors.Is(err, hrsstore.ErrNotFound) {
+		return nil, hrsauth.ErrToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.TokenRevoked(claims.Issued()) {
+		return nil, hrsauth.ErrToken
+	}
+	return user, nil
+}
+
+// CreateUser - Creates a user with the given password
+func (w *Worker) CreateUser(username string, password string) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - CreateUser [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	user := &hrsmodel.User{Username: username}
+	if err := user.Validate(); err != nil {
+		funcErr := hrstypes.FunctionalError{}
+		return generateErrorResponse(FAIL, err.Error(), funcErr, http.StatusConflict)
+	}
+	if err := w.setPassword(user, password); err != nil {
+		return passwordErrorResponse(err)
+	}
+
+	code, err := newUUID()
+	if err != nil {
+		return generateErrorResponse(TECHNICAL, "Fatal error generating code: "+err.Error(), err, http.StatusInternalServerError)
+	}
+	user.Code = code
+
+	if err = w.store.InsertUser(user); err != nil {
+		w.logger.Errorf("Worker - CreateUser - Error: " + err.Error())
+		return storeErrorResponse(err, "Insertion can't be accomplished, the username may be taken", "Fatal error trying to insert: ")
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusCreated,
+		Description: CREATED,
+	}
+	rsp.RespObj = userInfo(user)
+	rsp.SetError(nil)
+
+	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
+	w.logger.Debugf("Worker - CreateUser [OUT]")
+	return rsp
+}
+
+// ResetPassword - Sets a new password, the tokens issued before stop working
+func (w *Worker) ResetPassword(username string, password string) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - ResetPassword [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	user, err := w.store.GetUserByName(username)
+	if err != nil {
+		w.logger.Errorf("Worker - ResetPassword - Error: " + err.Error())
+		return storeErrorResponse(err, "Update can't be accomplished", "Fatal error trying to update: ")
+	}
+	if err = w.setPassword(user, password); err != nil {
+		return passwordErrorResponse(err)
+	}
+
+	if user, err = w.store.UpdateUser(user.Code, user); err != nil {
+		w.logger.Errorf("Worker - ResetPassword - Error: " + err.Error())
+		return storeErrorResponse(err, "Update can't be accomplished", "Fatal error trying to update: ")
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: PATCHED,
+	}
+	rsp.RespObj = userInfo(user)
+	rsp.SetError(nil)
+
+	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
+	w.logger.Debugf("Worker - ResetPassword [OUT]")
+	return rsp
+}
+
+// DeleteUserByName - Removes a user, its tokens and API keys stop working.
+// The user leaves their households, the ones left empty are removed; it
+// fails when some other member would be left without owner.
+func (w *Worker) DeleteUserByName(username string) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - DeleteUserByName [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	user, err := w.store.GetUserByName(username)
+	if err == nil {
+		err = w.leaveHouseholds(user)
+	}
+	if err == nil {
+		err = w.deleteAPIKeys(user)
+	}
+	if err == nil {
+		err = w.store.DeleteUser(user.Code)
+	}
+	if err != nil {
+		w.logger.Errorf("Worker - DeleteUserByName - Error: " + err.Error())
+		return householdErrorResponse(err)
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: REMOVED,
+	}
+	rsp.RespObj = userInfo(user)
+	rsp.SetError(nil)
+
+	w.logger.Debugf("Worker - DeleteUserByName [OUT]")
+	return rsp
+}
+
+/** PRIVATE METHODS **/
+
+// unauthorized - the response to requests without a good token
+func (s *Server) unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", REALM)
+	funcErr := hrstypes.FunctionalError{}
+	s.writeResponse(w, generateErrorResponse(UNAUTHORIZED, "A valid bearer token is needed", funcErr, http.StatusUnauthorized), "")
+}
+
+// bearerToken - the token of the Authorization header, empty when none
+func bearerToken(r *http.Request) string {
+	fields := strings.Fields(r.Header.Get("Authorization"))
+	if len(fields) != 2 || !strings.EqualFold(fields[0], "Bearer") {
+		return ""
+	}
+	return fields[1]
+}
+
+// feedKey - the API key of the "key" query parameter on the feed routes,
+// empty elsewhere and when it isn't an API key: tokens don't go in urls
+func feedKey(r *http.Request) string {
+	key := r.URL.Query().Get("key")
+	if _, ok := hrsauth.APIKeyID(key); !ok || !feedRoute(r) {
+		return ""
+	}
+	return key
+}
+
+// feedRoute - whether the request is one of the feedRoutes
+func feedRoute(r *http.Request) bool {
+	template, err := mux.CurrentRoute(r).GetPathTemplate()
+	return err == nil && feedRoutes[template]
+}
+
+// session - the response issuing a token for the user
+func (w *Worker) session(user *hrsmodel.User) hrstypes.HRAResponse {
+	rsp := hrstypes.HRAResponse{}
+
+	token, expires, err := w.signer.Issue(user.Code)
+	if err != nil {
+		return generateErrorResponse(TECHNICAL, "Fatal error issuing token: "+err.Error(), err, http.StatusInternalServerError)
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: QUERIED,
+	}
+	rsp.RespObj = &Session{Token: token, Expires: expires, User: userInfo(user)}
+	rsp.SetError(nil)
+
+	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
+	return rsp
+}
+
+// setPassword - hashes the password into the user. The change time is
+// kept to the millisecond, as token issue times are.
+func (w *Worker) setPassword(user *hrsmodel.User, password string) error {
+	hash, err := hrsauth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	user.PasswordHash = hash
+	user.PasswordChanged = time.Now().Truncate(time.Millisecond)
+	return nil
+}
+
+// passwordErrorResponse - the response to a password which can't be set
+func passwordErrorResponse(err error) hrstypes.HRAResponse {
+	if errors.Is(err, hrsauth.ErrPassword) {
+		funcErr := hrstypes.FunctionalError{}
+		return generateErrorResponse(FAIL, err.Error(), funcErr, http.StatusConflict)
+	}
+	return generateErrorResponse(TECHNICAL, "Fatal error hashing password: "+err.Error(), err, http.StatusInternalServerError)
+}
+
+// leaveHouseholds - takes the user out of their households before they
+// are removed
+func (w *Worker) leaveHouseholds(user *hrsmodel.User) error {
+	households, err := w.store.ListHouseholds(user.Code)
+	if err != nil {
+		return err
+	}
+
+	for _, household := range households {
+		household.RemoveMember(user.Code)
+		if len(household.Members) > 0 && household.Validate() != nil {
+			return errLastOwner
+		}
+	}
+	for _, household := range households {
+		if len(household.Members) == 0 {
+			err = w.store.DeleteHousehold(household.Code)
+		} else {
+			err = w.store.SaveHousehold(household)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// userInfo - what is shown of a user
+func userInfo(user *hrsmodel.User) *UserInfo {
+	return &UserInfo{Code: user.Code, Username: user.Username}
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/ninh0gauch0/homerecipes/hrsauth"
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/homerecipes/hrsstore"
+)
+
+func newAuthWorker(t *testing.T) *Worker {
+	w := newTestWorker(t)
+	w.signer, _ = hrsauth.NewSigner("secret", time.Hour)
+	if rsp := w.CreateUser("ana", "correct horse"); rsp.Status.Code != http.StatusCreated {
+		t.Fatalf("CreateUser() = %+v", rsp)
+	}
+	return w
+}
+
+func TestWorker_Login(t *testing.T) {
+	w := newAuthWorker(t)
+
+	if rsp := w.CreateUser("ANA", "other password"); rsp.Status.Code != http.StatusConflict {
+		t.Errorf("CreateUser() of a taken username = %+v", rsp)
+	}
+	if rsp := w.CreateUser("bea", "short"); rsp.Status.Code != http.StatusConflict {
+		t.Errorf("CreateUser() with a short password = %+v", rsp)
+	}
+
+	for _, credentials := range [][2]string{{"ana", "wrong password"}, {"nobody", "correct horse"}} {
+		if rsp := w.Login(credentials[0], credentials[1]); rsp.Status.Code != http.StatusUnauthorized {
+			t.Errorf("Login(%q, %q) = %+v", credentials[0], credentials[1], rsp)
+		}
+	}
+
+	rsp := w.Login("Ana", "correct horse")
+	if rsp.Error != nil {
+		t.Fatalf("Login() = %+v", rsp)
+	}
+	session := rsp.RespObj.(*Session)
+	if user, err := w.TokenUser(session.Token); err != nil || user.Username != "ana" {
+		t.Errorf("TokenUser() = %v, %v", user, err)
+	}
+
+	// A password set after the token was issued revokes it, even within
+	// the same second
+	if rsp := w.ResetPassword("ana", "battery staple"); rsp.Error != nil {
+		t.Fatalf("ResetPassword() = %+v", rsp)
+	}
+	if _, err := w.TokenUser(session.Token); err != hrsauth.ErrToken {
+		t.Errorf("TokenUser() after a reset error = %v", err)
+	}
+	rsp = w.Login("ana", "battery staple")
+	if rsp.Error != nil {
+		t.Fatalf("Login() with the new password = %+v", rsp)
+	}
+	token := rsp.RespObj.(*Session).Token
+	if _, err := w.TokenUser(token); err != nil {
+		t.Errorf("TokenUser() of a token issued after the reset error = %v", err)
+	}
+
+	w.DeleteUserByName("ana")
+	if _, err := w.TokenUser(token); err != hrsauth.ErrToken {
+		t.Errorf("TokenUser() of a removed user error = %v", err)
+	}
+}
+
+func newTestServer(t *testing.T) *Server {
+	logFileOn = false
+	w := newAuthWorker(t)
+	s := &Server{router: mux.NewRouter(), worker: w, store: w.store}
+	s.SetLogger(w.GetLogger())
+	s.addRoutes()
+	return s
+}
+
+// serve - the response of the server to a request with the token, if any
+func serve(s *Server, method string, path string, body string, token string, header ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, r)
+	return rec
+}
+
+func TestServer_authenticate(t *testing.T) {
+	s := newTestServer(t)
+	do := func(method string, path string, body string, token string) int {
+		return serve(s, method, path, body, token).Code
+	}
+
+	if code := do("GET", "/hrs/recipes", "", ""); code != http.StatusUnauthorized {
+		t.Errorf("GET without token = %d", code)
+	}
+	if code := do("POST", "/hrs/login", `{"username":"ana","password":"wrong password"}`, ""); code != http.StatusUnauthorized {
+		t.Errorf("POST /login with a wrong password = %d", code)
+	}
+	if code := do("POST", "/hrs/login", `{"username":"ana","password":"correct horse"}`, ""); code != http.StatusOK {
+		t.Fatalf("POST /login = %d", code)
+	}
+
+	token := s.worker.Login("ana", "correct horse").RespObj.(*Session).Token
+	if code := do("GET", "/hrs/me", "", token); code != http.StatusOK {
+		t.Errorf("GET /me = %d", code)
+	}
+	if code := do("GET", "/hrs/recipes", "", token+"x"); code != http.StatusUnauthorized {
+		t.Errorf("GET with a forged token = %d", code)
+	}
+
+	user, _ := s.worker.TokenUser(token)
+	s.anonymousRead = true
+	s.anonymousHousehold = s.worker.CreateHousehold(user, "Casa").RespObj.(*hrsmodel.Household).Code
+	if code := do("GET", "/hrs/recipes", "", ""); code != http.StatusOK {
+		t.Errorf("anonymous GET = %d", code)
+	}
+	if code := do("POST", "/hrs/pantry", `{}`, ""); code != http.StatusUnauthorized {
+		t.Errorf("anonymous POST = %d", code)
+	}
+	if code := do("GET", "/hrs/me", "", ""); code != http.StatusUnauthorized {
+		t.Errorf("anonymous GET /me = %d", code)
+	}
+}
+
+func TestServer_authenticate_accountless(t *testing.T) {
+	if !accountless(&hrsstore.MongoStore{Ctx: context.Background()}) {
+		t.Error("accountless() of the mongo store = false")
+	}
+	s := newTestServer(t)
+	if accountless(s.store) {
+		t.Error("accountless() of the memory store = true")
+	}
+
+	s.accountless = true
+	handler := s.authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/hrs/recipes", strings.NewReader("{}")))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("POST without token on an accountless store = %d", rec.Code)
+	}
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ninh0gauch0/homerecipes/hrsical"
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/hrstypes"
+)
+
+const (
+	// CALENDARPAST Constant
+	CALENDARPAST = 28
+	// CALENDARAHEAD Constant
+	CALENDARAHEAD = 56
+	// PREPHOUR Constant
+	PREPHOUR = 20
+)
+
+// mealTimes - when each meal starts and how long it lasts
+var mealTimes = map[string]struct {
+	Hour     int
+	Minute   int
+	Duration time.Duration
+}{
+	hrsmodel.BREAKFAST: {8, 0, 30 * time.Minute},
+	hrsmodel.LUNCH:     {14, 0, time.Hour},
+	hrsmodel.DINNER:    {21, 0, time.Hour},
+}
+
+// GetMealCalendar - Returns the planned meals as calendar events linking
+// their recipes. Without dates the last four weeks and the next eight are
+// returned. With prep, steps to start the day before become events the
+// evening before.
+func (w *Worker) GetMealCalendar(from string, to string, prep bool) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - GetMealCalendar [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	today := time.Now().UTC().Format(hrsmodel.DATEFORMAT)
+	if from == "" {
+		from = shiftDate(today, -CALENDARPAST)
+	}
+	if to == "" {
+		to = shiftDate(from, CALENDARPAST+CALENDARAHEAD)
+	}
+	start, end, err := planRange(from, to)
+	if err != nil {
+		funcErr := hrstypes.FunctionalError{}
+		return generateErrorResponse(FAIL, err.Error(), funcErr, http.StatusConflict)
+	}
+
+	entries, err := w.store.ListMealPlan(start.Format(hrsmodel.DATEFORMAT), end.Format(hrsmodel.DATEFORMAT))
+	if err != nil {
+		w.logger.Errorf("Worker - GetMealCalendar - Error: " + err.Error())
+		return storeErrorResponse(err, "Query can't be accomplished", "Fatal error trying to query: ")
+	}
+
+	calendar := &MealCalendar{Calendar: hrsical.Calendar{Name: "Home recipes", Events: []hrsical.Event{}}}
+	recipes := map[string]*hrsmodel.Recipe{}
+
+	for _, entry := range entries {
+		recipe, ok := recipes[entry.Recipe]
+		if !ok {
+			if recipe, err = w.store.GetRecipe(entry.Recipe); err != nil {
+				w.logger.Warnf("Worker - GetMealCalendar - %s %s: %s", entry.Date, entry.Slot, err.Error())
+			}
+			recipes[entry.Recipe] = recipe
+		}
+		if recipe == nil {
+			continue
+		}
+
+		date, _ := hrsmodel.ParseDate(entry.Date)
+		meal := mealTimes[entry.Slot]
+		startAt := date.Add(time.Duration(meal.Hour)*time.Hour + time.Duration(meal.Minute)*time.Minute)
+		link := "/hrs/recipes/" + recipe.Code
+
+		calendar.Events = append(calendar.Events, hrsical.Event{
+			UID:         entry.Code + "@homerecipes",
+			Start:       startAt,
+			End:         startAt.Add(meal.Duration),
+			Summary:     recipe.Name,
+			Description: recipe.Description,
+			URL:         link,
+		})
+
+		if steps := recipe.PrepAheadSteps(); prep && len(steps) > 0 {
+			prepAt := date.AddDate(0, 0, -1).Add(PREPHOUR * time.Hour)
+			calendar.Events = append(calendar.Events, hrsical.Event{
+				UID:         "prep-" + entry.Code + "@homerecipes",
+				Start:       prepAt,
+				End:         prepAt.Add(30 * time.Minute),
+				Summary:     "Prep: " + recipe.Name,
+				Description: strings.Join(steps, "\n"),
+				URL:         link,
+			})
+		}
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: QUERIED,
+	}
+	rsp.RespObj = calendar
+	rsp.SetError(nil)
+
+	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
+	w.logger.Debugf("Worker - GetMealCalendar [OUT]")
+	return rsp
+}
+
+/** PRIVATE METHODS **/
+
+// shiftDate - the 2006-01-02 date the given days later
+func shiftDate(date string, days int) string {
+	t, err := hrsmodel.ParseDate(date)
+	if err != nil {
+		return date
+	}
+	return t.AddDate(0, 0, days).Format(hrsmodel.DATEFORMAT)
+}
+
+// baseURL - the scheme and host the request was made to, as the client
+// sees them behind a proxy
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+
+	host := r.Host
+	if forwarded := r.Header.Get("X-Forwarded-Host"); forwarded != "" {
+		host = forwarded
+	}
+	return fmt.Sprintf("%s://%s", scheme, host)
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+)
+
+func TestWorker_GetMealCalendar(t *testing.T) {
+	w := newTestWorker(t)
+
+	recipe := newRecipe("Fabada")
+	recipe.Code = "fabada"
+	recipe.Steps = []string{"Poner las fabes en remojo toda la noche", "Cocer a fuego lento"}
+	w.CreateRecipe(recipe)
+	w.CreateMealPlanEntry(&hrsmodel.MealPlanEntry{Date: "2024-03-05", Slot: hrsmodel.LUNCH, Recipe: "fabada"})
+
+	calendar := w.GetMealCalendar("2024-03-04", "2024-03-10", false).RespObj.(*MealCalendar)
+	if len(calendar.Events) != 1 {
+		t.Fatalf("GetMealCalendar() = %+v", calendar.Events)
+	}
+	e := calendar.Events[0]
+	if e.Summary != "Fabada" || e.URL != "/hrs/recipes/fabada" || e.Start.Hour() != 14 || e.Start.Day() != 5 {
+		t.Errorf("GetMealCalendar() event = %+v", e)
+	}
+
+	calendar = w.GetMealCalendar("2024-03-04", "2024-03-10", true).RespObj.(*MealCalendar)
+	if len(calendar.Events) != 2 {
+		t.Fatalf("GetMealCalendar() with prep = %+v", calendar.Events)
+	}
+	prep := calendar.Events[1]
+	if prep.Start.Day() != 4 || prep.Start.Hour() != PREPHOUR || !strings.Contains(prep.Description, "remojo") {
+		t.Errorf("GetMealCalendar() prep event = %+v", prep)
+	}
+}
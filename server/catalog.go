@@ -0,0 +1,50 @@
+package server
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+)
+
+// catalog - concurrency safe copy of the stored ingredients, the names of
+// the ingredient lines are resolved against. The worker keeps it in step
+// with the ingredient writes, as it does the indexes with the recipe ones.
+type catalog struct {
+	mu          sync.RWMutex
+	ingredients map[string]*hrsmodel.Ingredient
+}
+
+// newCatalog - creates an empty catalog
+func newCatalog() *catalog {
+	return &catalog{ingredients: map[string]*hrsmodel.Ingredient{}}
+}
+
+// put - adds an ingredient, replacing the one with its code
+func (c *catalog) put(ingredient *hrsmodel.Ingredient) {
+	kept := *ingredient
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ingredients[kept.Code] = &kept
+}
+
+// remove - drops the ingredient with the code
+func (c *catalog) remove(code string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.ingredients, code)
+}
+
+// list - the ingredients in code order
+func (c *catalog) list() []*hrsmodel.Ingredient {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ingredients := make([]*hrsmodel.Ingredient, 0, len(c.ingredients))
+	for _, ingredient := range c.ingredients {
+		ingredients = append(ingredients, ingredient)
+	}
+	sort.Slice(ingredients, func(i, j int) bool { return ingredients[i].Code < ingredients[j].Code })
+	return ingredients
+}
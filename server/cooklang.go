@@ -0,0 +1,43 @@
+package server
+
+import (
+	"io/ioutil"
+	"net/http"
+
+	"github.com/ninh0gauch0/homerecipes/hrscooklang"
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/hrstypes"
+)
+
+// importCooklang - creates the recipe of the Cooklang body
+func (s *Server) importCooklang(w http.ResponseWriter, r *http.Request) {
+	s.logger.Debugln("importing Cooklang recipe...")
+	defer r.Body.Close()
+
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.writeResponse(w, generateErrorResponse(FAIL, err.Error(), hrstypes.FunctionalError{}, http.StatusConflict), "")
+		return
+	}
+
+	recipe, err := hrscooklang.Parse(string(data))
+	if err != nil {
+		s.writeResponse(w, generateErrorResponse(FAIL, err.Error(), hrstypes.FunctionalError{}, http.StatusConflict), "")
+		return
+	}
+	s.writeResponse(w, s.tenant(r).ImportRecipes([]*hrsmodel.Recipe{recipe}), "Recipes imported")
+}
+
+// writeCooklang - writes the recipe as Cooklang
+func (s *Server) writeCooklang(w http.ResponseWriter, r *http.Request, recipe *hrsmodel.Recipe) {
+	s.customInfoLogger("Recipe returned as Cooklang:\n%s", recipe.GetObjectInfo())
+	w.Header().Set("Content-Type", hrscooklang.MEDIATYPE+"; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(s.tenant(r).RecipeCooklang(recipe)))
+}
+
+// RecipeCooklang - the recipe in Cooklang, lines written with their
+// ingredient names
+func (w *Worker) RecipeCooklang(recipe *hrsmodel.Recipe) string {
+	return hrscooklang.Format(recipe, w.lineIngredients(recipe))
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ninh0gauch0/homerecipes/hrscooklang"
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/hrstypes"
+)
+
+func TestWorker_RecipeCooklang(t *testing.T) {
+	w := newTestWorker(t)
+	w.CreateIngredient(&hrsmodel.Ingredient{Ingredient: hrstypes.Ingredient{Code: "i-flour", Name: "harina"}})
+
+	recipe, err := hrscooklang.Parse(">> title: Crepes\n>> servings: 4\n\nBatir @harina{250%g} con @huevos{3} y @leche{500%ml}.\n\nCuajar en #sartén.\n")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	rsp := w.ImportRecipes([]*hrsmodel.Recipe{recipe})
+	if rsp.Status.Code != http.StatusCreated {
+		t.Fatalf("ImportRecipes() = %+v", rsp)
+	}
+	created := rsp.RespObj.(*RecipeImport).Items[0]
+	if created.Lines[0].Ingredient != "i-flour" || created.Lines[1].Ingredient == "" || len(created.Mentions) != 4 {
+		t.Errorf("ImportRecipes() = %+v", created)
+	}
+
+	got := w.GetRecipeViewByID(created.Code, RecipeView{Servings: 2})
+	if got.Error != nil {
+		t.Fatalf("GetRecipeViewByID() = %+v", got)
+	}
+	text := w.RecipeCooklang(got.RespObj.(*hrsmodel.Recipe))
+	if !strings.Contains(text, "Batir @harina{125%g} con @huevos{1 1/2} y @leche{250%ml}.") || !strings.Contains(text, "#sartén") {
+		t.Errorf("RecipeCooklang() =\n%s", text)
+	}
+}
@@ -0,0 +1,630 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/homerecipes/hrsstore"
+	"github.com/ninh0gauch0/hrstypes"
+)
+
+const (
+	// FORBIDDEN Constant
+	FORBIDDEN = "Forbidden"
+	// HOUSEHOLDHEADER Constant
+	HOUSEHOLDHEADER = "X-Household"
+	// INVITATIONTTL Constant
+	INVITATIONTTL = 7 * 24 * time.Hour
+)
+
+var (
+	errNotMember     = errors.New("not a member of the household")
+	errNotOwner      = errors.New("only the owners can manage the household")
+	errNoHousehold   = errors.New("not a member of any household, create one or accept an invitation")
+	errPickHousehold = errors.New("member of several households, pick one with the " + HOUSEHOLDHEADER + " header")
+	errLastOwner     = errors.New("the household would be left without owner")
+)
+
+// workerKey - the worker of the household in the request context
+const workerKey = contextKey("worker")
+
+// workerStart - the worker of a household, started by its first request
+// while the others wait for it; closed once the household is deleted
+type workerStart struct {
+	once   sync.Once
+	worker *Worker
+	err    error
+	closed bool
+}
+
+// viewerPosts - the POST routes which don't change anything, viewers may
+// use them
+var viewerPosts = map[string]bool{
+	"/hrs/recipes/match":          true,
+	"/hrs/parse/ingredient-lines": true,
+}
+
+// writerGets - the GET routes revealing what only writers may see, the
+// share links hand the recipe to anyone holding them
+var writerGets = map[string]bool{
+	"/hrs/recipes/{id}/shares": true,
+}
+
+// addHouseholdRoutes - households, their members and invitations
+func (s *Server) addHouseholdRoutes(accountRoutes *mux.Router) {
+	accountRoutes.HandleFunc("/households", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("creating household...")
+
+		var household hrsmodel.Household
+		if s.member(w, r) && s.decodeBody(w, r, &household) {
+			s.writeResponse(w, s.worker.CreateHousehold(currentUser(r), household.Name), "Household created")
+		}
+	}).Methods("POST")
+
+	accountRoutes.HandleFunc("/households", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("listing households...")
+		if s.member(w, r) {
+			s.writeResponse(w, s.worker.ListHouseholds(currentUser(r)), "Households returned")
+		}
+	}).Methods("GET")
+
+	accountRoutes.HandleFunc("/households/{id}", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("searching household...")
+		if s.member(w, r) {
+			s.writeResponse(w, s.worker.GetHouseholdByID(currentUser(r), mux.Vars(r)["id"]), "Household returned")
+		}
+	}).Methods("GET")
+
+	accountRoutes.HandleFunc("/households/{id}", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("deleting household...")
+		if s.member(w, r) {
+			id := mux.Vars(r)["id"]
+			rsp := s.worker.DeleteHousehold(currentUser(r), id)
+			if rsp.Error == nil {
+				s.forgetHousehold(id)
+			}
+			s.writeResponse(w, rsp, "Household deleted")
+		}
+	}).Methods("DELETE")
+
+	accountRoutes.HandleFunc("/households/{id}/members/{user}", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("changing member role...")
+
+		var member hrsmodel.Member
+		if s.member(w, r) && s.decodeBody(w, r, &member) {
+			vars := mux.Vars(r)
+			s.writeResponse(w, s.worker.SetMemberRole(currentUser(r), vars["id"], vars["user"], member.Role), "Member role changed")
+		}
+	}).Methods("PATCH")
+
+	accountRoutes.HandleFunc("/households/{id}/members/{user}", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("removing member...")
+		if s.member(w, r) {
+			vars := mux.Vars(r)
+			s.writeResponse(w, s.worker.RemoveMember(currentUser(r), vars["id"], vars["user"]), "Member removed")
+		}
+	}).Methods("DELETE")
+
+	accountRoutes.HandleFunc("/households/{id}/invitations", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("inviting to household...")
+
+		var invitation hrsmodel.Invitation
+		if s.member(w, r) && s.decodeBody(w, r, &invitation) {
+			invitation.Household = mux.Vars(r)["id"]
+			s.writeResponse(w, s.worker.CreateInvitation(currentUser(r), &invitation), "Invitation created")
+		}
+	}).Methods("POST")
+
+	accountRoutes.HandleFunc("/households/{id}/invitations", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("listing invitations...")
+		if s.member(w, r) {
+			s.writeResponse(w, s.worker.ListInvitations(currentUser(r), mux.Vars(r)["id"]), "Invitations returned")
+		}
+	}).Methods("GET")
+
+	accountRoutes.HandleFunc("/households/{id}/invitations/{code}", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("revoking invitation...")
+		if s.member(w, r) {
+			vars := mux.Vars(r)
+			s.writeResponse(w, s.worker.DeleteInvitation(currentUser(r), vars["id"], vars["code"]), "Invitation revoked")
+		}
+	}).Methods("DELETE")
+
+	accountRoutes.HandleFunc("/invitations/{code}/accept", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("accepting invitation...")
+		if s.member(w, r) {
+			s.writeResponse(w, s.worker.AcceptInvitation(currentUser(r), mux.Vars(r)["code"]), "Invitation accepted")
+		}
+	}).Methods("POST")
+}
+
+// scope - finds out the household of the request, the one named by the
+// X-Household header or the only one of the user, checks the role lets
+// the request through and hands the handlers the worker of the household.
+// Anonymous requests read the household configured for them. When the
+// store keeps no users nor households, every request acts on the data
+// stored before there were households.
+func (s *Server) scope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.accountless {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), workerKey, s.worker)))
+			return
+		}
+
+		code, role := s.anonymousHousehold, hrsmodel.VIEWER
+		if user := currentUser(r); user != nil {
+			household, err := s.worker.UserHousehold(user, requestHousehold(r))
+			if err != nil {
+				s.writeResponse(w, householdErrorResponse(err), "")
+				return
+			}
+			code, role = household.Code, household.Role(user.Code)
+		}
+
+		if code == "" {
+			s.unauthorized(w)
+			return
+		}
+		if !hrsmodel.CanWrite(role) && !readOnly(r) {
+			funcErr := hrstypes.FunctionalError{}
+			s.writeResponse(w, generateErrorResponse(FORBIDDEN, "Viewers can only read the household", funcErr, http.StatusForbidden), "")
+			return
+		}
+
+		worker, err := s.householdWorker(code)
+		if err != nil {
+			s.writeResponse(w, storeErrorResponse(err, "Household can't be opened", "Fatal error trying to open the household: "), "")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), workerKey, worker)))
+	})
+}
+
+// tenant - the worker of the household the request acts on
+func (s *Server) tenant(r *http.Request) *Worker {
+	return r.Context().Value(workerKey).(*Worker)
+}
+
+// UserHousehold - the household a user acts on: the given one, which they
+// must be a member of, or their only one when none is given
+func (w *Worker) UserHousehold(user *hrsmodel.User, code string) (*hrsmodel.Household, error) {
+	if code != "" {
+		return w.memberHousehold(user, code, false)
+	}
+
+	households, err := w.store.ListHouseholds(user.Code)
+	if err != nil {
+		return nil, err
+	}
+	switch len(households) {
+	case 0:
+		return nil, errNoHousehold
+	case 1:
+		return households[0], nil
+	default:
+		return nil, errPickHousehold
+	}
+}
+
+// CreateHousehold - Creates a household owned by the user
+func (w *Worker) CreateHousehold(user *hrsmodel.User, name string) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - CreateHousehold [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	household := &hrsmodel.Household{
+		Name:    strings.TrimSpace(name),
+		Members: []hrsmodel.Member{{User: user.Code, Username: user.Username, Role: hrsmodel.OWNER}},
+	}
+	if err := household.Validate(); err != nil {
+		funcErr := hrstypes.FunctionalError{}
+		return generateErrorResponse(FAIL, err.Error(), funcErr, http.StatusConflict)
+	}
+
+	code, err := newUUID()
+	if err != nil {
+		return generateErrorResponse(TECHNICAL, "Fatal error generating code: "+err.Error(), err, http.StatusInternalServerError)
+	}
+	household.Code = code
+
+	if err = w.store.InsertHousehold(household); err != nil {
+		w.logger.Errorf("Worker - CreateHousehold - Error: " + err.Error())
+		return storeErrorResponse(err, "Insertion can't be accomplished", "Fatal error trying to insert: ")
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusCreated,
+		Description: CREATED,
+	}
+	rsp.RespObj = household
+	rsp.SetError(nil)
+
+	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
+	w.logger.Debugf("Worker - CreateHousehold [OUT]")
+	return rsp
+}
+
+// ListHouseholds - Returns the households of the user
+func (w *Worker) ListHouseholds(user *hrsmodel.User) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - ListHouseholds [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	households, err := w.store.ListHouseholds(user.Code)
+	if err != nil {
+		w.logger.Errorf("Worker - ListHouseholds - Error: " + err.Error())
+		return storeErrorResponse(err, "Query can't be accomplished", "Fatal error trying to list: ")
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: QUERIED,
+	}
+	rsp.RespObj = &Households{Items: households}
+	rsp.SetError(nil)
+
+	w.logger.Debugf("Worker - ListHouseholds [OUT]")
+	return rsp
+}
+
+// GetHouseholdByID - Given an id, returns a household of the user
+func (w *Worker) GetHouseholdByID(user *hrsmodel.User, id string) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - GetHouseholdByID [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	household, err := w.memberHousehold(user, id, false)
+	if err != nil {
+		w.logger.Errorf("Worker - GetHouseholdByID - Error: " + err.Error())
+		return householdErrorResponse(err)
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: QUERIED,
+	}
+	rsp.RespObj = household
+	rsp.SetError(nil)
+
+	w.logger.Debugf("Worker - GetHouseholdByID [OUT]")
+	return rsp
+}
+
+// DeleteHousehold - Removes a household the user owns and all its data
+func (w *Worker) DeleteHousehold(user *hrsmodel.User, id string) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - DeleteHousehold [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	household, err := w.memberHousehold(user, id, true)
+	if err == nil {
+		err = w.store.DeleteHousehold(id)
+	}
+	if err != nil {
+		w.logger.Errorf("Worker - DeleteHousehold - Error: " + err.Error())
+		return householdErrorResponse(err)
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: REMOVED,
+	}
+	rsp.RespObj = household
+	rsp.SetError(nil)
+
+	w.logger.Debugf("Worker - DeleteHousehold [OUT]")
+	return rsp
+}
+
+// SetMemberRole - Changes the role of a member, the user must own the
+// household
+func (w *Worker) SetMemberRole(user *hrsmodel.User, id string, member string, role string) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - SetMemberRole [IN]")
+
+	household, err := w.memberHousehold(user, id, true)
+	if err != nil {
+		w.logger.Errorf("Worker - SetMemberRole - Error: " + err.Error())
+		return householdErrorResponse(err)
+	}
+	m := household.Member(member)
+	if m == nil {
+		return householdErrorResponse(errNotMember)
+	}
+	m.Role = role
+
+	w.logger.Debugf("Worker - SetMemberRole [OUT]")
+	return w.saveHousehold(household)
+}
+
+// RemoveMember - Takes a member out of a household; owners remove anyone,
+// the rest only themselves
+func (w *Worker) RemoveMember(user *hrsmodel.User, id string, member string) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - RemoveMember [IN]")
+
+	household, err := w.memberHousehold(user, id, member != user.Code)
+	if err == nil && !household.RemoveMember(member) {
+		err = errNotMember
+	}
+	if err != nil {
+		w.logger.Errorf("Worker - RemoveMember - Error: " + err.Error())
+		return householdErrorResponse(err)
+	}
+
+	w.logger.Debugf("Worker - RemoveMember [OUT]")
+	return w.saveHousehold(household)
+}
+
+// CreateInvitation - Invites to join a household the user owns, for
+// INVITATIONTTL unless the invitation says until when
+func (w *Worker) CreateInvitation(user *hrsmodel.User, invitation *hrsmodel.Invitation) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - CreateInvitation [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	if _, err := w.memberHousehold(user, invitation.Household, true); err != nil {
+		w.logger.Errorf("Worker - CreateInvitation - Error: " + err.Error())
+		return householdErrorResponse(err)
+	}
+	if err := invitation.Validate(); err != nil {
+		funcErr := hrstypes.FunctionalError{}
+		return generateErrorResponse(FAIL, err.Error(), funcErr, http.StatusConflict)
+	}
+
+	code, err := newUUID()
+	if err != nil {
+		return generateErrorResponse(TECHNICAL, "Fatal error generating code: "+err.Error(), err, http.StatusInternalServerError)
+	}
+	invitation.Code = code
+	invitation.InvitedBy = user.Username
+	if invitation.Expires.IsZero() {
+		invitation.Expires = time.Now().Add(INVITATIONTTL).Truncate(time.Second)
+	}
+
+	if err = w.store.InsertInvitation(invitation); err != nil {
+		w.logger.Errorf("Worker - CreateInvitation - Error: " + err.Error())
+		return storeErrorResponse(err, "Insertion can't be accomplished", "Fatal error trying to insert: ")
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusCreated,
+		Description: CREATED,
+	}
+	rsp.RespObj = invitation
+	rsp.SetError(nil)
+
+	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
+	w.logger.Debugf("Worker - CreateInvitation [OUT]")
+	return rsp
+}
+
+// ListInvitations - Returns the pending invitations to a household the
+// user owns
+func (w *Worker) ListInvitations(user *hrsmodel.User, id string) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - ListInvitations [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	var invitations []*hrsmodel.Invitation
+	_, err := w.memberHousehold(user, id, true)
+	if err == nil {
+		invitations, err = w.store.ListInvitations(id)
+	}
+	if err != nil {
+		w.logger.Errorf("Worker - ListInvitations - Error: " + err.Error())
+		return householdErrorResponse(err)
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: QUERIED,
+	}
+	rsp.RespObj = &Invitations{Items: invitations}
+	rsp.SetError(nil)
+
+	w.logger.Debugf("Worker - ListInvitations [OUT]")
+	return rsp
+}
+
+// DeleteInvitation - Revokes an invitation to a household the user owns
+func (w *Worker) DeleteInvitation(user *hrsmodel.User, id string, code string) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - DeleteInvitation [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	var invitation *hrsmodel.Invitation
+	_, err := w.memberHousehold(user, id, true)
+	if err == nil {
+		invitation, err = w.store.GetInvitation(code)
+	}
+	if err == nil && invitation.Household != id {
+		err = hrsstore.ErrNotFound
+	}
+	if err == nil {
+		err = w.store.DeleteInvitation(code)
+	}
+	if err != nil {
+		w.logger.Errorf("Worker - DeleteInvitation - Error: " + err.Error())
+		return householdErrorResponse(err)
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: REMOVED,
+	}
+	rsp.RespObj = invitation
+	rsp.SetError(nil)
+
+	w.logger.Debugf("Worker - DeleteInvitation [OUT]")
+	return rsp
+}
+
+// AcceptInvitation - Joins the user to the household of an invitation,
+// which is used up
+func (w *Worker) AcceptInvitation(user *hrsmodel.User, code string) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - AcceptInvitation [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	invitation, err := w.store.GetInvitation(code)
+	if err != nil {
+		w.logger.Errorf("Worker - AcceptInvitation - Error: " + err.Error())
+		return storeErrorResponse(err, "Invitation not found", "Fatal error trying to accept: ")
+	}
+	if invitation.Expired(time.Now()) || (invitation.Username != "" && !strings.EqualFold(invitation.Username, user.Username)) {
+		funcErr := hrstypes.FunctionalError{}
+		return generateErrorResponse(FAIL, "The invitation expired or is for someone else", funcErr, http.StatusConflict)
+	}
+
+	household, err := w.store.AcceptInvitation(code, hrsmodel.Member{User: user.Code, Username: user.Username})
+	if err != nil {
+		w.logger.Errorf("Worker - AcceptInvitation - Error: " + err.Error())
+		return storeErrorResponse(err, "Invitation can't be accepted, it may be used or you a member already", "Fatal error trying to accept: ")
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: PATCHED,
+	}
+	rsp.RespObj = household
+	rsp.SetError(nil)
+
+	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
+	w.logger.Debugf("Worker - AcceptInvitation [OUT]")
+	return rsp
+}
+
+/** PRIVATE METHODS **/
+
+// member - whether the request comes from a user, writing the
+// unauthorized response when it doesn't
+func (s *Server) member(w http.ResponseWriter, r *http.Request) bool {
+	if currentUser(r) == nil {
+		s.unauthorized(w)
+		return false
+	}
+	return true
+}
+
+// householdWorker - the worker over the store of a household, started on
+// its first request. Starting it indexes the household recipes, so it is
+// done out of the lock, once, while the other households go on.
+func (s *Server) householdWorker(code string) (*Worker, error) {
+	s.mu.Lock()
+	start, ok := s.workers[code]
+	if !ok {
+		if s.workers == nil {
+			s.workers = map[string]*workerStart{}
+		}
+		start = &workerStart{}
+		s.workers[code] = start
+	}
+	s.mu.Unlock()
+
+	start.once.Do(func() {
+		store, err := s.store.Scope(code)
+		if err != nil {
+			start.err = err
+			return
+		}
+		worker := &Worker{}
+		worker.Init(s.Ctx, s.GetLogger().WithField("household", code), store)
+		worker.household = code
+		start.worker = worker
+	})
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if start.closed {
+		return nil, hrsstore.ErrNotFound
+	}
+	if start.err != nil {
+		// The next request tries again
+		if s.workers[code] == start {
+			delete(s.workers, code)
+		}
+		return nil, start.err
+	}
+	return start.worker, nil
+}
+
+// forgetHousehold - drops the worker of a deleted household. The requests
+// still holding it can't write anymore, the store turns them down.
+func (s *Server) forgetHousehold(code string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if start, ok := s.workers[code]; ok {
+		start.closed = true
+		delete(s.workers, code)
+	}
+}
+
+// readOnly - whether the request only reads household data viewers may
+// read
+func readOnly(r *http.Request) bool {
+	template, err := mux.CurrentRoute(r).GetPathTemplate()
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return err != nil || !writerGets[template]
+	}
+	return err == nil && r.Method == http.MethodPost && viewerPosts[template]
+}
+
+// requestHousehold - the household named by the X-Household header, or by
+// the "household" query parameter on the feed routes
+func requestHousehold(r *http.Request) string {
+	if code := r.Header.Get(HOUSEHOLDHEADER); code != "" || !feedRoute(r) {
+		return code
+	}
+	return r.URL.Query().Get("household")
+}
+
+// memberHousehold - a household the user is a member of, or an owner when
+// owner is set
+func (w *Worker) memberHousehold(user *hrsmodel.User, id string, owner bool) (*hrsmodel.Household, error) {
+	household, err := w.store.GetHousehold(id)
+	if errors.Is(err, hrsstore.ErrNotFound) {
+		return nil, errNotMember
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch role := household.Role(user.Code); {
+	case role == "":
+		return nil, errNotMember
+	case owner && role != hrsmodel.OWNER:
+		return nil, errNotOwner
+	}
+	return household, nil
+}
+
+// saveHousehold - stores the household members once validated
+func (w *Worker) saveHousehold(household *hrsmodel.Household) hrstypes.HRAResponse {
+	rsp := hrstypes.HRAResponse{}
+
+	if err := household.Validate(); err != nil {
+		funcErr := hrstypes.FunctionalError{}
+		return generateErrorResponse(FAIL, err.Error(), funcErr, http.StatusConflict)
+	}
+	if err := w.store.SaveHousehold(household); err != nil {
+		w.logger.Errorf("Worker - saveHousehold - Error: " + err.Error())
+		return storeErrorResponse(err, "Update can't be accomplished", "Fatal error trying to update: ")
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: PATCHED,
+	}
+	rsp.RespObj = household
+	rsp.SetError(nil)
+	return rsp
+}
+
+// householdErrorResponse - the response to a household which can't be
+// used: forbidden to non members, and to non owners when managing it
+func householdErrorResponse(err error) hrstypes.HRAResponse {
+	funcErr := hrstypes.FunctionalError{}
+	switch err {
+	case errNotMember, errNotOwner, errNoHousehold:
+		return generateErrorResponse(FORBIDDEN, err.Error(), funcErr, http.StatusForbidden)
+	case errPickHousehold, errLastOwner:
+		return generateErrorResponse(FAIL, err.Error(), funcErr, http.StatusConflict)
+	}
+	return storeErrorResponse(err, "Operation can't be accomplished", "Fatal error trying to use the household: ")
+}
@@ -0,0 +1,197 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/homerecipes/hrsstore"
+)
+
+func TestServer_households(t *testing.T) {
+	s := newTestServer(t)
+	s.worker.CreateUser("bea", "correct horse")
+	ana := s.worker.Login("ana", "correct horse").RespObj.(*Session).Token
+	bea := s.worker.Login("bea", "correct horse").RespObj.(*Session).Token
+
+	if rec := serve(s, "GET", "/hrs/recipes", "", ana); rec.Code != http.StatusForbidden {
+		t.Errorf("GET without household = %d", rec.Code)
+	}
+
+	household := func(token string, name string) string {
+		rec := serve(s, "POST", "/hrs/households", `{"name":"`+name+`"}`, token)
+		var rsp struct {
+			RespObj hrsmodel.Household `json:"respObj"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &rsp); err != nil || rec.Code != http.StatusCreated {
+			t.Fatalf("POST /households = %d %s", rec.Code, rec.Body.String())
+		}
+		return rsp.RespObj.Code
+	}
+	anaHome, beaHome := household(ana, "Casa de Ana"), household(bea, "Casa de Bea")
+
+	rec := serve(s, "POST", "/hrs/ingredients", `{"code":"i-egg","name":"huevo"}`, ana)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST /ingredients = %d %s", rec.Code, rec.Body.String())
+	}
+	rec = serve(s, "POST", "/hrs/recipes", `{"name":"Tortilla","lines":[{"ingredient":"i-egg","quantity":4}]}`, ana)
+	var created struct {
+		RespObj hrsmodel.Recipe `json:"respObj"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil || rec.Code != http.StatusCreated {
+		t.Fatalf("POST /recipes = %d %s", rec.Code, rec.Body.String())
+	}
+	recipe := "/hrs/recipes/" + created.RespObj.Code
+
+	// Bea neither sees the recipe from her household nor can pick Ana's
+	if rec = serve(s, "GET", recipe, "", bea); rec.Code != http.StatusConflict {
+		t.Errorf("GET of another household recipe = %d", rec.Code)
+	}
+	if rec = serve(s, "PATCH", recipe, `{"name":"Mine"}`, bea); rec.Code != http.StatusConflict {
+		t.Errorf("PATCH of another household recipe = %d", rec.Code)
+	}
+	if rec = serve(s, "GET", recipe, "", bea, HOUSEHOLDHEADER, anaHome); rec.Code != http.StatusForbidden {
+		t.Errorf("GET picking another household = %d", rec.Code)
+	}
+	if rec = serve(s, "GET", "/hrs/households/"+anaHome, "", bea); rec.Code != http.StatusForbidden {
+		t.Errorf("GET /households of another household = %d", rec.Code)
+	}
+
+	// Invited as viewer, she reads Ana's household but doesn't change it
+	rec = serve(s, "POST", "/hrs/households/"+anaHome+"/invitations", `{"role":"viewer"}`, bea)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("POST /invitations by a non owner = %d", rec.Code)
+	}
+	rec = serve(s, "POST", "/hrs/households/"+anaHome+"/invitations", `{"role":"viewer","username":"bea"}`, ana)
+	var invitation struct {
+		RespObj hrsmodel.Invitation `json:"respObj"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &invitation); err != nil || rec.Code != http.StatusCreated {
+		t.Fatalf("POST /invitations = %d %s", rec.Code, rec.Body.String())
+	}
+	if rec = serve(s, "POST", "/hrs/invitations/"+invitation.RespObj.Code+"/accept", "", bea); rec.Code != http.StatusOK {
+		t.Fatalf("POST /accept = %d %s", rec.Code, rec.Body.String())
+	}
+
+	if rec = serve(s, "GET", "/hrs/recipes", "", bea); rec.Code != http.StatusConflict {
+		t.Errorf("GET in several households without header = %d", rec.Code)
+	}
+	if rec = serve(s, "GET", recipe, "", bea, HOUSEHOLDHEADER, anaHome); rec.Code != http.StatusOK {
+		t.Errorf("GET as viewer = %d", rec.Code)
+	}
+	if rec = serve(s, "POST", "/hrs/recipes/match", `{"ingredients":["i-egg"]}`, bea, HOUSEHOLDHEADER, anaHome); rec.Code != http.StatusOK {
+		t.Errorf("POST /recipes/match as viewer = %d %s", rec.Code, rec.Body.String())
+	}
+	if rec = serve(s, "DELETE", recipe, "", bea, HOUSEHOLDHEADER, anaHome); rec.Code != http.StatusForbidden {
+		t.Errorf("DELETE as viewer = %d", rec.Code)
+	}
+
+	user, _ := s.worker.TokenUser(bea)
+	if rec = serve(s, "PATCH", "/hrs/households/"+anaHome+"/members/"+user.Code, `{"role":"editor"}`, ana); rec.Code != http.StatusOK {
+		t.Fatalf("PATCH /members = %d %s", rec.Code, rec.Body.String())
+	}
+	if rec = serve(s, "DELETE", recipe, "", bea, HOUSEHOLDHEADER, anaHome); rec.Code != http.StatusNoContent {
+		t.Errorf("DELETE as editor = %d", rec.Code)
+	}
+
+	// Ana can't leave her household without owner, removing Bea drops hers
+	if rsp := s.worker.DeleteUserByName("ana"); rsp.Status.Code != http.StatusConflict {
+		t.Errorf("DeleteUserByName() of the last owner = %+v", rsp)
+	}
+	if rsp := s.worker.DeleteUserByName("bea"); rsp.Error != nil {
+		t.Fatalf("DeleteUserByName() = %+v", rsp)
+	}
+	if _, err := s.store.GetHousehold(beaHome); err == nil {
+		t.Errorf("GetHousehold() of a household left empty found it")
+	}
+	if household, _ := s.store.GetHousehold(anaHome); len(household.Members) != 1 {
+		t.Errorf("GetHousehold() members = %+v", household.Members)
+	}
+}
+
+// slowScope - a store whose household h1 takes until release to open
+type slowScope struct {
+	hrsstore.Store
+	release chan struct{}
+}
+
+func (s *slowScope) Scope(household string) (hrsstore.Store, error) {
+	if household == "h1" {
+		<-s.release
+	}
+	return s.Store.Scope(household)
+}
+
+func TestServer_householdWorker(t *testing.T) {
+	s := newTestServer(t)
+	store := &slowScope{Store: s.store, release: make(chan struct{})}
+	s.store = store
+
+	// A household starting doesn't hold up the others
+	workers := make(chan *Worker, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			worker, err := s.householdWorker("h1")
+			if err != nil {
+				t.Errorf("householdWorker(h1) error = %v", err)
+			}
+			workers <- worker
+		}()
+	}
+	if worker, err := s.householdWorker("h2"); err != nil || worker.household != "h2" {
+		t.Errorf("householdWorker(h2) = %v, %v", worker, err)
+	}
+	if _, _, err := s.startLogin(); err != nil {
+		t.Errorf("startLogin() while a household starts error = %v", err)
+	}
+
+	// It is started once, for every request waiting for it
+	close(store.release)
+	first, second := <-workers, <-workers
+	if first == nil || first != second || first.household != "h1" {
+		t.Errorf("householdWorker(h1) = %p and %p", first, second)
+	}
+}
+
+func TestServer_scope_accountless(t *testing.T) {
+	s := newTestServer(t)
+	s.accountless = true
+
+	if rec := serve(s, "POST", "/hrs/recipes", `{"name":"Tortilla","lines":["4 huevos"]}`, ""); rec.Code != http.StatusCreated {
+		t.Fatalf("POST /recipes without token = %d %s", rec.Code, rec.Body.String())
+	}
+	list, err := s.store.ListRecipes(hrsstore.Query{})
+	if err != nil || list.Total != 1 {
+		t.Errorf("unscoped recipes = %+v, %v, want the one created", list, err)
+	}
+}
+
+func TestServer_forgetHousehold(t *testing.T) {
+	s := newTestServer(t)
+	user, _ := s.store.GetUserByName("ana")
+	code := s.worker.CreateHousehold(user, "Casa").RespObj.(*hrsmodel.Household).Code
+	worker, err := s.householdWorker(code)
+	if err != nil {
+		t.Fatalf("householdWorker() error = %v", err)
+	}
+	start := s.workers[code]
+
+	// A request still holding the worker can't write after the deletion
+	if rsp := s.worker.DeleteHousehold(user, code); rsp.Error != nil {
+		t.Fatalf("DeleteHousehold() = %+v", rsp)
+	}
+	s.forgetHousehold(code)
+	if !start.closed {
+		t.Error("forgetHousehold() left the worker start open")
+	}
+	recipe := hrsmodel.Recipe{}
+	recipe.Name = "Tortilla"
+	if rsp := worker.CreateRecipe(&recipe); rsp.Error == nil {
+		t.Errorf("CreateRecipe() in a deleted household = %+v", rsp)
+	}
+	left, _ := s.store.Scope(code)
+	if list, err := left.ListRecipes(hrsstore.Query{}); err != nil || list.Total != 0 {
+		t.Errorf("recipes of a deleted household = %+v, %v", list, err)
+	}
+}
@@ -2,11 +2,17 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"sort"
+	"strings"
 
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/homerecipes/hrsparse"
+	"github.com/ninh0gauch0/homerecipes/hrssearch"
+	"github.com/ninh0gauch0/homerecipes/hrsstore"
 	"github.com/ninh0gauch0/hrstypes"
-	mongo "github.com/ninh0gauch0/mongoconnector"
 	uuid "github.com/satori/go.uuid"
 	log "github.com/sirupsen/logrus"
 )
@@ -27,51 +33,68 @@ const (
 	// OPNOTCOMPLETED Constant
 	OPNOTCOMPLETED = "Operation not completed"
 	// INGREDIENTCOLL Constant
-	INGREDIENTCOLL = "ingredients"
+	INGREDIENTCOLL = hrsstore.INGREDIENTCOLL
 	// RECIPECOLL Constant
-	RECIPECOLL = "recipes"
+	RECIPECOLL = hrsstore.RECIPECOLL
+	// MINSIMILARITY Constant
+	MINSIMILARITY = 0.3
+	// MAXSUGGESTIONS Constant
+	MAXSUGGESTIONS = 3
 )
 
-// Init - Starts the worker
-func (w *Worker) Init(ctx context.Context, logger *log.Entry) {
+// Init - Starts the worker over the given store
+func (w *Worker) Init(ctx context.Context, logger *log.Entry, store hrsstore.Store) {
 	w.SetLogger(logger)
 	w.Ctx = ctx
+	w.store = store
+	w.index = hrssearch.NewIndex()
+	w.matcher = hrssearch.NewIngredientIndex()
+	w.catalog = newCatalog()
+	w.indexRecipes()
+	w.loadCatalog()
 }
 
 // CreateRecipe - Creates a new recipe
-func (w *Worker) CreateRecipe(recipe *hrstypes.Recipe) hrstypes.HRAResponse {
+func (w *Worker) CreateRecipe(recipe *hrsmodel.Recipe) hrstypes.HRAResponse {
 	w.logger.Debugf("Worker - CreateRecipe [IN]")
 
 	rsp := hrstypes.HRAResponse{}
 
-	manager := mongo.Manager{
-		Ctx: w.Ctx,
+	if err := w.parseRawLines(recipe, false); err != nil {
+		w.logger.Errorf("Worker - CreateRecipe - Error: " + err.Error())
+		return storeErrorResponse(err, "Insertion can't be accomplished", "Fatal error trying to parse ingredients: ")
 	}
 
-	if manager.Init() {
-		res, err := manager.ExecuteInsert(RECIPECOLL, recipe)
+	if err := recipe.Validate(); err != nil {
+		funcErr := hrstypes.FunctionalError{}
+		return generateErrorResponse(FAIL, err.Error(), funcErr, http.StatusConflict)
+	}
+	recipe.Sync()
 
-		if err == nil {
-			if res == 0 {
-				rsp.Status = hrstypes.Status{
-					Code:        http.StatusCreated,
-					Description: CREATED,
-				}
-				rsp.RespObj = recipe
-				rsp.SetError(nil)
-			} else {
-				techErr := hrstypes.TechnicalError{}
-				return generateErrorResponse(OPNOTCOMPLETED, fmt.Sprintf("Insertion can't be accomplished"), techErr, http.StatusConflict)
-			}
-		} else {
-			w.logger.Errorf("Worker - CreateRecipe - Error: " + err.Error())
-			return generateErrorResponse(TECHNICAL, fmt.Sprintf("Fatal error trying to insert: "+err.Error()), err, http.StatusInternalServerError)
+	if recipe.Code == "" {
+		code, err := newUUID()
+		if err != nil {
+			return generateErrorResponse(TECHNICAL, "Fatal error generating code: "+err.Error(), err, http.StatusInternalServerError)
 		}
-	} else {
-		techErr := hrstypes.TechnicalError{}
-		return generateErrorResponse(TECHNICAL, fmt.Sprintf("Error trying to connect to database"), techErr, http.StatusInternalServerError)
+		recipe.Code = code
+	}
+
+	err := w.store.InsertRecipe(recipe)
+
+	if err != nil {
+		w.logger.Errorf("Worker - CreateRecipe - Error: " + err.Error())
+		return storeErrorResponse(err, "Insertion can't be accomplished", "Fatal error trying to insert: ")
 	}
 
+	w.indexRecipe(recipe)
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusCreated,
+		Description: CREATED,
+	}
+	rsp.RespObj = recipe
+	rsp.SetError(nil)
+
 	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
 	w.logger.Debugf("Worker - CreateRecipe [OUT]")
 	return rsp
@@ -87,47 +110,79 @@ func (w *Worker) GetRecipeByID(id string) hrstypes.HRAResponse {
 		return rsp
 	}
 
-	manager := mongo.Manager{
-		Ctx: w.Ctx,
+	res, err := w.store.GetRecipe(id)
+
+	if err != nil {
+		w.logger.Errorf("Worker - GetRecipebyId - Error: " + err.Error())
+		return storeErrorResponse(err, "Query can't be accomplished", "Fatal error trying to query: ")
 	}
 
-	if manager.Init() {
-		res, err := manager.ExecuteSearchByID(RECIPECOLL, id)
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: QUERIED,
+	}
+	rsp.RespObj = res
+	rsp.SetError(nil)
 
-		if err == nil {
-			if res != nil {
-				rsp.Status = hrstypes.Status{
-					Code:        http.StatusOK,
-					Description: QUERIED,
-				}
+	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
+	w.logger.Debugf("Worker - GetRecipebyId [OUT]")
+	return rsp
+}
 
-				// Casting MetadataObject to Recipe
-				//original := mngtypes.Recipe(res)
+// GetRecipeViewByID - Given an id, returns a copy of the recipe scaled to
+// the view servings and written in the view measurement system
+func (w *Worker) GetRecipeViewByID(id string, view RecipeView) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - GetRecipeViewByID [IN]")
 
-				//type assertion
-				//original, ok := res.(*mngtypes.Recipe)
-				rsp.RespObj = res
-				rsp.SetError(nil)
-			} else {
-				techErr := hrstypes.TechnicalError{}
-				return generateErrorResponse(OPNOTCOMPLETED, fmt.Sprintf("Query can't be accomplished"), techErr, http.StatusConflict)
-			}
-		} else {
-			w.logger.Errorf("Worker - GetRecipebyId - Error: " + err.Error())
-			return generateErrorResponse(TECHNICAL, fmt.Sprintf("Fatal error trying to query: "+err.Error()), err, http.StatusInternalServerError)
-		}
-	} else {
-		techErr := hrstypes.TechnicalError{}
-		return generateErrorResponse(TECHNICAL, fmt.Sprintf("Connection problem"), techErr, http.StatusInternalServerError)
+	rsp := w.GetRecipeByID(id)
+	if rsp.Error != nil {
+		return rsp
+	}
+
+	recipe := rsp.RespObj.(*hrsmodel.Recipe)
+	var err error
+
+	if view.Servings != 0 {
+		recipe, err = recipe.Scale(view.Servings)
+	}
+	if err == nil && view.Units != "" {
+		recipe, err = recipe.Convert(view.Units, w.densities(recipe))
 	}
 
+	if err != nil {
+		funcErr := hrstypes.FunctionalError{}
+		return generateErrorResponse(FAIL, err.Error(), funcErr, http.StatusConflict)
+	}
+	rsp.RespObj = recipe
+
 	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
-	w.logger.Debugf("Worker - GetRecipebyId [OUT]")
+	w.logger.Debugf("Worker - GetRecipeViewByID [OUT]")
+	return rsp
+}
+
+// GetRecipeNutritionByID - Given an id, returns the nutrition facts of the
+// recipe, in total and per serving, with the lines that couldn't be counted
+func (w *Worker) GetRecipeNutritionByID(id string) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - GetRecipeNutritionByID [IN]")
+
+	rsp := w.GetRecipeByID(id)
+	if rsp.Error != nil {
+		return rsp
+	}
+
+	recipe := rsp.RespObj.(*hrsmodel.Recipe)
+	rsp.RespObj = &RecipeNutrition{
+		Recipe:          recipe.Code,
+		NutritionReport: recipe.Nutrition(w.lineIngredients(recipe)),
+	}
+
+	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
+	w.logger.Debugf("Worker - GetRecipeNutritionByID [OUT]")
 	return rsp
 }
 
 // PatchRecipeByID - Given a id, a recipe is patched
-func (w *Worker) PatchRecipeByID(id string, recipe *hrstypes.Recipe) hrstypes.HRAResponse {
+func (w *Worker) PatchRecipeByID(id string, recipe *hrsmodel.Recipe) hrstypes.HRAResponse {
 	w.logger.Debugf("Worker - PatchRecipeByID [IN]")
 	rsp := hrstypes.HRAResponse{}
 
@@ -136,33 +191,37 @@ func (w *Worker) PatchRecipeByID(id string, recipe *hrstypes.Recipe) hrstypes.HR
 		rsp = generateErrorResponse(FAIL, fmt.Sprintf("Mandatory parameter %s", id), err, http.StatusConflict)
 		return rsp
 	}
-	manager := mongo.Manager{
-		Ctx: w.Ctx,
+
+	if err := w.parseRawLines(recipe, false); err != nil {
+		w.logger.Errorf("Worker - PatchRecipeByID - Error: " + err.Error())
+		return storeErrorResponse(err, "Patch can't be accomplished", "Fatal error trying to parse ingredients: ")
+	}
+
+	if err := recipe.Validate(); err != nil {
+		funcErr := hrstypes.FunctionalError{}
+		return generateErrorResponse(FAIL, err.Error(), funcErr, http.StatusConflict)
+	}
+	// A patch without lines keeps the stored ones, and their references
+	if recipe.Lines != nil {
+		recipe.Sync()
 	}
 
-	if manager.Init() {
-		res, err := manager.ExecuteUpdate(RECIPECOLL, id, recipe)
+	res, err := w.store.UpdateRecipe(id, recipe)
 
-		if err == nil {
-			if res != nil {
-				rsp.Status = hrstypes.Status{
-					Code:        http.StatusOK,
-					Description: PATCHED,
-				}
-				rsp.RespObj = res
-				rsp.SetError(nil)
-			} else {
-				techErr := hrstypes.TechnicalError{}
-				return generateErrorResponse(OPNOTCOMPLETED, fmt.Sprintf("Patch can't be accomplished"), techErr, http.StatusConflict)
-			}
-		} else {
-			w.logger.Errorf("Worker - PatchRecipeByID - Error: " + err.Error())
-			return generateErrorResponse(TECHNICAL, fmt.Sprintf("Fatal error trying to patch: "+err.Error()), err, http.StatusInternalServerError)
-		}
-	} else {
-		techErr := hrstypes.TechnicalError{}
-		return generateErrorResponse(TECHNICAL, fmt.Sprintf("Connection problem"), techErr, http.StatusInternalServerError)
+	if err != nil {
+		w.logger.Errorf("Worker - PatchRecipeByID - Error: " + err.Error())
+		return storeErrorResponse(err, "Patch can't be accomplished", "Fatal error trying to patch: ")
+	}
+
+	w.indexRecipe(res)
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: PATCHED,
 	}
+	rsp.RespObj = res
+	rsp.SetError(nil)
+
 	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
 	w.logger.Debugf("Worker - PatchRecipeByID [OUT]")
 	return rsp
@@ -179,72 +238,245 @@ func (w *Worker) DeleteRecipe(id string) hrstypes.HRAResponse {
 		return rsp
 	}
 
-	manager := mongo.Manager{
-		Ctx: w.Ctx,
-	}
+	err := w.store.DeleteRecipe(id)
 
-	if manager.Init() {
-		res, err := manager.ExecuteDelete(RECIPECOLL, id)
+	if err != nil {
+		w.logger.Errorf("Worker - DeleteRecipe - Error: " + err.Error())
+		return storeErrorResponse(err, "Remove can't be accomplished", "Fatal error trying to remove: ")
+	}
 
-		if err == nil {
-			if res == 0 {
-				rsp.Status = hrstypes.Status{
-					Code:        http.StatusNoContent,
-					Description: REMOVED,
-				}
-				rsp.RespObj = nil
-				rsp.SetError(nil)
-			} else {
-				techErr := hrstypes.TechnicalError{}
-				return generateErrorResponse(OPNOTCOMPLETED, fmt.Sprintf("Remove can't be accomplished"), techErr, http.StatusConflict)
-			}
-		} else {
-			w.logger.Errorf("Worker - DeleteRecipe - Error: " + err.Error())
-			return generateErrorResponse(TECHNICAL, fmt.Sprintf("Fatal error trying to remove"+err.Error()), err, http.StatusInternalServerError)
+	w.unindexRecipe(id)
+	if w.household != "" {
+		if err = w.store.DeleteShares(w.household, id); err != nil {
+			w.logger.Warnf("Worker - DeleteRecipe - share links of %s left: %s", id, err.Error())
 		}
-	} else {
-		techErr := hrstypes.TechnicalError{}
-		return generateErrorResponse(TECHNICAL, fmt.Sprintf("Connection problem"), techErr, http.StatusInternalServerError)
 	}
 
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusNoContent,
+		Description: REMOVED,
+	}
+	rsp.RespObj = nil
+	rsp.SetError(nil)
+
 	w.logger.Debugf(rsp.Status.GetObjectInfo())
 	w.logger.Debugf("Worker - DeleteRecipe [OUT]")
 	return rsp
 }
 
+// ListRecipes - Returns a page of the recipes matching the query
+func (w *Worker) ListRecipes(q hrsstore.Query) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - ListRecipes [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	res, err := w.store.ListRecipes(q)
+
+	if err != nil {
+		w.logger.Errorf("Worker - ListRecipes - Error: " + err.Error())
+		return storeErrorResponse(err, "Query can't be accomplished", "Fatal error trying to query: ")
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: QUERIED,
+	}
+	rsp.RespObj = res
+	rsp.SetError(nil)
+
+	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
+	w.logger.Debugf("Worker - ListRecipes [OUT]")
+	return rsp
+}
+
+// SearchRecipes - Full text search over names, descriptions and steps
+func (w *Worker) SearchRecipes(query string, limit int) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - SearchRecipes [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	if strings.TrimSpace(query) == "" {
+		err := hrstypes.FunctionalError{}
+		rsp = generateErrorResponse(FAIL, "Mandatory parameter q", err, http.StatusConflict)
+		return rsp
+	}
+	if limit <= 0 || limit > hrsstore.MAXLIMIT {
+		limit = hrsstore.DEFAULTLIMIT
+	}
+
+	res := &RecipeSearch{
+		Query: query,
+		Items: []RecipeHit{},
+	}
+
+	for _, hit := range w.index.Search(query, limit) {
+		recipe, err := w.store.GetRecipe(hit.ID)
+
+		if errors.Is(err, hrsstore.ErrNotFound) {
+			w.unindexRecipe(hit.ID)
+			continue
+		}
+		if err != nil {
+			w.logger.Errorf("Worker - SearchRecipes - Error: " + err.Error())
+			return storeErrorResponse(err, "Query can't be accomplished", "Fatal error trying to query: ")
+		}
+
+		res.Items = append(res.Items, RecipeHit{
+			Recipe:   recipe,
+			Score:    hit.Score,
+			Snippets: hit.Snippets,
+		})
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: QUERIED,
+	}
+	rsp.RespObj = res
+	rsp.SetError(nil)
+
+	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
+	w.logger.Debugf("Worker - SearchRecipes [OUT]")
+	return rsp
+}
+
+// MatchRecipes - Recipes that can be cooked with the available ingredients,
+// best covered first, along with what is missing for each one
+func (w *Worker) MatchRecipes(req *MatchRequest) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - MatchRecipes [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	if len(req.Ingredients) == 0 {
+		err := hrstypes.FunctionalError{}
+		rsp = generateErrorResponse(FAIL, "Mandatory parameter ingredients", err, http.StatusConflict)
+		return rsp
+	}
+
+	maxMissing := -1
+	if req.MaxMissing != nil {
+		maxMissing = *req.MaxMissing
+	}
+	limit := req.Limit
+	if limit <= 0 || limit > hrsstore.MAXLIMIT {
+		limit = hrsstore.DEFAULTLIMIT
+	}
+
+	res := &RecipeMatches{
+		Items: []RecipeMatch{},
+	}
+
+	for _, match := range w.matcher.Match(w.resolveIngredients(req.Ingredients), maxMissing) {
+		if len(res.Items) == limit {
+			break
+		}
+
+		recipe, err := w.store.GetRecipe(match.ID)
+
+		if errors.Is(err, hrsstore.ErrNotFound) {
+			w.unindexRecipe(match.ID)
+			continue
+		}
+		if err != nil {
+			w.logger.Errorf("Worker - MatchRecipes - Error: " + err.Error())
+			return storeErrorResponse(err, "Query can't be accomplished", "Fatal error trying to query: ")
+		}
+
+		res.Items = append(res.Items, RecipeMatch{
+			Recipe:   recipe,
+			Coverage: match.Coverage,
+			Missing:  match.Missing,
+		})
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: QUERIED,
+	}
+	rsp.RespObj = res
+	rsp.SetError(nil)
+
+	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
+	w.logger.Debugf("Worker - MatchRecipes [OUT]")
+	return rsp
+}
+
+// ParseIngredientLines - Parses free text ingredient lines, resolving their
+// names against the ingredients collection. Unknown names get suggestions,
+// or a new ingredient when req.Create is set.
+func (w *Worker) ParseIngredientLines(req *ParseRequest) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - ParseIngredientLines [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	if len(req.Lines) == 0 {
+		err := hrstypes.FunctionalError{}
+		rsp = generateErrorResponse(FAIL, "Mandatory parameter lines", err, http.StatusConflict)
+		return rsp
+	}
+
+	catalog := w.ingredientCatalog()
+	res := &ParsedLines{
+		Items: []ParsedLine{},
+	}
+
+	for _, text := range req.Lines {
+		line, suggestions, err := w.parseLine(text, catalog, req.Create)
+
+		if err != nil {
+			w.logger.Errorf("Worker - ParseIngredientLines - Error: " + err.Error())
+			return storeErrorResponse(err, "Parse can't be accomplished", "Fatal error trying to create ingredient: ")
+		}
+
+		res.Items = append(res.Items, ParsedLine{
+			Text:        text,
+			Line:        line,
+			Suggestions: suggestions,
+		})
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: QUERIED,
+	}
+	rsp.RespObj = res
+	rsp.SetError(nil)
+
+	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
+	w.logger.Debugf("Worker - ParseIngredientLines [OUT]")
+	return rsp
+}
+
 // CreateIngredient - creates an ingredient
-func (w *Worker) CreateIngredient(ingredient *hrstypes.Ingredient) hrstypes.HRAResponse {
+func (w *Worker) CreateIngredient(ingredient *hrsmodel.Ingredient) hrstypes.HRAResponse {
 	w.logger.Debugf("Worker - CreateIngredient [IN]")
 	rsp := hrstypes.HRAResponse{}
 
-	manager := mongo.Manager{
-		Ctx: w.Ctx,
+	if ingredient.Code == "" {
+		code, err := newUUID()
+		if err != nil {
+			return generateErrorResponse(TECHNICAL, "Fatal error generating code: "+err.Error(), err, http.StatusInternalServerError)
+		}
+		ingredient.Code = code
 	}
 
-	if manager.Init() {
-		res, err := manager.ExecuteInsert(INGREDIENTCOLL, ingredient)
+	if err := ingredient.Validate(); err != nil {
+		funcErr := hrstypes.FunctionalError{}
+		return generateErrorResponse(FAIL, err.Error(), funcErr, http.StatusConflict)
+	}
 
-		if err == nil {
-			if res == 0 {
-				rsp.Status = hrstypes.Status{
-					Code:        http.StatusCreated,
-					Description: CREATED,
-				}
-				rsp.RespObj = ingredient
-				rsp.SetError(nil)
-			} else {
-				techErr := hrstypes.TechnicalError{}
-				return generateErrorResponse(OPNOTCOMPLETED, fmt.Sprintf("Insertion can't be accomplished"), techErr, http.StatusConflict)
-			}
-		} else {
-			w.logger.Errorf("Worker - CreateIngredient - Error: " + err.Error())
-			return generateErrorResponse(TECHNICAL, fmt.Sprintf("Fatal error trying to insert: "+err.Error()), err, http.StatusInternalServerError)
-		}
-	} else {
-		techErr := hrstypes.TechnicalError{}
-		return generateErrorResponse(TECHNICAL, fmt.Sprintf("Connection problem"), techErr, http.StatusInternalServerError)
+	err := w.store.InsertIngredient(ingredient)
+
+	if err != nil {
+		w.logger.Errorf("Worker - CreateIngredient - Error: " + err.Error())
+		return storeErrorResponse(err, "Insertion can't be accomplished", "Fatal error trying to insert: ")
 	}
 
+	w.catalog.put(ingredient)
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusCreated,
+		Description: CREATED,
+	}
+	rsp.RespObj = ingredient
+	rsp.SetError(nil)
+
 	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
 	w.logger.Debugf("Worker - CreateIngredient [OUT]")
 	return rsp
@@ -261,40 +493,27 @@ func (w *Worker) GetIngredientByID(id string) hrstypes.HRAResponse {
 		return rsp
 	}
 
-	manager := mongo.Manager{
-		Ctx: w.Ctx,
-	}
+	res, err := w.store.GetIngredient(id)
 
-	if manager.Init() {
-		res, err := manager.ExecuteSearchByID(INGREDIENTCOLL, id)
+	if err != nil {
+		w.logger.Errorf("Worker - GetIngredientByID - Error: " + err.Error())
+		return storeErrorResponse(err, "Query can't be accomplished", "Fatal error trying to query: ")
+	}
 
-		if err == nil {
-			if res != nil {
-				rsp.Status = hrstypes.Status{
-					Code:        http.StatusOK,
-					Description: QUERIED,
-				}
-				rsp.RespObj = res
-				rsp.SetError(nil)
-			} else {
-				techErr := hrstypes.TechnicalError{}
-				return generateErrorResponse(OPNOTCOMPLETED, fmt.Sprintf("Query can't be accomplished"), techErr, http.StatusConflict)
-			}
-		} else {
-			w.logger.Errorf("Worker - GetIngredientByID - Error: " + err.Error())
-			return generateErrorResponse(TECHNICAL, fmt.Sprintf("Fatal error trying to query: "+err.Error()), err, http.StatusInternalServerError)
-		}
-	} else {
-		techErr := hrstypes.TechnicalError{}
-		return generateErrorResponse(TECHNICAL, fmt.Sprintf("Connection problem"), techErr, http.StatusInternalServerError)
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: QUERIED,
 	}
+	rsp.RespObj = res
+	rsp.SetError(nil)
+
 	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
 	w.logger.Debugf("Worker - GetIngredientByID [OUT]")
 	return rsp
 }
 
 // PatchIngredientByID - Given a id, an ingredient is patched
-func (w *Worker) PatchIngredientByID(id string, ingredient *hrstypes.Ingredient) hrstypes.HRAResponse {
+func (w *Worker) PatchIngredientByID(id string, ingredient *hrsmodel.Ingredient) hrstypes.HRAResponse {
 	w.logger.Debugf("Worker - PatchIngredientByID [IN]")
 	rsp := hrstypes.HRAResponse{}
 
@@ -304,36 +523,29 @@ func (w *Worker) PatchIngredientByID(id string, ingredient *hrstypes.Ingredient)
 		return rsp
 	}
 
-	manager := mongo.Manager{
-		Ctx: w.Ctx,
+	if err := ingredient.Validate(); err != nil {
+		funcErr := hrstypes.FunctionalError{}
+		return generateErrorResponse(FAIL, err.Error(), funcErr, http.StatusConflict)
 	}
 
-	if manager.Init() {
-		res, err := manager.ExecuteUpdate(INGREDIENTCOLL, id, ingredient)
+	res, err := w.store.UpdateIngredient(id, ingredient)
 
-		if err == nil {
-			if res != nil {
-				rsp.Status = hrstypes.Status{
-					Code:        http.StatusOK,
-					Description: PATCHED,
-				}
-				rsp.RespObj = res
-				rsp.SetError(nil)
-			} else {
-				techErr := hrstypes.TechnicalError{}
-				return generateErrorResponse(OPNOTCOMPLETED, fmt.Sprintf("Patch can't be accomplished"), techErr, http.StatusConflict)
-			}
-		} else {
-			w.logger.Errorf("Worker - PatchIngredientByID - Error: " + err.Error())
-			return generateErrorResponse(TECHNICAL, fmt.Sprintf("Fatal error trying to patch"+err.Error()), err, http.StatusInternalServerError)
-		}
-	} else {
-		techErr := hrstypes.TechnicalError{}
-		return generateErrorResponse(TECHNICAL, fmt.Sprintf("Connection problem"), techErr, http.StatusInternalServerError)
+	if err != nil {
+		w.logger.Errorf("Worker - PatchIngredientByID - Error: " + err.Error())
+		return storeErrorResponse(err, "Patch can't be accomplished", "Fatal error trying to patch: ")
 	}
 
+	w.catalog.put(res)
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: PATCHED,
+	}
+	rsp.RespObj = res
+	rsp.SetError(nil)
+
 	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
-	w.logger.Debugf("Worker - PatchIngredientByID [IN]")
+	w.logger.Debugf("Worker - PatchIngredientByID [OUT]")
 	return rsp
 }
 
@@ -348,41 +560,285 @@ func (w *Worker) DeleteIngredient(id string) hrstypes.HRAResponse {
 		return rsp
 	}
 
-	manager := mongo.Manager{
-		Ctx: w.Ctx,
+	err := w.store.DeleteIngredient(id)
+
+	if err != nil {
+		w.logger.Errorf("Worker - DeleteIngredient - Error: " + err.Error())
+		return storeErrorResponse(err, "Remove can't be accomplished", "Fatal error trying to remove: ")
 	}
 
-	if manager.Init() {
-		res, err := manager.ExecuteDelete(INGREDIENTCOLL, id)
+	w.catalog.remove(id)
 
-		if err == nil {
-			if res == 0 {
-				rsp.Status = hrstypes.Status{
-					Code:        http.StatusNoContent,
-					Description: REMOVED,
-				}
-				rsp.RespObj = nil
-				rsp.SetError(nil)
-			} else {
-				techErr := hrstypes.TechnicalError{}
-				return generateErrorResponse(OPNOTCOMPLETED, fmt.Sprintf("Remove can't be accomplished"), techErr, http.StatusConflict)
-			}
-		} else {
-			w.logger.Errorf("Worker - DeleteIngredient - Error: " + err.Error())
-			return generateErrorResponse(TECHNICAL, fmt.Sprintf("Fatal error trying to remove: "+err.Error()), err, http.StatusInternalServerError)
-		}
-	} else {
-		techErr := hrstypes.TechnicalError{}
-		return generateErrorResponse(TECHNICAL, fmt.Sprintf("Connection problem"), techErr, http.StatusInternalServerError)
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusNoContent,
+		Description: REMOVED,
 	}
+	rsp.RespObj = nil
+	rsp.SetError(nil)
 
 	w.logger.Debugf(rsp.Status.GetObjectInfo())
 	w.logger.Debugf("Worker - DeleteIngredient [OUT]")
 	return rsp
 }
 
+// ListIngredients - Returns a page of the ingredients matching the query
+func (w *Worker) ListIngredients(q hrsstore.Query) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - ListIngredients [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	res, err := w.store.ListIngredients(q)
+
+	if err != nil {
+		w.logger.Errorf("Worker - ListIngredients - Error: " + err.Error())
+		return storeErrorResponse(err, "Query can't be accomplished", "Fatal error trying to query: ")
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: QUERIED,
+	}
+	rsp.RespObj = res
+	rsp.SetError(nil)
+
+	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
+	w.logger.Debugf("Worker - ListIngredients [OUT]")
+	return rsp
+}
+
 /** PRIVATE METHODS **/
 
+// indexRecipes - fills the in-process indexes with every stored recipe
+func (w *Worker) indexRecipes() {
+	err := hrsstore.EachRecipe(w.store, func(recipe *hrsmodel.Recipe) error {
+		w.indexRecipe(recipe)
+		return nil
+	})
+
+	if err != nil {
+		w.logger.Warnf("Worker - indexRecipes - only new recipes will be searchable: " + err.Error())
+		return
+	}
+
+	w.logger.Debugf("Worker - indexRecipes - %d recipes indexed", w.index.Len())
+}
+
+// indexRecipe - keeps the in-process indexes in sync with a stored recipe
+func (w *Worker) indexRecipe(recipe *hrsmodel.Recipe) {
+	w.index.Add(recipe.Code, recipeFields(recipe))
+	w.matcher.Add(recipe.Code, recipe.Refs())
+}
+
+// unindexRecipe - drops a removed recipe from the in-process indexes
+func (w *Worker) unindexRecipe(id string) {
+	w.index.Remove(id)
+	w.matcher.Remove(id)
+}
+
+// resolveIngredients - the references an ingredient id or name may take
+// in recipes: the value itself, plus the name of the ingredient with that
+// id or the ids of the ingredients with that name
+func (w *Worker) resolveIngredients(values []string) []string {
+	refs := []string{}
+
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		refs = append(refs, value)
+
+		if ingredient, err := w.store.GetIngredient(value); err == nil {
+			refs = append(refs, ingredient.Name)
+			continue
+		}
+
+		list, err := w.store.ListIngredients(hrsstore.Query{NamePrefix: value, Limit: hrsstore.MAXLIMIT})
+		if err != nil {
+			continue
+		}
+		for _, ingredient := range list.Items {
+			if hrssearch.Fold(ingredient.Name) == hrssearch.Fold(value) {
+				refs = append(refs, ingredient.Code)
+			}
+		}
+	}
+	return refs
+}
+
+// parseRawLines - parses the free text lines of a recipe; with create the
+// ingredients not found are created, and lines only named get a reference
+// too
+func (w *Worker) parseRawLines(recipe *hrsmodel.Recipe, create bool) error {
+	var catalog []*hrsmodel.Ingredient
+
+	for i := range recipe.Lines {
+		named := create && recipe.Lines[i].Ingredient == "" && recipe.Lines[i].Name != ""
+		if !recipe.Lines[i].IsRaw() && !named {
+			continue
+		}
+		if catalog == nil {
+			catalog = w.ingredientCatalog()
+		}
+
+		line := recipe.Lines[i]
+		var err error
+		if named {
+			line.Ingredient, err = w.ingredientNamed(line.Name, catalog)
+		} else {
+			line, _, err = w.parseLine(line.Text, catalog, create)
+		}
+		if err != nil {
+			return err
+		}
+		if line.Ingredient != "" && !inCatalog(catalog, line.Ingredient) {
+			ingredient := &hrsmodel.Ingredient{Ingredient: hrstypes.Ingredient{Code: line.Ingredient, Name: line.Name}}
+			catalog = append(catalog, ingredient)
+		}
+		recipe.Lines[i] = line
+	}
+	return nil
+}
+
+// parseLine - parses a free text line and resolves its name. A text which
+// is an ingredient id is a legacy reference.
+func (w *Worker) parseLine(text string, catalog []*hrsmodel.Ingredient, create bool) (hrsmodel.IngredientLine, []IngredientSuggestion, error) {
+	suggestions := []IngredientSuggestion{}
+	text = strings.TrimSpace(text)
+
+	for _, ingredient := range catalog {
+		if ingredient.Code == text {
+			return hrsmodel.IngredientLine{Ingredient: text}, suggestions, nil
+		}
+	}
+
+	line := hrsparse.ParseLine(text)
+	line.Text = text
+	if line.Name == "" {
+		return line, suggestions, nil
+	}
+
+	for _, ingredient := range catalog {
+		score := hrssearch.Similarity(line.Name, ingredient.Name)
+		if score == 1 {
+			line.Ingredient = ingredient.Code
+			return line, []IngredientSuggestion{}, nil
+		}
+		if score >= MINSIMILARITY {
+			suggestions = append(suggestions, IngredientSuggestion{Code: ingredient.Code, Name: ingredient.Name, Score: score})
+		}
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		return suggestions[i].Score > suggestions[j].Score
+	})
+	if len(suggestions) > MAXSUGGESTIONS {
+		suggestions = suggestions[:MAXSUGGESTIONS]
+	}
+
+	if create {
+		code, err := newUUID()
+		if err != nil {
+			return line, suggestions, err
+		}
+
+		ingredient := &hrsmodel.Ingredient{Ingredient: hrstypes.Ingredient{Code: code, Name: line.Name}}
+		if err = w.store.InsertIngredient(ingredient); err != nil {
+			return line, suggestions, err
+		}
+		w.catalog.put(ingredient)
+		line.Ingredient = code
+	}
+	return line, suggestions, nil
+}
+
+// ingredientNamed - the code of the catalog ingredient with the name,
+// created when there's none
+func (w *Worker) ingredientNamed(name string, catalog []*hrsmodel.Ingredient) (string, error) {
+	for _, ingredient := range catalog {
+		if hrssearch.Similarity(name, ingredient.Name) == 1 {
+			return ingredient.Code, nil
+		}
+	}
+
+	code, err := newUUID()
+	if err != nil {
+		return "", err
+	}
+	ingredient := &hrsmodel.Ingredient{Ingredient: hrstypes.Ingredient{Code: code, Name: name}}
+	if err = w.store.InsertIngredient(ingredient); err != nil {
+		return "", err
+	}
+	w.catalog.put(ingredient)
+	return code, nil
+}
+
+// inCatalog - whether the ingredient code is in the catalog
+func inCatalog(catalog []*hrsmodel.Ingredient, code string) bool {
+	for _, ingredient := range catalog {
+		if ingredient.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// densities - the density of every referenced ingredient that has one
+func (w *Worker) densities(recipe *hrsmodel.Recipe) map[string]float64 {
+	densities := map[string]float64{}
+
+	for code, ingredient := range w.lineIngredients(recipe) {
+		if ingredient.Density > 0 {
+			densities[code] = ingredient.Density
+		}
+	}
+	return densities
+}
+
+// lineIngredients - the referenced ingredients of a recipe by code, those
+// which can't be read are left out
+func (w *Worker) lineIngredients(recipe *hrsmodel.Recipe) map[string]*hrsmodel.Ingredient {
+	ingredients := map[string]*hrsmodel.Ingredient{}
+
+	for _, line := range recipe.Lines {
+		if line.Ingredient == "" || ingredients[line.Ingredient] != nil {
+			continue
+		}
+		ingredient, err := w.store.GetIngredient(line.Ingredient)
+		if err != nil {
+			w.logger.Warnf("Worker - lineIngredients - ingredient %s: %s", line.Ingredient, err.Error())
+			continue
+		}
+		ingredients[line.Ingredient] = ingredient
+	}
+	return ingredients
+}
+
+// ingredientCatalog - every stored ingredient, as the worker keeps them
+func (w *Worker) ingredientCatalog() []*hrsmodel.Ingredient {
+	return w.catalog.list()
+}
+
+// loadCatalog - fills the catalog with every stored ingredient
+func (w *Worker) loadCatalog() {
+	err := hrsstore.EachIngredient(w.store, func(ingredient *hrsmodel.Ingredient) error {
+		w.catalog.put(ingredient)
+		return nil
+	})
+
+	if err != nil {
+		w.logger.Warnf("Worker - loadCatalog - only the names of new ingredients will be resolved: " + err.Error())
+	}
+}
+
+// recipeFields - the searchable text of a recipe, names weigh the most
+func recipeFields(recipe *hrsmodel.Recipe) []hrssearch.Field {
+	return []hrssearch.Field{
+		{Name: "name", Text: recipe.Name, Boost: 3},
+		{Name: "description", Text: recipe.Description, Boost: 2},
+		{Name: "steps", Text: strings.Join(recipe.Steps, "\n"), Boost: 1},
+	}
+}
+
 // newUUID generates a random UUID according to RFC 4122
 func newUUID() (string, error) {
 	UUID, err := uuid.NewV4()
@@ -393,6 +849,26 @@ func newUUID() (string, error) {
 	return UUID.String(), nil
 }
 
+// storeErrorResponse - translates a store error into a response
+func storeErrorResponse(err error, notCompleted string, fatal string) hrstypes.HRAResponse {
+	switch {
+	case errors.Is(err, hrsstore.ErrUnavailable):
+		techErr := hrstypes.TechnicalError{}
+		return generateErrorResponse(TECHNICAL, "Connection problem", techErr, http.StatusInternalServerError)
+	case errors.Is(err, hrsstore.ErrNotFound), errors.Is(err, hrsstore.ErrConflict):
+		techErr := hrstypes.TechnicalError{}
+		return generateErrorResponse(OPNOTCOMPLETED, notCompleted, techErr, http.StatusConflict)
+	case errors.Is(err, hrsstore.ErrInvalidQuery):
+		funcErr := hrstypes.FunctionalError{}
+		return generateErrorResponse(FAIL, err.Error(), funcErr, http.StatusConflict)
+	case errors.Is(err, hrsstore.ErrUnsupported):
+		techErr := hrstypes.TechnicalError{}
+		return generateErrorResponse(TECHNICAL, err.Error(), techErr, http.StatusNotImplemented)
+	default:
+		return generateErrorResponse(TECHNICAL, fatal+err.Error(), err, http.StatusInternalServerError)
+	}
+}
+
 // GenerateErrorResponse - generates a error response
 func generateErrorResponse(desc string, errorMsg string, err interface{}, status int) hrstypes.HRAResponse {
 	rsp := hrstypes.HRAResponse{}
@@ -0,0 +1,208 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/homerecipes/hrsstore"
+	"github.com/ninh0gauch0/hrstypes"
+	log "github.com/sirupsen/logrus"
+)
+
+// newTestWorker - a worker over an empty memory store
+func newTestWorker(t *testing.T) *Worker {
+	store, err := hrsstore.NewMemoryStore("")
+	if err != nil {
+		t.Fatalf("NewMemoryStore() error = %v", err)
+	}
+
+	logger := log.New()
+	logger.SetLevel(log.PanicLevel)
+
+	w := &Worker{}
+	w.Init(context.Background(), log.NewEntry(logger), store)
+	return w
+}
+
+// newRecipe - a recipe referencing the given ingredients
+func newRecipe(name string, refs ...string) *hrsmodel.Recipe {
+	return hrsmodel.FromLegacy(&hrstypes.Recipe{Name: name, Ingredients: refs})
+}
+
+func TestWorker_SearchRecipes(t *testing.T) {
+	w := newTestWorker(t)
+
+	recipe := newRecipe("Pulpo a feira")
+	recipe.Steps = []string{"Añadir pimentón"}
+	rsp := w.CreateRecipe(recipe)
+	if rsp.Status.Code != http.StatusCreated {
+		t.Fatalf("CreateRecipe() status = %d", rsp.Status.Code)
+	}
+	code := rsp.RespObj.(*hrsmodel.Recipe).Code
+
+	rsp = w.SearchRecipes("pimenton", 0)
+	if rsp.Status.Code != http.StatusOK || len(rsp.RespObj.(*RecipeSearch).Items) != 1 {
+		t.Fatalf("SearchRecipes() = %+v", rsp)
+	}
+
+	w.PatchRecipeByID(code, &hrsmodel.Recipe{Recipe: hrstypes.Recipe{Steps: []string{"Añadir sal"}}})
+	if items := w.SearchRecipes("pimenton", 0).RespObj.(*RecipeSearch).Items; len(items) != 0 {
+		t.Errorf("SearchRecipes() after patch = %+v", items)
+	}
+
+	w.DeleteRecipe(code)
+	if items := w.SearchRecipes("pulpo", 0).RespObj.(*RecipeSearch).Items; len(items) != 0 {
+		t.Errorf("SearchRecipes() after delete = %+v", items)
+	}
+
+	if rsp = w.SearchRecipes(" ", 0); rsp.Status.Code != http.StatusConflict {
+		t.Errorf("SearchRecipes() empty query status = %d", rsp.Status.Code)
+	}
+}
+
+func TestWorker_MatchRecipes(t *testing.T) {
+	w := newTestWorker(t)
+
+	w.CreateIngredient(&hrsmodel.Ingredient{Ingredient: hrstypes.Ingredient{Code: "i-egg", Name: "Huevo"}})
+	w.CreateRecipe(newRecipe("Tortilla", "i-egg", "patata", "cebolla"))
+	w.CreateRecipe(newRecipe("Huevo frito", "i-egg", "aceite"))
+
+	maxMissing := 1
+	rsp := w.MatchRecipes(&MatchRequest{Ingredients: []string{"huevo", "aceite", "patata"}, MaxMissing: &maxMissing})
+	if rsp.Status.Code != http.StatusOK {
+		t.Fatalf("MatchRecipes() status = %d", rsp.Status.Code)
+	}
+
+	items := rsp.RespObj.(*RecipeMatches).Items
+	if len(items) != 2 {
+		t.Fatalf("MatchRecipes() = %+v, want 2 recipes", items)
+	}
+	if items[0].Recipe.Name != "Huevo frito" || items[0].Coverage != 1 {
+		t.Errorf("MatchRecipes() best = %+v", items[0])
+	}
+	if len(items[1].Missing) != 1 || items[1].Missing[0] != "cebolla" {
+		t.Errorf("MatchRecipes() missing = %v, want [cebolla]", items[1].Missing)
+	}
+}
+
+func TestWorker_ParseIngredientLines(t *testing.T) {
+	w := newTestWorker(t)
+	w.CreateIngredient(&hrsmodel.Ingredient{Ingredient: hrstypes.Ingredient{Code: "i-flour", Name: "Harina de trigo"}})
+	w.CreateIngredient(&hrsmodel.Ingredient{Ingredient: hrstypes.Ingredient{Code: "i-sugar", Name: "Azúcar moreno"}})
+
+	rsp := w.ParseIngredientLines(&ParseRequest{Lines: []string{
+		"1 1/2 tazas de harina de trigo, tamizada",
+		"100 g de azúcar",
+		"2 huevos",
+	}, Create: true})
+	if rsp.Status.Code != http.StatusOK {
+		t.Fatalf("ParseIngredientLines() status = %d", rsp.Status.Code)
+	}
+
+	items := rsp.RespObj.(*ParsedLines).Items
+	if items[0].Line.Ingredient != "i-flour" || items[0].Line.Unit != "cup" {
+		t.Errorf("ParseIngredientLines() flour = %+v", items[0].Line)
+	}
+	if len(items[1].Suggestions) == 0 || items[1].Suggestions[0].Code != "i-sugar" {
+		t.Errorf("ParseIngredientLines() sugar suggestions = %+v", items[1].Suggestions)
+	}
+	if items[2].Line.Ingredient == "" {
+		t.Errorf("ParseIngredientLines() eggs not created = %+v", items[2].Line)
+	}
+
+	// Plain strings in a new recipe are parsed the same way, ids kept
+	recipe := newRecipe("Bizcocho")
+	recipe.Lines = []hrsmodel.IngredientLine{{Text: "i-sugar"}, {Text: "250 g harina de trigo"}}
+	rsp = w.CreateRecipe(recipe)
+	lines := rsp.RespObj.(*hrsmodel.Recipe).Lines
+	if lines[0].Ingredient != "i-sugar" || lines[1].Ingredient != "i-flour" || lines[1].Quantity.Value != 250 {
+		t.Errorf("CreateRecipe() lines = %+v", lines)
+	}
+}
+
+// listStore - a store counting the ingredient pages it lists
+type listStore struct {
+	hrsstore.Store
+	lists int
+}
+
+func (l *listStore) ListIngredients(q hrsstore.Query) (*hrsstore.IngredientList, error) {
+	l.lists++
+	return l.Store.ListIngredients(q)
+}
+
+func TestWorker_catalog(t *testing.T) {
+	memory, _ := hrsstore.NewMemoryStore("")
+	memory.InsertIngredient(&hrsmodel.Ingredient{Ingredient: hrstypes.Ingredient{Code: "i-salt", Name: "sal"}})
+	store := &listStore{Store: memory}
+	w := &Worker{}
+	w.Init(context.Background(), newTestWorker(t).logger, store)
+	listed := store.lists
+
+	resolved := func(text string) string {
+		return w.ParseIngredientLines(&ParseRequest{Lines: []string{text}}).RespObj.(*ParsedLines).Items[0].Line.Ingredient
+	}
+	if code := resolved("1 pizca de sal"); code != "i-salt" {
+		t.Errorf("stored ingredient resolved as %q", code)
+	}
+
+	// Ingredient writes reach the catalog, which isn't read again
+	w.CreateIngredient(&hrsmodel.Ingredient{Ingredient: hrstypes.Ingredient{Code: "i-flour", Name: "harina"}})
+	if code := resolved("200 g de harina"); code != "i-flour" {
+		t.Errorf("created ingredient resolved as %q", code)
+	}
+	patch := &hrsmodel.Ingredient{}
+	patch.Name = "harina de trigo"
+	w.PatchIngredientByID("i-flour", patch)
+	if code := resolved("200 g de harina de trigo"); code != "i-flour" {
+		t.Errorf("renamed ingredient resolved as %q", code)
+	}
+	w.DeleteIngredient("i-salt")
+	if code := resolved("1 pizca de sal"); code != "" {
+		t.Errorf("removed ingredient resolved as %q", code)
+	}
+	if store.lists != listed {
+		t.Errorf("ingredients listed %d times after the worker started", store.lists-listed)
+	}
+}
+
+// patchStore - a store recording the recipe patches it is given
+type patchStore struct {
+	hrsstore.Store
+	patches []*hrsmodel.Recipe
+}
+
+func (p *patchStore) UpdateRecipe(id string, recipe *hrsmodel.Recipe) (*hrsmodel.Recipe, error) {
+	p.patches = append(p.patches, recipe)
+	return p.Store.UpdateRecipe(id, recipe)
+}
+
+func TestWorker_PatchRecipeByID(t *testing.T) {
+	w := newTestWorker(t)
+	store := &patchStore{Store: w.store}
+	w.store = store
+	w.CreateIngredient(&hrsmodel.Ingredient{Ingredient: hrstypes.Ingredient{Code: "i-egg", Name: "huevo"}})
+	created := w.CreateRecipe(newRecipe("Tortilla", "i-egg")).RespObj.(*hrsmodel.Recipe)
+
+	// Only the name: the lines and the legacy references are left alone
+	patch := &hrsmodel.Recipe{}
+	patch.Name = "Tortilla de patatas"
+	rsp := w.PatchRecipeByID(created.Code, patch)
+	if rsp.Status.Code != http.StatusOK {
+		t.Fatalf("PatchRecipeByID() = %+v", rsp)
+	}
+	if patched := rsp.RespObj.(*hrsmodel.Recipe); patched.Name != "Tortilla de patatas" || len(patched.Lines) != 1 {
+		t.Errorf("PatchRecipeByID() of the name = %+v", patched)
+	}
+	if ingredients := store.patches[0].Recipe.Ingredients; ingredients != nil {
+		t.Errorf("PatchRecipeByID() of the name patched the ingredients with %v", ingredients)
+	}
+
+	// New lines replace the references
+	rsp = w.PatchRecipeByID(created.Code, newRecipe("", "i-salt"))
+	if ingredients := store.patches[1].Recipe.Ingredients; len(ingredients) != 1 || ingredients[0] != "i-salt" {
+		t.Errorf("PatchRecipeByID() of the lines patched the ingredients with %v", ingredients)
+	}
+}
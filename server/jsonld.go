@@ -0,0 +1,122 @@
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/ninh0gauch0/homerecipes/hrsjsonld"
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/hrstypes"
+)
+
+// importLinkedData - creates the recipes of the schema.org JSON-LD body
+func (s *Server) importLinkedData(w http.ResponseWriter, r *http.Request) {
+	s.logger.Debugln("importing JSON-LD recipes...")
+	defer r.Body.Close()
+
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.writeResponse(w, generateErrorResponse(FAIL, err.Error(), hrstypes.FunctionalError{}, http.StatusConflict), "")
+		return
+	}
+
+	recipes, err := hrsjsonld.Parse(data)
+	if err != nil {
+		s.writeResponse(w, generateErrorResponse(FAIL, err.Error(), hrstypes.FunctionalError{}, http.StatusConflict), "")
+		return
+	}
+	s.writeResponse(w, s.tenant(r).ImportRecipes(recipes), "Recipes imported")
+}
+
+// writeLinkedData - writes the recipe as schema.org JSON-LD
+func (s *Server) writeLinkedData(w http.ResponseWriter, r *http.Request, recipe *hrsmodel.Recipe) {
+	data, err := json.Marshal(s.tenant(r).RecipeLinkedData(recipe))
+	if err != nil {
+		s.customErrorLogger("Json marshaling error - error: %s", err.Error())
+		hrsResp := initResponse()
+		marshallError(&hrsResp, &data, &err)
+		w.WriteHeader(http.StatusConflict)
+		w.Write(data)
+		return
+	}
+
+	s.customInfoLogger("Recipe returned as JSON-LD:\n%s", recipe.GetObjectInfo())
+	w.Header().Set("Content-Type", hrsjsonld.MEDIATYPE)
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// ImportRecipes - Creates imported recipes the way POST does, creating
+// the ingredients of their lines not found. It stops at the first recipe
+// failing.
+func (w *Worker) ImportRecipes(recipes []*hrsmodel.Recipe) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - ImportRecipes [IN]")
+	rsp := hrstypes.HRAResponse{}
+	res := &RecipeImport{Items: []*hrsmodel.Recipe{}}
+
+	for _, recipe := range recipes {
+		if err := w.parseRawLines(recipe, true); err != nil {
+			w.logger.Errorf("Worker - ImportRecipes - Error: " + err.Error())
+			return storeErrorResponse(err, "Insertion can't be accomplished", "Fatal error trying to create ingredients: ")
+		}
+
+		created := w.CreateRecipe(recipe)
+		if created.Error != nil {
+			return created
+		}
+		res.Items = append(res.Items, recipe)
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusCreated,
+		Description: CREATED,
+	}
+	rsp.RespObj = res
+	rsp.SetError(nil)
+
+	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
+	w.logger.Debugf("Worker - ImportRecipes [OUT]")
+	return rsp
+}
+
+// ReimportRecipe - Patches a stored recipe with an imported one, creating
+// the ingredients of its lines not found
+func (w *Worker) ReimportRecipe(id string, recipe *hrsmodel.Recipe) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - ReimportRecipe [IN]")
+
+	if err := w.parseRawLines(recipe, true); err != nil {
+		w.logger.Errorf("Worker - ReimportRecipe - Error: " + err.Error())
+		return storeErrorResponse(err, "Patch can't be accomplished", "Fatal error trying to create ingredients: ")
+	}
+	rsp := w.PatchRecipeByID(id, recipe)
+
+	w.logger.Debugf("Worker - ReimportRecipe [OUT]")
+	return rsp
+}
+
+// RecipeLinkedData - the recipe as a schema.org Recipe, lines written with
+// their ingredient names
+func (w *Worker) RecipeLinkedData(recipe *hrsmodel.Recipe) *hrsjsonld.Recipe {
+	return hrsjsonld.FromRecipe(recipe, w.lineIngredients(recipe))
+}
+
+/** PRIVATE METHODS **/
+
+// accepts - whether the Accept header of the request lists the media type
+func accepts(r *http.Request, mediaType string) bool {
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		if t, _, err := mime.ParseMediaType(strings.TrimSpace(accepted)); err == nil && t == mediaType {
+			return true
+		}
+	}
+	return false
+}
+
+// hasContentType - whether the request body is of the media type
+func hasContentType(r *http.Request, mediaType string) bool {
+	t, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && t == mediaType
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/ninh0gauch0/homerecipes/hrsjsonld"
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/hrstypes"
+)
+
+func TestWorker_ImportRecipes(t *testing.T) {
+	w := newTestWorker(t)
+	w.CreateIngredient(&hrsmodel.Ingredient{Ingredient: hrstypes.Ingredient{Code: "i-flour", Name: "harina"}})
+
+	recipes, err := hrsjsonld.Parse([]byte(`{"@type": "Recipe", "name": "Crepes",
+		"recipeIngredient": ["250 g harina", "3 huevos", "1 pizca de sal", "2 huevos"],
+		"recipeInstructions": "Batir todo.\nReposar."}`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	rsp := w.ImportRecipes(recipes)
+	if rsp.Status.Code != http.StatusCreated {
+		t.Fatalf("ImportRecipes() = %+v", rsp)
+	}
+	recipe := rsp.RespObj.(*RecipeImport).Items[0]
+	lines := recipe.Lines
+	if lines[0].Ingredient != "i-flour" || lines[1].Ingredient == "" || lines[1].Ingredient != lines[3].Ingredient || len(recipe.Steps) != 2 {
+		t.Errorf("ImportRecipes() lines = %+v", lines)
+	}
+
+	egg := w.GetIngredientByID(lines[1].Ingredient)
+	if egg.Status.Code != http.StatusOK || egg.RespObj.(*hrsmodel.Ingredient).Name != "huevos" {
+		t.Errorf("ImportRecipes() created ingredient = %+v", egg)
+	}
+
+	doc := w.RecipeLinkedData(recipe)
+	if doc.Name != "Crepes" || doc.RecipeIngredient[1] != "3 huevos" {
+		t.Errorf("RecipeLinkedData() = %+v", doc)
+	}
+}
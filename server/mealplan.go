@@ -0,0 +1,373 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/hrstypes"
+)
+
+const (
+	// MAXPLANDAYS Constant
+	MAXPLANDAYS = 366
+	// WEEKDAYS Constant
+	WEEKDAYS = 7
+)
+
+// addMealPlanRoutes - meal planner endpoints
+func (s *Server) addMealPlanRoutes(hrsRoutes *mux.Router) {
+	hrsRoutes.HandleFunc("/mealplans", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("planning meal...")
+
+		var entry hrsmodel.MealPlanEntry
+		if s.decodeBody(w, r, &entry) {
+			s.writeResponse(w, s.tenant(r).CreateMealPlanEntry(&entry), "Meal planned")
+		}
+	}).Methods("POST")
+
+	hrsRoutes.HandleFunc("/mealplans", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("listing meal plan...")
+		values := r.URL.Query()
+		s.writeResponse(w, s.tenant(r).GetMealPlan(values.Get("from"), values.Get("to")), "Meal plan returned")
+	}).Methods("GET")
+
+	hrsRoutes.HandleFunc("/mealplans/copy-week", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("copying meal plan week...")
+
+		var req WeekCopy
+		if s.decodeBody(w, r, &req) {
+			s.writeResponse(w, s.tenant(r).CopyMealPlanWeek(&req), "Meal plan week copied")
+		}
+	}).Methods("POST")
+
+	hrsRoutes.HandleFunc("/mealplans/calendar.ics", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("exporting meal plan calendar...")
+		values := r.URL.Query()
+		prep, _ := strconv.ParseBool(values.Get("prep"))
+
+		hrsResp := s.tenant(r).GetMealCalendar(values.Get("from"), values.Get("to"), prep)
+		if hrsResp.Error != nil {
+			s.writeResponse(w, hrsResp, "")
+			return
+		}
+
+		calendar := hrsResp.RespObj.(*MealCalendar)
+		base := baseURL(r)
+		for i := range calendar.Events {
+			calendar.Events[i].URL = base + calendar.Events[i].URL
+		}
+		s.customInfoLogger("Meal calendar returned:\n%s", calendar.GetObjectInfo())
+
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		if err := calendar.Write(w); err != nil {
+			s.customErrorLogger("Calendar writing error - error: %s", err.Error())
+		}
+	}).Methods("GET")
+
+	hrsRoutes.HandleFunc("/mealplans/{id}", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("searching planned meal...")
+		s.writeResponse(w, s.tenant(r).GetMealPlanEntryByID(mux.Vars(r)["id"]), "Planned meal returned")
+	}).Methods("GET")
+
+	hrsRoutes.HandleFunc("/mealplans/{id}", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("moving planned meal...")
+
+		var entry hrsmodel.MealPlanEntry
+		if s.decodeBody(w, r, &entry) {
+			s.writeResponse(w, s.tenant(r).PatchMealPlanEntryByID(mux.Vars(r)["id"], &entry), "Planned meal modified")
+		}
+	}).Methods("PATCH")
+
+	hrsRoutes.HandleFunc("/mealplans/{id}", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("deleting planned meal...")
+		s.writeResponse(w, s.tenant(r).DeleteMealPlanEntry(mux.Vars(r)["id"]), "Planned meal deleted")
+	}).Methods("DELETE")
+}
+
+// CreateMealPlanEntry - Plans a recipe for a meal, the recipe must exist
+func (w *Worker) CreateMealPlanEntry(entry *hrsmodel.MealPlanEntry) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - CreateMealPlanEntry [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	if err := w.validateMealPlanEntry(entry); err != nil {
+		w.logger.Errorf("Worker - CreateMealPlanEntry - Error: " + err.Error())
+		return recipeErrorResponse(err)
+	}
+
+	code, err := newUUID()
+	if err != nil {
+		return generateErrorResponse(TECHNICAL, "Fatal error generating code: "+err.Error(), err, http.StatusInternalServerError)
+	}
+	entry.Code = code
+
+	if err = w.store.InsertMealPlanEntries(entry); err != nil {
+		w.logger.Errorf("Worker - CreateMealPlanEntry - Error: " + err.Error())
+		return storeErrorResponse(err, "Insertion can't be accomplished", "Fatal error trying to insert: ")
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusCreated,
+		Description: CREATED,
+	}
+	rsp.RespObj = entry
+	rsp.SetError(nil)
+
+	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
+	w.logger.Debugf("Worker - CreateMealPlanEntry [OUT]")
+	return rsp
+}
+
+// GetMealPlan - Returns the meals planned from one date to another, both
+// included, and the ingredients they need. Without from the current week is
+// returned; without to, the week from on.
+func (w *Worker) GetMealPlan(from string, to string) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - GetMealPlan [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	start, end, err := planRange(from, to)
+	if err != nil {
+		funcErr := hrstypes.FunctionalError{}
+		return generateErrorResponse(FAIL, err.Error(), funcErr, http.StatusConflict)
+	}
+
+	plan, err := w.mealPlan(start, end)
+	if err != nil {
+		w.logger.Errorf("Worker - GetMealPlan - Error: " + err.Error())
+		return storeErrorResponse(err, "Query can't be accomplished", "Fatal error trying to query: ")
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: QUERIED,
+	}
+	rsp.RespObj = plan
+	rsp.SetError(nil)
+
+	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
+	w.logger.Debugf("Worker - GetMealPlan [OUT]")
+	return rsp
+}
+
+// GetMealPlanEntryByID - Given an id, returns a planned meal
+func (w *Worker) GetMealPlanEntryByID(id string) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - GetMealPlanEntryByID [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	res, err := w.store.GetMealPlanEntry(id)
+
+	if err != nil {
+		w.logger.Errorf("Worker - GetMealPlanEntryByID - Error: " + err.Error())
+		return storeErrorResponse(err, "Query can't be accomplished", "Fatal error trying to query: ")
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: QUERIED,
+	}
+	rsp.RespObj = res
+	rsp.SetError(nil)
+
+	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
+	w.logger.Debugf("Worker - GetMealPlanEntryByID [OUT]")
+	return rsp
+}
+
+// PatchMealPlanEntryByID - Given an id, a planned meal is moved to another
+// date or slot, or gets another recipe or servings
+func (w *Worker) PatchMealPlanEntryByID(id string, entry *hrsmodel.MealPlanEntry) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - PatchMealPlanEntryByID [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	stored, err := w.store.GetMealPlanEntry(id)
+	if err != nil {
+		w.logger.Errorf("Worker - PatchMealPlanEntryByID - Error: " + err.Error())
+		return storeErrorResponse(err, "Patch can't be accomplished", "Fatal error trying to patch: ")
+	}
+
+	moved := *stored
+	if entry.Date != "" {
+		moved.Date = entry.Date
+	}
+	if entry.Slot != "" {
+		moved.Slot = entry.Slot
+	}
+	if entry.Recipe != "" {
+		moved.Recipe = entry.Recipe
+	}
+	if entry.Servings != 0 {
+		moved.Servings = entry.Servings
+	}
+
+	if err = w.validateMealPlanEntry(&moved); err != nil {
+		w.logger.Errorf("Worker - PatchMealPlanEntryByID - Error: " + err.Error())
+		return recipeErrorResponse(err)
+	}
+
+	res, err := w.store.UpdateMealPlanEntry(id, &moved)
+
+	if err != nil {
+		w.logger.Errorf("Worker - PatchMealPlanEntryByID - Error: " + err.Error())
+		return storeErrorResponse(err, "Patch can't be accomplished", "Fatal error trying to patch: ")
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: PATCHED,
+	}
+	rsp.RespObj = res
+	rsp.SetError(nil)
+
+	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
+	w.logger.Debugf("Worker - PatchMealPlanEntryByID [OUT]")
+	return rsp
+}
+
+// DeleteMealPlanEntry - Deletes a planned meal by id
+func (w *Worker) DeleteMealPlanEntry(id string) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - DeleteMealPlanEntry [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	err := w.store.DeleteMealPlanEntry(id)
+
+	if err != nil {
+		w.logger.Errorf("Worker - DeleteMealPlanEntry - Error: " + err.Error())
+		return storeErrorResponse(err, "Remove can't be accomplished", "Fatal error trying to remove: ")
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusNoContent,
+		Description: REMOVED,
+	}
+	rsp.RespObj = nil
+	rsp.SetError(nil)
+
+	w.logger.Debugf(rsp.Status.GetObjectInfo())
+	w.logger.Debugf("Worker - DeleteMealPlanEntry [OUT]")
+	return rsp
+}
+
+// CopyMealPlanWeek - Plans the meals of the week starting on From again in
+// the week starting on To, and returns that week
+func (w *Worker) CopyMealPlanWeek(req *WeekCopy) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - CopyMealPlanWeek [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	from, err := hrsmodel.ParseDate(req.From)
+	var to time.Time
+	if err == nil {
+		to, err = hrsmodel.ParseDate(req.To)
+	}
+	if err != nil {
+		funcErr := hrstypes.FunctionalError{}
+		return generateErrorResponse(FAIL, err.Error(), funcErr, http.StatusConflict)
+	}
+
+	entries, err := w.store.ListMealPlan(req.From, from.AddDate(0, 0, WEEKDAYS-1).Format(hrsmodel.DATEFORMAT))
+	if err != nil {
+		w.logger.Errorf("Worker - CopyMealPlanWeek - Error: " + err.Error())
+		return storeErrorResponse(err, "Query can't be accomplished", "Fatal error trying to query: ")
+	}
+
+	days := int(to.Sub(from).Hours() / 24)
+	copies := []*hrsmodel.MealPlanEntry{}
+	for _, entry := range entries {
+		copied := entry.Shifted(days)
+		if copied.Code, err = newUUID(); err != nil {
+			return generateErrorResponse(TECHNICAL, "Fatal error generating code: "+err.Error(), err, http.StatusInternalServerError)
+		}
+		copies = append(copies, copied)
+	}
+
+	if err = w.store.InsertMealPlanEntries(copies...); err != nil {
+		w.logger.Errorf("Worker - CopyMealPlanWeek - Error: " + err.Error())
+		return storeErrorResponse(err, "Insertion can't be accomplished", "Fatal error trying to insert: ")
+	}
+
+	plan, err := w.mealPlan(to, to.AddDate(0, 0, WEEKDAYS-1))
+	if err != nil {
+		w.logger.Errorf("Worker - CopyMealPlanWeek - Error: " + err.Error())
+		return storeErrorResponse(err, "Query can't be accomplished", "Fatal error trying to query: ")
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusCreated,
+		Description: CREATED,
+	}
+	rsp.RespObj = plan
+	rsp.SetError(nil)
+
+	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
+	w.logger.Debugf("Worker - CopyMealPlanWeek [OUT]")
+	return rsp
+}
+
+/** PRIVATE METHODS **/
+
+// validateMealPlanEntry - checks the entry and that its recipe exists
+func (w *Worker) validateMealPlanEntry(entry *hrsmodel.MealPlanEntry) error {
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+	_, err := w.scaledRecipe(hrsmodel.ShoppingRecipe{Recipe: entry.Recipe})
+	return err
+}
+
+// mealPlan - the entries of the range and the ingredients of their recipes,
+// scaled to the planned servings. Recipes removed after being planned are
+// left out of the ingredients.
+func (w *Worker) mealPlan(from time.Time, to time.Time) (*MealPlan, error) {
+	plan := &MealPlan{From: from.Format(hrsmodel.DATEFORMAT), To: to.Format(hrsmodel.DATEFORMAT)}
+
+	entries, err := w.store.ListMealPlan(plan.From, plan.To)
+	if err != nil {
+		return nil, err
+	}
+	plan.Entries = entries
+
+	recipes := []*hrsmodel.Recipe{}
+	for _, entry := range entries {
+		recipe, err := w.scaledRecipe(hrsmodel.ShoppingRecipe{Recipe: entry.Recipe, Servings: entry.Servings})
+		if errors.Is(err, hrsmodel.ErrInvalid) {
+			w.logger.Warnf("Worker - mealPlan - %s %s: %s", entry.Date, entry.Slot, err.Error())
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		recipes = append(recipes, recipe)
+	}
+
+	list := &hrsmodel.ShoppingList{}
+	list.Consolidate(recipes, w.recipesIngredients(recipes))
+	plan.Ingredients = list.Groups
+	return plan, nil
+}
+
+// planRange - the dates of a meal plan query
+func planRange(from string, to string) (start time.Time, end time.Time, err error) {
+	if from == "" {
+		now := time.Now().UTC()
+		start = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		start = start.AddDate(0, 0, -(int(start.Weekday())+6)%WEEKDAYS)
+	} else if start, err = hrsmodel.ParseDate(from); err != nil {
+		return start, end, err
+	}
+
+	end = start.AddDate(0, 0, WEEKDAYS-1)
+	if to != "" {
+		if end, err = hrsmodel.ParseDate(to); err != nil {
+			return start, end, err
+		}
+	}
+
+	if end.Before(start) || end.Sub(start).Hours()/24 >= MAXPLANDAYS {
+		return start, end, fmt.Errorf("%w: the range must go forward and span at most %d days", hrsmodel.ErrInvalid, MAXPLANDAYS)
+	}
+	return start, end, nil
+}
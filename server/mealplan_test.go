@@ -0,0 +1,56 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+)
+
+func TestWorker_MealPlan(t *testing.T) {
+	w := newTestWorker(t)
+
+	recipe := newRecipe("Lentejas", "lentejas")
+	recipe.Code, recipe.Servings = "lentejas", 2
+	recipe.Lines[0].Quantity, recipe.Lines[0].Unit = &hrsmodel.Quantity{Value: 200}, "g"
+	w.CreateRecipe(recipe)
+
+	rsp := w.CreateMealPlanEntry(&hrsmodel.MealPlanEntry{Date: "2024-03-04", Slot: hrsmodel.LUNCH, Recipe: "lentejas", Servings: 4})
+	if rsp.Status.Code != http.StatusCreated {
+		t.Fatalf("CreateMealPlanEntry() = %+v", rsp)
+	}
+	code := rsp.RespObj.(*hrsmodel.MealPlanEntry).Code
+	w.CreateMealPlanEntry(&hrsmodel.MealPlanEntry{Date: "2024-03-06", Slot: hrsmodel.DINNER, Recipe: "lentejas"})
+
+	for _, bad := range []hrsmodel.MealPlanEntry{
+		{Date: "2024-03-04", Slot: "brunch", Recipe: "lentejas"},
+		{Date: "04/03/2024", Slot: hrsmodel.LUNCH, Recipe: "lentejas"},
+		{Date: "2024-03-04", Slot: hrsmodel.LUNCH, Recipe: "missing"},
+	} {
+		if rsp = w.CreateMealPlanEntry(&bad); rsp.Status.Code != http.StatusConflict {
+			t.Errorf("CreateMealPlanEntry(%+v) status = %d", bad, rsp.Status.Code)
+		}
+	}
+
+	rsp = w.PatchMealPlanEntryByID(code, &hrsmodel.MealPlanEntry{Date: "2024-03-06", Slot: hrsmodel.BREAKFAST})
+	if entry := rsp.RespObj.(*hrsmodel.MealPlanEntry); entry.Date != "2024-03-06" || entry.Slot != hrsmodel.BREAKFAST || entry.Servings != 4 {
+		t.Errorf("PatchMealPlanEntryByID() = %+v", entry)
+	}
+
+	plan := w.GetMealPlan("2024-03-04", "").RespObj.(*MealPlan)
+	if plan.To != "2024-03-10" || len(plan.Entries) != 2 || plan.Entries[0].Slot != hrsmodel.BREAKFAST {
+		t.Errorf("GetMealPlan() = %+v", plan)
+	}
+	if item := plan.Ingredients[0].Items[0]; item.Quantity.Value != 600 || item.Unit != "g" {
+		t.Errorf("GetMealPlan() ingredients = %+v", plan.Ingredients)
+	}
+
+	rsp = w.CopyMealPlanWeek(&WeekCopy{From: "2024-03-04", To: "2024-03-11"})
+	if plan = rsp.RespObj.(*MealPlan); len(plan.Entries) != 2 || plan.Entries[0].Date != "2024-03-13" {
+		t.Errorf("CopyMealPlanWeek() = %+v", plan)
+	}
+
+	if rsp = w.GetMealPlan("2024-03-10", "2024-03-01"); rsp.Status.Code != http.StatusConflict {
+		t.Errorf("GetMealPlan() backwards status = %d", rsp.Status.Code)
+	}
+}
@@ -0,0 +1,293 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/homerecipes/hrsoidc"
+	"github.com/ninh0gauch0/homerecipes/hrsstore"
+	"github.com/ninh0gauch0/hrstypes"
+)
+
+const (
+	// SESSIONCOOKIE Constant
+	SESSIONCOOKIE = "hrs_session"
+	// LOGINCOOKIE Constant
+	LOGINCOOKIE = "hrs_oidc"
+	// LOGINTTL Constant
+	LOGINTTL = 10 * time.Minute
+	// MAXLOGINS Constant
+	MAXLOGINS = 10000
+)
+
+// oidcLogin - a login sent to the identity provider, until it comes back
+type oidcLogin struct {
+	verifier string
+	nonce    string
+	expires  time.Time
+}
+
+// configureOIDC - the OpenID Connect provider users may log in with, from
+// the "oidc-issuer", "oidc-client-id", "oidc-client-secret",
+// "oidc-redirect-url", "oidc-scopes" and "oidc-username-claims" keys; none
+// when there is no issuer. On their first login users join the
+// "oidc-household" with the "oidc-role", a viewer by default, or get a
+// household of their own.
+func (s *Server) configureOIDC(config map[string]string) error {
+	if config["oidc-issuer"] == "" {
+		return nil
+	}
+
+	provider, err := hrsoidc.NewProvider(hrsoidc.Config{
+		Issuer:         config["oidc-issuer"],
+		ClientID:       config["oidc-client-id"],
+		ClientSecret:   config["oidc-client-secret"],
+		RedirectURL:    config["oidc-redirect-url"],
+		Scopes:         strings.FieldsFunc(config["oidc-scopes"], listSeparator),
+		UsernameClaims: strings.FieldsFunc(config["oidc-username-claims"], listSeparator),
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	role := config["oidc-role"]
+	if role == "" {
+		role = hrsmodel.VIEWER
+	}
+	if role != hrsmodel.EDITOR && role != hrsmodel.VIEWER {
+		return errors.New("the users logging in with OpenID Connect can only join as " + hrsmodel.EDITOR + " or " + hrsmodel.VIEWER)
+	}
+	s.oidc, s.oidcHousehold, s.oidcRole = provider, config["oidc-household"], role
+	s.secureCookies = strings.HasPrefix(config["oidc-redirect-url"], "https://")
+	return nil
+}
+
+// addOIDCRoutes - the OpenID Connect login and its callback, on the main
+// router as they need no token, and the logout dropping the session
+func (s *Server) addOIDCRoutes() {
+	s.router.HandleFunc("/hrs/logout", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("logging out...")
+
+		s.setCookie(w, SESSIONCOOKIE, "", "/hrs", -1, http.SameSiteStrictMode)
+		rsp := hrstypes.HRAResponse{}
+		rsp.Status = hrstypes.Status{
+			Code:        http.StatusOK,
+			Description: REMOVED,
+		}
+		rsp.SetError(nil)
+		s.writeResponse(w, rsp, "Logged out")
+	}).Methods("POST")
+
+	if s.oidc == nil {
+		return
+	}
+
+	s.router.HandleFunc("/hrs/oidc/login", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("logging in with OpenID Connect...")
+
+		state, login, err := s.startLogin()
+		var authURL string
+		if err == nil {
+			authURL, err = s.oidc.AuthCodeURL(r.Context(), state, login.nonce, login.verifier)
+		}
+		if err != nil {
+			s.forgetLogin(state)
+			techErr := hrstypes.TechnicalError{}
+			s.writeResponse(w, generateErrorResponse(TECHNICAL, "The identity provider can't be reached: "+err.Error(), techErr, http.StatusBadGateway), "")
+			return
+		}
+
+		s.setCookie(w, LOGINCOOKIE, state, "/hrs/oidc", int(LOGINTTL/time.Second), http.SameSiteLaxMode)
+		http.Redirect(w, r, authURL, http.StatusFound)
+	}).Methods("GET")
+
+	s.router.HandleFunc("/hrs/oidc/callback", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("coming back from the identity provider...")
+
+		query := r.URL.Query()
+		cookie, _ := r.Cookie(LOGINCOOKIE)
+		s.setCookie(w, LOGINCOOKIE, "", "/hrs/oidc", -1, http.SameSiteLaxMode)
+
+		login, ok := s.forgetLogin(query.Get("state"))
+		if !ok || cookie == nil || cookie.Value != query.Get("state") {
+			funcErr := hrstypes.FunctionalError{}
+			s.writeResponse(w, generateErrorResponse(UNAUTHORIZED, "Unknown or expired login, start it again", funcErr, http.StatusUnauthorized), "")
+			return
+		}
+		if reason := query.Get("error"); reason != "" {
+			funcErr := hrstypes.FunctionalError{}
+			s.writeResponse(w, generateErrorResponse(UNAUTHORIZED, "The identity provider turned the login down: "+reason, funcErr, http.StatusUnauthorized), "")
+			return
+		}
+
+		identity, err := s.oidc.Exchange(r.Context(), query.Get("code"), login.verifier, login.nonce)
+		if errors.Is(err, hrsoidc.ErrLogin) {
+			funcErr := hrstypes.FunctionalError{}
+			s.writeResponse(w, generateErrorResponse(UNAUTHORIZED, err.Error(), funcErr, http.StatusUnauthorized), "")
+			return
+		}
+		if err != nil {
+			techErr := hrstypes.TechnicalError{}
+			s.writeResponse(w, generateErrorResponse(TECHNICAL, "The identity provider can't be reached: "+err.Error(), techErr, http.StatusBadGateway), "")
+			return
+		}
+
+		rsp := s.worker.OIDCLogin(identity, s.oidcHousehold, s.oidcRole)
+		if session, ok := rsp.RespObj.(*Session); ok {
+			s.setCookie(w, SESSIONCOOKIE, session.Token, "/hrs", int(time.Until(session.Expires)/time.Second), http.SameSiteStrictMode)
+		}
+		s.writeResponse(w, rsp, "Logged in")
+	}).Methods("GET")
+}
+
+// OIDCLogin - Issues a token for the user an OpenID Connect provider
+// vouched for. On their first login the user is created, without
+// password, and joins the household with the role; they get a household
+// of their own when none is given.
+func (w *Worker) OIDCLogin(identity *hrsoidc.Identity, household string, role string) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - OIDCLogin [IN]")
+
+	user, err := w.store.GetUserBySubject(identity.Issuer, identity.Subject)
+	if errors.Is(err, hrsstore.ErrNotFound) {
+		user, err = w.provisionUser(identity, household, role)
+	}
+	if errors.Is(err, hrsmodel.ErrInvalid) {
+		funcErr := hrstypes.FunctionalError{}
+		return generateErrorResponse(FAIL, err.Error(), funcErr, http.StatusConflict)
+	}
+	if err != nil {
+		w.logger.Errorf("Worker - OIDCLogin - Error: " + err.Error())
+		return storeErrorResponse(err, "The user can't be provisioned, the username may be taken", "Fatal error trying to provision: ")
+	}
+
+	rsp := w.session(user)
+	w.logger.Debugf("Worker - OIDCLogin [OUT]")
+	return rsp
+}
+
+/** PRIVATE METHODS **/
+
+// sessionToken - the token of the session cookie, empty when none
+func sessionToken(r *http.Request) string {
+	cookie, err := r.Cookie(SESSIONCOOKIE)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+// setCookie - sets an HTTP only cookie, or removes it when maxAge is
+// negative; the cookies are only sent over HTTPS when the server is
+// reached through it
+func (s *Server) setCookie(w http.ResponseWriter, name string, value string, path string, maxAge int, sameSite http.SameSite) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   s.secureCookies,
+		SameSite: sameSite,
+	})
+}
+
+// startLogin - a new pending login and its state. The expired ones are
+// dropped first; too many pending logins are turned down.
+func (s *Server) startLogin() (string, oidcLogin, error) {
+	state, err := hrsoidc.NewSecret()
+	if err != nil {
+		return "", oidcLogin{}, err
+	}
+	login := oidcLogin{expires: time.Now().Add(LOGINTTL)}
+	if login.verifier, err = hrsoidc.NewSecret(); err != nil {
+		return "", oidcLogin{}, err
+	}
+	if login.nonce, err = hrsoidc.NewSecret(); err != nil {
+		return "", oidcLogin{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for key, pending := range s.logins {
+		if now.After(pending.expires) {
+			delete(s.logins, key)
+		}
+	}
+	if len(s.logins) >= MAXLOGINS {
+		return "", oidcLogin{}, errors.New("too many logins in progress")
+	}
+	if s.logins == nil {
+		s.logins = map[string]oidcLogin{}
+	}
+	s.logins[state] = login
+	return state, login, nil
+}
+
+// forgetLogin - takes a pending login out, false when there is none with
+// the state or it expired
+func (s *Server) forgetLogin(state string) (oidcLogin, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	login, ok := s.logins[state]
+	delete(s.logins, state)
+	return login, ok && time.Now().Before(login.expires)
+}
+
+// provisionUser - creates the user an OpenID Connect provider vouched for
+// and puts them in a household; the user is removed again when that fails
+func (w *Worker) provisionUser(identity *hrsoidc.Identity, household string, role string) (*hrsmodel.User, error) {
+	user := &hrsmodel.User{Username: identity.Username, Issuer: identity.Issuer, Subject: identity.Subject}
+	if err := user.Validate(); err != nil {
+		return nil, err
+	}
+	code, err := newUUID()
+	if err != nil {
+		return nil, err
+	}
+	user.Code = code
+	if err = w.store.InsertUser(user); err != nil {
+		return nil, err
+	}
+
+	if household == "" {
+		err = w.ownHousehold(user)
+	} else {
+		err = w.joinHousehold(user, household, role)
+	}
+	if err != nil {
+		w.store.DeleteUser(user.Code)
+		return nil, err
+	}
+	w.logger.Infof("Provisioned %s of %s", user.GetObjectInfo(), identity.Issuer)
+	return user, nil
+}
+
+// ownHousehold - a household named after the user, who owns it
+func (w *Worker) ownHousehold(user *hrsmodel.User) error {
+	code, err := newUUID()
+	if err != nil {
+		return err
+	}
+	return w.store.InsertHousehold(&hrsmodel.Household{
+		Code:    code,
+		Name:    user.Username,
+		Members: []hrsmodel.Member{{User: user.Code, Username: user.Username, Role: hrsmodel.OWNER}},
+	})
+}
+
+// joinHousehold - makes the user a member of the household with the role
+func (w *Worker) joinHousehold(user *hrsmodel.User, code string, role string) error {
+	_, err := w.store.AddMember(code, hrsmodel.Member{User: user.Code, Username: user.Username, Role: role})
+	return err
+}
+
+// listSeparator - whether the rune separates the items of a configured
+// list, commas or spaces
+func listSeparator(r rune) bool {
+	return r == ',' || r == ' '
+}
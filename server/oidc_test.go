@@ -0,0 +1,165 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/homerecipes/hrsoidc"
+	"github.com/ninh0gauch0/homerecipes/hrsoidc/oidctest"
+)
+
+// oidcLoginAs - goes through the OpenID Connect login as whoever the
+// provider claims, returning the callback answer
+func oidcLoginAs(t *testing.T, s *Server, idp *oidctest.IdP, claims map[string]interface{}) *http.Response {
+	rec := serve(s, "GET", "/hrs/oidc/login", "", "")
+	if rec.Code != http.StatusFound {
+		t.Fatalf("GET /oidc/login = %d %s", rec.Code, rec.Body.String())
+	}
+	var state *http.Cookie
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == LOGINCOOKIE {
+			state = cookie
+		}
+	}
+	if state == nil {
+		t.Fatal("GET /oidc/login sets no login cookie")
+	}
+
+	idp.Claims = claims
+	back, err := idp.Authorize(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return serve(s, "GET", back.RequestURI(), "", "", "Cookie", LOGINCOOKIE+"="+state.Value).Result()
+}
+
+// sessionCookie - the session cookie set by a response, empty when none
+func sessionCookie(rsp *http.Response) string {
+	for _, cookie := range rsp.Cookies() {
+		if cookie.Name == SESSIONCOOKIE && cookie.MaxAge >= 0 {
+			return SESSIONCOOKIE + "=" + cookie.Value
+		}
+	}
+	return ""
+}
+
+func TestServer_oidc(t *testing.T) {
+	idp, err := oidctest.NewIdP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idp.Close()
+
+	logFileOn = false
+	w := newAuthWorker(t)
+	ana, _ := w.store.GetUserByName("ana")
+	home := w.CreateHousehold(ana, "Casa").RespObj.(*hrsmodel.Household).Code
+
+	s := &Server{router: mux.NewRouter(), worker: w, store: w.store}
+	s.SetLogger(w.GetLogger())
+	config := idp.Config("http://hrs.test/hrs/oidc/callback")
+	err = s.configureOIDC(map[string]string{
+		"oidc-issuer":        config.Issuer,
+		"oidc-client-id":     config.ClientID,
+		"oidc-client-secret": config.ClientSecret,
+		"oidc-redirect-url":  config.RedirectURL,
+		"oidc-household":     home,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.addRoutes()
+
+	if rec := serve(s, "GET", "/hrs/recipes", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("GET without session = %d", rec.Code)
+	}
+
+	// The first login provisions the user as a viewer of the household, the
+	// role they get by default
+	rsp := oidcLoginAs(t, s, idp, map[string]interface{}{"sub": "42", "preferred_username": "bea"})
+	session := sessionCookie(rsp)
+	if rsp.StatusCode != http.StatusOK || session == "" {
+		t.Fatalf("GET /oidc/callback = %d, session %q", rsp.StatusCode, session)
+	}
+	if rec := serve(s, "GET", "/hrs/recipes", "", "", "Cookie", session); rec.Code != http.StatusOK {
+		t.Errorf("GET with the session = %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := serve(s, "POST", "/hrs/ingredients", `{"code":"i-egg","name":"huevo"}`, "", "Cookie", session); rec.Code != http.StatusForbidden {
+		t.Errorf("POST as viewer = %d", rec.Code)
+	}
+	if rec := serve(s, "GET", "/hrs/me", "", "", "Cookie", session); !strings.Contains(rec.Body.String(), `"bea"`) {
+		t.Errorf("GET /me = %s", rec.Body.String())
+	}
+	if rec := serve(s, "POST", "/hrs/login", `{"username":"bea","password":""}`, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("POST /login without password = %d", rec.Code)
+	}
+
+	// Later logins find the same user, whatever their username now is
+	bea, err := s.store.GetUserBySubject(idp.URL, "42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp = oidcLoginAs(t, s, idp, map[string]interface{}{"sub": "42", "preferred_username": "beatriz"})
+	if user, _ := s.store.GetUserByName("beatriz"); rsp.StatusCode != http.StatusOK || user != nil {
+		t.Errorf("second login = %d, provisioned %v", rsp.StatusCode, user)
+	}
+	household, _ := s.store.GetHousehold(home)
+	if len(household.Members) != 2 || household.Role(bea.Code) != hrsmodel.VIEWER {
+		t.Errorf("household members = %+v", household.Members)
+	}
+
+	// Local users aren't taken over by whoever the provider calls alike
+	rsp = oidcLoginAs(t, s, idp, map[string]interface{}{"sub": "43", "preferred_username": "ana"})
+	if rsp.StatusCode != http.StatusConflict {
+		t.Errorf("login as a local username = %d", rsp.StatusCode)
+	}
+
+	// The state is good once and only along with its cookie
+	rec := serve(s, "GET", "/hrs/oidc/login", "", "")
+	location, _ := url.Parse(rec.Header().Get("Location"))
+	back, _ := idp.Authorize(location.String())
+	if rec = serve(s, "GET", back.RequestURI(), "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("callback without login cookie = %d", rec.Code)
+	}
+	if rec = serve(s, "GET", back.RequestURI(), "", "", "Cookie", LOGINCOOKIE+"="+location.Query().Get("state")); rec.Code != http.StatusUnauthorized {
+		t.Errorf("callback of a used state = %d", rec.Code)
+	}
+
+	rec = serve(s, "POST", "/hrs/logout", "", "", "Cookie", session)
+	if rec.Code != http.StatusOK || sessionCookie(rec.Result()) != "" {
+		t.Errorf("POST /logout = %d, cookies %v", rec.Code, rec.Result().Cookies())
+	}
+}
+
+func TestWorker_OIDCLogin(t *testing.T) {
+	w := newAuthWorker(t)
+	identity := &hrsoidc.Identity{Issuer: "https://idp.test", Subject: "7", Username: "carla@example.com"}
+
+	rsp := w.OIDCLogin(identity, "", "")
+	if rsp.Error != nil {
+		t.Fatalf("OIDCLogin() = %+v", rsp)
+	}
+	user, err := w.TokenUser(rsp.RespObj.(*Session).Token)
+	if err != nil || user.Username != "carla@example.com" || user.PasswordHash != "" {
+		t.Fatalf("TokenUser() = %+v, %v", user, err)
+	}
+	if households, _ := w.store.ListHouseholds(user.Code); len(households) != 1 || households[0].Role(user.Code) != hrsmodel.OWNER {
+		t.Errorf("households of the provisioned user = %+v", households)
+	}
+
+	identity = &hrsoidc.Identity{Issuer: "https://idp.test", Subject: "8", Username: "Carla Pérez"}
+	if rsp = w.OIDCLogin(identity, "", ""); rsp.Status.Code != http.StatusConflict {
+		t.Errorf("OIDCLogin() with spaces in the username = %+v", rsp)
+	}
+	identity.Username = "dora"
+	if rsp = w.OIDCLogin(identity, "no-such-household", hrsmodel.EDITOR); rsp.Error == nil {
+		t.Errorf("OIDCLogin() into a missing household = %+v", rsp)
+	}
+	if user, _ := w.store.GetUserByName("dora"); user != nil {
+		t.Errorf("user left after a failed provisioning: %+v", user)
+	}
+}
@@ -0,0 +1,420 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/homerecipes/hrsstore"
+	"github.com/ninh0gauch0/hrstypes"
+)
+
+const (
+	// EXPIRYDAYS Constant
+	EXPIRYDAYS = 3
+)
+
+// addPantryRoutes - pantry endpoints
+func (s *Server) addPantryRoutes(hrsRoutes *mux.Router) {
+	hrsRoutes.HandleFunc("/pantry", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("storing pantry item...")
+
+		var item hrsmodel.PantryItem
+		if s.decodeBody(w, r, &item) {
+			s.writeResponse(w, s.tenant(r).CreatePantryItem(&item), "Pantry item created")
+		}
+	}).Methods("POST")
+
+	hrsRoutes.HandleFunc("/pantry", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("listing pantry...")
+		values := r.URL.Query()
+		s.writeResponse(w, s.tenant(r).ListPantry(values.Get("location"), values.Get("ingredient")), "Pantry returned")
+	}).Methods("GET")
+
+	hrsRoutes.HandleFunc("/pantry/expiring", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("listing expiring pantry items...")
+
+		days, err := parseDays(r)
+		if err != nil {
+			s.writeResponse(w, generateErrorResponse(FAIL, err.Error(), hrstypes.FunctionalError{}, http.StatusConflict), "")
+			return
+		}
+		s.writeResponse(w, s.tenant(r).ExpiringPantryItems(days), "Expiring pantry items returned")
+	}).Methods("GET")
+
+	hrsRoutes.HandleFunc("/pantry/suggestions", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("suggesting recipes for expiring pantry items...")
+
+		days, err := parseDays(r)
+		if err != nil {
+			s.writeResponse(w, generateErrorResponse(FAIL, err.Error(), hrstypes.FunctionalError{}, http.StatusConflict), "")
+			return
+		}
+		s.writeResponse(w, s.tenant(r).SuggestPantryRecipes(days), "Pantry suggestions returned")
+	}).Methods("GET")
+
+	hrsRoutes.HandleFunc("/pantry/{id}", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("searching pantry item...")
+		s.writeResponse(w, s.tenant(r).GetPantryItemByID(mux.Vars(r)["id"]), "Pantry item returned")
+	}).Methods("GET")
+
+	hrsRoutes.HandleFunc("/pantry/{id}", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("modifying pantry item...")
+
+		var item hrsmodel.PantryItem
+		if s.decodeBody(w, r, &item) {
+			s.writeResponse(w, s.tenant(r).PatchPantryItemByID(mux.Vars(r)["id"], &item), "Pantry item modified")
+		}
+	}).Methods("PATCH")
+
+	hrsRoutes.HandleFunc("/pantry/{id}", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("deleting pantry item...")
+		s.writeResponse(w, s.tenant(r).DeletePantryItem(mux.Vars(r)["id"]), "Pantry item deleted")
+	}).Methods("DELETE")
+
+	hrsRoutes.HandleFunc("/recipes/{id}/cook", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("cooking recipe...")
+
+		var cooking Cooking
+		if s.decodeBody(w, r, &cooking) {
+			s.writeResponse(w, s.tenant(r).CookRecipe(mux.Vars(r)["id"], &cooking), "Recipe cooked")
+		}
+	}).Methods("POST")
+}
+
+// CreatePantryItem - Stores an amount of an ingredient we have, the
+// ingredient must exist
+func (w *Worker) CreatePantryItem(item *hrsmodel.PantryItem) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - CreatePantryItem [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	if err := w.validatePantryItem(item); err != nil {
+		w.logger.Errorf("Worker - CreatePantryItem - Error: " + err.Error())
+		return recipeErrorResponse(err)
+	}
+
+	code, err := newUUID()
+	if err != nil {
+		return generateErrorResponse(TECHNICAL, "Fatal error generating code: "+err.Error(), err, http.StatusInternalServerError)
+	}
+	item.Code = code
+
+	if err = w.store.InsertPantryItem(item); err != nil {
+		w.logger.Errorf("Worker - CreatePantryItem - Error: " + err.Error())
+		return storeErrorResponse(err, "Insertion can't be accomplished", "Fatal error trying to insert: ")
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusCreated,
+		Description: CREATED,
+	}
+	rsp.RespObj = item
+	rsp.SetError(nil)
+
+	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
+	w.logger.Debugf("Worker - CreatePantryItem [OUT]")
+	return rsp
+}
+
+// GetPantryItemByID - Given an id, returns a pantry item
+func (w *Worker) GetPantryItemByID(id string) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - GetPantryItemByID [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	res, err := w.store.GetPantryItem(id)
+
+	if err != nil {
+		w.logger.Errorf("Worker - GetPantryItemByID - Error: " + err.Error())
+		return storeErrorResponse(err, "Query can't be accomplished", "Fatal error trying to query: ")
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: QUERIED,
+	}
+	rsp.RespObj = res
+	rsp.SetError(nil)
+
+	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
+	w.logger.Debugf("Worker - GetPantryItemByID [OUT]")
+	return rsp
+}
+
+// PatchPantryItemByID - Changes the amount, place or date of a pantry item
+func (w *Worker) PatchPantryItemByID(id string, item *hrsmodel.PantryItem) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - PatchPantryItemByID [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	stored, err := w.store.GetPantryItem(id)
+	if err != nil {
+		w.logger.Errorf("Worker - PatchPantryItemByID - Error: " + err.Error())
+		return storeErrorResponse(err, "Patch can't be accomplished", "Fatal error trying to patch: ")
+	}
+
+	changed := *stored
+	if item.Ingredient != "" {
+		changed.Ingredient = item.Ingredient
+	}
+	if item.Quantity != 0 {
+		changed.Quantity = item.Quantity
+	}
+	if item.Unit != "" {
+		changed.Unit = item.Unit
+	}
+	if item.Location != "" {
+		changed.Location = item.Location
+	}
+	if item.BestBefore != "" {
+		changed.BestBefore = item.BestBefore
+	}
+
+	if err = w.validatePantryItem(&changed); err != nil {
+		w.logger.Errorf("Worker - PatchPantryItemByID - Error: " + err.Error())
+		return recipeErrorResponse(err)
+	}
+
+	res, err := w.store.UpdatePantryItem(id, &changed)
+
+	if err != nil {
+		w.logger.Errorf("Worker - PatchPantryItemByID - Error: " + err.Error())
+		return storeErrorResponse(err, "Patch can't be accomplished", "Fatal error trying to patch: ")
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: PATCHED,
+	}
+	rsp.RespObj = res
+	rsp.SetError(nil)
+
+	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
+	w.logger.Debugf("Worker - PatchPantryItemByID [OUT]")
+	return rsp
+}
+
+// DeletePantryItem - Deletes a pantry item by id
+func (w *Worker) DeletePantryItem(id string) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - DeletePantryItem [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	err := w.store.DeletePantryItem(id)
+
+	if err != nil {
+		w.logger.Errorf("Worker - DeletePantryItem - Error: " + err.Error())
+		return storeErrorResponse(err, "Remove can't be accomplished", "Fatal error trying to remove: ")
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusNoContent,
+		Description: REMOVED,
+	}
+	rsp.RespObj = nil
+	rsp.SetError(nil)
+
+	w.logger.Debugf(rsp.Status.GetObjectInfo())
+	w.logger.Debugf("Worker - DeletePantryItem [OUT]")
+	return rsp
+}
+
+// ListPantry - Returns the pantry items kept in a location and of an
+// ingredient, any when empty, the ones expiring first before
+func (w *Worker) ListPantry(location string, ingredient string) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - ListPantry [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	res, err := w.store.ListPantry(location, ingredient)
+
+	if err != nil {
+		w.logger.Errorf("Worker - ListPantry - Error: " + err.Error())
+		return storeErrorResponse(err, "Query can't be accomplished", "Fatal error trying to query: ")
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: QUERIED,
+	}
+	rsp.RespObj = &Pantry{Items: res}
+	rsp.SetError(nil)
+
+	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
+	w.logger.Debugf("Worker - ListPantry [OUT]")
+	return rsp
+}
+
+// ExpiringPantryItems - Returns the pantry items past their best-before
+// date within the given days, expired ones included
+func (w *Worker) ExpiringPantryItems(days int) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - ExpiringPantryItems [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	res, err := w.expiring(days)
+
+	if err != nil {
+		w.logger.Errorf("Worker - ExpiringPantryItems - Error: " + err.Error())
+		return storeErrorResponse(err, "Query can't be accomplished", "Fatal error trying to query: ")
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: QUERIED,
+	}
+	rsp.RespObj = res
+	rsp.SetError(nil)
+
+	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
+	w.logger.Debugf("Worker - ExpiringPantryItems [OUT]")
+	return rsp
+}
+
+// SuggestPantryRecipes - Returns the recipes using pantry items expiring
+// within the given days, the ones using more of them first
+func (w *Worker) SuggestPantryRecipes(days int) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - SuggestPantryRecipes [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	expiring, err := w.expiring(days)
+	if err != nil {
+		w.logger.Errorf("Worker - SuggestPantryRecipes - Error: " + err.Error())
+		return storeErrorResponse(err, "Query can't be accomplished", "Fatal error trying to query: ")
+	}
+	res := &PantrySuggestions{Until: expiring.Until, Expiring: expiring.Items, Recipes: []*hrsmodel.PantrySuggestion{}}
+
+	items, err := w.store.ListPantry("", "")
+	if err != nil {
+		w.logger.Errorf("Worker - SuggestPantryRecipes - Error: " + err.Error())
+		return storeErrorResponse(err, "Query can't be accomplished", "Fatal error trying to query: ")
+	}
+	pantry := map[string]bool{}
+	for _, item := range items {
+		pantry[item.Ingredient] = true
+	}
+
+	if len(res.Expiring) > 0 {
+		err = hrsstore.EachRecipe(w.store, func(recipe *hrsmodel.Recipe) error {
+			if suggestion := recipe.Suggest(res.Expiring, pantry); suggestion != nil {
+				res.Recipes = append(res.Recipes, suggestion)
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		w.logger.Errorf("Worker - SuggestPantryRecipes - Error: " + err.Error())
+		return storeErrorResponse(err, "Query can't be accomplished", "Fatal error trying to query: ")
+	}
+	hrsmodel.SortSuggestions(res.Recipes)
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: QUERIED,
+	}
+	rsp.RespObj = res
+	rsp.SetError(nil)
+
+	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
+	w.logger.Debugf("Worker - SuggestPantryRecipes [OUT]")
+	return rsp
+}
+
+// CookRecipe - Works out what cooking a recipe, scaled to the servings,
+// takes from the pantry and what it lacks. With deduct the amounts are
+// taken out, used up items removed.
+func (w *Worker) CookRecipe(id string, cooking *Cooking) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - CookRecipe [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	recipe, err := w.scaledRecipe(hrsmodel.ShoppingRecipe{Recipe: id, Servings: cooking.Servings})
+	if err != nil {
+		w.logger.Errorf("Worker - CookRecipe - Error: " + err.Error())
+		return recipeErrorResponse(err)
+	}
+
+	items, err := w.store.ListPantry("", "")
+	if err != nil {
+		w.logger.Errorf("Worker - CookRecipe - Error: " + err.Error())
+		return storeErrorResponse(err, "Query can't be accomplished", "Fatal error trying to query: ")
+	}
+
+	deduction := recipe.Deduct(items, w.lineIngredients(recipe))
+	description := QUERIED
+
+	if cooking.Deduct {
+		used := []*hrsmodel.PantryItem{}
+		for _, item := range items {
+			for _, use := range deduction.Used {
+				if use.Item == item.Code {
+					used = append(used, item)
+					break
+				}
+			}
+		}
+
+		if err = w.store.SavePantryItems(used...); err != nil {
+			w.logger.Errorf("Worker - CookRecipe - Error: " + err.Error())
+			return storeErrorResponse(err, "Patch can't be accomplished", "Fatal error trying to patch: ")
+		}
+		deduction.Deducted = true
+		description = PATCHED
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: description,
+	}
+	rsp.RespObj = deduction
+	rsp.SetError(nil)
+
+	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
+	w.logger.Debugf("Worker - CookRecipe [OUT]")
+	return rsp
+}
+
+/** PRIVATE METHODS **/
+
+// validatePantryItem - checks the item and that its ingredient exists
+func (w *Worker) validatePantryItem(item *hrsmodel.PantryItem) error {
+	if err := item.Validate(); err != nil {
+		return err
+	}
+	_, err := w.store.GetIngredient(item.Ingredient)
+	if errors.Is(err, hrsstore.ErrNotFound) {
+		return fmt.Errorf("%w: ingredient %s doesn't exist", hrsmodel.ErrInvalid, item.Ingredient)
+	}
+	return err
+}
+
+// expiring - the pantry items past their best-before date the given days
+// from today
+func (w *Worker) expiring(days int) (*Pantry, error) {
+	until := time.Now().AddDate(0, 0, days).Format(hrsmodel.DATEFORMAT)
+
+	items, err := w.store.ListPantry("", "")
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Pantry{Until: until, Items: []*hrsmodel.PantryItem{}}
+	for _, item := range items {
+		if item.Expires(until) {
+			res.Items = append(res.Items, item)
+		}
+	}
+	return res, nil
+}
+
+// parseDays - reads ?days, EXPIRYDAYS when missing
+func parseDays(r *http.Request) (int, error) {
+	value := r.URL.Query().Get("days")
+	if value == "" {
+		return EXPIRYDAYS, nil
+	}
+
+	days, err := strconv.Atoi(value)
+	if err != nil || days < 0 {
+		return 0, fmt.Errorf("days must be a non negative number, not %q", value)
+	}
+	return days, nil
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/hrstypes"
+)
+
+func TestWorker_Pantry(t *testing.T) {
+	w := newTestWorker(t)
+
+	w.CreateIngredient(&hrsmodel.Ingredient{Ingredient: hrstypes.Ingredient{Code: "i-milk", Name: "Leche"}})
+	recipe := newRecipe("Natillas")
+	recipe.Code, recipe.Servings = "natillas", 4
+	recipe.Lines = []hrsmodel.IngredientLine{{Ingredient: "i-milk", Quantity: &hrsmodel.Quantity{Value: 500}, Unit: "ml"}}
+	w.CreateRecipe(recipe)
+
+	tomorrow := time.Now().AddDate(0, 0, 1).Format(hrsmodel.DATEFORMAT)
+	rsp := w.CreatePantryItem(&hrsmodel.PantryItem{Ingredient: "i-milk", Quantity: 1, Unit: "l", Location: hrsmodel.FRIDGE, BestBefore: tomorrow})
+	if rsp.Status.Code != http.StatusCreated {
+		t.Fatalf("CreatePantryItem() = %+v", rsp)
+	}
+	milk := rsp.RespObj.(*hrsmodel.PantryItem)
+
+	if rsp = w.CreatePantryItem(&hrsmodel.PantryItem{Ingredient: "i-none", Quantity: 1, Location: hrsmodel.FRIDGE}); rsp.Status.Code != http.StatusConflict {
+		t.Errorf("CreatePantryItem() missing ingredient status = %d", rsp.Status.Code)
+	}
+
+	if items := w.ExpiringPantryItems(0).RespObj.(*Pantry).Items; len(items) != 0 {
+		t.Errorf("ExpiringPantryItems(0) = %+v", items)
+	}
+	suggestions := w.SuggestPantryRecipes(2).RespObj.(*PantrySuggestions)
+	if len(suggestions.Expiring) != 1 || len(suggestions.Recipes) != 1 || suggestions.Recipes[0].Recipe != "natillas" {
+		t.Errorf("SuggestPantryRecipes() = %+v", suggestions)
+	}
+
+	rsp = w.CookRecipe("natillas", &Cooking{Servings: 6})
+	if d := rsp.RespObj.(*hrsmodel.PantryDeduction); d.Deducted || len(d.Used) != 1 || d.Used[0].Quantity != 0.75 {
+		t.Errorf("CookRecipe() = %+v", d)
+	}
+	if left := w.GetPantryItemByID(milk.Code).RespObj.(*hrsmodel.PantryItem).Quantity; left != 1 {
+		t.Errorf("CookRecipe() without deduct left %v", left)
+	}
+
+	w.CookRecipe("natillas", &Cooking{Deduct: true})
+	if left := w.GetPantryItemByID(milk.Code).RespObj.(*hrsmodel.PantryItem).Quantity; left != 0.5 {
+		t.Errorf("CookRecipe() left %v, want 0.5", left)
+	}
+	rsp = w.CookRecipe("natillas", &Cooking{Deduct: true})
+	if d := rsp.RespObj.(*hrsmodel.PantryDeduction); !d.Deducted || len(d.Short) != 0 {
+		t.Errorf("CookRecipe() = %+v", d)
+	}
+	if rsp = w.GetPantryItemByID(milk.Code); rsp.Status.Code == http.StatusOK {
+		t.Errorf("CookRecipe() kept the used up item")
+	}
+}
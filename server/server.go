@@ -2,12 +2,19 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/leemcloughlin/logfile"
+	"github.com/ninh0gauch0/homerecipes/hrscooklang"
+	"github.com/ninh0gauch0/homerecipes/hrsjsonld"
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/homerecipes/hrsstore"
 	"github.com/ninh0gauch0/hrstypes"
 )
 
@@ -27,7 +34,8 @@ const (
 func (s *Server) Init() bool {
 
 	// Init logfile
-	logFile, err := logfile.New(
+	var err error
+	logFile, err = logfile.New(
 		&logfile.LogFile{
 			FileName: "homeRecipesServer.log",
 			MaxSize:  1000 * 1024,
@@ -64,11 +72,36 @@ func (s *Server) Start(config map[string]string) chan bool {
 
 	s.logger.Infof("Starting server....")
 
+	store, err := hrsstore.New(s.Ctx, config)
+	if err != nil {
+		s.logger.Errorf("Failed to open store: %s", err.Error())
+		return nil
+	}
+	s.store = store
+	if s.accountless = accountless(store); s.accountless {
+		s.logger.Warnf("The store keeps no users nor households, every request goes through unauthenticated " +
+			"and acts on the same data; hrs migrate --to bolt copies it to a store keeping them")
+	}
+
 	s.worker = &Worker{}
-	s.worker.Init(s.Ctx, s.GetLogger())
+	s.worker.Init(s.Ctx, s.GetLogger(), s.store)
+
+	if err = s.configureAuth(config); err != nil {
+		s.logger.Errorf("Failed to configure authentication: %s", err.Error())
+		return nil
+	}
+	if err = s.configureOIDC(config); err != nil {
+		s.logger.Errorf("Failed to configure OpenID Connect: %s", err.Error())
+		return nil
+	}
 
 	s.addRoutes()
 
+	s.Server = &http.Server{
+		Addr:    s.Addr,
+		Handler: s.router,
+	}
+
 	exitChan := make(chan bool)
 
 	// Go routines and channel to orchestrate
@@ -82,12 +115,28 @@ func (s *Server) Start(config map[string]string) chan bool {
 			s.customErrorLogger("Error shutdowning server - error: %s", err.Error())
 		}
 
+		// Release the store
+		err = s.store.Close()
+
+		if err != nil {
+			s.customErrorLogger("Error closing store - error: %s", err.Error())
+		}
+
 		// CLose the logfile
-		logFile.Close()
+		if logFileOn {
+			logFile.Close()
+		}
+
+		// Tells the caller the shutdown is over
+		exitChan <- true
 	}()
 	go func() {
 		log.Printf("Listening on... %s", s.Addr)
-		log.Fatal(http.ListenAndServe(addr, s.router))
+		err := s.Server.ListenAndServe()
+
+		if err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
 	}()
 
 	return exitChan
@@ -95,13 +144,31 @@ func (s *Server) Start(config map[string]string) chan bool {
 
 // addRoutes - Define API routes
 func (s *Server) addRoutes() {
+	/** ACCOUNT ENDPOINTS, outside any household **/
+	accountRoutes := s.router.PathPrefix("/hrs").Subrouter()
+	accountRoutes.Use(s.authenticate, s.personal)
+	s.addAuthRoutes(accountRoutes)
+	s.addHouseholdRoutes(accountRoutes)
+	s.addAPIKeyRoutes(accountRoutes)
+	s.addOIDCRoutes()
+
+	// Everything else is data of the household the request acts on
 	hrsRoutes := s.router.PathPrefix("/hrs").Subrouter()
+	hrsRoutes.Use(s.authenticate, s.authorize, s.scope)
 
 	/** RECIPES ENDPOINTS**/
 	hrsRoutes.HandleFunc("/recipes", func(w http.ResponseWriter, r *http.Request) {
+		if hasContentType(r, hrsjsonld.MEDIATYPE) {
+			s.importLinkedData(w, r)
+			return
+		}
+		if hasContentType(r, hrscooklang.MEDIATYPE) {
+			s.importCooklang(w, r)
+			return
+		}
 		s.logger.Debugln("creating recipe...")
 
-		var recipe hrstypes.Recipe
+		var recipe hrsmodel.Recipe
 		var data []byte
 		var err error
 
@@ -116,7 +183,7 @@ func (s *Server) addRoutes() {
 			decodeError(&hrsResp, &data, &err)
 			status = http.StatusConflict
 		} else {
-			hrsResp = s.worker.CreateRecipe(&recipe)
+			hrsResp = s.tenant(r).CreateRecipe(&recipe)
 
 			data, err = json.Marshal(hrsResp)
 
@@ -139,14 +206,86 @@ func (s *Server) addRoutes() {
 		w.Write(data)
 	}).Methods("POST")
 
-	hrsRoutes.HandleFunc("/recipes/{id}", func(w http.ResponseWriter, r *http.Request) {
-		s.logger.Debugln("searching recipe...")
+	hrsRoutes.HandleFunc("/recipes", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("listing recipes...")
+		var data []byte
+
 		status := http.StatusOK
+		hrsResp := initResponse()
 
-		vars := mux.Vars(r)
-		id := vars["id"]
+		q, err := parseQuery(r)
+
+		if err != nil {
+			decodeError(&hrsResp, &data, &err)
+			status = http.StatusConflict
+		} else {
+			hrsResp = s.tenant(r).ListRecipes(q)
+			data, err = json.Marshal(hrsResp)
 
-		hrsResp := s.worker.GetRecipeByID(id)
+			if err != nil {
+				status = http.StatusConflict
+				s.customErrorLogger("Json marshaling error - error: %s", err.Error())
+				marshallError(&hrsResp, &data, &err)
+			} else {
+				if hrsResp.Error != nil {
+					s.customErrorLogger(hrsResp.Error.ShowError())
+					status = hrsResp.Status.Code
+				} else {
+					s.customInfoLogger("Recipes returned:\n%s", hrsResp.RespObj.GetObjectInfo())
+				}
+			}
+		}
+
+		w.WriteHeader(status)
+		w.Write(data)
+	}).Methods("GET")
+
+	hrsRoutes.HandleFunc("/recipes/match", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("matching recipes...")
+
+		var req MatchRequest
+		var data []byte
+		var err error
+
+		status := http.StatusOK
+		hrsResp := initResponse()
+
+		decoder := json.NewDecoder(r.Body)
+		err = decoder.Decode(&req)
+		defer r.Body.Close()
+
+		if err != nil {
+			decodeError(&hrsResp, &data, &err)
+			status = http.StatusConflict
+		} else {
+			hrsResp = s.tenant(r).MatchRecipes(&req)
+			data, err = json.Marshal(hrsResp)
+
+			if err != nil {
+				status = http.StatusConflict
+				s.customErrorLogger("Json marshaling error - error: %s", err.Error())
+				marshallError(&hrsResp, &data, &err)
+			} else {
+				if hrsResp.Error != nil {
+					s.customErrorLogger(hrsResp.Error.ShowError())
+					status = hrsResp.Status.Code
+				} else {
+					s.customInfoLogger("Recipes matched:\n%s", hrsResp.RespObj.GetObjectInfo())
+				}
+			}
+		}
+
+		w.WriteHeader(status)
+		w.Write(data)
+	}).Methods("POST")
+
+	hrsRoutes.HandleFunc("/recipes/search", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("full text searching recipes...")
+		status := http.StatusOK
+
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+		hrsResp := s.tenant(r).SearchRecipes(r.URL.Query().Get("q"), limit)
 
 		data, err := json.Marshal(hrsResp)
 
@@ -159,12 +298,33 @@ func (s *Server) addRoutes() {
 				s.customErrorLogger(hrsResp.Error.ShowError())
 				status = hrsResp.Status.Code
 			} else {
-				if hrsResp.Error != nil {
-					s.customErrorLogger(hrsResp.Error.ShowError())
-					status = hrsResp.Status.Code
-				} else {
-					s.customInfoLogger("Recipe returned:\n%s", hrsResp.RespObj.GetObjectInfo())
-				}
+				s.customInfoLogger("Recipes found:\n%s", hrsResp.RespObj.GetObjectInfo())
+			}
+		}
+
+		w.WriteHeader(status)
+		w.Write(data)
+	}).Methods("GET")
+
+	hrsRoutes.HandleFunc("/recipes/{id}/nutrition", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("computing recipe nutrition...")
+		status := http.StatusOK
+
+		vars := mux.Vars(r)
+		hrsResp := s.tenant(r).GetRecipeNutritionByID(vars["id"])
+
+		data, err := json.Marshal(hrsResp)
+
+		if err != nil {
+			status = http.StatusConflict
+			s.customErrorLogger("Json marshaling error - error: %s", err.Error())
+			marshallError(&hrsResp, &data, &err)
+		} else {
+			if hrsResp.Error != nil {
+				s.customErrorLogger(hrsResp.Error.ShowError())
+				status = hrsResp.Status.Code
+			} else {
+				s.customInfoLogger("Nutrition returned:\n%s", hrsResp.RespObj.GetObjectInfo())
 			}
 		}
 
@@ -172,10 +332,34 @@ func (s *Server) addRoutes() {
 		w.Write(data)
 	}).Methods("GET")
 
+	hrsRoutes.HandleFunc("/recipes/{id}", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("searching recipe...")
+		vars := mux.Vars(r)
+		id := vars["id"]
+
+		var hrsResp hrstypes.HRAResponse
+		if view, ok := parseView(r); ok {
+			hrsResp = s.tenant(r).GetRecipeViewByID(id, view)
+		} else {
+			hrsResp = s.tenant(r).GetRecipeByID(id)
+		}
+
+		if hrsResp.Error == nil && accepts(r, hrsjsonld.MEDIATYPE) {
+			s.writeLinkedData(w, r, hrsResp.RespObj.(*hrsmodel.Recipe))
+			return
+		}
+		if hrsResp.Error == nil && accepts(r, hrscooklang.MEDIATYPE) {
+			s.writeCooklang(w, r, hrsResp.RespObj.(*hrsmodel.Recipe))
+			return
+		}
+
+		s.writeResponse(w, hrsResp, "Recipe returned")
+	}).Methods("GET")
+
 	hrsRoutes.HandleFunc("/recipes/{id}", func(w http.ResponseWriter, r *http.Request) {
 		s.logger.Debugln("patchting recipe...")
 		var data []byte
-		var recipe hrstypes.Recipe
+		var recipe hrsmodel.Recipe
 
 		status := http.StatusOK
 		vars := mux.Vars(r)
@@ -190,7 +374,7 @@ func (s *Server) addRoutes() {
 			decodeError(&hrsResp, &data, &err)
 			status = http.StatusConflict
 		} else {
-			hrsResp = s.worker.PatchRecipeByID(id, &recipe)
+			hrsResp = s.tenant(r).PatchRecipeByID(id, &recipe)
 			data, err = json.Marshal(hrsResp)
 
 			if err != nil {
@@ -217,7 +401,7 @@ func (s *Server) addRoutes() {
 		vars := mux.Vars(r)
 		id := vars["id"]
 
-		hrsResp := s.worker.DeleteRecipe(id)
+		hrsResp := s.tenant(r).DeleteRecipe(id)
 		data, err := json.Marshal(hrsResp)
 
 		if err != nil {
@@ -248,14 +432,14 @@ func (s *Server) addRoutes() {
 		hrsResp := initResponse()
 
 		decoder := json.NewDecoder(r.Body)
-		var ingredient hrstypes.Ingredient
+		var ingredient hrsmodel.Ingredient
 		err = decoder.Decode(&ingredient)
 
 		if err != nil {
 			decodeError(&hrsResp, &data, &err)
 			status = http.StatusConflict
 		} else {
-			hrsResp := s.worker.CreateIngredient(&ingredient)
+			hrsResp := s.tenant(r).CreateIngredient(&ingredient)
 			data, err = json.Marshal(hrsResp)
 
 			if err != nil {
@@ -277,13 +461,47 @@ func (s *Server) addRoutes() {
 		w.Write(data)
 	}).Methods("POST")
 
+	hrsRoutes.HandleFunc("/ingredients", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("listing ingredients...")
+		var data []byte
+
+		status := http.StatusOK
+		hrsResp := initResponse()
+
+		q, err := parseQuery(r)
+
+		if err != nil {
+			decodeError(&hrsResp, &data, &err)
+			status = http.StatusConflict
+		} else {
+			hrsResp = s.tenant(r).ListIngredients(q)
+			data, err = json.Marshal(hrsResp)
+
+			if err != nil {
+				status = http.StatusConflict
+				s.customErrorLogger("Json marshaling error - error: %s", err.Error())
+				marshallError(&hrsResp, &data, &err)
+			} else {
+				if hrsResp.Error != nil {
+					s.customErrorLogger(hrsResp.Error.ShowError())
+					status = hrsResp.Status.Code
+				} else {
+					s.customInfoLogger("Ingredients returned:\n%s", hrsResp.RespObj.GetObjectInfo())
+				}
+			}
+		}
+
+		w.WriteHeader(status)
+		w.Write(data)
+	}).Methods("GET")
+
 	hrsRoutes.HandleFunc("/ingredients/{id}", func(w http.ResponseWriter, r *http.Request) {
 		s.logger.Debugln("searching ingredients...")
 		status := http.StatusOK
 		vars := mux.Vars(r)
 		id := vars["id"]
 
-		hrsResp := s.worker.GetIngredientByID(id)
+		hrsResp := s.tenant(r).GetIngredientByID(id)
 		data, err := json.Marshal(hrsResp)
 
 		if err != nil {
@@ -306,7 +524,7 @@ func (s *Server) addRoutes() {
 	hrsRoutes.HandleFunc("/ingredients/{id}", func(w http.ResponseWriter, r *http.Request) {
 		s.logger.Debugln("patching ingredients...")
 		var data []byte
-		var ingredient hrstypes.Ingredient
+		var ingredient hrsmodel.Ingredient
 
 		status := http.StatusOK
 		hrsResp := initResponse()
@@ -321,7 +539,7 @@ func (s *Server) addRoutes() {
 			decodeError(&hrsResp, &data, &err)
 			status = http.StatusConflict
 		} else {
-			hrsResp = s.worker.PatchIngredientByID(id, &ingredient)
+			hrsResp = s.tenant(r).PatchIngredientByID(id, &ingredient)
 			data, err = json.Marshal(hrsResp)
 
 			if err != nil {
@@ -348,7 +566,7 @@ func (s *Server) addRoutes() {
 		vars := mux.Vars(r)
 		id := vars["id"]
 
-		hrsResp := s.worker.DeleteIngredient(id)
+		hrsResp := s.tenant(r).DeleteIngredient(id)
 		data, err := json.Marshal(hrsResp)
 
 		if err != nil {
@@ -368,6 +586,61 @@ func (s *Server) addRoutes() {
 		w.Write(data)
 	}).Methods("DELETE")
 
+	/** SHOPPING LIST ENDPOINTS **/
+	s.addShoppingRoutes(hrsRoutes)
+
+	/** MEAL PLAN ENDPOINTS **/
+	s.addMealPlanRoutes(hrsRoutes)
+
+	/** PANTRY ENDPOINTS **/
+	s.addPantryRoutes(hrsRoutes)
+
+	/** EXPORT AND IMPORT ENDPOINTS **/
+	s.addArchiveRoutes(hrsRoutes)
+
+	/** SHARE LINK ENDPOINTS **/
+	s.addShareRoutes(hrsRoutes)
+
+	/** PARSE ENDPOINTS **/
+	hrsRoutes.HandleFunc("/parse/ingredient-lines", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("parsing ingredient lines...")
+
+		var req ParseRequest
+		var data []byte
+		var err error
+
+		status := http.StatusOK
+		hrsResp := initResponse()
+
+		decoder := json.NewDecoder(r.Body)
+		err = decoder.Decode(&req)
+		defer r.Body.Close()
+
+		if err != nil {
+			decodeError(&hrsResp, &data, &err)
+			status = http.StatusConflict
+		} else {
+			hrsResp = s.tenant(r).ParseIngredientLines(&req)
+			data, err = json.Marshal(hrsResp)
+
+			if err != nil {
+				status = http.StatusConflict
+				s.customErrorLogger("Json marshaling error - error: %s", err.Error())
+				marshallError(&hrsResp, &data, &err)
+			} else {
+				if hrsResp.Error != nil {
+					s.customErrorLogger(hrsResp.Error.ShowError())
+					status = hrsResp.Status.Code
+				} else {
+					s.customInfoLogger("Ingredient lines parsed:\n%s", hrsResp.RespObj.GetObjectInfo())
+				}
+			}
+		}
+
+		w.WriteHeader(status)
+		w.Write(data)
+	}).Methods("POST")
+
 	/** OTHER ENDPOINTS **/
 	hrsRoutes.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintf(w, "WTF\n")
@@ -376,11 +649,113 @@ func (s *Server) addRoutes() {
 
 /** PRIVATE METHODS **/
 
+// accountless - whether the store keeps no users nor households, as the
+// mongo one
+func accountless(store hrsstore.Store) bool {
+	_, err := store.GetUser("")
+	return errors.Is(err, hrsstore.ErrUnsupported)
+}
+
 func initResponse() hrstypes.HRAResponse {
 	resp := hrstypes.HRAResponse{}
 	return resp
 }
 
+// parseQuery - reads the list criteria from the query string:
+// limit, cursor, sort, name (prefix) and ingredient (repeated or comma separated)
+func parseQuery(r *http.Request) (hrsstore.Query, error) {
+	values := r.URL.Query()
+	q := hrsstore.Query{
+		Cursor:     values.Get("cursor"),
+		Sort:       values.Get("sort"),
+		NamePrefix: values.Get("name"),
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return q, err
+		}
+		q.Limit = n
+	}
+
+	for _, value := range values["ingredient"] {
+		for _, id := range strings.Split(value, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				q.Ingredients = append(q.Ingredients, id)
+			}
+		}
+	}
+	return q, nil
+}
+
+// parseView - reads how a recipe should be rendered: ?servings, and ?units
+// or the Accept-Units header. ok is false when the request asks for none.
+func parseView(r *http.Request) (view RecipeView, ok bool) {
+	values := r.URL.Query()
+
+	if servings := values.Get("servings"); servings != "" {
+		n, err := strconv.Atoi(servings)
+		if err != nil || n <= 0 {
+			n = -1
+		}
+		view.Servings = n
+	}
+
+	view.Units = values.Get("units")
+	if view.Units == "" {
+		view.Units = r.Header.Get("Accept-Units")
+	}
+	view.Units = strings.ToLower(strings.TrimSpace(view.Units))
+
+	return view, view.Servings != 0 || view.Units != ""
+}
+
+// decodeBody - decodes the request body into v; when it can't, the decode
+// error is written and false returned
+func (s *Server) decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	defer r.Body.Close()
+
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err == nil {
+		return true
+	}
+
+	var data []byte
+	hrsResp := initResponse()
+	decodeError(&hrsResp, &data, &err)
+
+	w.WriteHeader(http.StatusConflict)
+	w.Write(data)
+	return false
+}
+
+// writeResponse - marshals a worker response and writes it with the status
+// it carries, logging info along the returned element
+func (s *Server) writeResponse(w http.ResponseWriter, hrsResp hrstypes.HRAResponse, info string) {
+	status := hrsResp.Status.Code
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	data, err := json.Marshal(hrsResp)
+
+	if err != nil {
+		status = http.StatusConflict
+		s.customErrorLogger("Json marshaling error - error: %s", err.Error())
+		marshallError(&hrsResp, &data, &err)
+	} else if hrsResp.Error != nil {
+		s.customErrorLogger(hrsResp.Error.ShowError())
+	} else if hrsResp.RespObj != nil {
+		s.customInfoLogger("%s:\n%s", info, hrsResp.RespObj.GetObjectInfo())
+	} else {
+		s.customInfoLogger(info)
+	}
+
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
 func fatalResponse(err error) hrstypes.HRAResponse {
 	status := hrstypes.Status{
 		Code:        http.StatusConflict,
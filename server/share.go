@@ -0,0 +1,311 @@
+package server
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/ninh0gauch0/homerecipes/hrsauth"
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/homerecipes/hrsstore"
+	"github.com/ninh0gauch0/hrstypes"
+)
+
+const (
+	// SHARENOTFOUND Constant
+	SHARENOTFOUND = "The link doesn't exist, expired or was revoked"
+	// SHAREPOLICY Constant
+	SHAREPOLICY = "default-src 'none'; style-src 'unsafe-inline'"
+)
+
+// recipePage - the printable page of a shared recipe
+var recipePage = template.Must(template.New("recipe").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{.Name}}</title>
+<style>
+body { font-family: Georgia, serif; max-width: 40em; margin: 2em auto; padding: 0 1em; line-height: 1.5; color: #222; }
+h1 { margin-bottom: 0.2em; }
+.facts { color: #555; }
+li { margin-bottom: 0.3em; }
+@media print {
+	body { max-width: none; margin: 0; font-size: 12pt; }
+	h2 { page-break-after: avoid; }
+	li { page-break-inside: avoid; }
+}
+</style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+{{with .Servings}}<p class="facts">Serves {{.}}</p>{{end}}
+{{with .Ingredients}}<h2>Ingredients</h2>
+<ul>{{range .}}
+<li>{{.}}</li>{{end}}
+</ul>
+{{end}}
+{{with .Steps}}<h2>Steps</h2>
+<ol>{{range .}}
+<li>{{.}}</li>{{end}}
+</ol>
+{{end}}
+</body>
+</html>
+`))
+
+// addShareRoutes - the share links of the recipes, and the public route
+// reading them which, on the main router, needs no token
+func (s *Server) addShareRoutes(hrsRoutes *mux.Router) {
+	hrsRoutes.HandleFunc("/recipes/{id}/shares", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("sharing recipe...")
+
+		var share hrsmodel.Share
+		if s.decodeBody(w, r, &share) {
+			s.writeResponse(w, s.tenant(r).CreateShare(currentUser(r), mux.Vars(r)["id"], &share), "Share link created")
+		}
+	}).Methods("POST")
+
+	hrsRoutes.HandleFunc("/recipes/{id}/shares", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("listing share links...")
+		s.writeResponse(w, s.tenant(r).ListShares(mux.Vars(r)["id"]), "Share links returned")
+	}).Methods("GET")
+
+	hrsRoutes.HandleFunc("/recipes/{id}/shares/{code}", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("revoking share link...")
+		vars := mux.Vars(r)
+		s.writeResponse(w, s.tenant(r).RevokeShare(vars["id"], vars["code"]), "Share link revoked")
+	}).Methods("DELETE")
+
+	s.router.HandleFunc("/share/{code}", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("reading shared recipe...")
+
+		rsp := s.worker.GetShare(mux.Vars(r)["code"])
+		if rsp.Error == nil {
+			share := rsp.RespObj.(*hrsmodel.Share)
+			worker, err := s.householdWorker(share.Household)
+			if err != nil {
+				rsp = storeErrorResponse(err, "Household can't be opened", "Fatal error trying to open the household: ")
+			} else {
+				rsp = worker.SharedRecipe(share.Recipe)
+			}
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Referrer-Policy", "no-referrer")
+		w.Header().Set("X-Robots-Tag", "noindex")
+		if !wantsPage(r) {
+			s.writeResponse(w, rsp, "Shared recipe returned")
+			return
+		}
+		if rsp.Error != nil {
+			s.customErrorLogger(rsp.Error.ShowError())
+			http.Error(w, rsp.Error.ShowError(), rsp.Status.Code)
+			return
+		}
+		s.writeRecipePage(w, rsp.RespObj.(*PublicRecipe))
+	}).Methods("GET")
+}
+
+// CreateShare - Creates a share link to a recipe of the household; it
+// lasts until its expiry, if given, or until it is revoked
+func (w *Worker) CreateShare(user *hrsmodel.User, recipe string, share *hrsmodel.Share) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - CreateShare [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	if w.household == "" {
+		funcErr := hrstypes.FunctionalError{}
+		return generateErrorResponse(FAIL, "Only the recipes of a household can be shared", funcErr, http.StatusConflict)
+	}
+	now := time.Now().Truncate(time.Second)
+	if !share.Expires.IsZero() && !share.Expires.After(now) {
+		funcErr := hrstypes.FunctionalError{}
+		return generateErrorResponse(FAIL, "The share link would be expired already", funcErr, http.StatusConflict)
+	}
+	if _, err := w.store.GetRecipe(recipe); err != nil {
+		w.logger.Errorf("Worker - CreateShare - Error: " + err.Error())
+		return storeErrorResponse(err, "Recipe not found", "Fatal error trying to query: ")
+	}
+
+	code, err := hrsauth.NewShareCode()
+	if err != nil {
+		return generateErrorResponse(TECHNICAL, "Fatal error generating code: "+err.Error(), err, http.StatusInternalServerError)
+	}
+	share.Code, share.Household, share.Recipe, share.Created = code, w.household, recipe, now
+	if user != nil {
+		share.CreatedBy = user.Code
+	}
+
+	if err = w.store.InsertShare(share); err != nil {
+		w.logger.Errorf("Worker - CreateShare - Error: " + err.Error())
+		return storeErrorResponse(err, "Insertion can't be accomplished", "Fatal error trying to insert: ")
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusCreated,
+		Description: CREATED,
+	}
+	rsp.RespObj = share
+	rsp.SetError(nil)
+
+	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
+	w.logger.Debugf("Worker - CreateShare [OUT]")
+	return rsp
+}
+
+// ListShares - Returns the share links of a recipe of the household
+func (w *Worker) ListShares(recipe string) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - ListShares [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	shares, err := w.store.ListShares(w.household, recipe)
+	if err != nil {
+		w.logger.Errorf("Worker - ListShares - Error: " + err.Error())
+		return storeErrorResponse(err, "Query can't be accomplished", "Fatal error trying to list: ")
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: QUERIED,
+	}
+	rsp.RespObj = &Shares{Items: shares}
+	rsp.SetError(nil)
+
+	w.logger.Debugf("Worker - ListShares [OUT]")
+	return rsp
+}
+
+// RevokeShare - Removes a share link of a recipe of the household, it
+// stops working
+func (w *Worker) RevokeShare(recipe string, code string) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - RevokeShare [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	share, err := w.store.GetShare(code)
+	if err == nil && (share.Household != w.household || share.Recipe != recipe) {
+		err = hrsstore.ErrNotFound
+	}
+	if err == nil {
+		err = w.store.DeleteShare(code)
+	}
+	if err != nil {
+		w.logger.Errorf("Worker - RevokeShare - Error: " + err.Error())
+		return storeErrorResponse(err, "Deletion can't be accomplished", "Fatal error trying to delete: ")
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: REMOVED,
+	}
+	rsp.RespObj = share
+	rsp.SetError(nil)
+
+	w.logger.Debugf("Worker - RevokeShare [OUT]")
+	return rsp
+}
+
+// GetShare - Returns the share link with the code, not found when it
+// expired as well
+func (w *Worker) GetShare(code string) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - GetShare [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	share, err := w.store.GetShare(code)
+	if err == nil && share.Expired(time.Now()) {
+		err = hrsstore.ErrNotFound
+	}
+	if errors.Is(err, hrsstore.ErrNotFound) {
+		funcErr := hrstypes.FunctionalError{}
+		return generateErrorResponse(FAIL, SHARENOTFOUND, funcErr, http.StatusNotFound)
+	}
+	if err != nil {
+		w.logger.Errorf("Worker - GetShare - Error: " + err.Error())
+		return storeErrorResponse(err, "Query can't be accomplished", "Fatal error trying to query: ")
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: QUERIED,
+	}
+	rsp.RespObj = share
+	rsp.SetError(nil)
+
+	w.logger.Debugf("Worker - GetShare [OUT]")
+	return rsp
+}
+
+// SharedRecipe - Returns a recipe of the household the way share links
+// show it: its name, servings, steps and lines written out, naming their
+// ingredients instead of referencing the household ones
+func (w *Worker) SharedRecipe(id string) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - SharedRecipe [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	recipe, err := w.store.GetRecipe(id)
+	if errors.Is(err, hrsstore.ErrNotFound) {
+		funcErr := hrstypes.FunctionalError{}
+		return generateErrorResponse(FAIL, SHARENOTFOUND, funcErr, http.StatusNotFound)
+	}
+	if err != nil {
+		w.logger.Errorf("Worker - SharedRecipe - Error: " + err.Error())
+		return storeErrorResponse(err, "Query can't be accomplished", "Fatal error trying to query: ")
+	}
+
+	shared := &PublicRecipe{Name: recipe.Name, Servings: recipe.Servings, Ingredients: []string{}, Steps: recipe.Steps}
+	if shared.Steps == nil {
+		shared.Steps = []string{}
+	}
+	ingredients := w.lineIngredients(recipe)
+	for _, line := range recipe.Lines {
+		if ingredient := ingredients[line.Ingredient]; ingredient != nil && line.Name == "" {
+			line.Name = ingredient.Name
+		}
+		line.Ingredient = ""
+		shared.Ingredients = append(shared.Ingredients, line.String())
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: QUERIED,
+	}
+	rsp.RespObj = shared
+	rsp.SetError(nil)
+
+	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
+	w.logger.Debugf("Worker - SharedRecipe [OUT]")
+	return rsp
+}
+
+/** PRIVATE METHODS **/
+
+// wantsPage - whether the shared recipe is asked as a page, with
+// ?format=html or by a browser, rather than as JSON
+func wantsPage(r *http.Request) bool {
+	switch r.URL.Query().Get("format") {
+	case "html":
+		return true
+	case "json":
+		return false
+	}
+	return accepts(r, "text/html")
+}
+
+// writeRecipePage - writes the printable page of the shared recipe
+func (s *Server) writeRecipePage(w http.ResponseWriter, recipe *PublicRecipe) {
+	var page strings.Builder
+	if err := recipePage.Execute(&page, recipe); err != nil {
+		s.customErrorLogger("Recipe page error - error: %s", err.Error())
+		http.Error(w, FATALERROR, http.StatusInternalServerError)
+		return
+	}
+
+	s.customInfoLogger("Shared recipe returned as a page:\n%s", recipe.GetObjectInfo())
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", SHAREPOLICY)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(page.String()))
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+)
+
+func TestServer_shares(t *testing.T) {
+	s := newTestServer(t)
+	s.worker.CreateUser("bea", "correct horse")
+	ana := s.worker.Login("ana", "correct horse").RespObj.(*Session).Token
+	bea := s.worker.Login("bea", "correct horse").RespObj.(*Session).Token
+	serve(s, "POST", "/hrs/households", `{"name":"Casa de Bea"}`, bea)
+
+	rec := serve(s, "POST", "/hrs/households", `{"name":"Casa de Ana"}`, ana)
+	var household struct {
+		RespObj hrsmodel.Household `json:"respObj"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &household); err != nil || rec.Code != http.StatusCreated {
+		t.Fatalf("POST /households = %d %s", rec.Code, rec.Body.String())
+	}
+	serve(s, "POST", "/hrs/ingredients", `{"code":"i-egg","name":"huevo"}`, ana)
+	rec = serve(s, "POST", "/hrs/recipes", `{"name":"Tortilla <de la abuela>","servings":2,"ingredients":[{"ingredient":"i-egg","quantity":4}],
+		"steps":["Batir los huevos","Cuajar"],"stepGroups":["Antes","Después"]}`, ana)
+	var recipe struct {
+		RespObj hrsmodel.Recipe `json:"respObj"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &recipe); err != nil || rec.Code != http.StatusCreated {
+		t.Fatalf("POST /recipes = %d %s", rec.Code, rec.Body.String())
+	}
+	shares := "/hrs/recipes/" + recipe.RespObj.Code + "/shares"
+
+	share := func(body string) string {
+		rec := serve(s, "POST", shares, body, ana)
+		var created struct {
+			RespObj hrsmodel.Share `json:"respObj"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil || rec.Code != http.StatusCreated {
+			t.Fatalf("POST /shares = %d %s", rec.Code, rec.Body.String())
+		}
+		return created.RespObj.Code
+	}
+	code := share(`{}`)
+
+	// Anybody with the link reads the recipe, with the ingredients named
+	rec = serve(s, "GET", "/share/"+code, "", "")
+	var shared struct {
+		RespObj map[string]interface{} `json:"respObj"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &shared); err != nil || rec.Code != http.StatusOK ||
+		!strings.Contains(rec.Body.String(), `"ingredients":["4 huevo"]`) || strings.Contains(rec.Body.String(), "i-egg") {
+		t.Errorf("GET /share = %d %s", rec.Code, rec.Body.String())
+	}
+	for field := range shared.RespObj {
+		switch field {
+		case "name", "servings", "ingredients", "steps":
+		default:
+			t.Errorf("GET /share shows the recipe %s", field)
+		}
+	}
+	rec = serve(s, "GET", "/share/"+code, "", "", "Accept", "text/html,*/*")
+	page := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") ||
+		!strings.Contains(page, "<h1>Tortilla &lt;de la abuela&gt;</h1>") || !strings.Contains(page, "<li>4 huevo</li>") ||
+		!strings.Contains(page, "<li>Cuajar</li>") || !strings.Contains(page, "Serves 2") {
+		t.Errorf("GET /share as a page = %d %s", rec.Code, page)
+	}
+	if rec = serve(s, "GET", "/share/"+code+"?format=json", "", "", "Accept", "text/html"); !strings.Contains(rec.Body.String(), `"respObj"`) {
+		t.Errorf("GET /share?format=json = %s", rec.Body.String())
+	}
+	if rec = serve(s, "GET", "/share/"+code+"x", "", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("GET /share of an unknown code = %d", rec.Code)
+	}
+	if rec = serve(s, "GET", "/hrs/recipes/"+recipe.RespObj.Code, "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("GET of the shared recipe without token = %d", rec.Code)
+	}
+
+	// Only the writers of the household see and revoke its links
+	if rec = serve(s, "GET", shares, "", ana); !strings.Contains(rec.Body.String(), code) {
+		t.Errorf("GET /shares = %s", rec.Body.String())
+	}
+	user, _ := s.worker.TokenUser(ana)
+	reader := &hrsmodel.APIKey{Name: "reader", Scopes: []string{hrsmodel.RECIPESREAD}}
+	if rec = serve(s, "GET", shares, "", s.worker.CreateAPIKey(user, reader).RespObj.(*NewAPIKey).Key); rec.Code != http.StatusForbidden {
+		t.Errorf("GET /shares with a recipes:read key = %d", rec.Code)
+	}
+	s.anonymousRead, s.anonymousHousehold = true, household.RespObj.Code
+	if rec = serve(s, "GET", shares, "", ""); rec.Code != http.StatusForbidden {
+		t.Errorf("anonymous GET /shares = %d", rec.Code)
+	}
+	s.anonymousRead, s.anonymousHousehold = false, ""
+	if rec = serve(s, "DELETE", shares+"/"+code, "", bea); rec.Code != http.StatusConflict {
+		t.Errorf("DELETE /shares from another household = %d", rec.Code)
+	}
+	if rec = serve(s, "POST", shares, `{}`, bea); rec.Code != http.StatusConflict {
+		t.Errorf("POST /shares of another household recipe = %d", rec.Code)
+	}
+	if rec = serve(s, "DELETE", shares+"/"+code, "", ana); rec.Code != http.StatusOK {
+		t.Errorf("DELETE /shares = %d", rec.Code)
+	}
+	if rec = serve(s, "GET", "/share/"+code, "", "", "Accept", "text/html"); rec.Code != http.StatusNotFound {
+		t.Errorf("GET /share of a revoked link = %d", rec.Code)
+	}
+
+	// Links expire and go along with their recipe
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	if rec = serve(s, "POST", shares, `{"expires":"`+past+`"}`, ana); rec.Code != http.StatusConflict {
+		t.Errorf("POST /shares expired already = %d", rec.Code)
+	}
+	code = share(`{}`)
+	stored, _ := s.store.GetShare(code)
+	stored.Expires = time.Now().Add(-time.Second)
+	s.store.DeleteShare(code)
+	s.store.InsertShare(stored)
+	if rec = serve(s, "GET", "/share/"+code, "", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("GET /share of an expired link = %d", rec.Code)
+	}
+
+	code = share(`{}`)
+	serve(s, "DELETE", "/hrs/recipes/"+recipe.RespObj.Code, "", ana)
+	if _, err := s.store.GetShare(code); err == nil {
+		t.Error("share link left after removing its recipe")
+	}
+	if left, _ := s.store.ListShares(household.RespObj.Code, recipe.RespObj.Code); len(left) != 0 {
+		t.Errorf("share links left = %+v", left)
+	}
+}
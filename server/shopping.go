@@ -0,0 +1,253 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/homerecipes/hrsstore"
+	"github.com/ninh0gauch0/hrstypes"
+)
+
+// addShoppingRoutes - shopping lists endpoints
+func (s *Server) addShoppingRoutes(hrsRoutes *mux.Router) {
+	hrsRoutes.HandleFunc("/shopping-lists", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("creating shopping list...")
+
+		var list hrsmodel.ShoppingList
+		if s.decodeBody(w, r, &list) {
+			s.writeResponse(w, s.tenant(r).CreateShoppingList(&list), "Shopping list created")
+		}
+	}).Methods("POST")
+
+	hrsRoutes.HandleFunc("/shopping-lists", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("listing shopping lists...")
+
+		q, err := parseQuery(r)
+		if err != nil {
+			s.writeResponse(w, generateErrorResponse(FAIL, err.Error(), hrstypes.FunctionalError{}, http.StatusConflict), "")
+			return
+		}
+		s.writeResponse(w, s.tenant(r).ListShoppingLists(q), "Shopping lists returned")
+	}).Methods("GET")
+
+	hrsRoutes.HandleFunc("/shopping-lists/{id}", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("searching shopping list...")
+		s.writeResponse(w, s.tenant(r).GetShoppingListByID(mux.Vars(r)["id"]), "Shopping list returned")
+	}).Methods("GET")
+
+	hrsRoutes.HandleFunc("/shopping-lists/{id}", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("deleting shopping list...")
+		s.writeResponse(w, s.tenant(r).DeleteShoppingList(mux.Vars(r)["id"]), "Shopping list deleted")
+	}).Methods("DELETE")
+
+	hrsRoutes.HandleFunc("/shopping-lists/{id}/items/{item}", func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Debugln("checking shopping list item...")
+		vars := mux.Vars(r)
+
+		var check ItemCheck
+		if s.decodeBody(w, r, &check) {
+			s.writeResponse(w, s.tenant(r).CheckShoppingItem(vars["id"], vars["item"], check.Checked), "Shopping list modified")
+		}
+	}).Methods("PATCH")
+}
+
+// CreateShoppingList - Consolidates the ingredients of the list recipes,
+// scaled, and stores the list
+func (w *Worker) CreateShoppingList(list *hrsmodel.ShoppingList) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - CreateShoppingList [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	if err := list.Validate(); err != nil {
+		funcErr := hrstypes.FunctionalError{}
+		return generateErrorResponse(FAIL, err.Error(), funcErr, http.StatusConflict)
+	}
+
+	recipes, err := w.scaledRecipes(list.Recipes)
+	if err != nil {
+		w.logger.Errorf("Worker - CreateShoppingList - Error: " + err.Error())
+		return recipeErrorResponse(err)
+	}
+
+	list.Consolidate(recipes, w.recipesIngredients(recipes))
+
+	code, err := newUUID()
+	if err != nil {
+		return generateErrorResponse(TECHNICAL, "Fatal error generating code: "+err.Error(), err, http.StatusInternalServerError)
+	}
+	list.Code = code
+
+	if err = w.store.InsertShoppingList(list); err != nil {
+		w.logger.Errorf("Worker - CreateShoppingList - Error: " + err.Error())
+		return storeErrorResponse(err, "Insertion can't be accomplished", "Fatal error trying to insert: ")
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusCreated,
+		Description: CREATED,
+	}
+	rsp.RespObj = list
+	rsp.SetError(nil)
+
+	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
+	w.logger.Debugf("Worker - CreateShoppingList [OUT]")
+	return rsp
+}
+
+// GetShoppingListByID - Given an id, returns a shopping list
+func (w *Worker) GetShoppingListByID(id string) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - GetShoppingListByID [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	res, err := w.store.GetShoppingList(id)
+
+	if err != nil {
+		w.logger.Errorf("Worker - GetShoppingListByID - Error: " + err.Error())
+		return storeErrorResponse(err, "Query can't be accomplished", "Fatal error trying to query: ")
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: QUERIED,
+	}
+	rsp.RespObj = res
+	rsp.SetError(nil)
+
+	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
+	w.logger.Debugf("Worker - GetShoppingListByID [OUT]")
+	return rsp
+}
+
+// CheckShoppingItem - Ticks or unticks an item of a shopping list
+func (w *Worker) CheckShoppingItem(id string, item string, checked bool) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - CheckShoppingItem [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	res, err := w.store.CheckShoppingItem(id, item, checked)
+
+	if err != nil {
+		w.logger.Errorf("Worker - CheckShoppingItem - Error: " + err.Error())
+		return storeErrorResponse(err, "Patch can't be accomplished", "Fatal error trying to patch: ")
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: PATCHED,
+	}
+	rsp.RespObj = res
+	rsp.SetError(nil)
+
+	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
+	w.logger.Debugf("Worker - CheckShoppingItem [OUT]")
+	return rsp
+}
+
+// DeleteShoppingList - Deletes a shopping list by id
+func (w *Worker) DeleteShoppingList(id string) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - DeleteShoppingList [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	err := w.store.DeleteShoppingList(id)
+
+	if err != nil {
+		w.logger.Errorf("Worker - DeleteShoppingList - Error: " + err.Error())
+		return storeErrorResponse(err, "Remove can't be accomplished", "Fatal error trying to remove: ")
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusNoContent,
+		Description: REMOVED,
+	}
+	rsp.RespObj = nil
+	rsp.SetError(nil)
+
+	w.logger.Debugf(rsp.Status.GetObjectInfo())
+	w.logger.Debugf("Worker - DeleteShoppingList [OUT]")
+	return rsp
+}
+
+// ListShoppingLists - Returns a page of the shopping lists matching the query
+func (w *Worker) ListShoppingLists(q hrsstore.Query) hrstypes.HRAResponse {
+	w.logger.Debugf("Worker - ListShoppingLists [IN]")
+	rsp := hrstypes.HRAResponse{}
+
+	res, err := w.store.ListShoppingLists(q)
+
+	if err != nil {
+		w.logger.Errorf("Worker - ListShoppingLists - Error: " + err.Error())
+		return storeErrorResponse(err, "Query can't be accomplished", "Fatal error trying to query: ")
+	}
+
+	rsp.Status = hrstypes.Status{
+		Code:        http.StatusOK,
+		Description: QUERIED,
+	}
+	rsp.RespObj = res
+	rsp.SetError(nil)
+
+	w.logger.Debugf(rsp.RespObj.GetObjectInfo())
+	w.logger.Debugf("Worker - ListShoppingLists [OUT]")
+	return rsp
+}
+
+/** PRIVATE METHODS **/
+
+// scaledRecipes - the recipes to shop for, each scaled to its servings or
+// multiplier. Missing recipes are a validation error.
+func (w *Worker) scaledRecipes(items []hrsmodel.ShoppingRecipe) ([]*hrsmodel.Recipe, error) {
+	recipes := []*hrsmodel.Recipe{}
+
+	for _, item := range items {
+		recipe, err := w.scaledRecipe(item)
+		if err != nil {
+			return nil, err
+		}
+		recipes = append(recipes, recipe)
+	}
+	return recipes, nil
+}
+
+// scaledRecipe - the recipe scaled to the item servings or multiplier
+func (w *Worker) scaledRecipe(item hrsmodel.ShoppingRecipe) (*hrsmodel.Recipe, error) {
+	recipe, err := w.store.GetRecipe(item.Recipe)
+	if errors.Is(err, hrsstore.ErrNotFound) {
+		return nil, fmt.Errorf("%w: recipe %s doesn't exist", hrsmodel.ErrInvalid, item.Recipe)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case item.Servings > 0 && recipe.Servings > 0:
+		return recipe.Scale(item.Servings)
+	case item.Servings > 0:
+		return nil, fmt.Errorf("%w: recipe %s has no servings to scale from", hrsmodel.ErrInvalid, item.Recipe)
+	case item.Multiplier > 0:
+		return recipe.ScaleBy(item.Multiplier), nil
+	default:
+		return recipe, nil
+	}
+}
+
+// recipesIngredients - the referenced ingredients of every recipe by code
+func (w *Worker) recipesIngredients(recipes []*hrsmodel.Recipe) map[string]*hrsmodel.Ingredient {
+	ingredients := map[string]*hrsmodel.Ingredient{}
+	for _, recipe := range recipes {
+		for code, ingredient := range w.lineIngredients(recipe) {
+			ingredients[code] = ingredient
+		}
+	}
+	return ingredients
+}
+
+// recipeErrorResponse - a validation error of the referenced recipes, or
+// the store one
+func recipeErrorResponse(err error) hrstypes.HRAResponse {
+	if errors.Is(err, hrsmodel.ErrInvalid) {
+		funcErr := hrstypes.FunctionalError{}
+		return generateErrorResponse(FAIL, err.Error(), funcErr, http.StatusConflict)
+	}
+	return storeErrorResponse(err, "Recipes can't be read", "Fatal error trying to query: ")
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/hrstypes"
+)
+
+func TestWorker_ShoppingList(t *testing.T) {
+	w := newTestWorker(t)
+
+	w.CreateIngredient(&hrsmodel.Ingredient{Ingredient: hrstypes.Ingredient{Code: "i-rice", Name: "Arroz"}, Category: "Despensa"})
+	recipe := newRecipe("Paella")
+	recipe.Code, recipe.Servings = "paella", 4
+	recipe.Lines = []hrsmodel.IngredientLine{{Ingredient: "i-rice", Quantity: &hrsmodel.Quantity{Value: 400}, Unit: "g"}}
+	w.CreateRecipe(recipe)
+
+	rsp := w.CreateShoppingList(&hrsmodel.ShoppingList{Recipes: []hrsmodel.ShoppingRecipe{
+		{Recipe: "paella", Servings: 8},
+		{Recipe: "paella", Multiplier: 0.5},
+	}})
+	if rsp.Status.Code != http.StatusCreated {
+		t.Fatalf("CreateShoppingList() = %+v", rsp)
+	}
+	list := rsp.RespObj.(*hrsmodel.ShoppingList)
+	item := list.Groups[0].Items[0]
+	if list.Groups[0].Category != "Despensa" || item.Quantity.Value != 1 || item.Unit != "kg" {
+		t.Errorf("CreateShoppingList() = %+v", list.Groups)
+	}
+
+	rsp = w.CheckShoppingItem(list.Code, item.ID, true)
+	if rsp.Status.Code != http.StatusOK || !w.GetShoppingListByID(list.Code).RespObj.(*hrsmodel.ShoppingList).Item(item.ID).Checked {
+		t.Errorf("CheckShoppingItem() = %+v", rsp)
+	}
+	if rsp = w.CheckShoppingItem(list.Code, "99", true); rsp.Status.Code != http.StatusConflict {
+		t.Errorf("CheckShoppingItem() unknown item status = %d", rsp.Status.Code)
+	}
+
+	rsp = w.CreateShoppingList(&hrsmodel.ShoppingList{Recipes: []hrsmodel.ShoppingRecipe{{Recipe: "missing"}}})
+	if rsp.Status.Code != http.StatusConflict {
+		t.Errorf("CreateShoppingList() missing recipe status = %d", rsp.Status.Code)
+	}
+}
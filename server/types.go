@@ -2,9 +2,18 @@ package server
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/ninh0gauch0/homerecipes/hrsauth"
+	"github.com/ninh0gauch0/homerecipes/hrsical"
+	"github.com/ninh0gauch0/homerecipes/hrsmodel"
+	"github.com/ninh0gauch0/homerecipes/hrsoidc"
+	"github.com/ninh0gauch0/homerecipes/hrssearch"
+	"github.com/ninh0gauch0/homerecipes/hrsstore"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -13,18 +22,258 @@ import (
 // Server struct
 type Server struct {
 	LoggerTrait
-	Server      *http.Server
-	Addr        string
-	router      *mux.Router
-	Ctx         context.Context
-	worker      *Worker
-	initialized bool
+	Server        *http.Server
+	Addr          string
+	router        *mux.Router
+	Ctx           context.Context
+	worker        *Worker
+	store         hrsstore.Store
+	anonymousRead bool
+	initialized   bool
+	// the store keeps no users nor households, requests go through
+	// unauthenticated and act on the unscoped data
+	accountless bool
+	// the household anonymous reads see and the workers of the households
+	anonymousHousehold string
+	workers            map[string]*workerStart
+	mu                 sync.Mutex
+	// the OpenID Connect provider, whom it provisions where and its
+	// logins in progress
+	oidc          *hrsoidc.Provider
+	oidcHousehold string
+	oidcRole      string
+	secureCookies bool
+	logins        map[string]oidcLogin
 }
 
 // Worker struct
 type Worker struct {
 	LoggerTrait
-	Ctx context.Context
+	Ctx     context.Context
+	store   hrsstore.Store
+	index   *hrssearch.Index
+	matcher *hrssearch.IngredientIndex
+	catalog *catalog
+	signer  *hrsauth.Signer
+	// the household the store is the one of, none for the root worker
+	household string
+}
+
+/** RESPONSE TYPES **/
+
+// RecipeSearch - full text search results
+type RecipeSearch struct {
+	Query string      `json:"query"`
+	Items []RecipeHit `json:"items"`
+}
+
+// RecipeHit - a found recipe, Snippets hold the marked matches per field
+type RecipeHit struct {
+	Recipe   *hrsmodel.Recipe  `json:"recipe"`
+	Score    float64           `json:"score"`
+	Snippets map[string]string `json:"snippets"`
+}
+
+// GetObjectInfo - Interface DTOObject Implementation
+func (rs *RecipeSearch) GetObjectInfo() string {
+	return fmt.Sprintf("%d recipes found for %q", len(rs.Items), rs.Query)
+}
+
+// RecipeNutrition - the nutrition facts of a recipe
+type RecipeNutrition struct {
+	Recipe string `json:"recipe"`
+	*hrsmodel.NutritionReport
+}
+
+// GetObjectInfo - Interface DTOObject Implementation
+func (rn *RecipeNutrition) GetObjectInfo() string {
+	return fmt.Sprintf("recipe %s: %.1f kcal, %d lines not counted", rn.Recipe, rn.Total.Energy, len(rn.Issues))
+}
+
+// ItemCheck - ticks or unticks a shopping list item
+type ItemCheck struct {
+	Checked bool `json:"checked"`
+}
+
+// WeekCopy - plans the week starting on From again from To on
+type WeekCopy struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// MealPlan - the meals planned in a date range and what they need,
+// grouped the way shopping lists are
+type MealPlan struct {
+	From        string                    `json:"from"`
+	To          string                    `json:"to"`
+	Entries     []*hrsmodel.MealPlanEntry `json:"entries"`
+	Ingredients []hrsmodel.ShoppingGroup  `json:"ingredients"`
+}
+
+// GetObjectInfo - Interface DTOObject Implementation
+func (mp *MealPlan) GetObjectInfo() string {
+	return fmt.Sprintf("%d meals planned from %s to %s", len(mp.Entries), mp.From, mp.To)
+}
+
+// MealCalendar - the meal plan as calendar events
+type MealCalendar struct {
+	hrsical.Calendar
+}
+
+// GetObjectInfo - Interface DTOObject Implementation
+func (mc *MealCalendar) GetObjectInfo() string {
+	return fmt.Sprintf("%d calendar events", len(mc.Events))
+}
+
+// Pantry - pantry items; Until is the date expiring ones are listed up to
+type Pantry struct {
+	Until string                 `json:"until,omitempty"`
+	Items []*hrsmodel.PantryItem `json:"items"`
+}
+
+// GetObjectInfo - Interface DTOObject Implementation
+func (p *Pantry) GetObjectInfo() string {
+	return fmt.Sprintf("%d pantry items", len(p.Items))
+}
+
+// PantrySuggestions - the recipes using the pantry items expiring up to
+// Until
+type PantrySuggestions struct {
+	Until    string                       `json:"until"`
+	Expiring []*hrsmodel.PantryItem       `json:"expiring"`
+	Recipes  []*hrsmodel.PantrySuggestion `json:"recipes"`
+}
+
+// GetObjectInfo - Interface DTOObject Implementation
+func (ps *PantrySuggestions) GetObjectInfo() string {
+	return fmt.Sprintf("%d recipes for %d expiring pantry items", len(ps.Recipes), len(ps.Expiring))
+}
+
+// Cooking - cooks a recipe for that many servings, the recipe ones when
+// zero. Deduct takes what it uses out of the pantry.
+type Cooking struct {
+	Servings int  `json:"servings,omitempty"`
+	Deduct   bool `json:"deduct"`
+}
+
+// RecipeImport - the recipes created by an import
+type RecipeImport struct {
+	Items []*hrsmodel.Recipe `json:"items"`
+}
+
+// GetObjectInfo - Interface DTOObject Implementation
+func (ri *RecipeImport) GetObjectInfo() string {
+	return fmt.Sprintf("%d recipes imported", len(ri.Items))
+}
+
+// CollectionImport - what a bulk import did, record kind by kind;
+// Unreadable counts the records which couldn't even be read. Failures
+// lists the records left out, the first MAXFAILURES of them.
+type CollectionImport struct {
+	Mode        string          `json:"mode"`
+	Ingredients ImportCounts    `json:"ingredients"`
+	Recipes     ImportCounts    `json:"recipes"`
+	Unreadable  int             `json:"unreadable"`
+	Failures    []ImportFailure `json:"failures"`
+}
+
+// ImportCounts - how the records of a kind were imported
+type ImportCounts struct {
+	Created     int `json:"created"`
+	Overwritten int `json:"overwritten"`
+	Skipped     int `json:"skipped"`
+	Failed      int `json:"failed"`
+}
+
+// ImportFailure - a record which couldn't be imported and why
+type ImportFailure struct {
+	Position string `json:"position"`
+	Kind     string `json:"kind,omitempty"`
+	Code     string `json:"code,omitempty"`
+	Error    string `json:"error"`
+}
+
+// GetObjectInfo - Interface DTOObject Implementation
+func (ci *CollectionImport) GetObjectInfo() string {
+	return fmt.Sprintf("%d ingredients and %d recipes imported, %d records failed",
+		ci.Ingredients.Created+ci.Ingredients.Overwritten, ci.Recipes.Created+ci.Recipes.Overwritten,
+		ci.Ingredients.Failed+ci.Recipes.Failed+ci.Unreadable)
+}
+
+// Credentials - what a user logs in with
+type Credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// UserInfo - a user as shown, without its password hash
+type UserInfo struct {
+	Code     string `json:"code"`
+	Username string `json:"username"`
+}
+
+// GetObjectInfo - Interface DTOObject Implementation
+func (ui *UserInfo) GetObjectInfo() string {
+	return fmt.Sprintf("user %s (%s)", ui.Username, ui.Code)
+}
+
+// Session - a token issued on login, sent back as "Authorization: Bearer"
+type Session struct {
+	Token   string    `json:"token"`
+	Expires time.Time `json:"expires"`
+	User    *UserInfo `json:"user"`
+}
+
+// GetObjectInfo - Interface DTOObject Implementation
+func (se *Session) GetObjectInfo() string {
+	return fmt.Sprintf("session of %s until %s", se.User.Username, se.Expires.Format(time.RFC3339))
+}
+
+// Shares - the share links of a recipe
+type Shares struct {
+	Items []*hrsmodel.Share `json:"items"`
+}
+
+// GetObjectInfo - Interface DTOObject Implementation
+func (sh *Shares) GetObjectInfo() string {
+	return fmt.Sprintf("%d share links", len(sh.Items))
+}
+
+// Households - the households of a user
+type Households struct {
+	Items []*hrsmodel.Household `json:"items"`
+}
+
+// GetObjectInfo - Interface DTOObject Implementation
+func (h *Households) GetObjectInfo() string {
+	return fmt.Sprintf("%d households", len(h.Items))
+}
+
+// Invitations - the pending invitations to a household
+type Invitations struct {
+	Items []*hrsmodel.Invitation `json:"items"`
+}
+
+// GetObjectInfo - Interface DTOObject Implementation
+func (i *Invitations) GetObjectInfo() string {
+	return fmt.Sprintf("%d invitations", len(i.Items))
+}
+
+// NewAPIKey - a created API key along with the key itself, which can't
+// be read again
+type NewAPIKey struct {
+	Key string `json:"key"`
+	*hrsmodel.APIKey
+}
+
+// APIKeys - the API keys of a user
+type APIKeys struct {
+	Items []*hrsmodel.APIKey `json:"items"`
+}
+
+// GetObjectInfo - Interface DTOObject Implementation
+func (ak *APIKeys) GetObjectInfo() string {
+	return fmt.Sprintf("%d API keys", len(ak.Items))
 }
 
 /* Logger */
@@ -45,3 +294,83 @@ func (lt *LoggerTrait) SetLogger(l *log.Entry) {
 func (lt *LoggerTrait) GetLogger() *log.Entry {
 	return lt.logger
 }
+
+// RecipeView - how a recipe is rendered: Servings to scale it to, Units is
+// the measurement system, metric or imperial. Zero values keep the recipe
+// as stored.
+type RecipeView struct {
+	Servings int
+	Units    string
+}
+
+// MatchRequest - "what can I cook?" criteria. Ingredients are ids or names;
+// without MaxMissing any number of missing ingredients is accepted.
+type MatchRequest struct {
+	Ingredients []string `json:"ingredients"`
+	MaxMissing  *int     `json:"maxMissing"`
+	Limit       int      `json:"limit"`
+}
+
+// RecipeMatches - recipes covered by the available ingredients
+type RecipeMatches struct {
+	Items []RecipeMatch `json:"items"`
+}
+
+// RecipeMatch - a recipe, the share of its ingredients available and the
+// ones missing
+type RecipeMatch struct {
+	Recipe   *hrsmodel.Recipe `json:"recipe"`
+	Coverage float64          `json:"coverage"`
+	Missing  []string         `json:"missing"`
+}
+
+// GetObjectInfo - Interface DTOObject Implementation
+func (rm *RecipeMatches) GetObjectInfo() string {
+	return fmt.Sprintf("%d recipes can be cooked", len(rm.Items))
+}
+
+// ParseRequest - free text ingredient lines; with Create, names matching no
+// ingredient are added to the ingredients collection
+type ParseRequest struct {
+	Lines  []string `json:"lines"`
+	Create bool     `json:"create"`
+}
+
+// ParsedLines - the parsed ingredient lines
+type ParsedLines struct {
+	Items []ParsedLine `json:"items"`
+}
+
+// ParsedLine - a parsed line; Suggestions are ingredients close to its name
+// when it didn't match one
+type ParsedLine struct {
+	Text        string                  `json:"text"`
+	Line        hrsmodel.IngredientLine `json:"line"`
+	Suggestions []IngredientSuggestion  `json:"suggestions"`
+}
+
+// IngredientSuggestion - an ingredient and how alike its name is
+type IngredientSuggestion struct {
+	Code  string  `json:"code"`
+	Name  string  `json:"name"`
+	Score float64 `json:"score"`
+}
+
+// GetObjectInfo - Interface DTOObject Implementation
+func (pl *ParsedLines) GetObjectInfo() string {
+	return fmt.Sprintf("%d ingredient lines parsed", len(pl.Items))
+}
+
+// PublicRecipe - what a share link shows of a recipe, to anyone holding
+// it: the lines are written out, naming their ingredients
+type PublicRecipe struct {
+	Name        string   `json:"name"`
+	Servings    int      `json:"servings,omitempty"`
+	Ingredients []string `json:"ingredients"`
+	Steps       []string `json:"steps"`
+}
+
+// GetObjectInfo - Interface DTOObject Implementation
+func (p *PublicRecipe) GetObjectInfo() string {
+	return fmt.Sprintf("%s: %d ingredients, %d steps", p.Name, len(p.Ingredients), len(p.Steps))
+}